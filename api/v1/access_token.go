@@ -0,0 +1,281 @@
+package v1
+
+import (
+	"gin_pipeline/global"
+	"gin_pipeline/model"
+	"gin_pipeline/model/request"
+	"gin_pipeline/model/response"
+	"gin_pipeline/service"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"strconv"
+)
+
+var accessTokenService = new(service.AccessTokenService)
+
+// parseTokenID 解析路径中的令牌ID
+func parseTokenID(c *gin.Context) (uint, bool) {
+	tokenID, err := strconv.ParseUint(c.Param("tokenId"), 10, 32)
+	if err != nil {
+		response.FailWithMessage("无效的令牌ID", c)
+		return 0, false
+	}
+	return uint(tokenID), true
+}
+
+// GetAccessTokens 获取个人访问令牌
+// @Summary 获取个人访问令牌
+// @Description 获取当前用户的访问令牌，包括已撤销和已过期的令牌，不返回令牌明文
+// @Tags 访问令牌
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=[]model.AccessToken} "获取成功"
+// @Router /user/tokens [get]
+func GetAccessTokens(c *gin.Context) {
+	tokens, err := accessTokenService.GetTokens(c.GetUint("userId"))
+	if err != nil {
+		global.Log.Error("获取访问令牌失败", zap.Error(err))
+		response.FailWithMessage("获取访问令牌失败", c)
+		return
+	}
+
+	response.OkWithData(tokens, c)
+}
+
+// CreateAccessToken 创建个人访问令牌
+// @Summary 创建个人访问令牌
+// @Description 创建以 pat_ 开头的访问令牌，令牌明文只在创建时返回一次，通过 Authorization 请求头使用
+// @Tags 访问令牌
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param data body request.CreateAccessToken true "令牌信息"
+// @Success 200 {object} response.Response{data=service.CreatedAccessToken} "创建成功"
+// @Router /user/tokens [post]
+func CreateAccessToken(c *gin.Context) {
+	var req request.CreateAccessToken
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("参数错误: "+err.Error(), c)
+		return
+	}
+
+	userID := c.GetUint("userId")
+	token, err := accessTokenService.CreateToken(userID, userID, req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		global.Log.Error("创建访问令牌失败", zap.Error(err))
+		response.FailWithMessage("创建访问令牌失败: "+err.Error(), c)
+		return
+	}
+
+	response.OkWithData(token, c)
+}
+
+// RevokeAccessToken 撤销个人访问令牌
+// @Summary 撤销个人访问令牌
+// @Description 撤销当前用户的访问令牌，撤销后立即失效
+// @Tags 访问令牌
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param tokenId path int true "令牌ID"
+// @Success 200 {object} response.Response "撤销成功"
+// @Router /user/tokens/{tokenId} [delete]
+func RevokeAccessToken(c *gin.Context) {
+	tokenID, ok := parseTokenID(c)
+	if !ok {
+		return
+	}
+
+	if err := accessTokenService.RevokeToken(c.GetUint("userId"), tokenID); err != nil {
+		global.Log.Error("撤销访问令牌失败", zap.Error(err))
+		response.FailWithMessage("撤销访问令牌失败: "+err.Error(), c)
+		return
+	}
+
+	response.OkWithMessage("撤销成功", c)
+}
+
+// GetServiceAccounts 获取服务账号
+// @Summary 获取服务账号
+// @Description 获取所有服务账号，仅管理员可用
+// @Tags 访问令牌
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=[]model.User} "获取成功"
+// @Router /service-account [get]
+func GetServiceAccounts(c *gin.Context) {
+	users, err := accessTokenService.GetServiceAccounts()
+	if err != nil {
+		global.Log.Error("获取服务账号失败", zap.Error(err))
+		response.FailWithMessage("获取服务账号失败", c)
+		return
+	}
+
+	response.OkWithData(users, c)
+}
+
+// CreateServiceAccount 创建服务账号
+// @Summary 创建服务账号
+// @Description 创建供CI等自动化工具使用的服务账号，服务账号不能登录，只能通过访问令牌调用接口，仅管理员可用
+// @Tags 访问令牌
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param data body request.CreateServiceAccount true "服务账号信息"
+// @Success 200 {object} response.Response{data=model.User} "创建成功"
+// @Router /service-account [post]
+func CreateServiceAccount(c *gin.Context) {
+	var req request.CreateServiceAccount
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("参数错误: "+err.Error(), c)
+		return
+	}
+
+	user := model.User{
+		Username: req.Username,
+		Name:     req.Name,
+		Role:     req.Role,
+	}
+	if err := accessTokenService.CreateServiceAccount(&user); err != nil {
+		global.Log.Error("创建服务账号失败", zap.Error(err))
+		response.FailWithMessage("创建服务账号失败: "+err.Error(), c)
+		return
+	}
+
+	response.OkWithData(user, c)
+}
+
+// DeleteServiceAccount 删除服务账号
+// @Summary 删除服务账号
+// @Description 删除服务账号并撤销它的所有访问令牌，仅管理员可用
+// @Tags 访问令牌
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "服务账号ID"
+// @Success 200 {object} response.Response "删除成功"
+// @Router /service-account/{id} [delete]
+func DeleteServiceAccount(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.FailWithMessage("无效的服务账号ID", c)
+		return
+	}
+
+	if err := accessTokenService.DeleteServiceAccount(uint(id)); err != nil {
+		global.Log.Error("删除服务账号失败", zap.Error(err))
+		response.FailWithMessage("删除服务账号失败: "+err.Error(), c)
+		return
+	}
+
+	response.OkWithMessage("删除成功", c)
+}
+
+// GetServiceAccountTokens 获取服务账号的访问令牌
+// @Summary 获取服务账号的访问令牌
+// @Description 获取服务账号的访问令牌，不返回令牌明文，仅管理员可用
+// @Tags 访问令牌
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "服务账号ID"
+// @Success 200 {object} response.Response{data=[]model.AccessToken} "获取成功"
+// @Router /service-account/{id}/tokens [get]
+func GetServiceAccountTokens(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.FailWithMessage("无效的服务账号ID", c)
+		return
+	}
+
+	user, err := accessTokenService.GetServiceAccount(uint(id))
+	if err != nil {
+		response.FailWithMessage("获取访问令牌失败: "+err.Error(), c)
+		return
+	}
+	tokens, err := accessTokenService.GetTokens(user.ID)
+	if err != nil {
+		global.Log.Error("获取访问令牌失败", zap.Error(err))
+		response.FailWithMessage("获取访问令牌失败", c)
+		return
+	}
+
+	response.OkWithData(tokens, c)
+}
+
+// CreateServiceAccountToken 为服务账号创建访问令牌
+// @Summary 为服务账号创建访问令牌
+// @Description 为服务账号创建访问令牌，令牌明文只在创建时返回一次，仅管理员可用
+// @Tags 访问令牌
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "服务账号ID"
+// @Param data body request.CreateAccessToken true "令牌信息"
+// @Success 200 {object} response.Response{data=service.CreatedAccessToken} "创建成功"
+// @Router /service-account/{id}/tokens [post]
+func CreateServiceAccountToken(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.FailWithMessage("无效的服务账号ID", c)
+		return
+	}
+
+	var req request.CreateAccessToken
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("参数错误: "+err.Error(), c)
+		return
+	}
+
+	user, err := accessTokenService.GetServiceAccount(uint(id))
+	if err != nil {
+		response.FailWithMessage("创建访问令牌失败: "+err.Error(), c)
+		return
+	}
+	token, err := accessTokenService.CreateToken(user.ID, c.GetUint("userId"), req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		global.Log.Error("创建访问令牌失败", zap.Error(err))
+		response.FailWithMessage("创建访问令牌失败: "+err.Error(), c)
+		return
+	}
+
+	response.OkWithData(token, c)
+}
+
+// RevokeServiceAccountToken 撤销服务账号的访问令牌
+// @Summary 撤销服务账号的访问令牌
+// @Description 撤销服务账号的访问令牌，撤销后立即失效，仅管理员可用
+// @Tags 访问令牌
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "服务账号ID"
+// @Param tokenId path int true "令牌ID"
+// @Success 200 {object} response.Response "撤销成功"
+// @Router /service-account/{id}/tokens/{tokenId} [delete]
+func RevokeServiceAccountToken(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.FailWithMessage("无效的服务账号ID", c)
+		return
+	}
+	tokenID, ok := parseTokenID(c)
+	if !ok {
+		return
+	}
+
+	user, err := accessTokenService.GetServiceAccount(uint(id))
+	if err != nil {
+		response.FailWithMessage("撤销访问令牌失败: "+err.Error(), c)
+		return
+	}
+	if err := accessTokenService.RevokeToken(user.ID, tokenID); err != nil {
+		global.Log.Error("撤销访问令牌失败", zap.Error(err))
+		response.FailWithMessage("撤销访问令牌失败: "+err.Error(), c)
+		return
+	}
+
+	response.OkWithMessage("撤销成功", c)
+}
@@ -6,6 +6,7 @@ import (
 	"gin_pipeline/model"
 	"gin_pipeline/model/request"
 	"gin_pipeline/model/response"
+	"gin_pipeline/service"
 	"gin_pipeline/utils"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -57,6 +58,10 @@ func CreateArtifact(c *gin.Context) {
 			return
 		}
 		pipelineID = uint(id)
+		if err := permissionService.CheckPipeline(currentActor(c), pipelineID, service.RoleDeveloper); err != nil {
+			response.FailWithMessage(err.Error(), c)
+			return
+		}
 	}
 
 	if pipelineRunIDStr != "" {
@@ -129,6 +134,7 @@ func CreateArtifact(c *gin.Context) {
 		response.FailWithMessage("创建制品记录失败", c)
 		return
 	}
+	service.PublishEvent(service.NewArtifactCreated(&artifact))
 
 	// 查询完整的制品信息
 	if err := global.DB.Preload("Pipeline").Preload("User").First(&artifact, artifact.ID).Error; err != nil {
@@ -0,0 +1,62 @@
+package v1
+
+import (
+	"gin_pipeline/global"
+	"gin_pipeline/model/request"
+	"gin_pipeline/model/response"
+	"gin_pipeline/service"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+var auditService = new(service.AuditService)
+
+// GetAuditLogs 获取审计日志
+// @Summary 获取审计日志
+// @Description 分页获取变更操作的审计日志，支持按操作人、资源、操作、结果和时间筛选，仅管理员可用
+// @Tags 审计
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "页码" default(1)
+// @Param pageSize query int false "每页大小" default(10)
+// @Param actor_id query int false "操作人ID"
+// @Param resource_type query string false "资源类型，如 pipeline, dag, environment, release"
+// @Param resource_id query string false "资源ID"
+// @Param action query string false "操作，如 create, update, delete, trigger, cancel, rollback, activate"
+// @Param outcome query string false "结果: success, failure"
+// @Param since query string false "开始时间(RFC3339)"
+// @Param until query string false "结束时间(RFC3339)"
+// @Success 200 {object} response.Response{data=response.PageResult{list=[]model.AuditLog}} "获取成功"
+// @Router /audit [get]
+func GetAuditLogs(c *gin.Context) {
+	var req request.AuditLogSearch
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.FailWithMessage("参数错误: "+err.Error(), c)
+		return
+	}
+	page := req.GetPage()
+	pageSize := req.GetPageSize()
+
+	logs, total, err := auditService.GetAuditLogs(service.AuditFilter{
+		ActorID:      req.ActorID,
+		ResourceType: req.ResourceType,
+		ResourceID:   req.ResourceID,
+		Action:       req.Action,
+		Outcome:      req.Outcome,
+		Since:        req.Since,
+		Until:        req.Until,
+	}, page, pageSize)
+	if err != nil {
+		global.Log.Error("获取审计日志失败", zap.Error(err))
+		response.FailWithMessage("获取审计日志失败", c)
+		return
+	}
+
+	response.OkWithData(response.PageResult{
+		List:     logs,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}, c)
+}
@@ -5,6 +5,7 @@ import (
 	"gin_pipeline/model"
 	"gin_pipeline/model/request"
 	"gin_pipeline/model/response"
+	"gin_pipeline/service"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
@@ -219,9 +220,9 @@ func UpdateBuildTemplate(c *gin.Context) {
 		return
 	}
 
-	// 检查权限
-	if template.CreatedBy != userID {
-		response.FailWithMessage("无权更新此模板", c)
+	// 检查权限，创建者和maintainer可以更新
+	if err := permissionService.CheckOwned(currentActor(c), template.CreatedBy, service.RoleMaintainer); err != nil {
+		response.FailWithMessage("无权更新此模板: "+err.Error(), c)
 		return
 	}
 
@@ -278,9 +279,9 @@ func DeleteBuildTemplate(c *gin.Context) {
 		return
 	}
 
-	// 检查权限
-	if template.CreatedBy != userID {
-		response.FailWithMessage("无权删除此模板", c)
+	// 检查权限，创建者和maintainer可以删除
+	if err := permissionService.CheckOwned(currentActor(c), template.CreatedBy, service.RoleMaintainer); err != nil {
+		response.FailWithMessage("无权删除此模板: "+err.Error(), c)
 		return
 	}
 
@@ -362,9 +363,9 @@ func ApplyBuildTemplate(c *gin.Context) {
 		}
 
 		// 检查权限
-		if pipeline.CreatorID != userID {
+		if err := permissionService.CheckPipeline(currentActor(c), pipeline.ID, service.RoleMaintainer); err != nil {
 			tx.Rollback()
-			response.FailWithMessage("无权更新此流水线", c)
+			response.FailWithMessage("无权更新此流水线: "+err.Error(), c)
 			return
 		}
 
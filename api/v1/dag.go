@@ -1,6 +1,7 @@
 package v1
 
 import (
+	"errors"
 	"gin_pipeline/global"
 	"gin_pipeline/model"
 	"gin_pipeline/model/request"
@@ -8,11 +9,24 @@ import (
 	"gin_pipeline/service"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"net/http"
 	"strconv"
 )
 
 var dagService = new(service.DAGService)
 
+// failWithDAGError 返回DAG相关的错误，验证错误时附带所有诊断
+func failWithDAGError(prefix string, err error, c *gin.Context) {
+	var validationErr *service.DAGValidationError
+	if errors.As(err, &validationErr) {
+		response.FailWithDetailed(map[string]interface{}{
+			"diagnostics": validationErr.Diagnostics,
+		}, prefix+err.Error(), c)
+		return
+	}
+	response.FailWithMessage(prefix+err.Error(), c)
+}
+
 // CreateDAG 创建DAG
 // @Summary 创建DAG
 // @Description 创建新的DAG
@@ -36,6 +50,10 @@ func CreateDAG(c *gin.Context) {
 		response.FailWithMessage("创建DAG失败", c)
 		return
 	}
+	if err := permissionService.CheckPipeline(currentActor(c), req.PipelineID, service.RoleMaintainer); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
 
 	// 创建DAG
 	dag := model.DAG{
@@ -50,7 +68,7 @@ func CreateDAG(c *gin.Context) {
 
 	if err := dagService.CreateDAG(&dag); err != nil {
 		global.Log.Error("创建DAG失败", zap.Error(err))
-		response.FailWithMessage("创建DAG失败: "+err.Error(), c)
+		failWithDAGError("创建DAG失败: ", err, c)
 		return
 	}
 
@@ -84,6 +102,108 @@ func GetDAGByID(c *gin.Context) {
 	response.OkWithData(dag, c)
 }
 
+// ExportDAG 导出DAG
+// @Summary 导出DAG
+// @Description 将DAG导出为Graphviz DOT、Mermaid或SVG，指定运行ID时按该运行的任务状态为节点着色
+// @Tags DAG管理
+// @Produce plain
+// @Produce image/svg+xml
+// @Security BearerAuth
+// @Param id path int true "DAG ID"
+// @Param format query string false "导出格式: dot, mermaid, svg，默认为svg"
+// @Param run_id query int false "运行ID"
+// @Success 200 {string} string "导出内容"
+// @Router /dag/{id}/export [get]
+func ExportDAG(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.FailWithMessage("无效的ID", c)
+		return
+	}
+
+	dag, err := dagService.GetDAGByID(uint(id))
+	if err != nil {
+		global.Log.Error("获取DAG失败", zap.Error(err))
+		response.FailWithMessage("获取DAG失败", c)
+		return
+	}
+
+	var statuses map[string]string
+	if runIDStr := c.Query("run_id"); runIDStr != "" {
+		runID, err := strconv.ParseUint(runIDStr, 10, 32)
+		if err != nil {
+			response.FailWithMessage("无效的运行ID", c)
+			return
+		}
+		var run model.PipelineRun
+		if err := global.DB.First(&run, runID).Error; err != nil {
+			response.FailWithMessage("获取运行记录失败", c)
+			return
+		}
+		if run.PipelineID != dag.PipelineID {
+			response.FailWithMessage("运行不属于该DAG的流水线", c)
+			return
+		}
+		statuses = make(map[string]string, len(run.TaskStatuses))
+		for nodeID, status := range run.TaskStatuses {
+			if s, ok := status.(string); ok {
+				statuses[nodeID] = s
+			}
+		}
+	}
+
+	content, contentType, err := service.ExportDAG(dag, c.DefaultQuery("format", service.DAGExportSVG), statuses)
+	if err != nil {
+		response.FailWithMessage("导出DAG失败: "+err.Error(), c)
+		return
+	}
+
+	c.Data(http.StatusOK, contentType, content)
+}
+
+// AnalyzeDAG 分析DAG的关键路径
+// @Summary 分析DAG的关键路径
+// @Description 根据最近的成功运行估计任务耗时，返回关键路径、总耗时、各节点的松弛时间和并行度；指定运行ID时返回该运行的预计完成时间
+// @Tags DAG管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "DAG ID"
+// @Param runs query int false "用于估计耗时的成功运行数，默认为10"
+// @Param run_id query int false "运行ID"
+// @Success 200 {object} response.Response{data=service.DAGAnalysis} "获取成功"
+// @Router /dag/{id}/analysis [get]
+func AnalyzeDAG(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.FailWithMessage("无效的ID", c)
+		return
+	}
+
+	runs, err := strconv.Atoi(c.DefaultQuery("runs", "10"))
+	if err != nil || runs <= 0 || runs > 100 {
+		response.FailWithMessage("runs必须在1到100之间", c)
+		return
+	}
+
+	var runID uint64
+	if runIDStr := c.Query("run_id"); runIDStr != "" {
+		if runID, err = strconv.ParseUint(runIDStr, 10, 32); err != nil {
+			response.FailWithMessage("无效的运行ID", c)
+			return
+		}
+	}
+
+	analysis, err := dagService.AnalyzeDAG(uint(id), runs, uint(runID))
+	if err != nil {
+		global.Log.Error("分析DAG失败", zap.Error(err))
+		response.FailWithMessage("分析DAG失败: "+err.Error(), c)
+		return
+	}
+
+	response.OkWithData(analysis, c)
+}
+
 // GetDAGsByPipelineID 获取流水线的所有DAG
 // @Summary 获取流水线的所有DAG
 // @Description 获取指定流水线的所有DAG
@@ -171,7 +291,7 @@ func UpdateDAG(c *gin.Context) {
 
 	if err := dagService.UpdateDAG(uint(id), updates); err != nil {
 		global.Log.Error("更新DAG失败", zap.Error(err))
-		response.FailWithMessage("更新DAG失败: "+err.Error(), c)
+		failWithDAGError("更新DAG失败: ", err, c)
 		return
 	}
 
@@ -214,13 +334,13 @@ func DeleteDAG(c *gin.Context) {
 
 // ValidateDAG 验证DAG
 // @Summary 验证DAG
-// @Description 验证DAG是否有效（无环）
+// @Description 检查DAG并一次返回所有问题，包括重复ID、自依赖、未知依赖和任务类型、节点配置错误、孤立节点以及环的完整路径；存在错误级别的问题时验证失败
 // @Tags DAG管理
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param data body request.ValidateDAG true "DAG节点"
-// @Success 200 {object} response.Response "验证成功"
+// @Success 200 {object} response.Response{data=map[string]interface{}} "验证成功"
 // @Router /dag/validate [post]
 func ValidateDAG(c *gin.Context) {
 	var req request.ValidateDAG
@@ -229,12 +349,47 @@ func ValidateDAG(c *gin.Context) {
 		return
 	}
 
-	if err := dagService.ValidateDAG(req.Nodes); err != nil {
-		response.FailWithMessage("DAG验证失败: "+err.Error(), c)
+	diagnostics := service.DiagnoseDAG(req.Nodes)
+	data := map[string]interface{}{
+		"valid":       !service.HasDAGErrors(diagnostics),
+		"diagnostics": diagnostics,
+	}
+	if service.HasDAGErrors(diagnostics) {
+		response.FailWithDetailed(data, "DAG验证失败", c)
+		return
+	}
+
+	response.OkWithDetailed(data, "DAG验证通过", c)
+}
+
+// ValidatePipelineFile 验证流水线文件
+// @Summary 验证流水线文件
+// @Description 解析流水线文件的YAML内容并验证其中的DAG，验证通过时返回转换后的节点
+// @Tags DAG管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param data body request.ValidatePipelineFile true "流水线文件内容"
+// @Success 200 {object} response.Response{data=[]model.DAGNode} "验证成功"
+// @Router /dag/pipeline-file/validate [post]
+func ValidatePipelineFile(c *gin.Context) {
+	var req request.ValidatePipelineFile
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("参数错误: "+err.Error(), c)
+		return
+	}
+
+	_, nodes, err := service.ParsePipelineFile([]byte(req.Content))
+	if err != nil {
+		response.FailWithMessage("流水线文件验证失败: "+err.Error(), c)
+		return
+	}
+	if err := dagService.ValidateDAG(nodes); err != nil {
+		failWithDAGError("流水线文件验证失败: ", err, c)
 		return
 	}
 
-	response.OkWithMessage("DAG验证通过", c)
+	response.OkWithDetailed(nodes, "流水线文件验证通过", c)
 }
 
 // CreateDAGVersion 创建DAG的新版本
@@ -300,13 +455,14 @@ func GetDAGHistory(c *gin.Context) {
 
 // ActivateDAG 激活DAG版本
 // @Summary 激活DAG版本
-// @Description 激活指定的DAG版本
+// @Description 激活指定的DAG版本，返回与原活动版本相比的变化；dry_run为true时只返回变化而不激活
 // @Tags DAG管理
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path int true "DAG ID"
-// @Success 200 {object} response.Response "激活成功"
+// @Param dry_run query bool false "只预览激活带来的变化"
+// @Success 200 {object} response.Response{data=service.DAGDiff} "激活成功"
 // @Router /dag/{id}/activate [post]
 func ActivateDAG(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -315,11 +471,96 @@ func ActivateDAG(c *gin.Context) {
 		return
 	}
 
+	diff, err := dagService.ActivationDiff(uint(id))
+	if err != nil {
+		global.Log.Error("比较DAG版本失败", zap.Error(err))
+		response.FailWithMessage("激活DAG失败", c)
+		return
+	}
+
+	if c.Query("dry_run") == "true" {
+		response.OkWithDetailed(diff, "激活预览", c)
+		return
+	}
+
 	if err := dagService.ActivateDAG(uint(id)); err != nil {
 		global.Log.Error("激活DAG失败", zap.Error(err))
 		response.FailWithMessage("激活DAG失败", c)
 		return
 	}
 
-	response.OkWithMessage("激活DAG成功", c)
+	response.OkWithDetailed(diff, "激活DAG成功", c)
+}
+
+// DiffDAG 比较两个DAG版本
+// @Summary 比较两个DAG版本
+// @Description 列出从id到otherId新增、删除、重命名的节点，依赖变化以及类型和配置的字段级变化；format为text时返回文本
+// @Tags DAG管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "原DAG ID"
+// @Param otherId path int true "目标DAG ID"
+// @Param format query string false "返回格式: json, text，默认为json"
+// @Success 200 {object} response.Response{data=service.DAGDiff} "获取成功"
+// @Router /dag/{id}/diff/{otherId} [get]
+func DiffDAG(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.FailWithMessage("无效的ID", c)
+		return
+	}
+	otherID, err := strconv.ParseUint(c.Param("otherId"), 10, 32)
+	if err != nil {
+		response.FailWithMessage("无效的ID", c)
+		return
+	}
+
+	diff, err := dagService.DiffDAGsByID(uint(id), uint(otherID))
+	if err != nil {
+		global.Log.Error("比较DAG版本失败", zap.Error(err))
+		response.FailWithMessage("比较DAG版本失败", c)
+		return
+	}
+
+	if c.Query("format") == "text" {
+		c.String(http.StatusOK, diff.Text)
+		return
+	}
+
+	response.OkWithData(diff, c)
+}
+
+// CreateDAGFromStages 将流水线阶段转换为DAG版本
+// @Summary 将流水线阶段转换为DAG版本
+// @Description 将流水线的阶段/作业编译为DAG并保存为新版本，同一阶段内的作业并行，阶段之间顺序执行
+// @Tags DAG管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param pipelineId path int true "流水线ID"
+// @Success 200 {object} response.Response{data=model.DAG} "创建成功"
+// @Router /dag/pipeline/{pipelineId}/from-stages [post]
+func CreateDAGFromStages(c *gin.Context) {
+	pipelineID, err := strconv.ParseUint(c.Param("pipelineId"), 10, 32)
+	if err != nil {
+		response.FailWithMessage("无效的流水线ID", c)
+		return
+	}
+
+	// 从上下文获取用户ID
+	userID := c.GetUint("userId")
+	if userID == 0 {
+		response.FailWithMessage("转换流水线阶段失败", c)
+		return
+	}
+
+	dag, err := dagService.CreateDAGFromStages(uint(pipelineID), userID)
+	if err != nil {
+		global.Log.Error("转换流水线阶段失败", zap.Error(err))
+		response.FailWithMessage("转换流水线阶段失败: "+err.Error(), c)
+		return
+	}
+
+	response.OkWithData(dag, c)
 }
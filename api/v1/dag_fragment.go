@@ -0,0 +1,148 @@
+package v1
+
+import (
+	"gin_pipeline/global"
+	"gin_pipeline/model"
+	"gin_pipeline/model/request"
+	"gin_pipeline/model/response"
+	"gin_pipeline/service"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"strconv"
+)
+
+var dagFragmentService = new(service.DAGFragmentService)
+
+// CreateDAGFragment 创建DAG片段版本
+// @Summary 创建DAG片段版本
+// @Description 创建可复用的DAG片段，同名片段已存在时创建新版本；DAG中类型为fragment的节点引用片段
+// @Tags DAG片段
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param data body request.CreateDAGFragment true "片段信息"
+// @Success 200 {object} response.Response{data=model.DAGFragment} "创建成功"
+// @Router /dag-fragment [post]
+func CreateDAGFragment(c *gin.Context) {
+	var req request.CreateDAGFragment
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("参数错误: "+err.Error(), c)
+		return
+	}
+
+	fragment := model.DAGFragment{
+		Name:        req.Name,
+		Description: req.Description,
+		Changelog:   req.Changelog,
+		Inputs:      req.Inputs,
+		Nodes:       req.Nodes,
+		CreatorID:   c.GetUint("userId"),
+	}
+
+	if err := dagFragmentService.CreateFragment(&fragment); err != nil {
+		global.Log.Error("创建DAG片段失败", zap.Error(err))
+		failWithDAGError("创建DAG片段失败: ", err, c)
+		return
+	}
+
+	response.OkWithData(fragment, c)
+}
+
+// GetDAGFragments 获取DAG片段列表
+// @Summary 获取DAG片段列表
+// @Description 获取所有片段的最新版本
+// @Tags DAG片段
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=[]model.DAGFragment} "获取成功"
+// @Router /dag-fragment [get]
+func GetDAGFragments(c *gin.Context) {
+	fragments, err := dagFragmentService.GetFragments()
+	if err != nil {
+		global.Log.Error("获取DAG片段列表失败", zap.Error(err))
+		response.FailWithMessage("获取DAG片段列表失败", c)
+		return
+	}
+
+	response.OkWithData(fragments, c)
+}
+
+// GetDAGFragmentVersions 获取DAG片段的所有版本
+// @Summary 获取DAG片段的所有版本
+// @Description 按版本号倒序获取片段的所有版本
+// @Tags DAG片段
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "片段名称"
+// @Success 200 {object} response.Response{data=[]model.DAGFragment} "获取成功"
+// @Router /dag-fragment/{name} [get]
+func GetDAGFragmentVersions(c *gin.Context) {
+	fragments, err := dagFragmentService.GetFragmentVersions(c.Param("name"))
+	if err != nil {
+		global.Log.Error("获取DAG片段版本失败", zap.Error(err))
+		response.FailWithMessage("获取DAG片段版本失败", c)
+		return
+	}
+
+	response.OkWithData(fragments, c)
+}
+
+// GetDAGFragment 获取DAG片段的指定版本
+// @Summary 获取DAG片段的指定版本
+// @Description 获取片段的指定版本，版本为latest时获取最新版本
+// @Tags DAG片段
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "片段名称"
+// @Param version path string true "版本号或latest"
+// @Success 200 {object} response.Response{data=model.DAGFragment} "获取成功"
+// @Router /dag-fragment/{name}/{version} [get]
+func GetDAGFragment(c *gin.Context) {
+	version := 0
+	if versionStr := c.Param("version"); versionStr != "latest" {
+		v, err := strconv.Atoi(versionStr)
+		if err != nil || v <= 0 {
+			response.FailWithMessage("无效的版本号", c)
+			return
+		}
+		version = v
+	}
+
+	fragment, err := dagFragmentService.GetFragment(c.Param("name"), version)
+	if err != nil {
+		global.Log.Error("获取DAG片段失败", zap.Error(err))
+		response.FailWithMessage("获取DAG片段失败", c)
+		return
+	}
+
+	response.OkWithData(fragment, c)
+}
+
+// ExpandDAGFragments 预览片段展开结果
+// @Summary 预览片段展开结果
+// @Description 将DAG节点中的片段节点展开，返回运行时实际执行的节点
+// @Tags DAG片段
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param data body request.ExpandDAG true "DAG节点"
+// @Success 200 {object} response.Response{data=[]model.DAGNode} "展开成功"
+// @Router /dag-fragment/expand [post]
+func ExpandDAGFragments(c *gin.Context) {
+	var req request.ExpandDAG
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("参数错误: "+err.Error(), c)
+		return
+	}
+
+	nodes, err := service.ExpandDAGFragments(req.Nodes)
+	if err != nil {
+		response.FailWithMessage("展开DAG片段失败: "+err.Error(), c)
+		return
+	}
+
+	response.OkWithData(nodes, c)
+}
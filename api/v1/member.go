@@ -0,0 +1,312 @@
+package v1
+
+import (
+	"gin_pipeline/global"
+	"gin_pipeline/model"
+	"gin_pipeline/model/request"
+	"gin_pipeline/model/response"
+	"gin_pipeline/service"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"strconv"
+)
+
+var (
+	memberService     = new(service.MemberService)
+	permissionService = new(service.PermissionService)
+)
+
+// currentActor 获取当前用户和全局角色
+func currentActor(c *gin.Context) service.Actor {
+	return service.Actor{ID: c.GetUint("userId"), Role: c.GetString("role")}
+}
+
+// parseMemberParams 解析路径中的资源ID和成员ID
+func parseMemberParams(c *gin.Context) (uint, uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.FailWithMessage("无效的ID", c)
+		return 0, 0, false
+	}
+	memberID, err := strconv.ParseUint(c.Param("memberId"), 10, 32)
+	if err != nil {
+		response.FailWithMessage("无效的成员ID", c)
+		return 0, 0, false
+	}
+	return uint(id), uint(memberID), true
+}
+
+// GetPipelineMembers 获取流水线成员
+// @Summary 获取流水线成员
+// @Description 获取流水线的成员及其角色，流水线创建者不在列表中但始终具有maintainer角色
+// @Tags 成员管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "流水线ID"
+// @Success 200 {object} response.Response{data=[]model.PipelineMember} "获取成功"
+// @Router /pipeline/{id}/members [get]
+func GetPipelineMembers(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.FailWithMessage("无效的流水线ID", c)
+		return
+	}
+
+	members, err := memberService.GetPipelineMembers(uint(id))
+	if err != nil {
+		global.Log.Error("获取流水线成员失败", zap.Error(err))
+		response.FailWithMessage("获取流水线成员失败", c)
+		return
+	}
+
+	response.OkWithData(members, c)
+}
+
+// AddPipelineMember 添加流水线成员
+// @Summary 添加流水线成员
+// @Description 将用户加入流水线并分配角色: viewer, developer, maintainer，需要流水线的maintainer角色
+// @Tags 成员管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "流水线ID"
+// @Param data body request.AddPipelineMember true "成员信息"
+// @Success 200 {object} response.Response{data=model.PipelineMember} "添加成功"
+// @Router /pipeline/{id}/members [post]
+func AddPipelineMember(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.FailWithMessage("无效的流水线ID", c)
+		return
+	}
+
+	var req request.AddPipelineMember
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("参数错误: "+err.Error(), c)
+		return
+	}
+
+	member := model.PipelineMember{
+		PipelineID: uint(id),
+		UserID:     req.UserID,
+		Role:       req.Role,
+	}
+	if err := memberService.AddPipelineMember(&member); err != nil {
+		global.Log.Error("添加流水线成员失败", zap.Error(err))
+		response.FailWithMessage("添加流水线成员失败: "+err.Error(), c)
+		return
+	}
+
+	response.OkWithData(member, c)
+}
+
+// UpdatePipelineMember 修改流水线成员
+// @Summary 修改流水线成员
+// @Description 修改流水线成员的角色，需要流水线的maintainer角色
+// @Tags 成员管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "流水线ID"
+// @Param memberId path int true "成员ID"
+// @Param data body request.UpdatePipelineMember true "成员角色"
+// @Success 200 {object} response.Response{data=model.PipelineMember} "修改成功"
+// @Router /pipeline/{id}/members/{memberId} [put]
+func UpdatePipelineMember(c *gin.Context) {
+	id, memberID, ok := parseMemberParams(c)
+	if !ok {
+		return
+	}
+
+	var req request.UpdatePipelineMember
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("参数错误: "+err.Error(), c)
+		return
+	}
+
+	member, err := memberService.UpdatePipelineMember(id, memberID, req.Role)
+	if err != nil {
+		global.Log.Error("修改流水线成员失败", zap.Error(err))
+		response.FailWithMessage("修改流水线成员失败: "+err.Error(), c)
+		return
+	}
+
+	response.OkWithData(member, c)
+}
+
+// RemovePipelineMember 移除流水线成员
+// @Summary 移除流水线成员
+// @Description 将用户移出流水线，需要流水线的maintainer角色
+// @Tags 成员管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "流水线ID"
+// @Param memberId path int true "成员ID"
+// @Success 200 {object} response.Response "移除成功"
+// @Router /pipeline/{id}/members/{memberId} [delete]
+func RemovePipelineMember(c *gin.Context) {
+	id, memberID, ok := parseMemberParams(c)
+	if !ok {
+		return
+	}
+
+	if err := memberService.RemovePipelineMember(id, memberID); err != nil {
+		global.Log.Error("移除流水线成员失败", zap.Error(err))
+		response.FailWithMessage("移除流水线成员失败: "+err.Error(), c)
+		return
+	}
+
+	response.OkWithMessage("移除流水线成员成功", c)
+}
+
+// GetEnvironmentMembers 获取环境成员
+// @Summary 获取环境成员
+// @Description 获取环境的成员、角色和生产环境部署权限，环境创建者不在列表中但始终具有maintainer角色和部署权限
+// @Tags 成员管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "环境ID"
+// @Success 200 {object} response.Response{data=[]model.EnvironmentMember} "获取成功"
+// @Router /environment/{id}/members [get]
+func GetEnvironmentMembers(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.FailWithMessage("无效的环境ID", c)
+		return
+	}
+
+	members, err := memberService.GetEnvironmentMembers(uint(id))
+	if err != nil {
+		global.Log.Error("获取环境成员失败", zap.Error(err))
+		response.FailWithMessage("获取环境成员失败", c)
+		return
+	}
+
+	response.OkWithData(members, c)
+}
+
+// checkGrantProduction 只有自己可以部署生产环境的用户才能授予该权限
+func checkGrantProduction(c *gin.Context, environmentID uint, canDeployProduction bool) bool {
+	if !canDeployProduction {
+		return true
+	}
+	allowed, err := permissionService.CanDeployProduction(currentActor(c), environmentID)
+	if err != nil {
+		global.Log.Error("检查生产环境部署权限失败", zap.Error(err))
+		response.FailWithMessage("检查生产环境部署权限失败", c)
+		return false
+	}
+	if !allowed {
+		response.FailWithMessage("无权授予生产环境部署权限", c)
+		return false
+	}
+	return true
+}
+
+// AddEnvironmentMember 添加环境成员
+// @Summary 添加环境成员
+// @Description 将用户加入环境并分配角色，授予生产环境部署权限要求操作人自己具有该权限，需要环境的maintainer角色
+// @Tags 成员管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "环境ID"
+// @Param data body request.AddEnvironmentMember true "成员信息"
+// @Success 200 {object} response.Response{data=model.EnvironmentMember} "添加成功"
+// @Router /environment/{id}/members [post]
+func AddEnvironmentMember(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.FailWithMessage("无效的环境ID", c)
+		return
+	}
+
+	var req request.AddEnvironmentMember
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("参数错误: "+err.Error(), c)
+		return
+	}
+	if !checkGrantProduction(c, uint(id), req.CanDeployProduction) {
+		return
+	}
+
+	member := model.EnvironmentMember{
+		EnvironmentID:       uint(id),
+		UserID:              req.UserID,
+		Role:                req.Role,
+		CanDeployProduction: req.CanDeployProduction,
+	}
+	if err := memberService.AddEnvironmentMember(&member); err != nil {
+		global.Log.Error("添加环境成员失败", zap.Error(err))
+		response.FailWithMessage("添加环境成员失败: "+err.Error(), c)
+		return
+	}
+
+	response.OkWithData(member, c)
+}
+
+// UpdateEnvironmentMember 修改环境成员
+// @Summary 修改环境成员
+// @Description 修改环境成员的角色和生产环境部署权限，需要环境的maintainer角色
+// @Tags 成员管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "环境ID"
+// @Param memberId path int true "成员ID"
+// @Param data body request.UpdateEnvironmentMember true "成员角色和权限"
+// @Success 200 {object} response.Response{data=model.EnvironmentMember} "修改成功"
+// @Router /environment/{id}/members/{memberId} [put]
+func UpdateEnvironmentMember(c *gin.Context) {
+	id, memberID, ok := parseMemberParams(c)
+	if !ok {
+		return
+	}
+
+	var req request.UpdateEnvironmentMember
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("参数错误: "+err.Error(), c)
+		return
+	}
+	if !checkGrantProduction(c, id, req.CanDeployProduction) {
+		return
+	}
+
+	member, err := memberService.UpdateEnvironmentMember(id, memberID, req.Role, req.CanDeployProduction)
+	if err != nil {
+		global.Log.Error("修改环境成员失败", zap.Error(err))
+		response.FailWithMessage("修改环境成员失败: "+err.Error(), c)
+		return
+	}
+
+	response.OkWithData(member, c)
+}
+
+// RemoveEnvironmentMember 移除环境成员
+// @Summary 移除环境成员
+// @Description 将用户移出环境，需要环境的maintainer角色
+// @Tags 成员管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "环境ID"
+// @Param memberId path int true "成员ID"
+// @Success 200 {object} response.Response "移除成功"
+// @Router /environment/{id}/members/{memberId} [delete]
+func RemoveEnvironmentMember(c *gin.Context) {
+	id, memberID, ok := parseMemberParams(c)
+	if !ok {
+		return
+	}
+
+	if err := memberService.RemoveEnvironmentMember(id, memberID); err != nil {
+		global.Log.Error("移除环境成员失败", zap.Error(err))
+		response.FailWithMessage("移除环境成员失败: "+err.Error(), c)
+		return
+	}
+
+	response.OkWithMessage("移除环境成员成功", c)
+}
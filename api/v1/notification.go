@@ -0,0 +1,462 @@
+package v1
+
+import (
+	"gin_pipeline/global"
+	"gin_pipeline/model"
+	"gin_pipeline/model/request"
+	"gin_pipeline/model/response"
+	"gin_pipeline/service"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"strconv"
+)
+
+var notificationService = new(service.NotificationService)
+
+// checkRulePipeline 不限流水线的规则只有管理员可以管理，其他规则需要流水线的maintainer角色
+func checkRulePipeline(c *gin.Context, pipelineID uint) bool {
+	var err error
+	if pipelineID == 0 {
+		err = permissionService.CheckRole(currentActor(c), service.RoleAdmin)
+	} else {
+		err = permissionService.CheckPipeline(currentActor(c), pipelineID, service.RoleMaintainer)
+	}
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return false
+	}
+	return true
+}
+
+// CreateNotificationRule 创建通知规则
+// @Summary 创建通知规则
+// @Description 创建通知规则，匹配的事件通过webhook、邮件或聊天机器人发送
+// @Tags 通知
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param data body request.CreateNotificationRule true "通知规则"
+// @Success 200 {object} response.Response{data=model.NotificationRule} "创建成功"
+// @Router /notification/rules [post]
+func CreateNotificationRule(c *gin.Context) {
+	var req request.CreateNotificationRule
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("参数错误: "+err.Error(), c)
+		return
+	}
+
+	if !checkRulePipeline(c, req.PipelineID) {
+		return
+	}
+
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+
+	rule := model.NotificationRule{
+		Name:        req.Name,
+		PipelineID:  req.PipelineID,
+		Environment: req.Environment,
+		Events:      req.Events,
+		Channel:     req.Channel,
+		URL:         req.URL,
+		Recipients:  req.Recipients,
+		Template:    req.Template,
+		ChatFormat:  req.ChatFormat,
+		Secret:      req.Secret,
+		Enabled:     enabled,
+		CreatorID:   c.GetUint("userId"),
+	}
+
+	if err := notificationService.CreateRule(&rule); err != nil {
+		global.Log.Error("创建通知规则失败", zap.Error(err))
+		response.FailWithMessage("创建通知规则失败: "+err.Error(), c)
+		return
+	}
+
+	response.OkWithData(rule, c)
+}
+
+// GetNotificationRules 获取通知规则列表
+// @Summary 获取通知规则列表
+// @Description 获取通知规则，指定流水线时返回作用于该流水线的规则（包括所有流水线的规则），非管理员必须指定自己维护的流水线且看不到所有流水线的规则
+// @Tags 通知
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param pipeline_id query int false "流水线ID"
+// @Success 200 {object} response.Response{data=[]model.NotificationRule} "获取成功"
+// @Router /notification/rules [get]
+func GetNotificationRules(c *gin.Context) {
+	var pipelineID uint64
+	if value := c.Query("pipeline_id"); value != "" {
+		var err error
+		if pipelineID, err = strconv.ParseUint(value, 10, 32); err != nil {
+			response.FailWithMessage("无效的流水线ID", c)
+			return
+		}
+	}
+
+	// 非管理员只能查看自己维护的流水线上的规则
+	actor := currentActor(c)
+	if !actor.IsAdmin() {
+		if pipelineID == 0 {
+			response.FailWithMessage("请指定流水线", c)
+			return
+		}
+		if err := permissionService.CheckPipeline(actor, uint(pipelineID), service.RoleMaintainer); err != nil {
+			response.FailWithMessage(err.Error(), c)
+			return
+		}
+	}
+
+	rules, err := notificationService.GetRules(uint(pipelineID))
+	if err != nil {
+		global.Log.Error("获取通知规则列表失败", zap.Error(err))
+		response.FailWithMessage("获取通知规则列表失败", c)
+		return
+	}
+	if !actor.IsAdmin() {
+		visible := make([]model.NotificationRule, 0, len(rules))
+		for _, rule := range rules {
+			if rule.PipelineID != 0 {
+				visible = append(visible, rule)
+			}
+		}
+		rules = visible
+	}
+
+	response.OkWithData(rules, c)
+}
+
+// GetNotificationRule 获取通知规则详情
+// @Summary 获取通知规则详情
+// @Description 根据ID获取通知规则
+// @Tags 通知
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "通知规则ID"
+// @Success 200 {object} response.Response{data=model.NotificationRule} "获取成功"
+// @Router /notification/rules/{id} [get]
+func GetNotificationRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.FailWithMessage("无效的通知规则ID", c)
+		return
+	}
+
+	rule, err := notificationService.GetRule(uint(id))
+	if err != nil {
+		global.Log.Error("获取通知规则失败", zap.Error(err))
+		response.FailWithMessage("获取通知规则失败", c)
+		return
+	}
+
+	response.OkWithData(rule, c)
+}
+
+// UpdateNotificationRule 更新通知规则
+// @Summary 更新通知规则
+// @Description 更新通知规则，未提供的字段保持不变
+// @Tags 通知
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "通知规则ID"
+// @Param data body request.UpdateNotificationRule true "通知规则"
+// @Success 200 {object} response.Response{data=model.NotificationRule} "更新成功"
+// @Router /notification/rules/{id} [put]
+func UpdateNotificationRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.FailWithMessage("无效的通知规则ID", c)
+		return
+	}
+
+	var req request.UpdateNotificationRule
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("参数错误: "+err.Error(), c)
+		return
+	}
+
+	rule, err := notificationService.GetRule(uint(id))
+	if err != nil {
+		global.Log.Error("获取通知规则失败", zap.Error(err))
+		response.FailWithMessage("更新通知规则失败: "+err.Error(), c)
+		return
+	}
+
+	if req.Name != nil {
+		rule.Name = *req.Name
+	}
+	if req.PipelineID != nil && *req.PipelineID != rule.PipelineID {
+		if !checkRulePipeline(c, *req.PipelineID) {
+			return
+		}
+		rule.PipelineID = *req.PipelineID
+	}
+	if req.Environment != nil {
+		rule.Environment = *req.Environment
+	}
+	if req.Events != nil {
+		rule.Events = req.Events
+	}
+	if req.Channel != nil {
+		rule.Channel = *req.Channel
+	}
+	if req.URL != nil {
+		rule.URL = *req.URL
+	}
+	if req.Recipients != nil {
+		rule.Recipients = req.Recipients
+	}
+	if req.Template != nil {
+		rule.Template = *req.Template
+	}
+	if req.ChatFormat != nil {
+		rule.ChatFormat = *req.ChatFormat
+	}
+	if req.Secret != nil {
+		rule.Secret = *req.Secret
+	}
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+
+	if err := notificationService.UpdateRule(rule); err != nil {
+		global.Log.Error("更新通知规则失败", zap.Error(err))
+		response.FailWithMessage("更新通知规则失败: "+err.Error(), c)
+		return
+	}
+
+	response.OkWithData(rule, c)
+}
+
+// DeleteNotificationRule 删除通知规则
+// @Summary 删除通知规则
+// @Description 删除通知规则，待重试的webhook投递不再发送
+// @Tags 通知
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "通知规则ID"
+// @Success 200 {object} response.Response "删除成功"
+// @Router /notification/rules/{id} [delete]
+func DeleteNotificationRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.FailWithMessage("无效的通知规则ID", c)
+		return
+	}
+
+	if err := notificationService.DeleteRule(uint(id)); err != nil {
+		global.Log.Error("删除通知规则失败", zap.Error(err))
+		response.FailWithMessage("删除通知规则失败: "+err.Error(), c)
+		return
+	}
+
+	response.OkWithMessage("删除通知规则成功", c)
+}
+
+// TestNotificationRule 测试通知规则
+// @Summary 测试通知规则
+// @Description 使用示例事件立即发送一次，返回投递结果
+// @Tags 通知
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "通知规则ID"
+// @Success 200 {object} response.Response{data=model.NotificationDelivery} "发送完成"
+// @Router /notification/rules/{id}/test [post]
+func TestNotificationRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.FailWithMessage("无效的通知规则ID", c)
+		return
+	}
+
+	delivery, err := notificationService.TestRule(uint(id))
+	if err != nil {
+		global.Log.Error("测试通知规则失败", zap.Error(err))
+		response.FailWithMessage("测试通知规则失败: "+err.Error(), c)
+		return
+	}
+
+	response.OkWithData(delivery, c)
+}
+
+// GetNotificationDeliveries 获取通知投递记录
+// @Summary 获取通知投递记录
+// @Description 分页获取通知投递记录，支持按规则、流水线和状态筛选，非管理员必须指定自己维护的流水线
+// @Tags 通知
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "页码" default(1)
+// @Param pageSize query int false "每页大小" default(10)
+// @Param rule_id query int false "通知规则ID"
+// @Param pipeline_id query int false "流水线ID"
+// @Param status query string false "状态: pending, success, failed"
+// @Success 200 {object} response.Response{data=response.PageResult{list=[]model.NotificationDelivery}} "获取成功"
+// @Router /notification/deliveries [get]
+func GetNotificationDeliveries(c *gin.Context) {
+	var pageInfo request.PageInfo
+	if err := c.ShouldBindQuery(&pageInfo); err != nil {
+		response.FailWithMessage("参数错误: "+err.Error(), c)
+		return
+	}
+	page := pageInfo.GetPage()
+	pageSize := pageInfo.GetPageSize()
+
+	filter := service.DeliveryFilter{Status: c.Query("status")}
+	if value := c.Query("rule_id"); value != "" {
+		ruleID, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			response.FailWithMessage("无效的通知规则ID", c)
+			return
+		}
+		filter.RuleID = uint(ruleID)
+	}
+	if value := c.Query("pipeline_id"); value != "" {
+		pipelineID, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			response.FailWithMessage("无效的流水线ID", c)
+			return
+		}
+		filter.PipelineID = uint(pipelineID)
+	}
+	// 非管理员只能查看自己维护的流水线上的投递
+	if actor := currentActor(c); !actor.IsAdmin() {
+		if filter.PipelineID == 0 {
+			response.FailWithMessage("请指定流水线", c)
+			return
+		}
+		if err := permissionService.CheckPipeline(actor, filter.PipelineID, service.RoleMaintainer); err != nil {
+			response.FailWithMessage(err.Error(), c)
+			return
+		}
+	}
+
+	deliveries, total, err := notificationService.GetDeliveries(filter, page, pageSize)
+	if err != nil {
+		global.Log.Error("获取通知投递记录失败", zap.Error(err))
+		response.FailWithMessage("获取通知投递记录失败", c)
+		return
+	}
+
+	response.OkWithData(response.PageResult{
+		List:     deliveries,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}, c)
+}
+
+// RetryNotificationDelivery 重试通知投递
+// @Summary 重试通知投递
+// @Description 立即重新发送失败的投递，仍然失败时按退避间隔继续重试
+// @Tags 通知
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "投递ID"
+// @Success 200 {object} response.Response{data=model.NotificationDelivery} "发送完成"
+// @Router /notification/deliveries/{id}/retry [post]
+func RetryNotificationDelivery(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.FailWithMessage("无效的投递ID", c)
+		return
+	}
+
+	delivery, err := notificationService.RetryDelivery(uint(id))
+	if err != nil {
+		global.Log.Error("重试通知投递失败", zap.Error(err))
+		response.FailWithMessage("重试通知投递失败: "+err.Error(), c)
+		return
+	}
+
+	response.OkWithData(delivery, c)
+}
+
+// GetNotificationSubscriptions 获取当前用户的订阅
+// @Summary 获取当前用户的订阅
+// @Description 获取当前用户订阅的流水线及事件
+// @Tags 通知
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=[]model.NotificationSubscription} "获取成功"
+// @Router /notification/subscriptions [get]
+func GetNotificationSubscriptions(c *gin.Context) {
+	subscriptions, err := notificationService.GetSubscriptions(c.GetUint("userId"))
+	if err != nil {
+		global.Log.Error("获取订阅失败", zap.Error(err))
+		response.FailWithMessage("获取订阅失败", c)
+		return
+	}
+
+	response.OkWithData(subscriptions, c)
+}
+
+// SubscribePipeline 订阅流水线
+// @Summary 订阅流水线
+// @Description 当前用户订阅流水线，事件通过邮件发送到用户的邮箱，已订阅时更新订阅的事件
+// @Tags 通知
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "流水线ID"
+// @Param data body request.SubscribePipeline true "订阅的事件"
+// @Success 200 {object} response.Response{data=model.NotificationSubscription} "订阅成功"
+// @Router /pipeline/{id}/subscription [put]
+func SubscribePipeline(c *gin.Context) {
+	pipelineID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.FailWithMessage("无效的流水线ID", c)
+		return
+	}
+
+	var req request.SubscribePipeline
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("参数错误: "+err.Error(), c)
+		return
+	}
+
+	subscription, err := notificationService.Subscribe(c.GetUint("userId"), uint(pipelineID), req.Events)
+	if err != nil {
+		global.Log.Error("订阅流水线失败", zap.Error(err))
+		response.FailWithMessage("订阅流水线失败: "+err.Error(), c)
+		return
+	}
+
+	response.OkWithData(subscription, c)
+}
+
+// UnsubscribePipeline 取消订阅流水线
+// @Summary 取消订阅流水线
+// @Description 当前用户取消订阅流水线
+// @Tags 通知
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "流水线ID"
+// @Success 200 {object} response.Response "取消订阅成功"
+// @Router /pipeline/{id}/subscription [delete]
+func UnsubscribePipeline(c *gin.Context) {
+	pipelineID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.FailWithMessage("无效的流水线ID", c)
+		return
+	}
+
+	if err := notificationService.Unsubscribe(c.GetUint("userId"), uint(pipelineID)); err != nil {
+		global.Log.Error("取消订阅流水线失败", zap.Error(err))
+		response.FailWithMessage("取消订阅流水线失败: "+err.Error(), c)
+		return
+	}
+
+	response.OkWithMessage("取消订阅成功", c)
+}
@@ -0,0 +1,167 @@
+package v1
+
+import (
+	"crypto/subtle"
+	"gin_pipeline/global"
+	"gin_pipeline/model/response"
+	"gin_pipeline/service"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var oidcService = new(service.OIDCService)
+
+// oidcStateCookie 保存登录state的Cookie，回调时与state参数比对，
+// 保证回调来自发起登录的同一浏览器，防止攻击者诱导用户登录到攻击者的账号
+const oidcStateCookie = "oidc_state"
+
+// setOIDCStateCookie 将state绑定到当前浏览器，maxAge为负数时删除
+func setOIDCStateCookie(c *gin.Context, state string, maxAge int) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/api/v1/user/oidc",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(global.Config.OIDC.RedirectURL, "https://"),
+		// 从身份提供方跳转回来是跨站的顶级导航，Lax才会携带Cookie
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// checkOIDCState 校验回调的state与Cookie一致，并删除Cookie
+func checkOIDCState(c *gin.Context) bool {
+	cookie, _ := c.Cookie(oidcStateCookie)
+	setOIDCStateCookie(c, "", -1)
+	state := c.Query("state")
+	return cookie != "" && subtle.ConstantTimeCompare([]byte(cookie), []byte(state)) == 1
+}
+
+// GetAuthOptions 获取登录方式
+// @Summary 获取登录方式
+// @Description 获取可用的登录方式，供登录页决定显示密码登录还是单点登录
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Success 200 {object} response.Response{data=map[string]interface{}} "获取成功"
+// @Router /user/auth-options [get]
+func GetAuthOptions(c *gin.Context) {
+	config := global.Config.OIDC
+	response.OkWithData(gin.H{
+		"password_login": !service.PasswordLoginDisabled(),
+		"oidc": gin.H{
+			"enabled":   config.Enabled,
+			"name":      config.Name,
+			"login_url": "/api/v1/user/oidc/login",
+		},
+	}, c)
+}
+
+// OIDCLogin 单点登录
+// @Summary 单点登录
+// @Description 跳转到OIDC身份提供方登录，使用授权码模式和PKCE
+// @Tags 用户管理
+// @Success 302 "跳转到身份提供方"
+// @Router /user/oidc/login [get]
+func OIDCLogin(c *gin.Context) {
+	if !global.Config.OIDC.Enabled {
+		response.FailWithMessage("未启用单点登录", c)
+		return
+	}
+
+	authURL, state, err := oidcService.AuthURL(c.Request.Context(), 0)
+	if err != nil {
+		global.Log.Error("生成单点登录地址失败", zap.Error(err))
+		response.FailWithMessage("单点登录失败: "+err.Error(), c)
+		return
+	}
+
+	setOIDCStateCookie(c, state, int(service.OIDCStateTTL.Seconds()))
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// LinkOIDC 关联单点登录账号
+// @Summary 关联单点登录账号
+// @Description 已登录的本地用户发起关联，返回身份提供方的授权地址，前端跳转后在回调中完成关联；关联后只能通过单点登录登录
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=map[string]string} "获取成功"
+// @Router /user/oidc/link [post]
+func LinkOIDC(c *gin.Context) {
+	if !global.Config.OIDC.Enabled {
+		response.FailWithMessage("未启用单点登录", c)
+		return
+	}
+
+	authURL, state, err := oidcService.AuthURL(c.Request.Context(), c.GetUint("userId"))
+	if err != nil {
+		global.Log.Error("生成单点登录地址失败", zap.Error(err))
+		response.FailWithMessage("关联单点登录失败: "+err.Error(), c)
+		return
+	}
+
+	setOIDCStateCookie(c, state, int(service.OIDCStateTTL.Seconds()))
+	response.OkWithData(gin.H{"auth_url": authURL}, c)
+}
+
+// OIDCCallback 单点登录回调
+// @Summary 单点登录回调
+// @Description 身份提供方登录后的回调，校验后创建会话；配置了前端地址时跳转到前端并在URL片段中携带令牌，否则返回与密码登录相同的JSON
+// @Tags 用户管理
+// @Produce json
+// @Param code query string false "授权码"
+// @Param state query string true "登录状态"
+// @Success 200 {object} response.Response{data=map[string]interface{}} "登录成功"
+// @Router /user/oidc/callback [get]
+func OIDCCallback(c *gin.Context) {
+	if !global.Config.OIDC.Enabled {
+		response.FailWithMessage("未启用单点登录", c)
+		return
+	}
+
+	if !checkOIDCState(c) {
+		response.FailWithMessage("单点登录失败: 登录状态与当前浏览器不匹配，请重新登录", c)
+		return
+	}
+
+	// 用户拒绝授权或身份提供方返回错误
+	if errorCode := c.Query("error"); errorCode != "" {
+		response.FailWithMessage("单点登录失败: "+errorCode+" "+c.Query("error_description"), c)
+		return
+	}
+
+	user, err := oidcService.Login(c.Request.Context(), c.Query("code"), c.Query("state"))
+	if err != nil {
+		global.Log.Warn("单点登录失败", zap.Error(err))
+		response.FailWithMessage("单点登录失败: "+err.Error(), c)
+		return
+	}
+
+	tokens, err := sessionService.CreateSession(c.Request.Context(), user, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		global.Log.Error("创建会话失败", zap.Error(err))
+		response.FailWithMessage("登录失败", c)
+		return
+	}
+
+	if frontend := global.Config.OIDC.FrontendRedirect; frontend != "" {
+		// 令牌放在URL片段中，不会发送到前端服务器或出现在访问日志里
+		fragment := url.Values{
+			"token":      {tokens.Token},
+			"expires_at": {strconv.FormatInt(tokens.ExpiresAt.Unix(), 10)},
+		}
+		if tokens.RefreshToken != "" {
+			fragment.Set("refresh_token", tokens.RefreshToken)
+		}
+		c.Redirect(http.StatusFound, frontend+"#"+fragment.Encode())
+		return
+	}
+
+	response.OkWithData(loginResult(user, tokens), c)
+}
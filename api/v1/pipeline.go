@@ -1,6 +1,7 @@
 package v1
 
 import (
+	"fmt"
 	"gin_pipeline/global"
 	"gin_pipeline/model"
 	"gin_pipeline/model/request"
@@ -29,6 +30,11 @@ func CreatePipeline(c *gin.Context) {
 		return
 	}
 
+	if err := service.ValidatePipelineParameters(req.Parameters); err != nil {
+		response.FailWithMessage("参数错误: "+err.Error(), c)
+		return
+	}
+
 	// 从上下文获取用户ID
 	userID := c.GetUint("userId")
 	if userID == 0 {
@@ -38,12 +44,16 @@ func CreatePipeline(c *gin.Context) {
 
 	// 创建流水线
 	pipeline := model.Pipeline{
-		Name:        req.Name,
-		Description: req.Description,
-		GitRepo:     req.GitRepo,
-		GitBranch:   req.GitBranch,
-		Status:      "inactive",
-		CreatorID:   userID,
+		Name:              req.Name,
+		Description:       req.Description,
+		GitRepo:           req.GitRepo,
+		GitBranch:         req.GitBranch,
+		Priority:          req.Priority,
+		Status:            "inactive",
+		ConcurrencyGroup:  req.ConcurrencyGroup,
+		ConcurrencyPolicy: req.ConcurrencyPolicy,
+		Parameters:        req.Parameters,
+		CreatorID:         userID,
 	}
 
 	// 开启事务
@@ -87,6 +97,7 @@ func CreatePipeline(c *gin.Context) {
 				Command:     job.Command,
 				Image:       job.Image,
 				Timeout:     job.Timeout,
+				RunsOn:      job.RunsOn,
 				StageID:     newStage.ID,
 			}
 
@@ -233,6 +244,22 @@ func UpdatePipeline(c *gin.Context) {
 	if req.Status != "" {
 		updates["status"] = req.Status
 	}
+	if req.Priority != nil {
+		updates["priority"] = *req.Priority
+	}
+	if req.ConcurrencyGroup != nil {
+		updates["concurrency_group"] = *req.ConcurrencyGroup
+	}
+	if req.ConcurrencyPolicy != nil {
+		updates["concurrency_policy"] = *req.ConcurrencyPolicy
+	}
+	if req.Parameters != nil {
+		if err := service.ValidatePipelineParameters(*req.Parameters); err != nil {
+			response.FailWithMessage("参数错误: "+err.Error(), c)
+			return
+		}
+		updates["parameters"] = model.PipelineParameters(*req.Parameters)
+	}
 
 	if err := global.DB.Model(&pipeline).Updates(updates).Error; err != nil {
 		global.Log.Error("更新流水线失败", zap.Error(err))
@@ -427,6 +454,54 @@ func GetPipelineRunByID(c *gin.Context) {
 	response.OkWithData(run, c)
 }
 
+// GetPipelineRunQueue 获取排队中流水线运行的位置和预计开始时间
+// @Summary 获取运行排队信息
+// @Description 获取等待中的流水线运行在调度队列中的位置和预计开始时间；队列是每个实例独立的，只有调度该运行的实例能返回排队信息
+// @Tags 流水线管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "流水线ID"
+// @Param runId path int true "运行记录ID"
+// @Success 200 {object} response.Response{data=service.RunQueueInfo} "获取成功"
+// @Router /pipeline/{id}/runs/{runId}/queue [get]
+func GetPipelineRunQueue(c *gin.Context) {
+	id := c.Param("id")
+	runID := c.Param("runId")
+
+	var run model.PipelineRun
+	if err := global.DB.Where("pipeline_id = ? AND id = ?", id, runID).First(&run).Error; err != nil {
+		global.Log.Error("查询流水线运行记录失败", zap.Error(err))
+		response.FailWithMessage("获取排队信息失败", c)
+		return
+	}
+
+	info := service.GetRunScheduler().QueueInfo(run.ID)
+	if info == nil {
+		if run.Status == "pending" && run.Instance != service.SchedulerInstance() {
+			response.FailWithMessage("该运行由实例 "+run.Instance+" 调度，排队信息只能在该实例上查询", c)
+			return
+		}
+		response.FailWithMessage("该运行不在队列中，当前状态: "+run.Status, c)
+		return
+	}
+
+	response.OkWithData(info, c)
+}
+
+// GetRunQueue 获取调度队列
+// @Summary 获取调度队列
+// @Description 按出队顺序获取当前实例上等待中的流水线运行及其预计开始时间，多实例部署时不包括其他实例的队列
+// @Tags 流水线管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=[]service.RunQueueInfo} "获取成功"
+// @Router /pipeline/queue [get]
+func GetRunQueue(c *gin.Context) {
+	response.OkWithData(service.GetRunScheduler().Queue(), c)
+}
+
 // GetPipelineRunLogs 获取流水线运行日志
 // @Summary 获取流水线运行日志
 // @Description 获取指定流水线运行记录的日志
@@ -489,6 +564,18 @@ func TriggerPipeline(c *gin.Context) {
 		return
 	}
 
+	// 优先级限制在固定范围内，与流水线默认值不同时需要维护者权限，避免普通成员插队
+	if req.Priority != nil && *req.Priority != pipeline.Priority {
+		if *req.Priority < service.MinRunPriority || *req.Priority > service.MaxRunPriority {
+			response.FailWithMessage(fmt.Sprintf("优先级应在 %d 到 %d 之间", service.MinRunPriority, service.MaxRunPriority), c)
+			return
+		}
+		if err := permissionService.CheckPipeline(currentActor(c), pipeline.ID, service.RoleMaintainer); err != nil {
+			response.FailWithMessage("指定优先级失败: "+err.Error(), c)
+			return
+		}
+	}
+
 	// 使用指定的分支或默认分支
 	gitBranch := req.GitBranch
 	if gitBranch == "" {
@@ -497,7 +584,12 @@ func TriggerPipeline(c *gin.Context) {
 
 	// 使用工作流服务触发流水线
 	workflowService := service.NewWorkflowService()
-	pipelineRun, err := workflowService.TriggerWorkflow(pipeline.ID, userID, gitBranch)
+	pipelineRun, err := workflowService.TriggerWorkflow(pipeline.ID, userID, service.TriggerOptions{
+		GitBranch:  gitBranch,
+		Priority:   req.Priority,
+		Parameters: req.Parameters,
+		Context:    c.Request.Context(),
+	})
 	if err != nil {
 		global.Log.Error("触发流水线失败", zap.Error(err))
 		response.FailWithMessage("触发流水线失败: "+err.Error(), c)
@@ -0,0 +1,20 @@
+package v1
+
+import (
+	"gin_pipeline/model/response"
+	"gin_pipeline/service"
+	"github.com/gin-gonic/gin"
+)
+
+// GetPlugins 获取执行器插件列表
+// @Summary 获取执行器插件列表
+// @Description 获取已加载的执行器插件及其声明的任务类型和配置Schema
+// @Tags 插件管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=[]service.Plugin} "获取成功"
+// @Router /plugin [get]
+func GetPlugins(c *gin.Context) {
+	response.OkWithData(service.GetPluginManager().Plugins(), c)
+}
@@ -5,6 +5,7 @@ import (
 	"gin_pipeline/model"
 	"gin_pipeline/model/request"
 	"gin_pipeline/model/response"
+	"gin_pipeline/service"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
@@ -23,11 +24,12 @@ import (
 // @Router /release [post]
 func CreateRelease(c *gin.Context) {
 	var req struct {
-		Version      string `json:"version" binding:"required"`
-		Description  string `json:"description"`
-		ReleaseNotes string `json:"release_notes"`
-		Environment  string `json:"environment" binding:"required"`
-		ArtifactID   uint   `json:"artifact_id" binding:"required"`
+		Version       string `json:"version" binding:"required"`
+		Description   string `json:"description"`
+		ReleaseNotes  string `json:"release_notes"`
+		Environment   string `json:"environment" binding:"required"`
+		EnvironmentID uint   `json:"environment_id"`
+		ArtifactID    uint   `json:"artifact_id" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -49,18 +51,28 @@ func CreateRelease(c *gin.Context) {
 		response.FailWithMessage("指定的制品不存在", c)
 		return
 	}
+	environment, err := permissionService.ResolveDeployEnvironment(req.EnvironmentID, req.Environment)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := permissionService.CheckDeploy(currentActor(c), artifact.PipelineID, environment, req.Environment); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
 
 	// 创建发布
 	release := model.Release{
-		Version:      req.Version,
-		Description:  req.Description,
-		ReleaseNotes: req.ReleaseNotes,
-		Status:       "pending",
-		Environment:  req.Environment,
-		ArtifactID:   req.ArtifactID,
-		DeployedAt:   time.Now(),
-		DeployedBy:   userID,
-		IsRollback:   false,
+		Version:       req.Version,
+		Description:   req.Description,
+		ReleaseNotes:  req.ReleaseNotes,
+		Status:        "pending",
+		Environment:   req.Environment,
+		ArtifactID:    req.ArtifactID,
+		EnvironmentID: deployEnvironmentID(environment),
+		DeployedAt:    time.Now(),
+		DeployedBy:    userID,
+		IsRollback:    false,
 	}
 
 	if err := global.DB.Create(&release).Error; err != nil {
@@ -82,11 +94,19 @@ func CreateRelease(c *gin.Context) {
 	response.OkWithData(release, c)
 }
 
+// deployEnvironmentID 发布记录的目标环境ID，没有登记该类型的环境时为0
+func deployEnvironmentID(environment *model.Environment) uint {
+	if environment == nil {
+		return 0
+	}
+	return environment.ID
+}
+
 // 异步执行部署（模拟）
 func deployRelease(releaseID uint) {
 	// 查询发布记录
 	var release model.Release
-	if err := global.DB.First(&release, releaseID).Error; err != nil {
+	if err := global.DB.Preload("Artifact").First(&release, releaseID).Error; err != nil {
 		global.Log.Error("查询发布记录失败", zap.Error(err), zap.Uint("releaseID", releaseID))
 		return
 	}
@@ -96,6 +116,7 @@ func deployRelease(releaseID uint) {
 		global.Log.Error("更新发布状态失败", zap.Error(err), zap.Uint("releaseID", releaseID))
 		return
 	}
+	service.PublishEvent(service.NewReleaseStarted(&release))
 
 	// 模拟部署过程
 	time.Sleep(5 * time.Second)
@@ -114,8 +135,13 @@ func deployRelease(releaseID uint) {
 
 	// 如果部署成功，更新环境的最后部署时间
 	if status == "success" {
+		// 早期的发布没有记录目标环境ID，按环境类型匹配
 		var environment model.Environment
-		if err := global.DB.Where("type = ?", release.Environment).First(&environment).Error; err == nil {
+		query := global.DB.Where("type = ?", release.Environment)
+		if release.EnvironmentID != 0 {
+			query = global.DB.Where("id = ?", release.EnvironmentID)
+		}
+		if err := query.First(&environment).Error; err == nil {
 			now := time.Now()
 			if err := global.DB.Model(&environment).Update("last_deployed_at", &now).Error; err != nil {
 				global.Log.Warn("更新环境最后部署时间失败", zap.Error(err))
@@ -123,6 +149,9 @@ func deployRelease(releaseID uint) {
 		}
 	}
 
+	release.Status = status
+	service.PublishEvent(service.NewReleaseDeployed(&release))
+
 	global.Log.Info("发布执行完成", zap.Uint("releaseID", releaseID), zap.String("status", status))
 }
 
@@ -272,18 +301,28 @@ func RollbackRelease(c *gin.Context) {
 		response.FailWithMessage("只能回滚成功的发布", c)
 		return
 	}
+	environment, err := permissionService.ResolveDeployEnvironment(originalRelease.EnvironmentID, originalRelease.Environment)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := permissionService.CheckDeploy(currentActor(c), originalRelease.Artifact.PipelineID, environment, originalRelease.Environment); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
 
 	// 创建回滚发布
 	rollbackRelease := model.Release{
-		Version:      originalRelease.Version + "-rollback",
-		Description:  "回滚到 " + originalRelease.Version,
-		ReleaseNotes: "自动回滚到版本 " + originalRelease.Version,
-		Status:       "pending",
-		Environment:  originalRelease.Environment,
-		ArtifactID:   originalRelease.ArtifactID,
-		DeployedAt:   time.Now(),
-		DeployedBy:   userID,
-		IsRollback:   true,
+		Version:       originalRelease.Version + "-rollback",
+		Description:   "回滚到 " + originalRelease.Version,
+		ReleaseNotes:  "自动回滚到版本 " + originalRelease.Version,
+		Status:        "pending",
+		Environment:   originalRelease.Environment,
+		ArtifactID:    originalRelease.ArtifactID,
+		EnvironmentID: deployEnvironmentID(environment),
+		DeployedAt:    time.Now(),
+		DeployedBy:    userID,
+		IsRollback:    true,
 	}
 
 	if err := global.DB.Create(&rollbackRelease).Error; err != nil {
@@ -0,0 +1,295 @@
+package v1
+
+import (
+	"crypto/subtle"
+	"gin_pipeline/global"
+	"gin_pipeline/model"
+	"gin_pipeline/model/request"
+	"gin_pipeline/model/response"
+	"gin_pipeline/service"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"strconv"
+	"time"
+)
+
+var runnerService = new(service.RunnerService)
+
+// currentRunner 从上下文获取当前执行器
+func currentRunner(c *gin.Context) *model.Runner {
+	value, exists := c.Get("runner")
+	if !exists {
+		return nil
+	}
+	runner, _ := value.(*model.Runner)
+	return runner
+}
+
+// RegisterRunner 注册执行器
+// @Summary 注册执行器
+// @Description 执行器使用注册令牌注册，返回后续请求使用的执行器令牌
+// @Tags 执行器管理
+// @Accept json
+// @Produce json
+// @Param data body request.RegisterRunner true "执行器信息"
+// @Success 200 {object} response.Response{data=map[string]interface{}} "注册成功"
+// @Router /runner/register [post]
+func RegisterRunner(c *gin.Context) {
+	var req request.RegisterRunner
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("参数错误: "+err.Error(), c)
+		return
+	}
+
+	// 校验注册令牌
+	if !service.RegistrationTokenConfigured() {
+		response.FailWithMessage("未配置执行器注册令牌，拒绝注册", c)
+		return
+	}
+	expected := global.Config.Runner.RegistrationToken
+	if subtle.ConstantTimeCompare([]byte(req.Token), []byte(expected)) != 1 {
+		response.FailWithMessage("注册令牌无效", c)
+		return
+	}
+
+	runner := model.Runner{
+		Name:     req.Name,
+		Labels:   req.Labels,
+		Capacity: req.Capacity,
+		Version:  req.Version,
+		IP:       c.ClientIP(),
+	}
+
+	token, err := runnerService.Register(&runner)
+	if err != nil {
+		global.Log.Error("注册执行器失败", zap.Error(err))
+		response.FailWithMessage("注册执行器失败", c)
+		return
+	}
+
+	response.OkWithData(map[string]interface{}{
+		"runner": runner,
+		"token":  token,
+	}, c)
+}
+
+// RunnerHeartbeat 执行器心跳
+// @Summary 执行器心跳
+// @Description 上报执行器标签、容量和正在运行的任务，返回需要停止的任务
+// @Tags 执行器管理
+// @Accept json
+// @Produce json
+// @Param X-Runner-Token header string true "执行器令牌"
+// @Param data body request.RunnerHeartbeat true "心跳信息"
+// @Success 200 {object} response.Response{data=map[string]interface{}} "上报成功"
+// @Router /runner/heartbeat [post]
+func RunnerHeartbeat(c *gin.Context) {
+	var req request.RunnerHeartbeat
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("参数错误: "+err.Error(), c)
+		return
+	}
+
+	runner := currentRunner(c)
+	updates := map[string]interface{}{
+		"running": len(req.Running),
+		"ip":      c.ClientIP(),
+	}
+	if req.Labels != "" {
+		updates["labels"] = req.Labels
+	}
+	if req.Capacity > 0 {
+		updates["capacity"] = req.Capacity
+	}
+	if req.Version != "" {
+		updates["version"] = req.Version
+	}
+
+	stopped, err := runnerService.Heartbeat(runner, updates, req.Running)
+	if err != nil {
+		global.Log.Error("处理执行器心跳失败", zap.Error(err))
+		response.FailWithMessage("处理心跳失败", c)
+		return
+	}
+
+	response.OkWithData(map[string]interface{}{
+		"stop": stopped,
+	}, c)
+}
+
+// PollRunnerTask 长轮询获取任务
+// @Summary 长轮询获取任务
+// @Description 执行器长轮询获取分配给自己的任务，超时未获取到任务时task为空
+// @Tags 执行器管理
+// @Accept json
+// @Produce json
+// @Param X-Runner-Token header string true "执行器令牌"
+// @Param wait query int false "最长等待时间(秒)"
+// @Success 200 {object} response.Response{data=map[string]interface{}} "获取成功"
+// @Router /runner/tasks/poll [get]
+func PollRunnerTask(c *gin.Context) {
+	maxWait := global.Config.Runner.PollTimeout
+	if maxWait <= 0 {
+		maxWait = 30
+	}
+	wait := maxWait
+	if waitStr := c.Query("wait"); waitStr != "" {
+		if n, err := strconv.Atoi(waitStr); err == nil && n >= 0 && n < maxWait {
+			wait = n
+		}
+	}
+
+	task, err := runnerService.PollTask(c.Request.Context(), currentRunner(c), time.Duration(wait)*time.Second)
+	if err != nil {
+		global.Log.Error("获取远程任务失败", zap.Error(err))
+		response.FailWithMessage("获取任务失败", c)
+		return
+	}
+
+	response.OkWithData(map[string]interface{}{
+		"task": task,
+	}, c)
+}
+
+// AppendRunnerTaskLogs 上报任务日志
+// @Summary 上报任务日志
+// @Description 追加任务日志，首次上报时任务状态变为运行中；返回任务是否已被取消
+// @Tags 执行器管理
+// @Accept json
+// @Produce json
+// @Param X-Runner-Token header string true "执行器令牌"
+// @Param taskId path int true "任务ID"
+// @Param data body request.RunnerTaskLogs true "日志"
+// @Success 200 {object} response.Response{data=map[string]interface{}} "上报成功"
+// @Router /runner/tasks/{taskId}/logs [post]
+func AppendRunnerTaskLogs(c *gin.Context) {
+	taskID, err := strconv.ParseUint(c.Param("taskId"), 10, 32)
+	if err != nil {
+		response.FailWithMessage("无效的任务ID", c)
+		return
+	}
+
+	var req request.RunnerTaskLogs
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("参数错误: "+err.Error(), c)
+		return
+	}
+
+	canceled, err := runnerService.AppendLogs(currentRunner(c), uint(taskID), req.Logs)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	response.OkWithData(map[string]interface{}{
+		"canceled": canceled,
+	}, c)
+}
+
+// CompleteRunnerTask 上报任务结果
+// @Summary 上报任务结果
+// @Description 执行器上报任务的最终状态和输出
+// @Tags 执行器管理
+// @Accept json
+// @Produce json
+// @Param X-Runner-Token header string true "执行器令牌"
+// @Param taskId path int true "任务ID"
+// @Param data body request.RunnerTaskResult true "任务结果"
+// @Success 200 {object} response.Response "上报成功"
+// @Router /runner/tasks/{taskId}/result [post]
+func CompleteRunnerTask(c *gin.Context) {
+	taskID, err := strconv.ParseUint(c.Param("taskId"), 10, 32)
+	if err != nil {
+		response.FailWithMessage("无效的任务ID", c)
+		return
+	}
+
+	var req request.RunnerTaskResult
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("参数错误: "+err.Error(), c)
+		return
+	}
+
+	if err := runnerService.CompleteTask(currentRunner(c), uint(taskID), req.Status, req.Outputs, req.Error); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	response.OkWithMessage("上报成功", c)
+}
+
+// GetRunners 获取执行器列表
+// @Summary 获取执行器列表
+// @Description 获取所有已注册的远程执行器
+// @Tags 执行器管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=[]model.Runner} "获取成功"
+// @Router /runner [get]
+func GetRunners(c *gin.Context) {
+	runners, err := runnerService.GetRunners()
+	if err != nil {
+		global.Log.Error("获取执行器列表失败", zap.Error(err))
+		response.FailWithMessage("获取执行器列表失败", c)
+		return
+	}
+
+	response.OkWithData(runners, c)
+}
+
+// DeleteRunner 删除执行器
+// @Summary 删除执行器
+// @Description 删除执行器，其上未完成的任务重新排队
+// @Tags 执行器管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "执行器ID"
+// @Success 200 {object} response.Response "删除成功"
+// @Router /runner/{id} [delete]
+func DeleteRunner(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.FailWithMessage("无效的ID", c)
+		return
+	}
+
+	if err := runnerService.DeleteRunner(uint(id)); err != nil {
+		global.Log.Error("删除执行器失败", zap.Error(err))
+		response.FailWithMessage("删除执行器失败", c)
+		return
+	}
+
+	response.OkWithMessage("删除执行器成功", c)
+}
+
+// GetRunnerQueue 获取远程任务排队情况
+// @Summary 获取远程任务排队情况
+// @Description 按标签统计排队任务数，并列出排队任务及其未被调度的原因
+// @Tags 执行器管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=map[string]interface{}} "获取成功"
+// @Router /runner/queue [get]
+func GetRunnerQueue(c *gin.Context) {
+	depths, err := runnerService.GetQueueDepth()
+	if err != nil {
+		global.Log.Error("统计排队任务失败", zap.Error(err))
+		response.FailWithMessage("获取排队情况失败", c)
+		return
+	}
+
+	tasks, err := runnerService.GetPendingTasks()
+	if err != nil {
+		global.Log.Error("获取排队任务失败", zap.Error(err))
+		response.FailWithMessage("获取排队情况失败", c)
+		return
+	}
+
+	response.OkWithData(map[string]interface{}{
+		"labels": depths,
+		"tasks":  tasks,
+	}, c)
+}
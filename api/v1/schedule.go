@@ -0,0 +1,216 @@
+package v1
+
+import (
+	"gin_pipeline/global"
+	"gin_pipeline/model"
+	"gin_pipeline/model/request"
+	"gin_pipeline/model/response"
+	"gin_pipeline/service"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"strconv"
+)
+
+var scheduleService = new(service.ScheduleService)
+
+// parseScheduleParams 解析路径中的流水线ID和定时任务ID
+func parseScheduleParams(c *gin.Context) (uint, uint, bool) {
+	pipelineID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.FailWithMessage("无效的流水线ID", c)
+		return 0, 0, false
+	}
+	scheduleID, err := strconv.ParseUint(c.Param("scheduleId"), 10, 32)
+	if err != nil {
+		response.FailWithMessage("无效的定时任务ID", c)
+		return 0, 0, false
+	}
+	return uint(pipelineID), uint(scheduleID), true
+}
+
+// CreatePipelineSchedule 创建定时任务
+// @Summary 创建定时任务
+// @Description 为流水线创建cron定时触发
+// @Tags 定时触发
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "流水线ID"
+// @Param data body request.CreatePipelineSchedule true "定时任务信息"
+// @Success 200 {object} response.Response{data=model.PipelineSchedule} "创建成功"
+// @Router /pipeline/{id}/schedules [post]
+func CreatePipelineSchedule(c *gin.Context) {
+	pipelineID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.FailWithMessage("无效的流水线ID", c)
+		return
+	}
+
+	var req request.CreatePipelineSchedule
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("参数错误: "+err.Error(), c)
+		return
+	}
+
+	timezone := req.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+
+	schedule := model.PipelineSchedule{
+		PipelineID: uint(pipelineID),
+		Name:       req.Name,
+		Cron:       req.Cron,
+		Timezone:   timezone,
+		GitBranch:  req.GitBranch,
+		Parameters: req.Parameters,
+		Enabled:    enabled,
+		CreatorID:  c.GetUint("userId"),
+	}
+
+	if err := scheduleService.CreateSchedule(&schedule); err != nil {
+		global.Log.Error("创建定时任务失败", zap.Error(err))
+		response.FailWithMessage("创建定时任务失败: "+err.Error(), c)
+		return
+	}
+
+	response.OkWithData(schedule, c)
+}
+
+// GetPipelineSchedules 获取定时任务列表
+// @Summary 获取定时任务列表
+// @Description 获取流水线的所有定时任务及下次触发时间
+// @Tags 定时触发
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "流水线ID"
+// @Success 200 {object} response.Response{data=[]service.ScheduleDetail} "获取成功"
+// @Router /pipeline/{id}/schedules [get]
+func GetPipelineSchedules(c *gin.Context) {
+	pipelineID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.FailWithMessage("无效的流水线ID", c)
+		return
+	}
+
+	schedules, err := scheduleService.GetSchedules(uint(pipelineID))
+	if err != nil {
+		global.Log.Error("获取定时任务列表失败", zap.Error(err))
+		response.FailWithMessage("获取定时任务列表失败", c)
+		return
+	}
+
+	response.OkWithData(schedules, c)
+}
+
+// GetPipelineSchedule 获取定时任务详情
+// @Summary 获取定时任务详情
+// @Description 获取定时任务详情及接下来和最近的触发时间
+// @Tags 定时触发
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "流水线ID"
+// @Param scheduleId path int true "定时任务ID"
+// @Success 200 {object} response.Response{data=service.ScheduleDetail} "获取成功"
+// @Router /pipeline/{id}/schedules/{scheduleId} [get]
+func GetPipelineSchedule(c *gin.Context) {
+	pipelineID, scheduleID, ok := parseScheduleParams(c)
+	if !ok {
+		return
+	}
+
+	schedule, err := scheduleService.GetSchedule(pipelineID, scheduleID)
+	if err != nil {
+		global.Log.Error("获取定时任务失败", zap.Error(err))
+		response.FailWithMessage("获取定时任务失败", c)
+		return
+	}
+
+	response.OkWithData(schedule, c)
+}
+
+// UpdatePipelineSchedule 更新定时任务
+// @Summary 更新定时任务
+// @Description 更新定时任务的表达式、时区、分支、参数或启用状态
+// @Tags 定时触发
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "流水线ID"
+// @Param scheduleId path int true "定时任务ID"
+// @Param data body request.UpdatePipelineSchedule true "定时任务信息"
+// @Success 200 {object} response.Response{data=model.PipelineSchedule} "更新成功"
+// @Router /pipeline/{id}/schedules/{scheduleId} [put]
+func UpdatePipelineSchedule(c *gin.Context) {
+	pipelineID, scheduleID, ok := parseScheduleParams(c)
+	if !ok {
+		return
+	}
+
+	var req request.UpdatePipelineSchedule
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("参数错误: "+err.Error(), c)
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.Cron != nil {
+		updates["cron"] = *req.Cron
+	}
+	if req.Timezone != nil {
+		updates["timezone"] = *req.Timezone
+	}
+	if req.GitBranch != nil {
+		updates["git_branch"] = *req.GitBranch
+	}
+	if req.Parameters != nil {
+		updates["parameters"] = model.JSONMap(req.Parameters)
+	}
+	if req.Enabled != nil {
+		updates["enabled"] = *req.Enabled
+	}
+
+	schedule, err := scheduleService.UpdateSchedule(pipelineID, scheduleID, updates)
+	if err != nil {
+		global.Log.Error("更新定时任务失败", zap.Error(err))
+		response.FailWithMessage("更新定时任务失败: "+err.Error(), c)
+		return
+	}
+
+	response.OkWithData(schedule, c)
+}
+
+// DeletePipelineSchedule 删除定时任务
+// @Summary 删除定时任务
+// @Description 删除流水线的定时任务
+// @Tags 定时触发
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "流水线ID"
+// @Param scheduleId path int true "定时任务ID"
+// @Success 200 {object} response.Response "删除成功"
+// @Router /pipeline/{id}/schedules/{scheduleId} [delete]
+func DeletePipelineSchedule(c *gin.Context) {
+	pipelineID, scheduleID, ok := parseScheduleParams(c)
+	if !ok {
+		return
+	}
+
+	if err := scheduleService.DeleteSchedule(pipelineID, scheduleID); err != nil {
+		global.Log.Error("删除定时任务失败", zap.Error(err))
+		response.FailWithMessage("删除定时任务失败: "+err.Error(), c)
+		return
+	}
+
+	response.OkWithMessage("删除定时任务成功", c)
+}
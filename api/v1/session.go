@@ -0,0 +1,127 @@
+package v1
+
+import (
+	"errors"
+	"gin_pipeline/global"
+	"gin_pipeline/model/request"
+	"gin_pipeline/model/response"
+	"gin_pipeline/service"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+var sessionService = new(service.SessionService)
+
+// RefreshToken 刷新访问令牌
+// @Summary 刷新访问令牌
+// @Description 使用刷新令牌换取新的访问令牌和刷新令牌，旧的刷新令牌随即失效；已使用过的刷新令牌再次使用会注销整个会话
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param data body request.RefreshToken true "刷新令牌"
+// @Success 200 {object} response.Response{data=service.SessionTokens} "刷新成功"
+// @Router /user/refresh [post]
+func RefreshToken(c *gin.Context) {
+	var req request.RefreshToken
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("参数错误: "+err.Error(), c)
+		return
+	}
+
+	tokens, err := sessionService.Refresh(c.Request.Context(), req.RefreshToken, c.ClientIP())
+	if err != nil {
+		response.FailWithDetailed(gin.H{"reload": true}, "刷新令牌失败: "+err.Error(), c)
+		return
+	}
+
+	response.OkWithData(tokens, c)
+}
+
+// Logout 退出登录
+// @Summary 退出登录
+// @Description 注销当前会话，会话的访问令牌和刷新令牌立即失效
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response "退出成功"
+// @Router /user/logout [post]
+func Logout(c *gin.Context) {
+	sessionID := c.GetString("sessionId")
+	if sessionID == "" {
+		// 未启用会话管理时访问令牌无法提前失效，由客户端丢弃
+		response.OkWithMessage("退出成功", c)
+		return
+	}
+
+	if err := sessionService.Revoke(c.Request.Context(), c.GetUint("userId"), sessionID); err != nil {
+		global.Log.Error("退出登录失败", zap.Error(err))
+		response.FailWithMessage("退出登录失败: "+err.Error(), c)
+		return
+	}
+
+	response.OkWithMessage("退出成功", c)
+}
+
+// GetSessions 获取登录会话
+// @Summary 获取登录会话
+// @Description 获取当前用户的有效会话，包括登录设备、IP和最近刷新时间，current 标记发起请求的会话
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=[]model.Session} "获取成功"
+// @Router /user/sessions [get]
+func GetSessions(c *gin.Context) {
+	sessions, err := sessionService.GetSessions(c.Request.Context(), c.GetUint("userId"), c.GetString("sessionId"))
+	if err != nil {
+		if !errors.Is(err, service.ErrSessionUnavailable) {
+			global.Log.Error("获取会话失败", zap.Error(err))
+		}
+		response.FailWithMessage("获取会话失败: "+err.Error(), c)
+		return
+	}
+
+	response.OkWithData(sessions, c)
+}
+
+// RevokeSession 注销登录会话
+// @Summary 注销登录会话
+// @Description 注销当前用户的指定会话，如在其他设备上退出登录
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param sessionId path string true "会话ID"
+// @Success 200 {object} response.Response "注销成功"
+// @Router /user/sessions/{sessionId} [delete]
+func RevokeSession(c *gin.Context) {
+	if err := sessionService.Revoke(c.Request.Context(), c.GetUint("userId"), c.Param("sessionId")); err != nil {
+		response.FailWithMessage("注销会话失败: "+err.Error(), c)
+		return
+	}
+
+	response.OkWithMessage("注销成功", c)
+}
+
+// RevokeAllSessions 注销所有登录会话
+// @Summary 注销所有登录会话
+// @Description 注销当前用户的所有会话，包括当前会话，所有设备都需要重新登录
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=map[string]int} "注销成功"
+// @Router /user/sessions [delete]
+func RevokeAllSessions(c *gin.Context) {
+	revoked, err := sessionService.RevokeAll(c.Request.Context(), c.GetUint("userId"), "")
+	if err != nil {
+		if !errors.Is(err, service.ErrSessionUnavailable) {
+			global.Log.Error("注销所有会话失败", zap.Error(err))
+		}
+		response.FailWithMessage("注销所有会话失败: "+err.Error(), c)
+		return
+	}
+
+	response.OkWithDetailed(gin.H{"revoked": revoked}, "注销成功", c)
+}
@@ -1,16 +1,22 @@
 package v1
 
 import (
+	"errors"
+	"fmt"
 	"gin_pipeline/global"
 	"gin_pipeline/model"
 	"gin_pipeline/model/request"
 	"gin_pipeline/model/response"
+	"gin_pipeline/service"
 	"gin_pipeline/utils"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"strconv"
 	"time"
 )
 
+var loginLockoutService = new(service.LoginLockoutService)
+
 // Register 用户注册
 // @Summary 用户注册
 // @Description 用户注册接口
@@ -21,6 +27,11 @@ import (
 // @Success 200 {object} response.Response{data=model.User} "注册成功"
 // @Router /user/register [post]
 func Register(c *gin.Context) {
+	if service.PasswordLoginDisabled() {
+		response.FailWithMessage("已禁用本地注册，请使用单点登录", c)
+		return
+	}
+
 	var req request.Register
 	if err := c.ShouldBindJSON(&req); err != nil {
 		response.FailWithMessage("参数错误: "+err.Error(), c)
@@ -54,7 +65,7 @@ func Register(c *gin.Context) {
 		Name:      req.Name,
 		Email:     req.Email,
 		Phone:     req.Phone,
-		Role:      "user", // 默认角色
+		Role:      service.RoleDeveloper, // 默认角色
 		LastLogin: time.Now(),
 	}
 
@@ -71,7 +82,7 @@ func Register(c *gin.Context) {
 
 // Login 用户登录
 // @Summary 用户登录
-// @Description 用户登录接口
+// @Description 用户登录接口，返回访问令牌；启用Redis时访问令牌按access_expire短期有效，同时返回刷新令牌，通过 /user/refresh 续期，未启用Redis时按jwt_expire过期。连续失败过多时账号被暂时锁定，返回429和Retry-After
 // @Tags 用户管理
 // @Accept json
 // @Produce json
@@ -79,30 +90,54 @@ func Register(c *gin.Context) {
 // @Success 200 {object} response.Response{data=map[string]interface{}} "登录成功"
 // @Router /user/login [post]
 func Login(c *gin.Context) {
+	if service.PasswordLoginDisabled() {
+		response.FailWithMessage("已禁用密码登录，请使用单点登录", c)
+		return
+	}
+
 	var req request.Login
 	if err := c.ShouldBindJSON(&req); err != nil {
 		response.FailWithMessage("参数错误: "+err.Error(), c)
 		return
 	}
 
+	// 连续失败次数过多的账号暂时锁定
+	ctx := c.Request.Context()
+	if locked := loginLockoutService.Locked(ctx, req.Username); locked > 0 {
+		response.TooManyRequests(locked, fmt.Sprintf("登录失败次数过多，请在%d秒后重试", int(locked.Seconds())+1), c)
+		return
+	}
+
 	// 查询用户
 	var user model.User
 	if err := global.DB.Where("username = ?", req.Username).First(&user).Error; err != nil {
-		response.FailWithMessage("用户名或密码错误", c)
+		loginFailed(c, req.Username)
+		return
+	}
+
+	// 服务账号只能使用访问令牌
+	if user.IsService {
+		response.FailWithMessage("服务账号不能登录，请使用访问令牌", c)
+		return
+	}
+
+	// 单点登录用户不使用本地密码
+	if user.Provider == service.ProviderOIDC {
+		response.FailWithMessage("该用户请使用单点登录", c)
 		return
 	}
 
 	// 验证密码
 	if !utils.CheckPasswordHash(req.Password, user.Password) {
-		response.FailWithMessage("用户名或密码错误", c)
+		loginFailed(c, req.Username)
 		return
 	}
+	loginLockoutService.Reset(ctx, req.Username)
 
-	// 生成Token
-	j := utils.NewJWT()
-	token, err := j.GenerateToken(user.ID, user.Username, user.Role)
+	// 创建会话并生成Token
+	tokens, err := sessionService.CreateSession(ctx, &user, c.Request.UserAgent(), c.ClientIP())
 	if err != nil {
-		global.Log.Error("生成Token失败", zap.Error(err))
+		global.Log.Error("创建会话失败", zap.Error(err))
 		response.FailWithMessage("登录失败", c)
 		return
 	}
@@ -111,8 +146,26 @@ func Login(c *gin.Context) {
 	global.DB.Model(&user).Update("last_login", time.Now())
 
 	// 返回结果
-	data := map[string]interface{}{
-		"token": token,
+	response.OkWithData(loginResult(&user, tokens), c)
+}
+
+// loginFailed 记录登录失败，达到上限时提示账号已锁定
+func loginFailed(c *gin.Context, username string) {
+	if cooldown := loginLockoutService.RecordFailure(c.Request.Context(), username); cooldown > 0 {
+		response.TooManyRequests(cooldown, fmt.Sprintf("登录失败次数过多，账号已锁定%d秒", int(cooldown.Seconds())), c)
+		return
+	}
+	response.FailWithMessage("用户名或密码错误", c)
+}
+
+// loginResult 登录成功后返回的令牌和用户信息
+func loginResult(user *model.User, tokens *service.SessionTokens) map[string]interface{} {
+	return map[string]interface{}{
+		"token":              tokens.Token,
+		"expires_at":         tokens.ExpiresAt,
+		"refresh_token":      tokens.RefreshToken,
+		"refresh_expires_at": tokens.RefreshExpiresAt,
+		"session_id":         tokens.SessionID,
 		"user": map[string]interface{}{
 			"id":       user.ID,
 			"username": user.Username,
@@ -123,7 +176,6 @@ func Login(c *gin.Context) {
 			"role":     user.Role,
 		},
 	}
-	response.OkWithData(data, c)
 }
 
 // GetUserInfo 获取用户信息
@@ -209,7 +261,7 @@ func UpdateUserInfo(c *gin.Context) {
 
 // ChangePassword 修改密码
 // @Summary 修改密码
-// @Description 修改当前登录用户密码
+// @Description 修改当前登录用户密码，修改后该用户的其他会话被注销
 // @Tags 用户管理
 // @Accept json
 // @Produce json
@@ -239,6 +291,12 @@ func ChangePassword(c *gin.Context) {
 		return
 	}
 
+	// 单点登录用户的密码由身份提供方管理
+	if user.Provider == service.ProviderOIDC {
+		response.FailWithMessage("单点登录用户不能修改密码", c)
+		return
+	}
+
 	// 验证旧密码
 	if !utils.CheckPasswordHash(req.OldPassword, user.Password) {
 		response.FailWithMessage("旧密码错误", c)
@@ -260,5 +318,44 @@ func ChangePassword(c *gin.Context) {
 		return
 	}
 
+	// 注销其他会话，当前会话保持登录
+	if _, err := sessionService.RevokeAll(c.Request.Context(), userID, c.GetString("sessionId")); err != nil && !errors.Is(err, service.ErrSessionUnavailable) {
+		global.Log.Warn("注销其他会话失败", zap.Uint("user_id", userID), zap.Error(err))
+	}
+
 	response.OkWithMessage("修改密码成功", c)
 }
+
+// UpdateUserRole 修改用户角色
+// @Summary 修改用户角色
+// @Description 修改用户的全局角色，仅管理员可用，用户刷新访问令牌或重新登录后生效
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Param data body request.UpdateUserRole true "角色: viewer, developer, maintainer, admin"
+// @Success 200 {object} response.Response{data=model.User} "修改成功"
+// @Router /user/{id}/role [put]
+func UpdateUserRole(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.FailWithMessage("无效的用户ID", c)
+		return
+	}
+
+	var req request.UpdateUserRole
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("参数错误: "+err.Error(), c)
+		return
+	}
+
+	user, err := memberService.UpdateUserRole(uint(id), req.Role)
+	if err != nil {
+		global.Log.Error("修改用户角色失败", zap.Error(err))
+		response.FailWithMessage("修改用户角色失败: "+err.Error(), c)
+		return
+	}
+
+	response.OkWithData(user, c)
+}
@@ -0,0 +1,169 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"gin_pipeline/global"
+	"gin_pipeline/model"
+	"gin_pipeline/model/request"
+	"gin_pipeline/model/response"
+	"gin_pipeline/service"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+var webhookService = new(service.WebhookService)
+
+// maxWebhookBodySize Webhook请求体大小上限
+const maxWebhookBodySize = 5 << 20
+
+// ReceivePipelineWebhook 接收Git平台的Webhook
+// @Summary 接收Git Webhook
+// @Description 接收GitHub、GitLab、Gitea的推送和标签事件，校验签名后按仓库和分支过滤条件触发流水线
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Param id path int true "流水线ID"
+// @Param parameters query string false "触发参数，如 ?env=staging；只对GitLab生效，GitHub和Gitea的签名不覆盖查询参数"
+// @Success 200 {object} response.Response{data=model.WebhookDelivery} "处理成功"
+// @Router /webhook/pipeline/{id} [post]
+func ReceivePipelineWebhook(c *gin.Context) {
+	pipelineID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.FailWithMessage("无效的流水线ID", c)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBodySize))
+	if err != nil {
+		response.FailWithMessage("读取请求体失败", c)
+		return
+	}
+
+	// 查询参数作为触发参数，如 ?env=staging，只对以令牌认证的GitLab生效
+	parameters := map[string]interface{}{}
+	for key, values := range c.Request.URL.Query() {
+		if len(values) > 0 {
+			parameters[key] = values[0]
+		}
+	}
+
+	delivery, err := webhookService.HandleWebhook(c.Request.Context(), uint(pipelineID), c.Request.Header, body, parameters)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrWebhookSignature):
+			c.JSON(http.StatusUnauthorized, response.Response{
+				Code: response.ERROR,
+				Data: map[string]interface{}{},
+				Msg:  err.Error(),
+			})
+		case errors.Is(err, service.ErrWebhookDuplicate):
+			response.OkWithMessage("重复投递，已忽略", c)
+		default:
+			global.Log.Error("处理Webhook失败", zap.Error(err))
+			response.FailWithMessage("处理Webhook失败: "+err.Error(), c)
+		}
+		return
+	}
+
+	response.OkWithData(delivery, c)
+}
+
+// pipelineWebhookInfo 流水线Webhook配置
+func pipelineWebhookInfo(pipeline *model.Pipeline) map[string]interface{} {
+	deliveries, err := webhookService.GetDeliveries(pipeline.ID, 20)
+	if err != nil {
+		global.Log.Error("获取Webhook投递记录失败", zap.Error(err))
+		deliveries = []model.WebhookDelivery{}
+	}
+	return map[string]interface{}{
+		"url":               fmt.Sprintf("/api/v1/webhook/pipeline/%d", pipeline.ID),
+		"secret_configured": pipeline.WebhookSecret != "",
+		"branch_filter":     pipeline.WebhookBranchFilter,
+		"tag_filter":        pipeline.WebhookTagFilter,
+		"deliveries":        deliveries,
+	}
+}
+
+// GetPipelineWebhook 获取流水线Webhook配置
+// @Summary 获取Webhook配置
+// @Description 获取流水线的Webhook地址、过滤条件和最近的投递记录
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "流水线ID"
+// @Success 200 {object} response.Response{data=map[string]interface{}} "获取成功"
+// @Router /pipeline/{id}/webhook [get]
+func GetPipelineWebhook(c *gin.Context) {
+	var pipeline model.Pipeline
+	if err := global.DB.First(&pipeline, c.Param("id")).Error; err != nil {
+		global.Log.Error("查询流水线失败", zap.Error(err))
+		response.FailWithMessage("获取Webhook配置失败", c)
+		return
+	}
+
+	response.OkWithData(pipelineWebhookInfo(&pipeline), c)
+}
+
+// UpdatePipelineWebhook 更新流水线Webhook配置
+// @Summary 更新Webhook配置
+// @Description 更新分支和标签过滤条件，或重新生成密钥；密钥只在生成时返回一次
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "流水线ID"
+// @Param data body request.UpdatePipelineWebhook true "Webhook配置"
+// @Success 200 {object} response.Response{data=map[string]interface{}} "更新成功"
+// @Router /pipeline/{id}/webhook [put]
+func UpdatePipelineWebhook(c *gin.Context) {
+	var req request.UpdatePipelineWebhook
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("参数错误: "+err.Error(), c)
+		return
+	}
+
+	var pipeline model.Pipeline
+	if err := global.DB.First(&pipeline, c.Param("id")).Error; err != nil {
+		global.Log.Error("查询流水线失败", zap.Error(err))
+		response.FailWithMessage("更新Webhook配置失败", c)
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.BranchFilter != nil {
+		updates["webhook_branch_filter"] = *req.BranchFilter
+	}
+	if req.TagFilter != nil {
+		updates["webhook_tag_filter"] = *req.TagFilter
+	}
+	if len(updates) > 0 {
+		if err := global.DB.Model(&pipeline).Updates(updates).Error; err != nil {
+			global.Log.Error("更新Webhook配置失败", zap.Error(err))
+			response.FailWithMessage("更新Webhook配置失败", c)
+			return
+		}
+	}
+
+	// 首次配置或要求时生成密钥
+	var secret string
+	if req.RegenerateSecret || pipeline.WebhookSecret == "" {
+		var err error
+		if secret, err = webhookService.GenerateWebhookSecret(pipeline.ID); err != nil {
+			global.Log.Error("生成Webhook密钥失败", zap.Error(err))
+			response.FailWithMessage("生成Webhook密钥失败", c)
+			return
+		}
+		pipeline.WebhookSecret = secret
+	}
+
+	info := pipelineWebhookInfo(&pipeline)
+	if secret != "" {
+		info["secret"] = secret
+	}
+	response.OkWithData(info, c)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// envelope 服务端统一响应结构
+type envelope struct {
+	Code int             `json:"code"`
+	Data json.RawMessage `json:"data"`
+	Msg  string          `json:"msg"`
+}
+
+// Task 服务端分配的任务
+type Task struct {
+	ID       uint                   `json:"id"`
+	RunID    uint                   `json:"run_id"`
+	NodeID   string                 `json:"node_id"`
+	Name     string                 `json:"name"`
+	Type     string                 `json:"type"`
+	Config   map[string]interface{} `json:"config"`
+	Env      map[string]string      `json:"env"`
+	Attempts int                    `json:"attempts"`
+}
+
+// Client 服务端API客户端
+type Client struct {
+	server string
+	token  string
+	http   *http.Client
+}
+
+// NewClient 创建客户端
+func NewClient(server string) *Client {
+	return &Client{
+		server: server,
+		http:   &http.Client{},
+	}
+}
+
+// SetToken 设置执行器令牌
+func (c *Client) SetToken(token string) {
+	c.token = token
+}
+
+// do 发送请求并解析响应
+func (c *Client) do(ctx context.Context, method, path string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.server+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("X-Runner-Token", c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("服务端返回状态码 %d", resp.StatusCode)
+	}
+
+	var env envelope
+	if err := json.NewDecoder(resp.Body).Decode(&env); err != nil {
+		return err
+	}
+	if env.Code != 0 {
+		return errors.New(env.Msg)
+	}
+	if out != nil && len(env.Data) > 0 {
+		return json.Unmarshal(env.Data, out)
+	}
+	return nil
+}
+
+// Register 使用注册令牌注册执行器，返回执行器令牌
+func (c *Client) Register(ctx context.Context, registrationToken, name, labels string, capacity int) (string, error) {
+	var result struct {
+		Token string `json:"token"`
+	}
+	err := c.do(ctx, http.MethodPost, "/runner/register", map[string]interface{}{
+		"token":    registrationToken,
+		"name":     name,
+		"labels":   labels,
+		"capacity": capacity,
+		"version":  version,
+	}, &result)
+	return result.Token, err
+}
+
+// Heartbeat 发送心跳，返回需要停止的任务
+func (c *Client) Heartbeat(ctx context.Context, labels string, capacity int, running []uint) ([]uint, error) {
+	var result struct {
+		Stop []uint `json:"stop"`
+	}
+	err := c.do(ctx, http.MethodPost, "/runner/heartbeat", map[string]interface{}{
+		"labels":   labels,
+		"capacity": capacity,
+		"running":  running,
+		"version":  version,
+	}, &result)
+	return result.Stop, err
+}
+
+// Poll 长轮询获取任务
+func (c *Client) Poll(ctx context.Context, wait time.Duration) (*Task, error) {
+	var result struct {
+		Task *Task `json:"task"`
+	}
+	path := fmt.Sprintf("/runner/tasks/poll?wait=%d", int(wait.Seconds()))
+	err := c.do(ctx, http.MethodGet, path, nil, &result)
+	return result.Task, err
+}
+
+// AppendLogs 上报日志，返回任务是否已被取消
+func (c *Client) AppendLogs(ctx context.Context, taskID uint, logs string) (bool, error) {
+	var result struct {
+		Canceled bool `json:"canceled"`
+	}
+	err := c.do(ctx, http.MethodPost, fmt.Sprintf("/runner/tasks/%d/logs", taskID), map[string]interface{}{
+		"logs": logs,
+	}, &result)
+	return result.Canceled, err
+}
+
+// Complete 上报任务结果
+func (c *Client) Complete(ctx context.Context, taskID uint, status, errMsg string, outputs map[string]interface{}) error {
+	return c.do(ctx, http.MethodPost, fmt.Sprintf("/runner/tasks/%d/result", taskID), map[string]interface{}{
+		"status":  status,
+		"error":   errMsg,
+		"outputs": outputs,
+	}, nil)
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// logBuffer 线程安全的日志缓冲区，定期刷新到服务端
+type logBuffer struct {
+	buf   bytes.Buffer
+	mutex sync.Mutex
+}
+
+// Write 实现io.Writer
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.Write(p)
+}
+
+// Drain 取出缓冲区中的全部内容
+func (b *logBuffer) Drain() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	s := b.buf.String()
+	b.buf.Reset()
+	return s
+}
+
+// configString 从任务配置中读取字符串
+func configString(config map[string]interface{}, key string) string {
+	if value, ok := config[key].(string); ok {
+		return value
+	}
+	return ""
+}
+
+// configInt 从任务配置中读取整数
+func configInt(config map[string]interface{}, key string) int {
+	if value, ok := config[key].(float64); ok {
+		return int(value)
+	}
+	return 0
+}
+
+// buildCommand 根据任务类型构建要执行的命令
+func buildCommand(ctx context.Context, task *Task, workDir string) (*exec.Cmd, error) {
+	command := configString(task.Config, "command")
+
+	switch task.Type {
+	case "shell":
+		if command == "" {
+			return nil, errors.New("shell任务缺少command配置")
+		}
+		cmd := exec.CommandContext(ctx, "sh", "-c", command)
+		cmd.Dir = workDir
+		return cmd, nil
+	case "docker":
+		image := configString(task.Config, "image")
+		if image == "" {
+			return nil, errors.New("docker任务缺少image配置")
+		}
+		args := []string{"run", "--rm", "-v", workDir + ":/workspace", "-w", "/workspace"}
+		// 只传递变量名，值从docker客户端进程的环境变量中读取
+		for key := range task.Env {
+			args = append(args, "-e", key)
+		}
+		args = append(args, image)
+		if command != "" {
+			args = append(args, "sh", "-c", command)
+		}
+		return exec.CommandContext(ctx, "docker", args...), nil
+	}
+	return nil, fmt.Errorf("不支持的任务类型: %s", task.Type)
+}
+
+// runTask 执行任务并定期上报日志，返回最终状态和错误信息
+func runTask(ctx context.Context, client *Client, task *Task, baseDir string) (string, string) {
+	workDir := filepath.Join(baseDir, fmt.Sprintf("run-%d", task.RunID), task.NodeID)
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		return "failed", "创建工作目录失败: " + err.Error()
+	}
+
+	// 超时由执行器本地控制，取消由服务端通知
+	if timeout := configInt(task.Config, "timeout"); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+		defer cancel()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	cmd, err := buildCommand(ctx, task, workDir)
+	if err != nil {
+		return "failed", err.Error()
+	}
+
+	env := os.Environ()
+	env = append(env,
+		fmt.Sprintf("PIPELINE_RUN_ID=%d", task.RunID),
+		"PIPELINE_TASK_ID="+task.NodeID,
+		"PIPELINE_TASK_NAME="+task.Name,
+	)
+	for key, value := range task.Env {
+		env = append(env, key+"="+value)
+	}
+	cmd.Env = env
+
+	logs := &logBuffer{}
+	cmd.Stdout = logs
+	cmd.Stderr = logs
+	fmt.Fprintf(logs, "[runner] $ %s\n", strings.Join(cmd.Args, " "))
+
+	// 首次上报日志使任务进入运行中状态
+	if canceled, err := client.AppendLogs(ctx, task.ID, logs.Drain()); err == nil && canceled {
+		return "", ""
+	}
+
+	if err := cmd.Start(); err != nil {
+		return "failed", "启动命令失败: " + err.Error()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	var waitErr error
+	canceledByServer := false
+loop:
+	for {
+		select {
+		case waitErr = <-done:
+			break loop
+		case <-ticker.C:
+			canceled, err := client.AppendLogs(context.Background(), task.ID, logs.Drain())
+			if err != nil {
+				log.Printf("上报任务 %d 日志失败: %v", task.ID, err)
+				continue
+			}
+			if canceled {
+				canceledByServer = true
+				cancel()
+			}
+		}
+	}
+
+	// 上报剩余日志
+	if rest := logs.Drain(); rest != "" {
+		if _, err := client.AppendLogs(context.Background(), task.ID, rest); err != nil {
+			log.Printf("上报任务 %d 日志失败: %v", task.ID, err)
+		}
+	}
+
+	if canceledByServer {
+		return "", ""
+	}
+	if waitErr != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "failed", fmt.Sprintf("任务执行超时(%d秒)", configInt(task.Config, "timeout"))
+		}
+		if ctx.Err() != nil {
+			return "", ""
+		}
+		return "failed", waitErr.Error()
+	}
+	return "success", ""
+}
@@ -0,0 +1,214 @@
+// 远程执行器：向服务端注册后长轮询获取任务，在本机执行shell/docker任务并回传日志和结果
+//
+// 用法:
+//
+//	runner -server http://localhost:8080/api/v1 -registration-token xxx -labels linux,docker -capacity 2
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const version = "1.0.0"
+
+// Runner 执行器运行时状态
+type Runner struct {
+	client   *Client
+	labels   string
+	capacity int
+	workDir  string
+
+	mutex   sync.Mutex
+	running map[uint]context.CancelFunc
+	slots   chan struct{}
+	wg      sync.WaitGroup
+}
+
+func main() {
+	server := flag.String("server", "http://localhost:8080/api/v1", "服务端API地址")
+	registrationToken := flag.String("registration-token", "", "注册令牌（首次注册时使用）")
+	tokenFile := flag.String("token-file", ".runner-token", "执行器令牌保存路径")
+	name := flag.String("name", "", "执行器名称（默认主机名）")
+	labels := flag.String("labels", "", "执行器标签，逗号分隔")
+	capacity := flag.Int("capacity", 1, "最大并发任务数")
+	workDir := flag.String("workdir", "./runner-work", "任务工作目录")
+	flag.Parse()
+
+	if *name == "" {
+		*name, _ = os.Hostname()
+	}
+	if *capacity <= 0 {
+		*capacity = 1
+	}
+	// docker -v 只把绝对路径当作绑定挂载，相对路径会被当成命名卷
+	baseDir, err := filepath.Abs(*workDir)
+	if err != nil {
+		log.Fatalf("解析工作目录失败: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	client := NewClient(strings.TrimRight(*server, "/"))
+
+	token, err := loadOrRegister(ctx, client, *tokenFile, *registrationToken, *name, *labels, *capacity)
+	if err != nil {
+		log.Fatalf("注册执行器失败: %v", err)
+	}
+	client.SetToken(token)
+
+	runner := &Runner{
+		client:   client,
+		labels:   *labels,
+		capacity: *capacity,
+		workDir:  baseDir,
+		running:  make(map[uint]context.CancelFunc),
+		slots:    make(chan struct{}, *capacity),
+	}
+
+	log.Printf("执行器 %s 已启动，标签: %s，容量: %d", *name, *labels, *capacity)
+
+	// 心跳使用独立的上下文，收到退出信号后继续发送，直到正在执行的任务结束，
+	// 否则服务端会判定执行器离线并重新分配这些任务
+	heartbeatCtx, stopHeartbeat := context.WithCancel(context.Background())
+	go runner.heartbeatLoop(heartbeatCtx)
+	runner.pollLoop(ctx)
+
+	// 等待正在执行的任务结束
+	runner.wg.Wait()
+	stopHeartbeat()
+	log.Printf("执行器已停止")
+}
+
+// loadOrRegister 读取已保存的执行器令牌，不存在时使用注册令牌注册
+func loadOrRegister(ctx context.Context, client *Client, tokenFile, registrationToken, name, labels string, capacity int) (string, error) {
+	if data, err := os.ReadFile(tokenFile); err == nil {
+		if token := strings.TrimSpace(string(data)); token != "" {
+			return token, nil
+		}
+	}
+
+	token, err := client.Register(ctx, registrationToken, name, labels, capacity)
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(tokenFile, []byte(token), 0600); err != nil {
+		log.Printf("保存执行器令牌失败: %v", err)
+	}
+	return token, nil
+}
+
+// runningIDs 返回正在执行的任务ID
+func (r *Runner) runningIDs() []uint {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	ids := make([]uint, 0, len(r.running))
+	for id := range r.running {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// cancelTask 取消正在执行的任务
+func (r *Runner) cancelTask(id uint) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if cancel, ok := r.running[id]; ok {
+		log.Printf("任务 %d 已被服务端取消", id)
+		cancel()
+	}
+}
+
+// heartbeatLoop 定期发送心跳
+func (r *Runner) heartbeatLoop(ctx context.Context) {
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		stopIDs, err := r.client.Heartbeat(ctx, r.labels, r.capacity, r.runningIDs())
+		if err != nil {
+			log.Printf("发送心跳失败: %v", err)
+		}
+		for _, id := range stopIDs {
+			r.cancelTask(id)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pollLoop 在有空闲容量时长轮询获取任务
+func (r *Runner) pollLoop(ctx context.Context) {
+	for {
+		// 占用一个执行槽
+		select {
+		case <-ctx.Done():
+			return
+		case r.slots <- struct{}{}:
+		}
+
+		task, err := r.client.Poll(ctx, 30*time.Second)
+		if err != nil || task == nil {
+			<-r.slots
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				log.Printf("获取任务失败: %v", err)
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(5 * time.Second):
+				}
+			}
+			continue
+		}
+
+		r.wg.Add(1)
+		go r.execute(task)
+	}
+}
+
+// execute 执行任务并上报结果
+func (r *Runner) execute(task *Task) {
+	defer r.wg.Done()
+	defer func() { <-r.slots }()
+
+	// 任务不随执行器的退出信号取消，执行完毕后再退出
+	ctx, cancel := context.WithCancel(context.Background())
+	r.mutex.Lock()
+	r.running[task.ID] = cancel
+	r.mutex.Unlock()
+	defer func() {
+		r.mutex.Lock()
+		delete(r.running, task.ID)
+		r.mutex.Unlock()
+		cancel()
+	}()
+
+	log.Printf("开始执行任务 %d: %s (%s)", task.ID, task.Name, task.Type)
+	status, errMsg := runTask(ctx, r.client, task, r.workDir)
+	if status == "" {
+		log.Printf("任务 %d 已取消", task.ID)
+		return
+	}
+
+	if err := r.client.Complete(context.Background(), task.ID, status, errMsg, nil); err != nil {
+		log.Printf("上报任务 %d 结果失败: %v", task.ID, err)
+		return
+	}
+	log.Printf("任务 %d 执行完成: %s", task.ID, status)
+}
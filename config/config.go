@@ -2,15 +2,18 @@ package config
 
 // System 系统配置
 type System struct {
-	Env           string `mapstructure:"env" json:"env" yaml:"env"`                                  // 环境
-	Port          string `mapstructure:"port" json:"port" yaml:"port"`                               // 端口
-	DbType        string `mapstructure:"db_type" json:"db_type" yaml:"db_type"`                      // 数据库类型
-	UseRedis      bool   `mapstructure:"use_redis" json:"use_redis" yaml:"use_redis"`                // 使用redis
-	UseMultipoint bool   `mapstructure:"use_multipoint" json:"use_multipoint" yaml:"use_multipoint"` // 多点登录拦截
-	OssType       string `mapstructure:"oss_type" json:"oss_type" yaml:"oss_type"`                   // 存储类型
-	UseHttps      bool   `mapstructure:"use_https" json:"use_https" yaml:"use_https"`                // 使用https
-	JwtSecret     string `mapstructure:"jwt_secret" json:"jwt_secret" yaml:"jwt_secret"`             // jwt密钥
-	JwtExpire     int    `mapstructure:"jwt_expire" json:"jwt_expire" yaml:"jwt_expire"`             // jwt过期时间
+	Env            string   `mapstructure:"env" json:"env" yaml:"env"`                                     // 环境
+	Port           string   `mapstructure:"port" json:"port" yaml:"port"`                                  // 端口
+	DbType         string   `mapstructure:"db_type" json:"db_type" yaml:"db_type"`                         // 数据库类型
+	UseRedis       bool     `mapstructure:"use_redis" json:"use_redis" yaml:"use_redis"`                   // 使用redis
+	UseMultipoint  bool     `mapstructure:"use_multipoint" json:"use_multipoint" yaml:"use_multipoint"`    // 多点登录拦截，开启后新登录使该用户的其他会话失效
+	OssType        string   `mapstructure:"oss_type" json:"oss_type" yaml:"oss_type"`                      // 存储类型
+	UseHttps       bool     `mapstructure:"use_https" json:"use_https" yaml:"use_https"`                   // 使用https
+	JwtSecret      string   `mapstructure:"jwt_secret" json:"jwt_secret" yaml:"jwt_secret"`                // jwt密钥
+	JwtExpire      int      `mapstructure:"jwt_expire" json:"jwt_expire" yaml:"jwt_expire"`                // jwt过期时间，未启用redis时登录令牌无法刷新，使用该有效期
+	AccessExpire   int      `mapstructure:"access_expire" json:"access_expire" yaml:"access_expire"`       // 可刷新的会话访问令牌过期时间，为0时使用jwt_expire
+	RefreshExpire  int      `mapstructure:"refresh_expire" json:"refresh_expire" yaml:"refresh_expire"`    // 刷新令牌过期时间
+	TrustedProxies []string `mapstructure:"trusted_proxies" json:"trusted_proxies" yaml:"trusted_proxies"` // 可信的反向代理地址或网段，只有来自这些地址的X-Forwarded-For才被采信
 }
 
 // Log 日志配置
@@ -82,12 +85,173 @@ type Upload struct {
 	Qiniu Qiniu `mapstructure:"qiniu" json:"qiniu" yaml:"qiniu"`
 }
 
+// Plugin 执行器插件配置
+type Plugin struct {
+	Enabled         bool   `mapstructure:"enabled" json:"enabled" yaml:"enabled"`                            // 是否启用插件
+	Dir             string `mapstructure:"dir" json:"dir" yaml:"dir"`                                        // 插件目录
+	DescribeTimeout int    `mapstructure:"describe_timeout" json:"describe_timeout" yaml:"describe_timeout"` // 获取插件描述的超时时间(秒)
+}
+
+// Runner 远程执行器配置
+type Runner struct {
+	Enabled           bool     `mapstructure:"enabled" json:"enabled" yaml:"enabled"`                                  // 是否将任务分发给远程执行器
+	RegistrationToken string   `mapstructure:"registration_token" json:"registration_token" yaml:"registration_token"` // 执行器注册令牌
+	TaskTypes         []string `mapstructure:"task_types" json:"task_types" yaml:"task_types"`                         // 分发给远程执行器的任务类型
+	HeartbeatTimeout  int      `mapstructure:"heartbeat_timeout" json:"heartbeat_timeout" yaml:"heartbeat_timeout"`    // 心跳超时时间(秒)
+	PollTimeout       int      `mapstructure:"poll_timeout" json:"poll_timeout" yaml:"poll_timeout"`                   // 长轮询最长等待时间(秒)
+	MaxAttempts       int      `mapstructure:"max_attempts" json:"max_attempts" yaml:"max_attempts"`                   // 任务最多分配次数
+}
+
+// Scheduler 流水线运行调度配置
+type Scheduler struct {
+	MaxConcurrentRuns  int    `mapstructure:"max_concurrent_runs" json:"max_concurrent_runs" yaml:"max_concurrent_runs"`    // 同时运行的流水线数量上限
+	FairShareBy        string `mapstructure:"fair_share_by" json:"fair_share_by" yaml:"fair_share_by"`                      // 公平调度维度: pipeline, user
+	DefaultRunDuration int    `mapstructure:"default_run_duration" json:"default_run_duration" yaml:"default_run_duration"` // 没有历史数据时预估的运行时长(秒)
+}
+
+// Cron 定时触发配置
+type Cron struct {
+	Enabled       bool `mapstructure:"enabled" json:"enabled" yaml:"enabled"`                      // 是否启用定时触发
+	CheckInterval int  `mapstructure:"check_interval" json:"check_interval" yaml:"check_interval"` // 检查到期定时任务的间隔(秒)
+}
+
+// PipelineFile 流水线文件配置
+type PipelineFile struct {
+	Enabled      bool   `mapstructure:"enabled" json:"enabled" yaml:"enabled"`                   // 是否从仓库读取流水线文件
+	Path         string `mapstructure:"path" json:"path" yaml:"path"`                            // 流水线文件在仓库中的路径
+	FetchTimeout int    `mapstructure:"fetch_timeout" json:"fetch_timeout" yaml:"fetch_timeout"` // 拉取仓库的超时时间(秒)
+}
+
+// Metrics Prometheus指标配置
+type Metrics struct {
+	Enabled       bool   `mapstructure:"enabled" json:"enabled" yaml:"enabled"`                      // 是否暴露指标
+	Path          string `mapstructure:"path" json:"path" yaml:"path"`                               // 指标路径
+	Token         string `mapstructure:"token" json:"token" yaml:"token"`                            // 非空时抓取需要携带 Bearer Token
+	PipelineLabel bool   `mapstructure:"pipeline_label" json:"pipeline_label" yaml:"pipeline_label"` // 运行计数是否带pipeline_id标签，流水线很多时会产生大量时间序列
+}
+
+// Tracing OpenTelemetry链路追踪配置
+type Tracing struct {
+	Enabled     bool              `mapstructure:"enabled" json:"enabled" yaml:"enabled"`                // 是否启用链路追踪
+	Exporter    string            `mapstructure:"exporter" json:"exporter" yaml:"exporter"`             // otlp, file
+	Endpoint    string            `mapstructure:"endpoint" json:"endpoint" yaml:"endpoint"`             // OTLP HTTP接收地址，如 localhost:4318
+	Insecure    bool              `mapstructure:"insecure" json:"insecure" yaml:"insecure"`             // 是否使用HTTP而非HTTPS
+	Headers     map[string]string `mapstructure:"headers" json:"headers" yaml:"headers"`                // 导出时附加的请求头
+	FilePath    string            `mapstructure:"file_path" json:"file_path" yaml:"file_path"`          // file导出器的输出文件
+	ServiceName string            `mapstructure:"service_name" json:"service_name" yaml:"service_name"` // 服务名
+	SampleRatio float64           `mapstructure:"sample_ratio" json:"sample_ratio" yaml:"sample_ratio"` // 采样比例，0到1之间
+}
+
+// Notification 通知配置
+type Notification struct {
+	Enabled             bool `mapstructure:"enabled" json:"enabled" yaml:"enabled"`                                           // 是否发送通知
+	MaxAttempts         int  `mapstructure:"max_attempts" json:"max_attempts" yaml:"max_attempts"`                            // 每次投递最多尝试次数
+	RetryInterval       int  `mapstructure:"retry_interval" json:"retry_interval" yaml:"retry_interval"`                      // 首次重试的间隔(秒)，之后每次翻倍
+	Timeout             int  `mapstructure:"timeout" json:"timeout" yaml:"timeout"`                                           // 单次投递的超时时间(秒)
+	CheckInterval       int  `mapstructure:"check_interval" json:"check_interval" yaml:"check_interval"`                      // 检查待重试投递的间隔(秒)
+	AllowPrivateNetwork bool `mapstructure:"allow_private_network" json:"allow_private_network" yaml:"allow_private_network"` // 是否允许通知地址指向回环、链路本地和内网地址
+	SMTP                SMTP `mapstructure:"smtp" json:"smtp" yaml:"smtp"`
+}
+
+// SMTP 邮件发送配置
+type SMTP struct {
+	Host     string `mapstructure:"host" json:"host" yaml:"host"`
+	Port     int    `mapstructure:"port" json:"port" yaml:"port"`
+	Username string `mapstructure:"username" json:"username" yaml:"username"`
+	Password string `mapstructure:"password" json:"password" yaml:"password"`
+	From     string `mapstructure:"from" json:"from" yaml:"from"` // 发件人地址
+	TLS      bool   `mapstructure:"tls" json:"tls" yaml:"tls"`    // 是否直接使用TLS连接(如465端口)，否则在服务器支持时使用STARTTLS
+}
+
+// EventBus 事件总线配置
+type EventBus struct {
+	Redis          bool   `mapstructure:"redis" json:"redis" yaml:"redis"`                               // 是否通过Redis发布订阅在实例间转发事件
+	Channel        string `mapstructure:"channel" json:"channel" yaml:"channel"`                         // Redis频道
+	QueueSize      int    `mapstructure:"queue_size" json:"queue_size" yaml:"queue_size"`                // 每个订阅者的队列长度
+	PublishTimeout int    `mapstructure:"publish_timeout" json:"publish_timeout" yaml:"publish_timeout"` // 队列满时发布方最多等待的毫秒数，超时丢弃事件并计数
+}
+
+// Audit 审计日志配置
+type Audit struct {
+	Enabled         bool `mapstructure:"enabled" json:"enabled" yaml:"enabled"`                            // 是否记录审计日志
+	RetentionDays   int  `mapstructure:"retention_days" json:"retention_days" yaml:"retention_days"`       // 保留天数，0表示永久保留
+	CleanupInterval int  `mapstructure:"cleanup_interval" json:"cleanup_interval" yaml:"cleanup_interval"` // 清理过期日志的间隔(分钟)
+}
+
+// OIDC 单点登录配置
+type OIDC struct {
+	Enabled              bool              `mapstructure:"enabled" json:"enabled" yaml:"enabled"`                                              // 是否启用OIDC登录
+	Name                 string            `mapstructure:"name" json:"name" yaml:"name"`                                                       // 登录页显示的名称
+	Issuer               string            `mapstructure:"issuer" json:"issuer" yaml:"issuer"`                                                 // 身份提供方地址，从 /.well-known/openid-configuration 发现端点
+	ClientID             string            `mapstructure:"client_id" json:"client_id" yaml:"client_id"`                                        // 客户端ID
+	ClientSecret         string            `mapstructure:"client_secret" json:"client_secret" yaml:"client_secret"`                            // 客户端密钥，公共客户端为空
+	RedirectURL          string            `mapstructure:"redirect_url" json:"redirect_url" yaml:"redirect_url"`                               // 回调地址，指向 /api/v1/user/oidc/callback
+	Scopes               []string          `mapstructure:"scopes" json:"scopes" yaml:"scopes"`                                                 // 请求的scope，openid总是包含
+	UsernameClaim        string            `mapstructure:"username_claim" json:"username_claim" yaml:"username_claim"`                         // 作为用户名的claim
+	GroupsClaim          string            `mapstructure:"groups_claim" json:"groups_claim" yaml:"groups_claim"`                               // 用户组claim
+	RoleMappings         []OIDCRoleMapping `mapstructure:"role_mappings" json:"role_mappings" yaml:"role_mappings"`                            // 用户组到角色的映射，配置后每次登录按用户组同步角色
+	DefaultRole          string            `mapstructure:"default_role" json:"default_role" yaml:"default_role"`                               // 没有匹配用户组时的角色
+	AutoProvision        bool              `mapstructure:"auto_provision" json:"auto_provision" yaml:"auto_provision"`                         // 首次登录时自动创建用户
+	LinkVerifiedEmail    bool              `mapstructure:"link_verified_email" json:"link_verified_email" yaml:"link_verified_email"`          // 首次登录时按邮箱关联本地账号，要求身份提供方返回email_verified为true
+	DisablePasswordLogin bool              `mapstructure:"disable_password_login" json:"disable_password_login" yaml:"disable_password_login"` // 禁用本地密码登录和注册
+	FrontendRedirect     string            `mapstructure:"frontend_redirect" json:"frontend_redirect" yaml:"frontend_redirect"`                // 登录成功后跳转的前端地址，令牌放在URL片段中；为空时回调直接返回JSON
+}
+
+// OIDCRoleMapping 用户组到角色的映射
+type OIDCRoleMapping struct {
+	Group string `mapstructure:"group" json:"group" yaml:"group"`
+	Role  string `mapstructure:"role" json:"role" yaml:"role"`
+}
+
+// RateLimit 限流配置
+type RateLimit struct {
+	Enabled      bool             `mapstructure:"enabled" json:"enabled" yaml:"enabled"`                   // 是否启用接口限流
+	PerIP        RateLimitRule    `mapstructure:"per_ip" json:"per_ip" yaml:"per_ip"`                      // 每个IP对所有接口的限制
+	PerUser      RateLimitRule    `mapstructure:"per_user" json:"per_user" yaml:"per_user"`                // 每个登录用户(包括访问令牌)对所有接口的限制
+	Routes       []RouteRateLimit `mapstructure:"routes" json:"routes" yaml:"routes"`                      // 单个接口的限制，与全局限制同时生效
+	LoginLockout LoginLockout     `mapstructure:"login_lockout" json:"login_lockout" yaml:"login_lockout"` // 登录失败锁定，不受enabled影响
+}
+
+// RateLimitRule 固定窗口内允许的请求数，limit为0表示不限制
+type RateLimitRule struct {
+	Limit  int `mapstructure:"limit" json:"limit" yaml:"limit"`    // 窗口内允许的请求数
+	Window int `mapstructure:"window" json:"window" yaml:"window"` // 窗口长度(秒)
+}
+
+// RouteRateLimit 单个接口的限流规则
+type RouteRateLimit struct {
+	Method  string        `mapstructure:"method" json:"method" yaml:"method"`       // 请求方法，为空表示所有方法
+	Path    string        `mapstructure:"path" json:"path" yaml:"path"`             // 路由模板，如 /api/v1/pipeline/:id/trigger
+	PerIP   RateLimitRule `mapstructure:"per_ip" json:"per_ip" yaml:"per_ip"`       // 每个IP的限制
+	PerUser RateLimitRule `mapstructure:"per_user" json:"per_user" yaml:"per_user"` // 每个登录用户的限制
+}
+
+// LoginLockout 登录失败锁定配置
+type LoginLockout struct {
+	MaxFailures   int `mapstructure:"max_failures" json:"max_failures" yaml:"max_failures"`       // 连续失败多少次后锁定，0表示不锁定
+	FailureWindow int `mapstructure:"failure_window" json:"failure_window" yaml:"failure_window"` // 失败次数的统计时间(秒)
+	Cooldown      int `mapstructure:"cooldown" json:"cooldown" yaml:"cooldown"`                   // 首次锁定的时间(秒)，之后每次锁定翻倍
+	MaxCooldown   int `mapstructure:"max_cooldown" json:"max_cooldown" yaml:"max_cooldown"`       // 最长锁定时间(秒)
+}
+
 // Configuration 总配置结构
 type Configuration struct {
-	System System `mapstructure:"system" json:"system" yaml:"system"`
-	Log    Log    `mapstructure:"log" json:"log" yaml:"log"`
-	Mysql  Mysql  `mapstructure:"mysql" json:"mysql" yaml:"mysql"`
-	Redis  Redis  `mapstructure:"redis" json:"redis" yaml:"redis"`
-	CORS   CORS   `mapstructure:"cors" json:"cors" yaml:"cors"`
-	Upload Upload `mapstructure:"upload" json:"upload" yaml:"upload"`
+	System       System       `mapstructure:"system" json:"system" yaml:"system"`
+	Log          Log          `mapstructure:"log" json:"log" yaml:"log"`
+	Mysql        Mysql        `mapstructure:"mysql" json:"mysql" yaml:"mysql"`
+	Redis        Redis        `mapstructure:"redis" json:"redis" yaml:"redis"`
+	CORS         CORS         `mapstructure:"cors" json:"cors" yaml:"cors"`
+	Upload       Upload       `mapstructure:"upload" json:"upload" yaml:"upload"`
+	Plugin       Plugin       `mapstructure:"plugin" json:"plugin" yaml:"plugin"`
+	Runner       Runner       `mapstructure:"runner" json:"runner" yaml:"runner"`
+	Scheduler    Scheduler    `mapstructure:"scheduler" json:"scheduler" yaml:"scheduler"`
+	Cron         Cron         `mapstructure:"cron" json:"cron" yaml:"cron"`
+	PipelineFile PipelineFile `mapstructure:"pipeline_file" json:"pipeline_file" yaml:"pipeline_file"`
+	Metrics      Metrics      `mapstructure:"metrics" json:"metrics" yaml:"metrics"`
+	Tracing      Tracing      `mapstructure:"tracing" json:"tracing" yaml:"tracing"`
+	Notification Notification `mapstructure:"notification" json:"notification" yaml:"notification"`
+	EventBus     EventBus     `mapstructure:"event_bus" json:"event_bus" yaml:"event_bus"`
+	Audit        Audit        `mapstructure:"audit" json:"audit" yaml:"audit"`
+	OIDC         OIDC         `mapstructure:"oidc" json:"oidc" yaml:"oidc"`
+	RateLimit    RateLimit    `mapstructure:"rate_limit" json:"rate_limit" yaml:"rate_limit"`
 }
@@ -0,0 +1,84 @@
+// echo 是一个示例执行器插件，演示插件协议的实现方式
+//
+// 编译后放入插件目录即可使用：
+//
+//	go build -o plugins/echo ./examples/plugins/echo
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int            `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  interface{}     `json:"result,omitempty"`
+}
+
+type executeParams struct {
+	Task struct {
+		ID     string                 `json:"id"`
+		Config map[string]interface{} `json:"config"`
+	} `json:"task"`
+}
+
+var writer = json.NewEncoder(os.Stdout)
+
+func send(msg message) {
+	msg.JSONRPC = "2.0"
+	_ = writer.Encode(msg)
+}
+
+func main() {
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var msg message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			continue
+		}
+
+		switch msg.Method {
+		case "describe":
+			send(message{ID: msg.ID, Result: map[string]interface{}{
+				"name":    "echo",
+				"version": "1.0.0",
+				"task_types": []map[string]interface{}{{
+					"type":        "echo",
+					"description": "输出配置中的消息",
+					"config_schema": map[string]interface{}{
+						"type":     "object",
+						"required": []string{"message"},
+						"properties": map[string]interface{}{
+							"message": map[string]interface{}{"type": "string"},
+							"repeat":  map[string]interface{}{"type": "integer", "minimum": 1},
+						},
+					},
+				}},
+			}})
+		case "execute":
+			var params executeParams
+			_ = json.Unmarshal(msg.Params, &params)
+			text := fmt.Sprint(params.Task.Config["message"])
+			repeat := 1
+			if n, ok := params.Task.Config["repeat"].(float64); ok {
+				repeat = int(n)
+			}
+			for i := 0; i < repeat; i++ {
+				line, _ := json.Marshal(map[string]string{"line": text})
+				send(message{Method: "log", Params: line})
+			}
+			send(message{ID: msg.ID, Result: map[string]interface{}{
+				"status":  "success",
+				"outputs": map[string]interface{}{"message": strings.Repeat(text, repeat)},
+			}})
+		case "cancel":
+			os.Exit(1)
+		}
+	}
+}
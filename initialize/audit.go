@@ -0,0 +1,41 @@
+package initialize
+
+import (
+	"gin_pipeline/global"
+	"gin_pipeline/service"
+	"go.uber.org/zap"
+	"time"
+)
+
+// InitAuditCleanupLoop 启动审计日志清理循环，删除超过保留天数的日志
+func InitAuditCleanupLoop() {
+	cfg := global.Config.Audit
+	if cfg.RetentionDays <= 0 {
+		return
+	}
+
+	interval := time.Duration(cfg.CleanupInterval) * time.Minute
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	go func() {
+		auditService := new(service.AuditService)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if global.DB == nil {
+				continue
+			}
+			deleted, err := auditService.PurgeExpired(cfg.RetentionDays)
+			if err != nil {
+				global.Log.Error("清理审计日志失败", zap.Error(err))
+				continue
+			}
+			if deleted > 0 {
+				global.Log.Info("清理过期审计日志", zap.Int64("deleted", deleted))
+			}
+		}
+	}()
+}
@@ -79,24 +79,44 @@ func InitDB() {
 	// 自动迁移
 	err = db.AutoMigrate(
 		&model.User{},
+		&model.AccessToken{},
 		&model.Pipeline{},
+		&model.PipelineMember{},
 		&model.Stage{},
 		&model.Job{},
 		&model.PipelineRun{},
+		&model.PipelineSchedule{},
+		&model.PipelineRunTask{},
+		&model.WebhookDelivery{},
+		&model.NotificationRule{},
+		&model.NotificationSubscription{},
+		&model.NotificationDelivery{},
+		&model.AuditLog{},
 		&model.Artifact{},
 		&model.Environment{},
+		&model.EnvironmentMember{},
 		&model.Release{},
 		&model.BuildTemplate{},
 		&model.DAG{},
+		&model.DAGFragment{},
 		&model.YAMLValidation{},
 		&model.YAMLSchema{},
 		&model.TemplateCategory{},
 		&model.Template{},
 		&model.TemplateVersion{},
+		&model.Runner{},
+		&model.RunnerTask{},
 	)
 	if err != nil {
 		global.Log.Error("自动迁移失败", zap.Any("err", err))
 		return
 	}
+
+	// 旧的Webhook去重索引不含流水线ID，会把发给其他流水线的同一投递当作重复
+	if db.Migrator().HasIndex(&model.WebhookDelivery{}, "idx_webhook_delivery") {
+		if err := db.Migrator().DropIndex(&model.WebhookDelivery{}, "idx_webhook_delivery"); err != nil {
+			global.Log.Error("删除旧的Webhook去重索引失败", zap.Any("err", err))
+		}
+	}
 	global.Log.Info("自动迁移成功")
 }
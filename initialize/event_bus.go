@@ -0,0 +1,23 @@
+package initialize
+
+import (
+	"gin_pipeline/global"
+	"gin_pipeline/service"
+)
+
+// InitEventBus 启用事件转发并注册内置的事件订阅者
+func InitEventBus() {
+	cfg := global.Config.EventBus
+	if cfg.Redis {
+		channel := cfg.Channel
+		if channel == "" {
+			channel = "pipeline:events"
+		}
+		service.GetEventBus().EnableRedis(channel)
+	}
+
+	service.RegisterMetricsSubscribers()
+	if global.Config.Notification.Enabled {
+		service.RegisterNotificationSubscribers()
+	}
+}
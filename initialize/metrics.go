@@ -0,0 +1,27 @@
+package initialize
+
+import (
+	"gin_pipeline/global"
+	"gin_pipeline/service"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"go.uber.org/zap"
+	"time"
+)
+
+// InitMetrics 注册依赖数据库的指标收集器
+func InitMetrics() {
+	if !global.Config.Metrics.Enabled || global.DB == nil {
+		return
+	}
+
+	sqlDB, err := global.DB.DB()
+	if err != nil {
+		global.Log.Error("获取数据库连接池失败", zap.Error(err))
+		return
+	}
+	prometheus.MustRegister(
+		collectors.NewDBStatsCollector(sqlDB, global.Config.Mysql.DbName),
+		service.NewArtifactStorageCollector(time.Minute),
+	)
+}
@@ -0,0 +1,36 @@
+package initialize
+
+import (
+	"gin_pipeline/global"
+	"gin_pipeline/service"
+	"go.uber.org/zap"
+	"time"
+)
+
+// InitNotificationLoop 启动通知重试循环
+// 投递在发送前通过条件更新认领，多实例同时运行也不会重复发送
+func InitNotificationLoop() {
+	if !global.Config.Notification.Enabled {
+		return
+	}
+
+	interval := time.Duration(global.Config.Notification.CheckInterval) * time.Second
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+
+	go func() {
+		notificationService := new(service.NotificationService)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if global.DB == nil {
+				continue
+			}
+			if err := notificationService.RetryDueDeliveries(); err != nil {
+				global.Log.Error("重试通知投递失败", zap.Error(err))
+			}
+		}
+	}()
+}
@@ -0,0 +1,27 @@
+package initialize
+
+import (
+	"gin_pipeline/global"
+	"gin_pipeline/service"
+	"go.uber.org/zap"
+	"time"
+)
+
+// InitPlugins 加载执行器插件
+func InitPlugins() {
+	p := global.Config.Plugin
+	if !p.Enabled {
+		return
+	}
+
+	timeout := time.Duration(p.DescribeTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	if err := service.GetPluginManager().LoadDir(p.Dir, timeout); err != nil {
+		global.Log.Error("加载插件目录失败", zap.String("dir", p.Dir), zap.Error(err))
+		return
+	}
+	global.Log.Info("插件加载完成", zap.Strings("taskTypes", service.GetPluginManager().TaskTypes()))
+}
@@ -1,14 +1,17 @@
 package initialize
 
 import (
+	"fmt"
 	"gin_pipeline/global"
 	"gin_pipeline/middleware"
 	"gin_pipeline/router"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-contrib/zap"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"net/http"
 	"time"
 
@@ -24,17 +27,33 @@ func InitRouter() *gin.Engine {
 
 	r := gin.New()
 
+	// 只采信可信代理转发的X-Forwarded-For，否则客户端可以伪造IP绕过限流、篡改审计记录
+	var trustedProxies []string
+	if len(global.Config.System.TrustedProxies) > 0 {
+		trustedProxies = global.Config.System.TrustedProxies
+	}
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		panic(fmt.Errorf("可信代理配置错误: %s", err))
+	}
+
 	// 使用中间件
 	r.Use(middleware.GinLogger())
 	r.Use(middleware.GinRecovery(true))
 	r.Use(ginzap.RecoveryWithZap(global.Log.Desugar(), true))
 
+	// 链路追踪，健康检查和指标抓取不产生链路
+	if global.Config.Tracing.Enabled {
+		r.Use(otelgin.Middleware(global.Config.Tracing.ServiceName, otelgin.WithFilter(func(req *http.Request) bool {
+			return req.URL.Path != "/health" && req.URL.Path != global.Config.Metrics.Path
+		})))
+	}
+
 	// 跨域配置
 	corsConfig := cors.Config{
 		AllowOrigins:     []string{"*"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length", "Access-Control-Allow-Origin", "Access-Control-Allow-Headers"},
+		ExposeHeaders:    []string{"Content-Length", "Access-Control-Allow-Origin", "Access-Control-Allow-Headers", "Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}
@@ -46,6 +65,16 @@ func InitRouter() *gin.Engine {
 
 	r.Use(cors.New(corsConfig))
 
+	// Prometheus指标
+	if global.Config.Metrics.Enabled {
+		r.Use(middleware.Metrics())
+		path := global.Config.Metrics.Path
+		if path == "" {
+			path = "/metrics"
+		}
+		r.GET(path, middleware.MetricsAuth(), gin.WrapH(promhttp.Handler()))
+	}
+
 	// 健康检查
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
@@ -56,8 +85,8 @@ func InitRouter() *gin.Engine {
 	// Swagger文档
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	// 注册路由
-	apiGroup := r.Group("/api/v1")
+	// 注册路由，所有接口按IP限流
+	apiGroup := r.Group("/api/v1", middleware.RateLimit())
 	router.InitPublicRouter(apiGroup)         // 公共路由
 	router.InitUserRouter(apiGroup)           // 用户路由
 	router.InitPipelineRouter(apiGroup)       // 流水线路由
@@ -66,8 +95,15 @@ func InitRouter() *gin.Engine {
 	router.InitReleaseRouter(apiGroup)        // 发布路由
 	router.InitBuildTemplateRouter(apiGroup)  // 构建模板路由
 	router.InitDAGRouter(apiGroup)            // DAG路由
+	router.InitDAGFragmentRouter(apiGroup)    // DAG片段路由
 	router.InitYAMLValidatorRouter(apiGroup)  // YAML验证路由
 	router.InitTemplateMarketRouter(apiGroup) // 模板市场路由
+	router.InitPluginRouter(apiGroup)         // 插件路由
+	router.InitRunnerRouter(apiGroup)         // 远程执行器路由
+	router.InitWebhookRouter(apiGroup)        // Webhook路由
+	router.InitNotificationRouter(apiGroup)   // 通知路由
+	router.InitAuditRouter(apiGroup)          // 审计路由
+	router.InitServiceAccountRouter(apiGroup) // 服务账号路由
 
 	global.Log.Info("路由注册成功")
 	return r
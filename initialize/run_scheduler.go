@@ -0,0 +1,14 @@
+package initialize
+
+import (
+	"gin_pipeline/global"
+	"gin_pipeline/service"
+)
+
+// InitRunScheduler 启动流水线运行调度器，接管重启前或已停止实例遗留的运行
+func InitRunScheduler() {
+	if global.DB == nil {
+		return
+	}
+	service.GetRunScheduler().Start()
+}
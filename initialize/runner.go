@@ -0,0 +1,41 @@
+package initialize
+
+import (
+	"gin_pipeline/global"
+	"gin_pipeline/service"
+	"go.uber.org/zap"
+	"time"
+)
+
+// InitRunnerMonitor 启动远程执行器心跳检测，超时执行器上的任务重新排队，并刷新排队任务未被调度的原因
+func InitRunnerMonitor() {
+	r := global.Config.Runner
+	if !r.Enabled {
+		return
+	}
+	if !service.RegistrationTokenConfigured() {
+		global.Log.Error("执行器注册令牌为空或仍为示例值，执行器将无法注册，请在runner.registration_token中配置随机令牌")
+	}
+
+	timeout := time.Duration(r.HeartbeatTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = 60 * time.Second
+	}
+
+	go func() {
+		runnerService := new(service.RunnerService)
+		ticker := time.NewTicker(timeout / 3)
+		defer ticker.Stop()
+		for range ticker.C {
+			if global.DB == nil {
+				continue
+			}
+			if err := runnerService.RequeueStaleRunners(timeout, r.MaxAttempts); err != nil {
+				global.Log.Error("检测执行器心跳失败", zap.Error(err))
+			}
+			if err := runnerService.RefreshUnscheduledReasons(); err != nil {
+				global.Log.Error("更新任务调度状态失败", zap.Error(err))
+			}
+		}
+	}()
+}
@@ -0,0 +1,62 @@
+package initialize
+
+import (
+	"context"
+	"gin_pipeline/global"
+	"gin_pipeline/service"
+	"go.uber.org/zap"
+	"time"
+)
+
+// InitScheduleLoop 启动定时触发循环
+// 多实例部署时通过Redis锁选举一个实例作为leader，只有leader负责触发
+func InitScheduleLoop() {
+	if !global.Config.Cron.Enabled {
+		return
+	}
+
+	interval := time.Duration(global.Config.Cron.CheckInterval) * time.Second
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+	// leader未续期超过3个周期后由其他实例接替
+	leaderTTL := 3 * interval
+
+	go func() {
+		scheduleService := new(service.ScheduleService)
+		ctx := context.Background()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		var leader *service.DistributedLock
+		for range ticker.C {
+			if global.DB == nil {
+				continue
+			}
+
+			if leader != nil {
+				held, err := leader.Renew(ctx)
+				if err != nil || !held {
+					global.Log.Warn("失去定时触发leader身份", zap.Error(err))
+					leader = nil
+				}
+			}
+			if leader == nil {
+				lock, ok, err := service.TryLock(ctx, "schedule:leader", leaderTTL)
+				if err != nil {
+					global.Log.Error("竞选定时触发leader失败", zap.Error(err))
+					continue
+				}
+				if !ok {
+					continue
+				}
+				global.Log.Info("成为定时触发leader")
+				leader = lock
+			}
+
+			if err := scheduleService.FireDueSchedules(); err != nil {
+				global.Log.Error("触发定时任务失败", zap.Error(err))
+			}
+		}
+	}()
+}
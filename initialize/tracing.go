@@ -0,0 +1,192 @@
+package initialize
+
+import (
+	"context"
+	"errors"
+	"gin_pipeline/global"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// InitTracing 初始化链路追踪，返回用于导出剩余span的关闭函数
+// 未启用时使用otel默认的空实现，埋点不产生开销
+func InitTracing() func() {
+	cfg := global.Config.Tracing
+	if !cfg.Enabled {
+		return func() {}
+	}
+
+	exporter, closeOutput, err := newTraceExporter()
+	if err != nil {
+		global.Log.Error("创建链路追踪导出器失败", zap.Error(err))
+		return func() {}
+	}
+
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = "gin_pipeline"
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(serviceName)))
+	if err != nil {
+		global.Log.Error("创建链路追踪资源失败", zap.Error(err))
+		res = resource.Default()
+	}
+
+	sampleRatio := cfg.SampleRatio
+	if sampleRatio <= 0 || sampleRatio > 1 {
+		sampleRatio = 1
+	}
+
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	}
+	if cfg.Exporter == "file" {
+		// 本地调试时同步写入，span结束后立即可见
+		opts = append(opts, sdktrace.WithSyncer(exporter))
+	} else {
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+	provider := sdktrace.NewTracerProvider(opts...)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if global.DB != nil {
+		if err := registerGormTracing(global.DB); err != nil {
+			global.Log.Error("注册数据库链路追踪失败", zap.Error(err))
+		}
+	}
+
+	global.Log.Info("链路追踪已启用", zap.String("exporter", cfg.Exporter), zap.String("service", serviceName))
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := provider.Shutdown(ctx); err != nil {
+			global.Log.Error("关闭链路追踪失败", zap.Error(err))
+		}
+		closeOutput()
+	}
+}
+
+// newTraceExporter 按配置创建导出器，第二个返回值用于关闭输出文件
+func newTraceExporter() (sdktrace.SpanExporter, func(), error) {
+	cfg := global.Config.Tracing
+	switch cfg.Exporter {
+	case "file":
+		path := cfg.FilePath
+		if path == "" {
+			path = "logs/traces.json"
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, nil, err
+		}
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, nil, err
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+		return exporter, func() { file.Close() }, nil
+	case "otlp", "":
+		opts := []otlptracehttp.Option{}
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		if len(cfg.Headers) > 0 {
+			opts = append(opts, otlptracehttp.WithHeaders(cfg.Headers))
+		}
+		exporter, err := otlptracehttp.New(context.Background(), opts...)
+		if err != nil {
+			return nil, nil, err
+		}
+		return exporter, func() {}, nil
+	default:
+		return nil, nil, errors.New("不支持的链路追踪导出方式: " + cfg.Exporter)
+	}
+}
+
+// gormSpanKey 在语句实例中保存span的键
+const gormSpanKey = "otel:span"
+
+// registerGormTracing 为数据库操作创建span
+// 只追踪通过 WithContext 传入了链路上下文的操作，避免后台查询各自产生孤立的链路
+func registerGormTracing(db *gorm.DB) error {
+	tracer := otel.Tracer("gin_pipeline/gorm")
+
+	before := func(operation string) func(*gorm.DB) {
+		return func(tx *gorm.DB) {
+			ctx := tx.Statement.Context
+			if ctx == nil || !trace.SpanContextFromContext(ctx).IsValid() {
+				return
+			}
+			ctx, span := tracer.Start(ctx, "gorm."+operation,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(semconv.DBSystemMySQL, semconv.DBOperationName(operation)))
+			tx.Statement.Context = ctx
+			tx.InstanceSet(gormSpanKey, span)
+		}
+	}
+
+	after := func(tx *gorm.DB) {
+		value, ok := tx.InstanceGet(gormSpanKey)
+		if !ok {
+			return
+		}
+		span, ok := value.(trace.Span)
+		if !ok {
+			return
+		}
+		defer span.End()
+
+		span.SetAttributes(
+			semconv.DBQueryText(tx.Statement.SQL.String()),
+			semconv.DBCollectionName(tx.Statement.Table),
+			attribute.Int64("db.rows_affected", tx.Statement.RowsAffected),
+		)
+		if tx.Error != nil && !errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+			span.RecordError(tx.Error)
+			span.SetStatus(codes.Error, tx.Error.Error())
+		}
+	}
+
+	callback := db.Callback()
+	for _, err := range []error{
+		callback.Create().Before("gorm:create").Register("otel:before_create", before("create")),
+		callback.Create().After("gorm:create").Register("otel:after_create", after),
+		callback.Query().Before("gorm:query").Register("otel:before_query", before("query")),
+		callback.Query().After("gorm:query").Register("otel:after_query", after),
+		callback.Update().Before("gorm:update").Register("otel:before_update", before("update")),
+		callback.Update().After("gorm:update").Register("otel:after_update", after),
+		callback.Delete().Before("gorm:delete").Register("otel:before_delete", before("delete")),
+		callback.Delete().After("gorm:delete").Register("otel:after_delete", after),
+		callback.Row().Before("gorm:row").Register("otel:before_row", before("row")),
+		callback.Row().After("gorm:row").Register("otel:after_row", after),
+		callback.Raw().Before("gorm:raw").Register("otel:before_raw", before("raw")),
+		callback.Raw().After("gorm:raw").Register("otel:after_raw", after),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -40,6 +40,35 @@ func main() {
 	initialize.InitRedis()
 	utils.Success("Redis连接初始化成功")
 
+	// 初始化事件总线
+	initialize.InitEventBus()
+
+	// 注册指标收集器
+	initialize.InitMetrics()
+
+	// 初始化链路追踪
+	shutdownTracing := initialize.InitTracing()
+	defer shutdownTracing()
+
+	// 加载执行器插件
+	initialize.InitPlugins()
+	utils.Success("执行器插件加载完成")
+
+	// 启动远程执行器心跳检测
+	initialize.InitRunnerMonitor()
+
+	// 启动运行调度，恢复重启前排队的运行
+	initialize.InitRunScheduler()
+
+	// 启动定时触发
+	initialize.InitScheduleLoop()
+
+	// 启动通知重试
+	initialize.InitNotificationLoop()
+
+	// 启动审计日志清理
+	initialize.InitAuditCleanupLoop()
+
 	// 初始化路由
 	r := initialize.InitRouter()
 	utils.Success("路由初始化成功")
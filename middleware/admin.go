@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"gin_pipeline/model/response"
+	"gin_pipeline/utils"
 	"github.com/gin-gonic/gin"
 )
 
@@ -16,7 +17,8 @@ func AdminAuth() gin.HandlerFunc {
 		}
 
 		// 检查用户角色
-		if claims.(*CustomClaims).Role != "admin" {
+		customClaims, ok := claims.(*utils.CustomClaims)
+		if !ok || customClaims.Role != "admin" {
 			response.FailWithMessage("权限不足", c)
 			c.Abort()
 			return
@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"gin_pipeline/global"
+	"gin_pipeline/model"
+	"gin_pipeline/model/response"
+	"gin_pipeline/service"
+	"gin_pipeline/utils"
+	"github.com/gin-gonic/gin"
+	"strconv"
+	"time"
+)
+
+// auditBodyLimit 为判断结果而保留的响应体长度上限
+const auditBodyLimit = 1 << 20
+
+// auditResponseWriter 在写出响应的同时保留响应体
+type auditResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *auditResponseWriter) Write(data []byte) (int, error) {
+	if w.body.Len()+len(data) <= auditBodyLimit {
+		w.body.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *auditResponseWriter) WriteString(s string) (int, error) {
+	if w.body.Len()+len(s) <= auditBodyLimit {
+		w.body.WriteString(s)
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Audit 审计中间件，记录操作人、来源IP、资源、操作前后的差异和结果，需放在JWTAuth之后
+// 资源ID取最后一个路径参数；create操作的资源ID取自响应数据中的id；
+// 没有路径参数的非创建操作作用于当前用户自身
+func Audit(resourceType, action string) gin.HandlerFunc {
+	auditService := new(service.AuditService)
+	return func(c *gin.Context) {
+		if !global.Config.Audit.Enabled {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		entry := &model.AuditLog{
+			IP:           c.ClientIP(),
+			RemoteIP:     c.RemoteIP(),
+			UserAgent:    truncate(c.Request.UserAgent(), 255),
+			Method:       c.Request.Method,
+			Route:        c.FullPath(),
+			Action:       action,
+			ResourceType: resourceType,
+		}
+		if claims, ok := c.Get("claims"); ok {
+			if customClaims, ok := claims.(*utils.CustomClaims); ok {
+				entry.ActorID = customClaims.ID
+				entry.ActorName = customClaims.Username
+				entry.ActorRole = customClaims.Role
+			}
+		}
+
+		if action != "create" {
+			if len(c.Params) > 0 {
+				entry.ResourceID = c.Params[len(c.Params)-1].Value
+			} else if entry.ActorID != 0 {
+				entry.ResourceID = strconv.FormatUint(uint64(entry.ActorID), 10)
+			}
+			entry.Before = auditService.Snapshot(resourceType, entry.ResourceID)
+		}
+
+		writer := &auditResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		var result struct {
+			Code int             `json:"code"`
+			Data json.RawMessage `json:"data"`
+			Msg  string          `json:"msg"`
+		}
+		parsed := json.Unmarshal(writer.body.Bytes(), &result) == nil
+		if writer.Status() < 400 && parsed && result.Code == response.SUCCESS {
+			entry.Outcome = service.AuditSuccess
+		} else {
+			entry.Outcome = service.AuditFailure
+			switch {
+			case parsed && result.Msg != "":
+				entry.Error = result.Msg
+			case len(c.Errors) > 0:
+				entry.Error = c.Errors.String()
+			default:
+				entry.Error = "HTTP " + strconv.Itoa(writer.Status())
+			}
+		}
+
+		if action == "create" && entry.Outcome == service.AuditSuccess {
+			var data struct {
+				ID uint `json:"id"`
+			}
+			if json.Unmarshal(result.Data, &data) == nil && data.ID != 0 {
+				entry.ResourceID = strconv.FormatUint(uint64(data.ID), 10)
+			}
+		}
+		if entry.ResourceID != "" {
+			entry.After = auditService.Snapshot(resourceType, entry.ResourceID)
+		}
+
+		entry.Duration = time.Since(start).Milliseconds()
+		auditService.Record(entry)
+	}
+}
+
+// truncate 截断过长的字符串
+func truncate(s string, size int) string {
+	if len(s) > size {
+		return s[:size]
+	}
+	return s
+}
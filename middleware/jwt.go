@@ -4,24 +4,48 @@ import (
 	"errors"
 	"gin_pipeline/global"
 	"gin_pipeline/model/response"
+	"gin_pipeline/service"
 	"gin_pipeline/utils"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 	_ "net/http"
 	_ "strconv"
+	"strings"
 	"time"
 )
 
-// JWTAuth JWT认证中间件
+// JWTAuth JWT认证中间件，同时接受以 pat_ 开头的个人访问令牌，认证后按用户限流
 func JWTAuth() gin.HandlerFunc {
+	accessTokenService := new(service.AccessTokenService)
+	sessionService := new(service.SessionService)
+	rateLimitService := new(service.RateLimitService)
 	return func(c *gin.Context) {
-		token := c.Request.Header.Get("Authorization")
+		token := strings.TrimPrefix(c.Request.Header.Get("Authorization"), "Bearer ")
 		if token == "" {
 			response.FailWithDetailed(gin.H{"reload": true}, "未登录或非法访问", c)
 			c.Abort()
 			return
 		}
 
+		// 访问令牌每次请求都校验，撤销后立即失效
+		if service.IsAccessToken(token) {
+			accessToken, user, err := accessTokenService.Authenticate(token, c.ClientIP())
+			if err != nil {
+				response.FailWithMessage(err.Error(), c)
+				c.Abort()
+				return
+			}
+			c.Set("claims", &utils.CustomClaims{ID: user.ID, Username: user.Username, Role: user.Role})
+			c.Set("userId", user.ID)
+			c.Set("role", user.Role)
+			c.Set("accessToken", accessToken)
+			if !limitUser(c, rateLimitService, user.ID) {
+				return
+			}
+			c.Next()
+			return
+		}
+
 		// 解析token
 		j := utils.NewJWT()
 		claims, err := j.ParseToken(token)
@@ -36,9 +60,23 @@ func JWTAuth() gin.HandlerFunc {
 			return
 		}
 
+		// 会话注销后，它签发的访问令牌立即失效
+		if claims.SessionID != "" {
+			if err := sessionService.Check(c.Request.Context(), claims.SessionID); err != nil {
+				response.FailWithDetailed(gin.H{"reload": true}, err.Error(), c)
+				c.Abort()
+				return
+			}
+		}
+
 		// 将用户信息存入上下文
 		c.Set("claims", claims)
 		c.Set("userId", claims.ID)
+		c.Set("role", claims.Role)
+		c.Set("sessionId", claims.SessionID)
+		if !limitUser(c, rateLimitService, claims.ID) {
+			return
+		}
 		c.Next()
 	}
 }
@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"encoding/json"
+	"gin_pipeline/global"
+	"gin_pipeline/model/response"
+	"gin_pipeline/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+)
+
+func TestJWTAuth(t *testing.T) {
+	previousSystem, previousRateLimit, previousRedis := global.Config.System, global.Config.RateLimit, global.Redis
+	t.Cleanup(func() {
+		global.Config.System, global.Config.RateLimit, global.Redis = previousSystem, previousRateLimit, previousRedis
+	})
+	global.Config.System.JwtSecret = "test-secret"
+	global.Config.System.JwtExpire = 900
+	global.Config.RateLimit.Enabled = false
+	// 未启用Redis时无法校验会话，携带会话ID的令牌同样放行
+	global.Redis = nil
+
+	token, err := utils.NewJWT().GenerateToken(7, "alice", "admin")
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+	sessionToken, err := utils.NewJWT().GenerateSessionToken(7, "alice", "admin", "session-1")
+	if err != nil {
+		t.Fatalf("GenerateSessionToken() error = %v", err)
+	}
+	foreign, err := (&utils.JWT{SigningKey: []byte("other-secret")}).GenerateToken(7, "alice", "admin")
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+	expired, err := utils.NewJWT().CreateToken(utils.CustomClaims{ID: 7, StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(-time.Minute).Unix()}})
+	if err != nil {
+		t.Fatalf("CreateToken() error = %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantSession   string
+		wantErr       bool
+	}{
+		{name: "missing", wantErr: true},
+		{name: "malformed", authorization: "Bearer not-a-jwt", wantErr: true},
+		{name: "wrong signing key", authorization: "Bearer " + foreign, wantErr: true},
+		{name: "expired", authorization: "Bearer " + expired, wantErr: true},
+		{name: "valid", authorization: "Bearer " + token},
+		{name: "valid without bearer prefix", authorization: token},
+		{name: "session token", authorization: "Bearer " + sessionToken, wantSession: "session-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			var userID uint
+			var role, sessionID string
+			router := gin.New()
+			router.GET("/test", JWTAuth(), func(c *gin.Context) {
+				userID, role, sessionID = c.GetUint("userId"), c.GetString("role"), c.GetString("sessionId")
+				response.Ok(c)
+			})
+
+			request := httptest.NewRequest(http.MethodGet, "/test", nil)
+			if tt.authorization != "" {
+				request.Header.Set("Authorization", tt.authorization)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			var body response.Response
+			if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decode response %q: %v", recorder.Body.String(), err)
+			}
+			if (body.Code != response.SUCCESS) != tt.wantErr {
+				t.Fatalf("response = %+v, wantErr %v", body, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if userID != 7 || role != "admin" || sessionID != tt.wantSession {
+				t.Fatalf("context = (%d, %q, %q), want (7, admin, %q)", userID, role, sessionID, tt.wantSession)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"gin_pipeline/global"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// HTTP请求指标
+var (
+	httpRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "pipeline",
+		Name:      "http_requests_total",
+		Help:      "HTTP请求数，按方法、路由和状态码区分",
+	}, []string{"method", "route", "status"})
+
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "pipeline",
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP请求的处理耗时",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+)
+
+// Metrics 记录HTTP请求指标，路由使用注册时的路径模板以控制标签数量
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		httpRequestsTotal.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		httpRequestDuration.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// MetricsAuth 配置了指标令牌时校验抓取请求的 Bearer Token
+func MetricsAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := global.Config.Metrics.Token
+		if token == "" {
+			c.Next()
+			return
+		}
+
+		expected := "Bearer " + token
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), []byte(expected)) != 1 {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"gin_pipeline/model"
+	"gin_pipeline/model/response"
+	"gin_pipeline/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// CurrentActor 获取当前用户和全局角色，需放在JWTAuth之后
+func CurrentActor(c *gin.Context) service.Actor {
+	return service.Actor{ID: c.GetUint("userId"), Role: c.GetString("role")}
+}
+
+// RequireRole 要求当前用户的全局角色不低于指定角色，需放在JWTAuth之后
+func RequireRole(role string) gin.HandlerFunc {
+	permissionService := new(service.PermissionService)
+	return func(c *gin.Context) {
+		if err := permissionService.CheckRole(CurrentActor(c), role); err != nil {
+			response.FailWithMessage(err.Error(), c)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// Authorize 按路径参数指定的资源检查权限，需放在JWTAuth之后
+// 属于流水线的资源要求流水线角色，环境要求环境角色，共享资源要求创建者或全局角色
+func Authorize(resourceType, param, role string) gin.HandlerFunc {
+	permissionService := new(service.PermissionService)
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param(param), 10, 32)
+		if err != nil {
+			response.FailWithMessage("无效的ID", c)
+			c.Abort()
+			return
+		}
+		if err := permissionService.CheckResource(CurrentActor(c), resourceType, uint(id), role); err != nil {
+			response.FailWithMessage(err.Error(), c)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireScope 检查访问令牌的授权范围，查询请求需要read，其他请求需要指定的范围，登录会话不受限制
+// 管理接口的查询(如审计日志、执行器队列)同样需要admin范围
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, ok := c.Get("accessToken")
+		if !ok {
+			c.Next()
+			return
+		}
+		required := scope
+		if scope != service.ScopeAdmin && (c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead) {
+			required = service.ScopeRead
+		}
+		if !service.HasScope(value.(*model.AccessToken), required) {
+			response.FailWithMessage("访问令牌缺少授权范围: "+required, c)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// SessionOnly 只允许登录会话访问，访问令牌不能管理令牌和账号
+func SessionOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("accessToken"); ok {
+			response.FailWithMessage("该接口不能使用访问令牌", c)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"gin_pipeline/model"
+	"gin_pipeline/service"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// runMiddleware 依次执行中间件，返回请求是否到达最终的处理函数
+func runMiddleware(method string, handlers ...gin.HandlerFunc) (bool, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	reached := false
+	router := gin.New()
+	handlers = append(handlers, func(c *gin.Context) { reached = true })
+	router.Handle(method, "/test", handlers...)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(method, "/test", nil))
+	return reached, recorder
+}
+
+// withAccessToken 模拟JWTAuth认证通过的访问令牌
+func withAccessToken(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("accessToken", &model.AccessToken{Scopes: scopes})
+		c.Next()
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	session := func(c *gin.Context) { c.Next() }
+
+	tests := []struct {
+		name   string
+		method string
+		scope  string
+		auth   gin.HandlerFunc
+		want   bool
+	}{
+		{name: "session bypasses scopes", method: http.MethodPost, auth: session, want: true},
+		{name: "get requires read", method: http.MethodGet, auth: withAccessToken(service.ScopeRead), want: true},
+		{name: "head requires read", method: http.MethodHead, auth: withAccessToken(service.ScopeRead), want: true},
+		{name: "get without read", method: http.MethodGet, auth: withAccessToken(service.ScopeTriggerPipeline), want: false},
+		{name: "post with scope", method: http.MethodPost, auth: withAccessToken(service.ScopeTriggerPipeline), want: true},
+		{name: "post with read only", method: http.MethodPost, auth: withAccessToken(service.ScopeRead), want: false},
+		{name: "delete with other scope", method: http.MethodDelete, auth: withAccessToken(service.ScopeRead, service.ScopeWriteArtifact), want: false},
+		{name: "admin does not imply other scopes", method: http.MethodPost, auth: withAccessToken(service.ScopeAdmin), want: false},
+		// 管理接口的查询不降级为read
+		{name: "admin get with read only", method: http.MethodGet, scope: service.ScopeAdmin, auth: withAccessToken(service.ScopeRead), want: false},
+		{name: "admin get with admin", method: http.MethodGet, scope: service.ScopeAdmin, auth: withAccessToken(service.ScopeAdmin), want: true},
+		{name: "admin session", method: http.MethodGet, scope: service.ScopeAdmin, auth: session, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scope := tt.scope
+			if scope == "" {
+				scope = service.ScopeTriggerPipeline
+			}
+			reached, recorder := runMiddleware(tt.method, tt.auth, RequireScope(scope))
+			if reached != tt.want {
+				t.Fatalf("reached = %v, want %v (response %s)", reached, tt.want, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestSessionOnly(t *testing.T) {
+	if reached, _ := runMiddleware(http.MethodPost, SessionOnly()); !reached {
+		t.Fatal("SessionOnly() rejected a login session")
+	}
+	if reached, _ := runMiddleware(http.MethodPost, withAccessToken(service.AccessTokenScopes...), SessionOnly()); reached {
+		t.Fatal("SessionOnly() accepted an access token")
+	}
+}
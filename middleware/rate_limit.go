@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"gin_pipeline/config"
+	"gin_pipeline/global"
+	"gin_pipeline/model/response"
+	"gin_pipeline/service"
+	"github.com/gin-gonic/gin"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rateLimitCheck 一条需要检查的限流规则
+type rateLimitCheck struct {
+	key  string
+	rule config.RateLimitRule
+}
+
+// RateLimit 按IP限流，注册在所有接口之前；按用户限流在JWTAuth认证之后进行
+func RateLimit() gin.HandlerFunc {
+	rateLimitService := new(service.RateLimitService)
+	return func(c *gin.Context) {
+		rateLimitConfig := global.Config.RateLimit
+		if !rateLimitConfig.Enabled {
+			c.Next()
+			return
+		}
+
+		ip := c.ClientIP()
+		checks := []rateLimitCheck{{key: "ip:" + ip, rule: rateLimitConfig.PerIP}}
+		if route := matchRouteRateLimit(c); route != nil {
+			checks = append(checks, rateLimitCheck{key: routeRateLimitKey(c) + ":ip:" + ip, rule: route.PerIP})
+		}
+		if !applyRateLimits(c, rateLimitService, checks) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// limitUser 按登录用户限流，访问令牌与用户的登录会话共用额度，返回false时请求已被拒绝
+func limitUser(c *gin.Context, rateLimitService *service.RateLimitService, userID uint) bool {
+	rateLimitConfig := global.Config.RateLimit
+	if !rateLimitConfig.Enabled {
+		return true
+	}
+
+	user := strconv.FormatUint(uint64(userID), 10)
+	checks := []rateLimitCheck{{key: "user:" + user, rule: rateLimitConfig.PerUser}}
+	if route := matchRouteRateLimit(c); route != nil {
+		checks = append(checks, rateLimitCheck{key: routeRateLimitKey(c) + ":user:" + user, rule: route.PerUser})
+	}
+	return applyRateLimits(c, rateLimitService, checks)
+}
+
+// matchRouteRateLimit 查找当前路由的限流规则，按路由模板匹配
+func matchRouteRateLimit(c *gin.Context) *config.RouteRateLimit {
+	path := c.FullPath()
+	if path == "" {
+		return nil
+	}
+	routes := global.Config.RateLimit.Routes
+	for i := range routes {
+		if routes[i].Path == path && (routes[i].Method == "" || strings.EqualFold(routes[i].Method, c.Request.Method)) {
+			return &routes[i]
+		}
+	}
+	return nil
+}
+
+// routeRateLimitKey 单个接口的计数键
+func routeRateLimitKey(c *gin.Context) string {
+	return "route:" + c.Request.Method + ":" + c.FullPath()
+}
+
+// applyRateLimits 依次检查限流规则，响应头反映剩余额度最少的规则，超出任一规则时返回429
+func applyRateLimits(c *gin.Context, rateLimitService *service.RateLimitService, checks []rateLimitCheck) bool {
+	for _, check := range checks {
+		result := rateLimitService.Allow(c.Request.Context(), check.key, check.rule.Limit, check.rule.Window)
+		if result.Limit == 0 {
+			continue
+		}
+		setRateLimitHeaders(c, result)
+		if !result.Allowed {
+			response.TooManyRequests(result.Reset, "请求过于频繁，请稍后重试", c)
+			return false
+		}
+	}
+	return true
+}
+
+// setRateLimitHeaders 设置限流响应头，已有更严格的规则时保留原值
+func setRateLimitHeaders(c *gin.Context, result service.RateLimitResult) {
+	if existing := c.Writer.Header().Get("X-RateLimit-Remaining"); existing != "" {
+		if remaining, err := strconv.Atoi(existing); err == nil && remaining <= result.Remaining {
+			return
+		}
+	}
+	c.Header("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Header("X-RateLimit-Reset", strconv.Itoa(int((result.Reset+time.Second-1)/time.Second)))
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"gin_pipeline/model/response"
+	"gin_pipeline/service"
+	"github.com/gin-gonic/gin"
+)
+
+// RunnerTokenHeader 执行器令牌请求头
+const RunnerTokenHeader = "X-Runner-Token"
+
+// RunnerAuth 远程执行器认证中间件
+func RunnerAuth() gin.HandlerFunc {
+	runnerService := new(service.RunnerService)
+	return func(c *gin.Context) {
+		runner, err := runnerService.Authenticate(c.Request.Header.Get(RunnerTokenHeader))
+		if err != nil {
+			response.FailWithMessage(err.Error(), c)
+			c.Abort()
+			return
+		}
+
+		// 将执行器信息存入上下文
+		c.Set("runner", runner)
+		c.Next()
+	}
+}
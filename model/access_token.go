@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// AccessToken 个人访问令牌，供脚本和CI以用户或服务账号身份调用接口
+type AccessToken struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	UserID     uint       `gorm:"not null;index" json:"user_id"`         // 令牌所属用户
+	Name       string     `gorm:"size:100;not null" json:"name"`         // 令牌名称
+	TokenHash  string     `gorm:"size:64;not null;uniqueIndex" json:"-"` // 令牌的SHA-256
+	Prefix     string     `gorm:"size:16" json:"prefix"`                 // 令牌开头几位，便于识别
+	Scopes     StringList `gorm:"type:json" json:"scopes"`               // 授权范围，如 read, trigger:pipeline, write:artifact
+	ExpiresAt  *time.Time `json:"expires_at"`                            // 过期时间，为空表示不过期
+	LastUsedAt *time.Time `json:"last_used_at"`                          // 最近使用时间
+	LastUsedIP string     `gorm:"size:64" json:"last_used_ip"`           // 最近使用的IP
+	RevokedAt  *time.Time `json:"revoked_at"`                            // 撤销时间，撤销后立即失效
+	CreatorID  uint       `json:"creator_id"`                            // 创建者，服务账号的令牌由管理员创建
+}
+
+// TableName 设置表名
+func (AccessToken) TableName() string {
+	return "access_tokens"
+}
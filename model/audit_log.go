@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// AuditLog 审计日志，记录一次变更操作，写入后不再修改
+type AuditLog struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	CreatedAt    time.Time `gorm:"index" json:"created_at"`
+	ActorID      uint      `gorm:"index" json:"actor_id"`                                 // 操作人，来自JWT
+	ActorName    string    `gorm:"size:50" json:"actor_name"`                             // 操作人用户名
+	ActorRole    string    `gorm:"size:20" json:"actor_role"`                             // 操作时的角色
+	IP           string    `gorm:"size:64" json:"ip"`                                     // 客户端IP，经过可信代理时取自X-Forwarded-For
+	RemoteIP     string    `gorm:"size:64" json:"remote_ip"`                              // 连接的对端地址，经过代理时为代理的地址
+	UserAgent    string    `gorm:"size:255" json:"user_agent"`                            // 客户端标识
+	Method       string    `gorm:"size:10" json:"method"`                                 // HTTP方法
+	Route        string    `gorm:"size:255" json:"route"`                                 // 路由模板
+	Action       string    `gorm:"size:30;index" json:"action"`                           // create, update, delete, trigger, cancel, rollback, activate 等
+	ResourceType string    `gorm:"size:50;index:idx_audit_resource" json:"resource_type"` // 资源类型
+	ResourceID   string    `gorm:"size:64;index:idx_audit_resource" json:"resource_id"`   // 资源ID，创建失败时为空
+	Before       JSONMap   `gorm:"type:json" json:"before"`                               // 操作前的资源
+	After        JSONMap   `gorm:"type:json" json:"after"`                                // 操作后的资源，删除后为空
+	Diff         JSONMap   `gorm:"type:json" json:"diff"`                                 // 变化的字段: {字段: {before, after}}
+	Outcome      string    `gorm:"size:20;index" json:"outcome"`                          // success, failure
+	Error        string    `gorm:"type:text" json:"error"`                                // 失败原因
+	Duration     int64     `json:"duration"`                                              // 处理耗时(毫秒)
+}
+
+// TableName 设置表名
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
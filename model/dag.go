@@ -14,8 +14,35 @@ type DAGNode struct {
 	Name         string   `json:"name"`
 	Type         string   `json:"type"` // task, condition, parallel, etc.
 	Config       JSONMap  `json:"config"`
-	Dependencies []string `json:"dependencies"` // 依赖的节点ID列表
-	Position     JSONMap  `json:"position"`     // 节点在UI中的位置
+	Dependencies []string `json:"dependencies"`      // 依赖的节点ID列表
+	Position     JSONMap  `json:"position"`          // 节点在UI中的位置
+	RunsOn       string   `json:"runs_on,omitempty"` // 执行器标签表达式，如 linux && docker && !gpu
+}
+
+// DAGNodeList 是一个可以存储在数据库中的节点列表
+type DAGNodeList []DAGNode
+
+// Value 实现driver.Valuer接口
+func (l DAGNodeList) Value() (driver.Value, error) {
+	if l == nil {
+		return nil, nil
+	}
+	return json.Marshal(l)
+}
+
+// Scan 实现sql.Scanner接口
+func (l *DAGNodeList) Scan(value interface{}) error {
+	if value == nil {
+		*l = nil
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(bytes, l)
 }
 
 // JSONMap 是一个可以存储在数据库中的JSON对象
@@ -70,6 +97,42 @@ func (j *JSONArray) Scan(value interface{}) error {
 	return json.Unmarshal(bytes, j)
 }
 
+// StringList 是一个可以存储在数据库中的字符串数组
+type StringList []string
+
+// Value 实现driver.Valuer接口
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return nil, nil
+	}
+	return json.Marshal(l)
+}
+
+// Scan 实现sql.Scanner接口
+func (l *StringList) Scan(value interface{}) error {
+	if value == nil {
+		*l = nil
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(bytes, l)
+}
+
+// Contains 是否包含指定字符串
+func (l StringList) Contains(s string) bool {
+	for _, item := range l {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
 // DAG 表示有向无环图
 type DAG struct {
 	ID          uint           `gorm:"primarykey" json:"id"`
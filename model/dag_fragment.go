@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+)
+
+// DAGFragment 可复用的DAG片段，DAG中类型为fragment的节点在校验和运行时展开为片段中的节点
+type DAGFragment struct {
+	ID          uint               `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+	Name        string             `gorm:"size:100;not null;uniqueIndex:idx_fragment_version" json:"name"`
+	Version     int                `gorm:"not null;uniqueIndex:idx_fragment_version" json:"version"` // 版本号，同名片段递增
+	Description string             `gorm:"size:500" json:"description"`
+	Changelog   string             `gorm:"type:text" json:"changelog"` // 变更说明
+	Inputs      PipelineParameters `gorm:"type:json" json:"inputs"`    // 声明的输入，节点配置中通过 ${{ inputs.name }} 引用
+	Nodes       DAGNodeList        `gorm:"type:json" json:"nodes"`     // 片段中的节点，ID在片段内唯一
+	CreatorID   uint               `json:"creator_id"`
+	Creator     User               `gorm:"foreignKey:CreatorID" json:"creator"`
+}
+
+// TableName 设置表名
+func (DAGFragment) TableName() string {
+	return "dag_fragments"
+}
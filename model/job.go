@@ -16,6 +16,7 @@ type Job struct {
 	Command     string         `gorm:"type:text;not null" json:"command"`
 	Image       string         `gorm:"size:255" json:"image"`
 	Timeout     int            `gorm:"default:3600" json:"timeout"` // 超时时间(秒)
+	RunsOn      string         `gorm:"size:255" json:"runs_on"`     // 执行器标签表达式
 	StageID     uint           `json:"stage_id"`
 }
 
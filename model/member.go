@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+// PipelineMember 流水线成员，角色决定成员在该流水线上可以执行的操作
+type PipelineMember struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	PipelineID uint      `gorm:"not null;uniqueIndex:idx_pipeline_member" json:"pipeline_id"`
+	UserID     uint      `gorm:"not null;uniqueIndex:idx_pipeline_member;index" json:"user_id"`
+	Role       string    `gorm:"size:20;not null" json:"role"` // viewer, developer, maintainer
+	User       User      `gorm:"foreignKey:UserID" json:"user"`
+}
+
+// TableName 设置表名
+func (PipelineMember) TableName() string {
+	return "pipeline_members"
+}
+
+// EnvironmentMember 环境成员，部署到生产环境需要单独授权
+type EnvironmentMember struct {
+	ID                  uint      `gorm:"primarykey" json:"id"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+	EnvironmentID       uint      `gorm:"not null;uniqueIndex:idx_environment_member" json:"environment_id"`
+	UserID              uint      `gorm:"not null;uniqueIndex:idx_environment_member;index" json:"user_id"`
+	Role                string    `gorm:"size:20;not null" json:"role"`               // viewer, developer, maintainer
+	CanDeployProduction bool      `gorm:"default:false" json:"can_deploy_production"` // 是否可以部署到生产环境
+	User                User      `gorm:"foreignKey:UserID" json:"user"`
+}
+
+// TableName 设置表名
+func (EnvironmentMember) TableName() string {
+	return "environment_members"
+}
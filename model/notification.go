@@ -0,0 +1,73 @@
+package model
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// NotificationRule 通知规则，指定哪些事件通过哪个渠道发送到哪里
+type NotificationRule struct {
+	ID          uint           `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	Name        string         `gorm:"size:100;not null" json:"name"`
+	PipelineID  uint           `gorm:"default:0;index" json:"pipeline_id"` // 限定的流水线，0表示所有流水线
+	Environment string         `gorm:"size:50" json:"environment"`         // 限定的环境，设置后只匹配该环境的发布事件
+	Events      StringList     `gorm:"type:json" json:"events"`            // run.failed, run.fixed, release.succeeded, release.failed
+	Channel     string         `gorm:"size:20;not null" json:"channel"`    // webhook, email, chat
+	URL         string         `gorm:"size:500" json:"url"`                // webhook和chat渠道的地址
+	Recipients  StringList     `gorm:"type:json" json:"recipients"`        // email渠道的收件人
+	Template    string         `gorm:"type:text" json:"template"`          // webhook渠道的JSON模板，为空时发送事件本身
+	ChatFormat  string         `gorm:"size:20" json:"chat_format"`         // chat渠道的消息格式: slack, dingtalk, feishu
+	Secret      string         `gorm:"size:128" json:"-"`                  // webhook渠道的签名密钥，配置后请求头携带HMAC-SHA256签名
+	Enabled     bool           `gorm:"not null" json:"enabled"`            // 是否启用，创建时未指定则默认启用
+	CreatorID   uint           `json:"creator_id"`                         // 创建者
+}
+
+// TableName 设置表名
+func (NotificationRule) TableName() string {
+	return "notification_rules"
+}
+
+// NotificationSubscription 用户对流水线的订阅，事件通过邮件发送给用户
+type NotificationSubscription struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	UserID     uint       `gorm:"not null;uniqueIndex:idx_subscription" json:"user_id"`
+	PipelineID uint       `gorm:"not null;uniqueIndex:idx_subscription" json:"pipeline_id"`
+	Events     StringList `gorm:"type:json" json:"events"` // 订阅的事件，为空表示运行失败和修复
+}
+
+// TableName 设置表名
+func (NotificationSubscription) TableName() string {
+	return "notification_subscriptions"
+}
+
+// NotificationDelivery 通知投递记录，失败后按退避间隔重试
+type NotificationDelivery struct {
+	ID             uint       `gorm:"primarykey" json:"id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	RuleID         uint       `gorm:"default:0;index" json:"rule_id"`         // 来自通知规则时的规则ID
+	SubscriptionID uint       `gorm:"default:0;index" json:"subscription_id"` // 来自用户订阅时的订阅ID
+	Event          string     `gorm:"size:50;not null" json:"event"`
+	Channel        string     `gorm:"size:20;not null" json:"channel"`
+	Target         string     `gorm:"size:500" json:"target"` // 投递地址或收件人
+	PipelineID     uint       `gorm:"default:0;index" json:"pipeline_id"`
+	RunID          uint       `gorm:"default:0" json:"run_id"`
+	ReleaseID      uint       `gorm:"default:0" json:"release_id"`
+	Payload        string     `gorm:"type:text" json:"payload"`                    // 渲染后的请求体或邮件正文
+	Subject        string     `gorm:"size:255" json:"subject"`                     // 邮件主题
+	Status         string     `gorm:"size:20;default:pending;index" json:"status"` // pending, success, failed
+	Attempts       int        `gorm:"default:0" json:"attempts"`                   // 已尝试次数
+	LastError      string     `gorm:"size:500" json:"last_error"`                  // 最近一次失败的原因
+	NextAttemptAt  *time.Time `gorm:"index" json:"next_attempt_at"`                // 下次尝试时间，不再重试时为空
+	DeliveredAt    *time.Time `json:"delivered_at"`                                // 投递成功的时间
+}
+
+// TableName 设置表名
+func (NotificationDelivery) TableName() string {
+	return "notification_deliveries"
+}
@@ -7,19 +7,26 @@ import (
 
 // Pipeline 流水线模型
 type Pipeline struct {
-	ID          uint           `gorm:"primarykey" json:"id"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
-	Name        string         `gorm:"size:100;not null" json:"name"`
-	Description string         `gorm:"size:500" json:"description"`
-	GitRepo     string         `gorm:"size:255;not null" json:"git_repo"`
-	GitBranch   string         `gorm:"size:100;default:main" json:"git_branch"`
-	Status      string         `gorm:"size:20;default:inactive" json:"status"` // inactive, active, running, success, failed
-	LastRunAt   *time.Time     `json:"last_run_at"`
-	CreatorID   uint           `json:"creator_id"`
-	Creator     User           `gorm:"foreignKey:CreatorID" json:"creator"`
-	Stages      []Stage        `gorm:"foreignKey:PipelineID" json:"stages"`
+	ID                  uint               `gorm:"primarykey" json:"id"`
+	CreatedAt           time.Time          `json:"created_at"`
+	UpdatedAt           time.Time          `json:"updated_at"`
+	DeletedAt           gorm.DeletedAt     `gorm:"index" json:"-"`
+	Name                string             `gorm:"size:100;not null" json:"name"`
+	Description         string             `gorm:"size:500" json:"description"`
+	GitRepo             string             `gorm:"size:255;not null" json:"git_repo"`
+	GitBranch           string             `gorm:"size:100;default:main" json:"git_branch"`
+	Status              string             `gorm:"size:20;default:inactive" json:"status"` // inactive, active, running, success, failed
+	LastRunAt           *time.Time         `json:"last_run_at"`
+	Priority            int                `gorm:"default:0" json:"priority"`             // 默认运行优先级，数值越大越优先
+	ConcurrencyGroup    string             `gorm:"size:255" json:"concurrency_group"`     // 并发组，支持 {pipeline} 和 {branch} 占位符，如 deploy-{branch}
+	ConcurrencyPolicy   string             `gorm:"size:20" json:"concurrency_policy"`     // 同组已有运行时的策略: queue, cancel, skip
+	WebhookSecret       string             `gorm:"size:128" json:"-"`                     // Webhook签名密钥
+	WebhookBranchFilter string             `gorm:"size:500" json:"webhook_branch_filter"` // 触发的分支，逗号分隔的通配符，为空时只匹配默认分支
+	WebhookTagFilter    string             `gorm:"size:500" json:"webhook_tag_filter"`    // 触发的标签，逗号分隔的通配符，为空时标签推送不触发
+	Parameters          PipelineParameters `gorm:"type:json" json:"parameters"`           // 触发参数声明
+	CreatorID           uint               `json:"creator_id"`
+	Creator             User               `gorm:"foreignKey:CreatorID" json:"creator"`
+	Stages              []Stage            `gorm:"foreignKey:PipelineID" json:"stages"`
 }
 
 // TableName 设置表名
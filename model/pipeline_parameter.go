@@ -0,0 +1,43 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+// PipelineParameter 流水线声明的触发参数
+type PipelineParameter struct {
+	Name        string      `json:"name"`                  // 参数名，只能包含字母、数字和下划线
+	Type        string      `json:"type"`                  // string, number, boolean, choice
+	Description string      `json:"description,omitempty"` // 参数说明
+	Default     interface{} `json:"default,omitempty"`     // 默认值
+	Required    bool        `json:"required,omitempty"`    // 是否必填，必填参数没有默认值时触发必须提供
+	Options     []string    `json:"options,omitempty"`     // choice类型的可选值
+}
+
+// PipelineParameters 流水线参数列表
+type PipelineParameters []PipelineParameter
+
+// Value 实现driver.Valuer接口
+func (p PipelineParameters) Value() (driver.Value, error) {
+	if p == nil {
+		return nil, nil
+	}
+	return json.Marshal(p)
+}
+
+// Scan 实现sql.Scanner接口
+func (p *PipelineParameters) Scan(value interface{}) error {
+	if value == nil {
+		*p = nil
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(bytes, p)
+}
@@ -7,21 +7,35 @@ import (
 
 // PipelineRun 流水线运行记录
 type PipelineRun struct {
-	ID         uint           `gorm:"primarykey" json:"id"`
-	CreatedAt  time.Time      `json:"created_at"`
-	UpdatedAt  time.Time      `json:"updated_at"`
-	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
-	PipelineID uint           `json:"pipeline_id"`
-	Pipeline   Pipeline       `gorm:"foreignKey:PipelineID" json:"pipeline"`
-	Status     string         `gorm:"size:20;default:pending" json:"status"` // pending, running, success, failed, canceled
-	StartTime  *time.Time     `json:"start_time"`
-	EndTime    *time.Time     `json:"end_time"`
-	Duration   int            `json:"duration"` // 持续时间(秒)
-	GitBranch  string         `gorm:"size:100" json:"git_branch"`
-	GitCommit  string         `gorm:"size:100" json:"git_commit"`
-	TriggerBy  uint           `json:"trigger_by"`
-	User       User           `gorm:"foreignKey:TriggerBy" json:"user"`
-	Logs       string         `gorm:"type:longtext" json:"logs"`
+	ID               uint           `gorm:"primarykey" json:"id"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
+	PipelineID       uint           `json:"pipeline_id"`
+	Pipeline         Pipeline       `gorm:"foreignKey:PipelineID" json:"pipeline"`
+	Status           string         `gorm:"size:20;default:pending" json:"status"` // pending, running, success, failed, canceled, skipped
+	Priority         int            `gorm:"default:0" json:"priority"`             // 运行优先级，数值越大越优先
+	StartTime        *time.Time     `json:"start_time"`
+	EndTime          *time.Time     `json:"end_time"`
+	Duration         int            `json:"duration"` // 持续时间(秒)
+	GitBranch        string         `gorm:"size:100" json:"git_branch"`
+	GitCommit        string         `gorm:"size:100" json:"git_commit"`
+	TriggerBy        uint           `json:"trigger_by"`
+	User             User           `gorm:"foreignKey:TriggerBy" json:"user"`
+	Logs             string         `gorm:"type:longtext" json:"logs"`
+	ConcurrencyGroup string         `gorm:"size:255;index" json:"concurrency_group"`            // 解析后的并发组
+	SupersededBy     uint           `gorm:"default:0" json:"superseded_by"`                     // 取代该运行的运行ID：被取消时为新运行，被跳过时为正在进行的运行
+	ScheduleID       uint           `gorm:"default:0;index" json:"schedule_id"`                 // 触发该运行的定时任务ID，手动触发时为0
+	TriggerType      string         `gorm:"size:20;default:manual" json:"trigger_type"`         // manual, schedule, webhook
+	GitRef           string         `gorm:"size:255" json:"git_ref"`                            // 完整的Git引用，如 refs/heads/main
+	Pusher           string         `gorm:"size:100" json:"pusher"`                             // Webhook推送者
+	Parameters       JSONMap        `gorm:"type:json" json:"parameters"`                        // 本次运行的参数值
+	DAGID            uint           `gorm:"default:0" json:"dag_id"`                            // 使用的已存储DAG，来自流水线文件或阶段时为0
+	DAGSource        string         `gorm:"size:20" json:"dag_source"`                          // DAG来源: pipeline_file, dag, stages
+	DAGNodes         DAGNodeList    `gorm:"type:json" json:"dag_nodes"`                         // 本次运行使用的DAG快照
+	TaskStatuses     JSONMap        `gorm:"type:json" json:"task_statuses"`                     // 各任务的状态，键为节点ID
+	TraceID          string         `gorm:"size:32;index" json:"trace_id"`                      // 链路追踪ID，未启用或未采样时为空
+	Instance         string         `gorm:"size:100;not null;default:'';index" json:"instance"` // 调度该运行的服务实例，实例停止后由其他实例接管
 }
 
 // TableName 设置表名
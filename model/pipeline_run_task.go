@@ -0,0 +1,24 @@
+package model
+
+import (
+	"time"
+)
+
+// PipelineRunTask 流水线运行中单个任务的执行记录
+type PipelineRunTask struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	RunID      uint       `gorm:"not null;uniqueIndex:idx_run_node" json:"run_id"`
+	NodeID     string     `gorm:"size:100;not null;uniqueIndex:idx_run_node" json:"node_id"`
+	Status     string     `gorm:"size:20;index" json:"status"` // running, success, failed
+	StartedAt  *time.Time `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+	Duration   float64    `json:"duration"` // 持续时间(秒)
+	Error      string     `gorm:"type:text" json:"error"`
+}
+
+// TableName 设置表名
+func (PipelineRunTask) TableName() string {
+	return "pipeline_run_tasks"
+}
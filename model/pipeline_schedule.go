@@ -0,0 +1,31 @@
+package model
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// PipelineSchedule 流水线定时触发配置
+type PipelineSchedule struct {
+	ID         uint           `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+	PipelineID uint           `gorm:"index;not null" json:"pipeline_id"`
+	Name       string         `gorm:"size:100" json:"name"`
+	Cron       string         `gorm:"size:100;not null" json:"cron"`       // 标准5段cron表达式，支持 @daily 等描述符
+	Timezone   string         `gorm:"size:64;default:UTC" json:"timezone"` // IANA时区，如 Asia/Shanghai
+	GitBranch  string         `gorm:"size:100" json:"git_branch"`          // 为空时使用流水线的默认分支
+	Parameters JSONMap        `gorm:"type:json" json:"parameters"`         // 触发参数
+	Enabled    bool           `gorm:"not null" json:"enabled"`             // 是否启用，创建时未指定则默认启用
+	CreatorID  uint           `json:"creator_id"`                          // 以该用户身份触发
+	LastFireAt *time.Time     `json:"last_fire_at"`                        // 上次触发时间
+	LastRunID  uint           `json:"last_run_id"`                         // 上次触发的运行ID
+	NextFireAt *time.Time     `gorm:"index" json:"next_fire_at"`           // 下次触发时间
+	LastError  string         `gorm:"size:500" json:"last_error"`          // 上次触发失败的原因
+}
+
+// TableName 设置表名
+func (PipelineSchedule) TableName() string {
+	return "pipeline_schedules"
+}
@@ -7,21 +7,22 @@ import (
 
 // Release 发布模型
 type Release struct {
-	ID           uint           `gorm:"primarykey" json:"id"`
-	CreatedAt    time.Time      `json:"created_at"`
-	UpdatedAt    time.Time      `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
-	Version      string         `gorm:"size:50;not null" json:"version"`
-	Description  string         `gorm:"size:500" json:"description"`
-	ReleaseNotes string         `gorm:"type:text" json:"release_notes"`
-	Status       string         `gorm:"size:20;default:pending" json:"status"` // pending, in_progress, success, failed, rolled_back
-	Environment  string         `gorm:"size:50;not null" json:"environment"`   // development, testing, staging, production
-	ArtifactID   uint           `json:"artifact_id"`
-	Artifact     Artifact       `gorm:"foreignKey:ArtifactID" json:"artifact"`
-	DeployedAt   time.Time      `json:"deployed_at"`
-	DeployedBy   uint           `json:"deployed_by"`
-	User         User           `gorm:"foreignKey:DeployedBy" json:"user"`
-	IsRollback   bool           `gorm:"default:false" json:"is_rollback"`
+	ID            uint           `gorm:"primarykey" json:"id"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+	Version       string         `gorm:"size:50;not null" json:"version"`
+	Description   string         `gorm:"size:500" json:"description"`
+	ReleaseNotes  string         `gorm:"type:text" json:"release_notes"`
+	Status        string         `gorm:"size:20;default:pending" json:"status"` // pending, in_progress, success, failed, rolled_back
+	Environment   string         `gorm:"size:50;not null" json:"environment"`   // development, testing, staging, production
+	EnvironmentID uint           `gorm:"index" json:"environment_id"`           // 目标环境ID，为0时没有登记该类型的环境
+	ArtifactID    uint           `json:"artifact_id"`
+	Artifact      Artifact       `gorm:"foreignKey:ArtifactID" json:"artifact"`
+	DeployedAt    time.Time      `json:"deployed_at"`
+	DeployedBy    uint           `json:"deployed_by"`
+	User          User           `gorm:"foreignKey:DeployedBy" json:"user"`
+	IsRollback    bool           `gorm:"default:false" json:"is_rollback"`
 }
 
 // TableName 设置表名
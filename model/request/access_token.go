@@ -0,0 +1,17 @@
+package request
+
+import "time"
+
+// CreateAccessToken 创建访问令牌请求参数
+type CreateAccessToken struct {
+	Name      string     `json:"name" binding:"required,max=100"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"` // read, trigger:pipeline, write:pipeline, write:artifact, deploy:release, write:environment, write:template, write:notification, admin
+	ExpiresAt *time.Time `json:"expires_at"`                      // 过期时间(RFC3339)，为空表示不过期
+}
+
+// CreateServiceAccount 创建服务账号请求参数
+type CreateServiceAccount struct {
+	Username string `json:"username" binding:"required,min=3,max=50"`
+	Name     string `json:"name" binding:"required,max=50"`
+	Role     string `json:"role" binding:"required,oneof=viewer developer maintainer admin"`
+}
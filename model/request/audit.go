@@ -0,0 +1,15 @@
+package request
+
+import "time"
+
+// AuditLogSearch 审计日志查询参数
+type AuditLogSearch struct {
+	PageInfo
+	ActorID      uint       `form:"actor_id"`                                          // 操作人ID
+	ResourceType string     `form:"resource_type"`                                     // 资源类型
+	ResourceID   string     `form:"resource_id"`                                       // 资源ID
+	Action       string     `form:"action"`                                            // 操作
+	Outcome      string     `form:"outcome" binding:"omitempty,oneof=success failure"` // 结果
+	Since        *time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"`     // 开始时间(RFC3339)，包含
+	Until        *time.Time `form:"until" time_format:"2006-01-02T15:04:05Z07:00"`     // 结束时间(RFC3339)，不包含
+}
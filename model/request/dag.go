@@ -21,3 +21,22 @@ type UpdateDAG struct {
 type ValidateDAG struct {
 	Nodes []model.DAGNode `json:"nodes" binding:"required"`
 }
+
+// ValidatePipelineFile 验证流水线文件请求参数
+type ValidatePipelineFile struct {
+	Content string `json:"content" binding:"required"` // 流水线文件的YAML内容
+}
+
+// CreateDAGFragment 创建DAG片段版本请求参数
+type CreateDAGFragment struct {
+	Name        string                    `json:"name" binding:"required"`
+	Description string                    `json:"description"`
+	Changelog   string                    `json:"changelog"`
+	Inputs      []model.PipelineParameter `json:"inputs"`
+	Nodes       []model.DAGNode           `json:"nodes" binding:"required"`
+}
+
+// ExpandDAG 展开DAG片段请求参数
+type ExpandDAG struct {
+	Nodes []model.DAGNode `json:"nodes" binding:"required"`
+}
@@ -0,0 +1,25 @@
+package request
+
+// AddPipelineMember 添加流水线成员请求参数
+type AddPipelineMember struct {
+	UserID uint   `json:"user_id" binding:"required"`
+	Role   string `json:"role" binding:"required,oneof=viewer developer maintainer"`
+}
+
+// UpdatePipelineMember 修改流水线成员请求参数
+type UpdatePipelineMember struct {
+	Role string `json:"role" binding:"required,oneof=viewer developer maintainer"`
+}
+
+// AddEnvironmentMember 添加环境成员请求参数
+type AddEnvironmentMember struct {
+	UserID              uint   `json:"user_id" binding:"required"`
+	Role                string `json:"role" binding:"required,oneof=viewer developer maintainer"`
+	CanDeployProduction bool   `json:"can_deploy_production"` // 是否可以部署到生产环境
+}
+
+// UpdateEnvironmentMember 修改环境成员请求参数
+type UpdateEnvironmentMember struct {
+	Role                string `json:"role" binding:"required,oneof=viewer developer maintainer"`
+	CanDeployProduction bool   `json:"can_deploy_production"` // 是否可以部署到生产环境
+}
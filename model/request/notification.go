@@ -0,0 +1,36 @@
+package request
+
+// CreateNotificationRule 创建通知规则请求参数
+type CreateNotificationRule struct {
+	Name        string   `json:"name" binding:"required,max=100"`
+	PipelineID  uint     `json:"pipeline_id"` // 0表示所有流水线
+	Environment string   `json:"environment" binding:"max=50"`
+	Events      []string `json:"events" binding:"required,min=1"`
+	Channel     string   `json:"channel" binding:"required,oneof=webhook email chat"`
+	URL         string   `json:"url" binding:"max=500"`
+	Recipients  []string `json:"recipients"`
+	Template    string   `json:"template"`
+	ChatFormat  string   `json:"chat_format"`
+	Secret      string   `json:"secret" binding:"max=128"`
+	Enabled     *bool    `json:"enabled"` // 默认启用
+}
+
+// UpdateNotificationRule 更新通知规则请求参数
+type UpdateNotificationRule struct {
+	Name        *string  `json:"name" binding:"omitempty,max=100"`
+	PipelineID  *uint    `json:"pipeline_id"`
+	Environment *string  `json:"environment" binding:"omitempty,max=50"`
+	Events      []string `json:"events"`
+	Channel     *string  `json:"channel" binding:"omitempty,oneof=webhook email chat"`
+	URL         *string  `json:"url" binding:"omitempty,max=500"`
+	Recipients  []string `json:"recipients"`
+	Template    *string  `json:"template"`
+	ChatFormat  *string  `json:"chat_format"`
+	Secret      *string  `json:"secret" binding:"omitempty,max=128"` // 空字符串表示清除密钥
+	Enabled     *bool    `json:"enabled"`
+}
+
+// SubscribePipeline 订阅流水线请求参数
+type SubscribePipeline struct {
+	Events []string `json:"events"` // 为空时订阅运行失败和修复
+}
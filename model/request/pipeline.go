@@ -1,12 +1,18 @@
 package request
 
+import "gin_pipeline/model"
+
 // CreatePipeline 创建流水线请求参数
 type CreatePipeline struct {
-	Name        string  `json:"name" binding:"required,min=2,max=100"`
-	Description string  `json:"description"`
-	GitRepo     string  `json:"git_repo" binding:"required"`
-	GitBranch   string  `json:"git_branch" default:"main"`
-	Stages      []Stage `json:"stages" binding:"required,min=1"`
+	Name              string                    `json:"name" binding:"required,min=2,max=100"`
+	Description       string                    `json:"description"`
+	GitRepo           string                    `json:"git_repo" binding:"required"`
+	GitBranch         string                    `json:"git_branch" default:"main"`
+	Priority          int                       `json:"priority" binding:"min=-100,max=100"`
+	ConcurrencyGroup  string                    `json:"concurrency_group"`
+	ConcurrencyPolicy string                    `json:"concurrency_policy" binding:"omitempty,oneof=queue cancel skip"`
+	Parameters        []model.PipelineParameter `json:"parameters"`
+	Stages            []Stage                   `json:"stages" binding:"required,min=1"`
 }
 
 // Stage 阶段请求参数
@@ -24,18 +30,25 @@ type Job struct {
 	Command     string `json:"command" binding:"required"`
 	Image       string `json:"image"`
 	Timeout     int    `json:"timeout" default:"3600"`
+	RunsOn      string `json:"runs_on"`
 }
 
 // UpdatePipeline 更新流水线请求参数
 type UpdatePipeline struct {
-	Name        string `json:"name" binding:"required,min=2,max=100"`
-	Description string `json:"description"`
-	GitRepo     string `json:"git_repo" binding:"required"`
-	GitBranch   string `json:"git_branch" default:"main"`
-	Status      string `json:"status"`
+	Name              string                     `json:"name" binding:"required,min=2,max=100"`
+	Description       string                     `json:"description"`
+	GitRepo           string                     `json:"git_repo" binding:"required"`
+	GitBranch         string                     `json:"git_branch" default:"main"`
+	Status            string                     `json:"status"`
+	Priority          *int                       `json:"priority" binding:"omitempty,min=-100,max=100"`
+	ConcurrencyGroup  *string                    `json:"concurrency_group"`
+	ConcurrencyPolicy *string                    `json:"concurrency_policy" binding:"omitempty,oneof=queue cancel skip"`
+	Parameters        *[]model.PipelineParameter `json:"parameters"`
 }
 
 // TriggerPipeline 触发流水线请求参数
 type TriggerPipeline struct {
-	GitBranch string `json:"git_branch"`
+	GitBranch  string                 `json:"git_branch"`
+	Priority   *int                   `json:"priority"`   // 不指定时使用流水线的默认优先级，范围-100到100，与默认值不同时需要维护者权限
+	Parameters map[string]interface{} `json:"parameters"` // 触发参数，未提供的参数使用默认值
 }
@@ -2,11 +2,12 @@ package request
 
 // CreateRelease 创建发布请求参数
 type CreateRelease struct {
-	Version      string `json:"version" binding:"required"`
-	Description  string `json:"description"`
-	ReleaseNotes string `json:"release_notes"`
-	Environment  string `json:"environment" binding:"required"`
-	ArtifactID   uint   `json:"artifact_id" binding:"required"`
+	Version       string `json:"version" binding:"required"`
+	Description   string `json:"description"`
+	ReleaseNotes  string `json:"release_notes"`
+	Environment   string `json:"environment" binding:"required"`
+	EnvironmentID uint   `json:"environment_id"` // 目标环境ID，同一类型登记了多个环境时必须指定
+	ArtifactID    uint   `json:"artifact_id" binding:"required"`
 }
 
 // UpdateRelease 更新发布请求参数
@@ -0,0 +1,32 @@
+package request
+
+import "gin_pipeline/model"
+
+// RegisterRunner 注册执行器请求参数
+type RegisterRunner struct {
+	Token    string `json:"token" binding:"required"`
+	Name     string `json:"name" binding:"required,min=1,max=100"`
+	Labels   string `json:"labels"`
+	Capacity int    `json:"capacity"`
+	Version  string `json:"version"`
+}
+
+// RunnerHeartbeat 执行器心跳请求参数
+type RunnerHeartbeat struct {
+	Labels   string `json:"labels"`
+	Capacity int    `json:"capacity"`
+	Running  []uint `json:"running"` // 执行器上正在运行的任务ID
+	Version  string `json:"version"`
+}
+
+// RunnerTaskLogs 上报任务日志请求参数
+type RunnerTaskLogs struct {
+	Logs string `json:"logs"`
+}
+
+// RunnerTaskResult 上报任务结果请求参数
+type RunnerTaskResult struct {
+	Status  string        `json:"status" binding:"required,oneof=success failed"`
+	Error   string        `json:"error"`
+	Outputs model.JSONMap `json:"outputs"`
+}
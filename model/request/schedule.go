@@ -0,0 +1,21 @@
+package request
+
+// CreatePipelineSchedule 创建定时任务请求参数
+type CreatePipelineSchedule struct {
+	Name       string                 `json:"name" binding:"max=100"`
+	Cron       string                 `json:"cron" binding:"required"`
+	Timezone   string                 `json:"timezone"`
+	GitBranch  string                 `json:"git_branch"`
+	Parameters map[string]interface{} `json:"parameters"`
+	Enabled    *bool                  `json:"enabled"` // 默认启用
+}
+
+// UpdatePipelineSchedule 更新定时任务请求参数
+type UpdatePipelineSchedule struct {
+	Name       *string                `json:"name" binding:"omitempty,max=100"`
+	Cron       *string                `json:"cron"`
+	Timezone   *string                `json:"timezone"`
+	GitBranch  *string                `json:"git_branch"`
+	Parameters map[string]interface{} `json:"parameters"`
+	Enabled    *bool                  `json:"enabled"`
+}
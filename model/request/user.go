@@ -28,3 +28,13 @@ type UpdateUserInfo struct {
 	Phone  string `json:"phone"`
 	Avatar string `json:"avatar"`
 }
+
+// UpdateUserRole 修改用户角色请求参数
+type UpdateUserRole struct {
+	Role string `json:"role" binding:"required,oneof=viewer developer maintainer admin"`
+}
+
+// RefreshToken 刷新令牌请求参数
+type RefreshToken struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
@@ -0,0 +1,8 @@
+package request
+
+// UpdatePipelineWebhook 更新流水线Webhook配置请求参数
+type UpdatePipelineWebhook struct {
+	BranchFilter     *string `json:"branch_filter"`     // 逗号分隔的分支通配符
+	TagFilter        *string `json:"tag_filter"`        // 逗号分隔的标签通配符
+	RegenerateSecret bool    `json:"regenerate_secret"` // 是否重新生成密钥
+}
@@ -3,6 +3,8 @@ package response
 import (
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 // Response 统一响应结构
@@ -68,3 +70,17 @@ func FailWithMessage(message string, c *gin.Context) {
 func FailWithDetailed(data interface{}, message string, c *gin.Context) {
 	Result(ERROR, data, message, c)
 }
+
+// TooManyRequests 请求过于频繁，返回HTTP 429和Retry-After(秒，向上取整)
+func TooManyRequests(retryAfter time.Duration, message string, c *gin.Context) {
+	seconds := int((retryAfter + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, Response{
+		Code: ERROR,
+		Data: map[string]interface{}{"retry_after": seconds},
+		Msg:  message,
+	})
+}
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// Runner 远程执行器
+type Runner struct {
+	ID              uint           `gorm:"primarykey" json:"id"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
+	Name            string         `gorm:"size:100;not null" json:"name"`
+	TokenHash       string         `gorm:"size:64;not null;uniqueIndex" json:"-"` // 执行器令牌的SHA-256
+	Labels          string         `gorm:"size:500" json:"labels"`                // 逗号分隔的标签
+	Capacity        int            `gorm:"default:1" json:"capacity"`             // 最大并发任务数
+	Running         int            `gorm:"default:0" json:"running"`              // 上报的运行中任务数
+	Status          string         `gorm:"size:20;default:online" json:"status"`  // online, offline
+	Version         string         `gorm:"size:50" json:"version"`
+	IP              string         `gorm:"size:50" json:"ip"`
+	LastHeartbeatAt *time.Time     `json:"last_heartbeat_at"`
+}
+
+// TableName 设置表名
+func (Runner) TableName() string {
+	return "runners"
+}
+
+// RunnerTask 分发给远程执行器的任务
+type RunnerTask struct {
+	ID                uint           `gorm:"primarykey" json:"id"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `gorm:"index" json:"-"`
+	RunID             uint           `gorm:"index" json:"run_id"`
+	NodeID            string         `gorm:"size:100;not null" json:"node_id"`
+	Name              string         `gorm:"size:255" json:"name"`
+	Type              string         `gorm:"size:50;not null" json:"type"`
+	Config            JSONMap        `gorm:"type:json" json:"config"`
+	Env               JSONMap        `gorm:"type:json" json:"env"`                        // 任务环境变量
+	RunsOn            string         `gorm:"size:255" json:"runs_on"`                     // 执行器标签表达式
+	Status            string         `gorm:"size:20;default:pending;index" json:"status"` // pending, assigned, running, success, failed, canceled
+	UnscheduledReason string         `gorm:"size:255" json:"unscheduled_reason"`          // 未被调度的原因
+	RunnerID          uint           `gorm:"index" json:"runner_id"`
+	Attempts          int            `gorm:"default:0" json:"attempts"` // 已分配次数
+	Logs              string         `gorm:"type:longtext" json:"logs"`
+	Error             string         `gorm:"type:text" json:"error"`
+	Outputs           JSONMap        `gorm:"type:json" json:"outputs"`
+	AssignedAt        *time.Time     `json:"assigned_at"`
+	StartedAt         *time.Time     `json:"started_at"`
+	FinishedAt        *time.Time     `json:"finished_at"`
+}
+
+// TableName 设置表名
+func (RunnerTask) TableName() string {
+	return "runner_tasks"
+}
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// Session 登录会话，保存在Redis中
+// 每个会话持有一个刷新令牌，刷新时轮换，注销会话后它签发的访问令牌立即失效
+type Session struct {
+	ID            string    `json:"id"`
+	UserID        uint      `json:"user_id"`
+	Device        string    `json:"device"`          // 登录设备(User-Agent)
+	IP            string    `json:"ip"`              // 最近一次登录或刷新的IP
+	CreatedAt     time.Time `json:"created_at"`      // 登录时间
+	LastRefreshAt time.Time `json:"last_refresh_at"` // 最近一次刷新时间
+	ExpiresAt     time.Time `json:"expires_at"`      // 刷新令牌过期时间，过期后需要重新登录
+	Current       bool      `json:"current"`         // 是否为发起请求的会话
+}
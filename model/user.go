@@ -7,18 +7,21 @@ import (
 
 // User 用户模型
 type User struct {
-	ID        uint           `gorm:"primarykey" json:"id"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
-	Username  string         `gorm:"size:50;not null;unique" json:"username"`
-	Password  string         `gorm:"size:100;not null" json:"-"`
-	Name      string         `gorm:"size:50" json:"name"`
-	Email     string         `gorm:"size:100" json:"email"`
-	Phone     string         `gorm:"size:20" json:"phone"`
-	Avatar    string         `gorm:"size:255" json:"avatar"`
-	Role      string         `gorm:"size:20;default:user" json:"role"` // admin, user
-	LastLogin time.Time      `json:"last_login"`
+	ID         uint           `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+	Username   string         `gorm:"size:50;not null;unique" json:"username"`
+	Password   string         `gorm:"size:100;not null" json:"-"`
+	Name       string         `gorm:"size:50" json:"name"`
+	Email      string         `gorm:"size:100" json:"email"`
+	Phone      string         `gorm:"size:20" json:"phone"`
+	Avatar     string         `gorm:"size:255" json:"avatar"`
+	Role       string         `gorm:"size:20;default:developer" json:"role"` // admin, maintainer, developer, viewer，旧的user按developer处理
+	LastLogin  time.Time      `json:"last_login"`
+	IsService  bool           `gorm:"default:false" json:"is_service"`       // 是否为服务账号，服务账号不能登录，只能使用访问令牌
+	Provider   string         `gorm:"size:20;default:local" json:"provider"` // 账号来源: local, oidc
+	ExternalID string         `gorm:"size:255;index" json:"external_id"`     // 身份提供方的用户标识(sub)
 }
 
 // TableName 设置表名
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+)
+
+// WebhookDelivery Webhook投递记录，用于去重和排查
+type WebhookDelivery struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	PipelineID uint      `gorm:"uniqueIndex:idx_webhook_delivery_pipeline" json:"pipeline_id"`                   // 同一投递可以发给多个流水线，按流水线去重
+	Provider   string    `gorm:"size:20;not null;uniqueIndex:idx_webhook_delivery_pipeline" json:"provider"`     // github, gitlab, gitea
+	DeliveryID string    `gorm:"size:100;not null;uniqueIndex:idx_webhook_delivery_pipeline" json:"delivery_id"` // 平台提供的投递ID
+	Event      string    `gorm:"size:50" json:"event"`
+	Ref        string    `gorm:"size:255" json:"ref"`
+	Commit     string    `gorm:"size:100" json:"commit"`
+	Status     string    `gorm:"size:20" json:"status"` // triggered, ignored, failed
+	Message    string    `gorm:"size:500" json:"message"`
+	RunID      uint      `json:"run_id"`
+}
+
+// TableName 设置表名
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
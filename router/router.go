@@ -3,6 +3,7 @@ package router
 import (
 	v1 "gin_pipeline/api/v1"
 	"gin_pipeline/middleware"
+	"gin_pipeline/service"
 	"github.com/gin-gonic/gin"
 )
 
@@ -20,142 +21,279 @@ func InitPublicRouter(Router *gin.RouterGroup) {
 		// 用户注册登录
 		PublicRouter.POST("/user/register", v1.Register)
 		PublicRouter.POST("/user/login", v1.Login)
+		PublicRouter.POST("/user/refresh", v1.RefreshToken)
+
+		// 单点登录
+		PublicRouter.GET("/user/auth-options", v1.GetAuthOptions)
+		PublicRouter.GET("/user/oidc/login", v1.OIDCLogin)
+		PublicRouter.GET("/user/oidc/callback", v1.OIDCCallback)
 	}
 }
 
 // InitUserRouter 初始化用户路由
 func InitUserRouter(Router *gin.RouterGroup) {
-	UserRouter := Router.Group("/user").Use(middleware.JWTAuth())
+	// 个人信息、访问令牌和会话只能通过登录会话管理
+	UserRouter := Router.Group("/user").Use(middleware.JWTAuth(), middleware.SessionOnly())
 	{
 		UserRouter.GET("/info", v1.GetUserInfo)
-		UserRouter.PUT("/info", v1.UpdateUserInfo)
-		UserRouter.PUT("/password", v1.ChangePassword)
+		UserRouter.PUT("/info", middleware.Audit("user", "update"), v1.UpdateUserInfo)
+		UserRouter.PUT("/password", middleware.Audit("user", "change_password"), v1.ChangePassword)
+		UserRouter.GET("/tokens", v1.GetAccessTokens)
+		UserRouter.POST("/tokens", middleware.Audit("access_token", "create"), v1.CreateAccessToken)
+		UserRouter.DELETE("/tokens/:tokenId", middleware.Audit("access_token", "revoke"), v1.RevokeAccessToken)
+		UserRouter.POST("/logout", middleware.Audit("session", "logout"), v1.Logout)
+		UserRouter.GET("/sessions", v1.GetSessions)
+		UserRouter.DELETE("/sessions", middleware.Audit("session", "revoke_all"), v1.RevokeAllSessions)
+		UserRouter.DELETE("/sessions/:sessionId", middleware.Audit("session", "revoke"), v1.RevokeSession)
+		UserRouter.POST("/oidc/link", middleware.Audit("user", "link_oidc"), v1.LinkOIDC)
+	}
+	UserAdminRouter := Router.Group("/user").Use(middleware.JWTAuth(), middleware.AdminAuth(), middleware.RequireScope(service.ScopeAdmin))
+	{
+		UserAdminRouter.PUT("/:id/role", middleware.Audit("user", "update_role"), v1.UpdateUserRole)
 	}
 }
 
 // InitPipelineRouter 初始化流水线路由
 func InitPipelineRouter(Router *gin.RouterGroup) {
-	PipelineRouter := Router.Group("/pipeline").Use(middleware.JWTAuth())
+	PipelineRouter := Router.Group("/pipeline").Use(middleware.JWTAuth(), middleware.RequireScope(service.ScopeWritePipeline))
 	{
-		PipelineRouter.POST("", v1.CreatePipeline)
+		PipelineRouter.POST("", middleware.Audit("pipeline", "create"), middleware.RequireRole(service.RoleDeveloper), v1.CreatePipeline)
 		PipelineRouter.GET("", v1.GetPipelines)
+		PipelineRouter.GET("/queue", v1.GetRunQueue)
 		PipelineRouter.GET("/:id", v1.GetPipelineByID)
-		PipelineRouter.PUT("/:id", v1.UpdatePipeline)
-		PipelineRouter.DELETE("/:id", v1.DeletePipeline)
-		PipelineRouter.POST("/:id/trigger", v1.TriggerPipeline)
+		PipelineRouter.PUT("/:id", middleware.Audit("pipeline", "update"), middleware.Authorize("pipeline", "id", service.RoleMaintainer), v1.UpdatePipeline)
+		PipelineRouter.DELETE("/:id", middleware.Audit("pipeline", "delete"), middleware.Authorize("pipeline", "id", service.RoleMaintainer), v1.DeletePipeline)
 		PipelineRouter.GET("/:id/runs", v1.GetPipelineRuns)
 		PipelineRouter.GET("/:id/runs/:runId", v1.GetPipelineRunByID)
 		PipelineRouter.GET("/:id/runs/:runId/logs", v1.GetPipelineRunLogs)
-		PipelineRouter.POST("/:id/runs/:runId/cancel", v1.CancelPipelineRun)
+		PipelineRouter.GET("/:id/runs/:runId/queue", v1.GetPipelineRunQueue)
+		PipelineRouter.POST("/:id/schedules", middleware.Audit("pipeline_schedule", "create"), middleware.Authorize("pipeline", "id", service.RoleMaintainer), v1.CreatePipelineSchedule)
+		PipelineRouter.GET("/:id/schedules", v1.GetPipelineSchedules)
+		PipelineRouter.GET("/:id/schedules/:scheduleId", v1.GetPipelineSchedule)
+		PipelineRouter.PUT("/:id/schedules/:scheduleId", middleware.Audit("pipeline_schedule", "update"), middleware.Authorize("pipeline", "id", service.RoleMaintainer), v1.UpdatePipelineSchedule)
+		PipelineRouter.DELETE("/:id/schedules/:scheduleId", middleware.Audit("pipeline_schedule", "delete"), middleware.Authorize("pipeline", "id", service.RoleMaintainer), v1.DeletePipelineSchedule)
+		PipelineRouter.GET("/:id/webhook", v1.GetPipelineWebhook)
+		PipelineRouter.PUT("/:id/webhook", middleware.Audit("pipeline", "update_webhook"), middleware.Authorize("pipeline", "id", service.RoleMaintainer), v1.UpdatePipelineWebhook)
+		PipelineRouter.PUT("/:id/subscription", middleware.Audit("pipeline", "subscribe"), v1.SubscribePipeline)
+		PipelineRouter.DELETE("/:id/subscription", middleware.Audit("pipeline", "unsubscribe"), v1.UnsubscribePipeline)
+		PipelineRouter.GET("/:id/members", v1.GetPipelineMembers)
+		PipelineRouter.POST("/:id/members", middleware.Audit("pipeline_member", "create"), middleware.Authorize("pipeline", "id", service.RoleMaintainer), v1.AddPipelineMember)
+		PipelineRouter.PUT("/:id/members/:memberId", middleware.Audit("pipeline_member", "update"), middleware.Authorize("pipeline", "id", service.RoleMaintainer), v1.UpdatePipelineMember)
+		PipelineRouter.DELETE("/:id/members/:memberId", middleware.Audit("pipeline_member", "delete"), middleware.Authorize("pipeline", "id", service.RoleMaintainer), v1.RemovePipelineMember)
+	}
+	// 触发和取消运行使用单独的授权范围，CI只需要trigger:pipeline
+	PipelineRunRouter := Router.Group("/pipeline").Use(middleware.JWTAuth(), middleware.RequireScope(service.ScopeTriggerPipeline))
+	{
+		PipelineRunRouter.POST("/:id/trigger", middleware.Audit("pipeline", "trigger"), middleware.Authorize("pipeline", "id", service.RoleDeveloper), v1.TriggerPipeline)
+		PipelineRunRouter.POST("/:id/runs/:runId/cancel", middleware.Audit("pipeline_run", "cancel"), middleware.Authorize("pipeline", "id", service.RoleDeveloper), v1.CancelPipelineRun)
 	}
 }
 
 // InitArtifactRouter 初始化制品路由
 func InitArtifactRouter(Router *gin.RouterGroup) {
-	ArtifactRouter := Router.Group("/artifact").Use(middleware.JWTAuth())
+	ArtifactRouter := Router.Group("/artifact").Use(middleware.JWTAuth(), middleware.RequireScope(service.ScopeWriteArtifact))
 	{
-		ArtifactRouter.POST("", v1.CreateArtifact)
+		ArtifactRouter.POST("", middleware.Audit("artifact", "create"), middleware.RequireRole(service.RoleDeveloper), v1.CreateArtifact)
 		ArtifactRouter.GET("", v1.GetArtifacts)
 		ArtifactRouter.GET("/:id", v1.GetArtifactByID)
-		ArtifactRouter.DELETE("/:id", v1.DeleteArtifact)
+		ArtifactRouter.DELETE("/:id", middleware.Audit("artifact", "delete"), middleware.Authorize("artifact", "id", service.RoleMaintainer), v1.DeleteArtifact)
 		ArtifactRouter.GET("/:id/download", v1.DownloadArtifact)
 	}
 }
 
 // InitEnvironmentRouter 初始化环境路由
 func InitEnvironmentRouter(Router *gin.RouterGroup) {
-	EnvironmentRouter := Router.Group("/environment").Use(middleware.JWTAuth())
+	EnvironmentRouter := Router.Group("/environment").Use(middleware.JWTAuth(), middleware.RequireScope(service.ScopeWriteEnvironment))
 	{
-		EnvironmentRouter.POST("", v1.CreateEnvironment)
+		EnvironmentRouter.POST("", middleware.Audit("environment", "create"), middleware.RequireRole(service.RoleMaintainer), v1.CreateEnvironment)
 		EnvironmentRouter.GET("", v1.GetEnvironments)
 		EnvironmentRouter.GET("/:id", v1.GetEnvironmentByID)
-		EnvironmentRouter.PUT("/:id", v1.UpdateEnvironment)
-		EnvironmentRouter.DELETE("/:id", v1.DeleteEnvironment)
+		EnvironmentRouter.PUT("/:id", middleware.Audit("environment", "update"), middleware.Authorize("environment", "id", service.RoleMaintainer), v1.UpdateEnvironment)
+		EnvironmentRouter.DELETE("/:id", middleware.Audit("environment", "delete"), middleware.Authorize("environment", "id", service.RoleMaintainer), v1.DeleteEnvironment)
+		EnvironmentRouter.GET("/:id/members", v1.GetEnvironmentMembers)
+		EnvironmentRouter.POST("/:id/members", middleware.Audit("environment_member", "create"), middleware.Authorize("environment", "id", service.RoleMaintainer), v1.AddEnvironmentMember)
+		EnvironmentRouter.PUT("/:id/members/:memberId", middleware.Audit("environment_member", "update"), middleware.Authorize("environment", "id", service.RoleMaintainer), v1.UpdateEnvironmentMember)
+		EnvironmentRouter.DELETE("/:id/members/:memberId", middleware.Audit("environment_member", "delete"), middleware.Authorize("environment", "id", service.RoleMaintainer), v1.RemoveEnvironmentMember)
 	}
 }
 
 // InitReleaseRouter 初始化发布路由
 func InitReleaseRouter(Router *gin.RouterGroup) {
-	ReleaseRouter := Router.Group("/release").Use(middleware.JWTAuth())
+	ReleaseRouter := Router.Group("/release").Use(middleware.JWTAuth(), middleware.RequireScope(service.ScopeDeployRelease))
 	{
-		ReleaseRouter.POST("", v1.CreateRelease)
+		ReleaseRouter.POST("", middleware.Audit("release", "create"), middleware.RequireRole(service.RoleDeveloper), v1.CreateRelease)
 		ReleaseRouter.GET("", v1.GetReleases)
 		ReleaseRouter.GET("/:id", v1.GetReleaseByID)
-		ReleaseRouter.DELETE("/:id", v1.DeleteRelease)
-		ReleaseRouter.POST("/:id/rollback", v1.RollbackRelease)
+		ReleaseRouter.DELETE("/:id", middleware.Audit("release", "delete"), middleware.Authorize("release", "id", service.RoleMaintainer), v1.DeleteRelease)
+		ReleaseRouter.POST("/:id/rollback", middleware.Audit("release", "rollback"), middleware.Authorize("release", "id", service.RoleDeveloper), v1.RollbackRelease)
 	}
 }
 
 // InitBuildTemplateRouter 初始化构建模板路由
 func InitBuildTemplateRouter(Router *gin.RouterGroup) {
-	BuildTemplateRouter := Router.Group("/build-template").Use(middleware.JWTAuth())
+	BuildTemplateRouter := Router.Group("/build-template").Use(middleware.JWTAuth(), middleware.RequireScope(service.ScopeWriteTemplate))
 	{
-		BuildTemplateRouter.POST("", v1.CreateBuildTemplate)
+		BuildTemplateRouter.POST("", middleware.Audit("build_template", "create"), middleware.RequireRole(service.RoleDeveloper), v1.CreateBuildTemplate)
 		BuildTemplateRouter.GET("", v1.GetBuildTemplates)
 		BuildTemplateRouter.GET("/:id", v1.GetBuildTemplateByID)
-		BuildTemplateRouter.PUT("/:id", v1.UpdateBuildTemplate)
-		BuildTemplateRouter.DELETE("/:id", v1.DeleteBuildTemplate)
-		BuildTemplateRouter.POST("/:id/apply", v1.ApplyBuildTemplate)
+		BuildTemplateRouter.PUT("/:id", middleware.Audit("build_template", "update"), v1.UpdateBuildTemplate)
+		BuildTemplateRouter.DELETE("/:id", middleware.Audit("build_template", "delete"), v1.DeleteBuildTemplate)
+		BuildTemplateRouter.POST("/:id/apply", middleware.Audit("build_template", "apply"), middleware.RequireRole(service.RoleDeveloper), v1.ApplyBuildTemplate)
 	}
 }
 
 // InitDAGRouter 初始化DAG路由
 func InitDAGRouter(Router *gin.RouterGroup) {
-	DAGRouter := Router.Group("/dag").Use(middleware.JWTAuth())
+	DAGRouter := Router.Group("/dag").Use(middleware.JWTAuth(), middleware.RequireScope(service.ScopeWritePipeline))
 	{
-		DAGRouter.POST("", v1.CreateDAG)
+		DAGRouter.POST("", middleware.Audit("dag", "create"), middleware.RequireRole(service.RoleDeveloper), v1.CreateDAG)
 		DAGRouter.GET("/:id", v1.GetDAGByID)
+		DAGRouter.GET("/:id/export", v1.ExportDAG)
+		DAGRouter.GET("/:id/diff/:otherId", v1.DiffDAG)
+		DAGRouter.GET("/:id/analysis", v1.AnalyzeDAG)
 		DAGRouter.GET("/pipeline/:pipelineId", v1.GetDAGsByPipelineID)
 		DAGRouter.GET("/pipeline/:pipelineId/active", v1.GetActiveDAG)
-		DAGRouter.PUT("/:id", v1.UpdateDAG)
-		DAGRouter.DELETE("/:id", v1.DeleteDAG)
+		DAGRouter.PUT("/:id", middleware.Audit("dag", "update"), middleware.Authorize("dag", "id", service.RoleMaintainer), v1.UpdateDAG)
+		DAGRouter.DELETE("/:id", middleware.Audit("dag", "delete"), middleware.Authorize("dag", "id", service.RoleMaintainer), v1.DeleteDAG)
 		DAGRouter.POST("/validate", v1.ValidateDAG)
-		DAGRouter.POST("/:id/version", v1.CreateDAGVersion)
+		DAGRouter.POST("/pipeline-file/validate", v1.ValidatePipelineFile)
+		DAGRouter.POST("/:id/version", middleware.Audit("dag", "create"), middleware.Authorize("dag", "id", service.RoleMaintainer), v1.CreateDAGVersion)
 		DAGRouter.GET("/pipeline/:pipelineId/history", v1.GetDAGHistory)
-		DAGRouter.POST("/:id/activate", v1.ActivateDAG)
+		DAGRouter.POST("/:id/activate", middleware.Audit("dag", "activate"), middleware.Authorize("dag", "id", service.RoleMaintainer), v1.ActivateDAG)
+		DAGRouter.POST("/pipeline/:pipelineId/from-stages", middleware.Audit("dag", "create"), middleware.Authorize("pipeline", "pipelineId", service.RoleMaintainer), v1.CreateDAGFromStages)
+	}
+}
+
+// InitDAGFragmentRouter 初始化DAG片段路由
+func InitDAGFragmentRouter(Router *gin.RouterGroup) {
+	DAGFragmentRouter := Router.Group("/dag-fragment").Use(middleware.JWTAuth(), middleware.RequireScope(service.ScopeWriteTemplate))
+	{
+		DAGFragmentRouter.POST("", middleware.Audit("dag_fragment", "create"), middleware.RequireRole(service.RoleDeveloper), v1.CreateDAGFragment)
+		DAGFragmentRouter.GET("", v1.GetDAGFragments)
+		DAGFragmentRouter.POST("/expand", v1.ExpandDAGFragments)
+		DAGFragmentRouter.GET("/:name", v1.GetDAGFragmentVersions)
+		DAGFragmentRouter.GET("/:name/:version", v1.GetDAGFragment)
 	}
 }
 
 // InitYAMLValidatorRouter 初始化YAML验证路由
 func InitYAMLValidatorRouter(Router *gin.RouterGroup) {
-	YAMLRouter := Router.Group("/yaml").Use(middleware.JWTAuth())
+	YAMLRouter := Router.Group("/yaml").Use(middleware.JWTAuth(), middleware.RequireScope(service.ScopeWriteTemplate))
 	{
 		YAMLRouter.POST("/validate", v1.ValidateYAML)
 		YAMLRouter.GET("/history", v1.GetValidationHistory)
-		YAMLRouter.POST("/schema", v1.CreateYAMLSchema)
+		YAMLRouter.POST("/schema", middleware.Audit("yaml_schema", "create"), middleware.RequireRole(service.RoleMaintainer), v1.CreateYAMLSchema)
 		YAMLRouter.GET("/schema", v1.GetYAMLSchemas)
 		YAMLRouter.GET("/schema/:id", v1.GetYAMLSchemaByID)
-		YAMLRouter.PUT("/schema/:id", v1.UpdateYAMLSchema)
-		YAMLRouter.DELETE("/schema/:id", v1.DeleteYAMLSchema)
+		YAMLRouter.PUT("/schema/:id", middleware.Audit("yaml_schema", "update"), middleware.Authorize("yaml_schema", "id", service.RoleMaintainer), v1.UpdateYAMLSchema)
+		YAMLRouter.DELETE("/schema/:id", middleware.Audit("yaml_schema", "delete"), middleware.Authorize("yaml_schema", "id", service.RoleMaintainer), v1.DeleteYAMLSchema)
 	}
 }
 
 // InitTemplateMarketRouter 初始化模板市场路由
 func InitTemplateMarketRouter(Router *gin.RouterGroup) {
-	TemplateMarketRouter := Router.Group("/template-market").Use(middleware.JWTAuth())
+	TemplateMarketRouter := Router.Group("/template-market").Use(middleware.JWTAuth(), middleware.RequireScope(service.ScopeWriteTemplate))
 	{
 		// 分类管理
-		TemplateMarketRouter.POST("/category", v1.CreateTemplateCategory)
+		TemplateMarketRouter.POST("/category", middleware.Audit("template_category", "create"), middleware.RequireRole(service.RoleMaintainer), v1.CreateTemplateCategory)
 		TemplateMarketRouter.GET("/category", v1.GetTemplateCategories)
-		TemplateMarketRouter.PUT("/category/:id", v1.UpdateTemplateCategory)
-		TemplateMarketRouter.DELETE("/category/:id", v1.DeleteTemplateCategory)
+		TemplateMarketRouter.PUT("/category/:id", middleware.Audit("template_category", "update"), middleware.Authorize("template_category", "id", service.RoleAdmin), v1.UpdateTemplateCategory)
+		TemplateMarketRouter.DELETE("/category/:id", middleware.Audit("template_category", "delete"), middleware.Authorize("template_category", "id", service.RoleAdmin), v1.DeleteTemplateCategory)
 
 		// 模板管理
-		TemplateMarketRouter.POST("/template", v1.CreateTemplate)
+		TemplateMarketRouter.POST("/template", middleware.Audit("template", "create"), middleware.RequireRole(service.RoleDeveloper), v1.CreateTemplate)
 		TemplateMarketRouter.GET("/template", v1.GetTemplates)
 		TemplateMarketRouter.GET("/template/:id", v1.GetTemplateByID)
-		TemplateMarketRouter.PUT("/template/:id", v1.UpdateTemplate)
-		TemplateMarketRouter.DELETE("/template/:id", v1.DeleteTemplate)
+		TemplateMarketRouter.PUT("/template/:id", middleware.Audit("template", "update"), middleware.Authorize("template", "id", service.RoleMaintainer), v1.UpdateTemplate)
+		TemplateMarketRouter.DELETE("/template/:id", middleware.Audit("template", "delete"), middleware.Authorize("template", "id", service.RoleMaintainer), v1.DeleteTemplate)
 
 		// 版本管理
-		TemplateMarketRouter.POST("/template/:id/version", v1.CreateTemplateVersion)
+		TemplateMarketRouter.POST("/template/:id/version", middleware.Audit("template_version", "create"), middleware.Authorize("template", "id", service.RoleMaintainer), v1.CreateTemplateVersion)
 		TemplateMarketRouter.GET("/template/:id/version", v1.GetTemplateVersions)
 		TemplateMarketRouter.GET("/template/:id/version/:versionId", v1.GetTemplateVersionByID)
-		TemplateMarketRouter.DELETE("/template/:id/version/:versionId", v1.DeleteTemplateVersion)
-		TemplateMarketRouter.POST("/template/:id/version/:versionId/latest", v1.SetVersionAsLatest)
+		TemplateMarketRouter.DELETE("/template/:id/version/:versionId", middleware.Audit("template_version", "delete"), middleware.Authorize("template", "id", service.RoleMaintainer), v1.DeleteTemplateVersion)
+		TemplateMarketRouter.POST("/template/:id/version/:versionId/latest", middleware.Audit("template_version", "set_latest"), middleware.Authorize("template", "id", service.RoleMaintainer), v1.SetVersionAsLatest)
 
 		// 搜索和下载
 		TemplateMarketRouter.GET("/search", v1.SearchTemplates)
 		TemplateMarketRouter.GET("/template/:id/download", v1.DownloadTemplate)
 	}
 }
+
+// InitPluginRouter 初始化插件路由
+func InitPluginRouter(Router *gin.RouterGroup) {
+	PluginRouter := Router.Group("/plugin").Use(middleware.JWTAuth(), middleware.RequireScope(service.ScopeRead))
+	{
+		PluginRouter.GET("", v1.GetPlugins)
+	}
+}
+
+// InitRunnerRouter 初始化远程执行器路由
+func InitRunnerRouter(Router *gin.RouterGroup) {
+	// 执行器使用注册令牌和执行器令牌认证
+	RunnerAgentRouter := Router.Group("/runner")
+	{
+		RunnerAgentRouter.POST("/register", v1.RegisterRunner)
+	}
+	RunnerTaskRouter := Router.Group("/runner").Use(middleware.RunnerAuth())
+	{
+		RunnerTaskRouter.POST("/heartbeat", v1.RunnerHeartbeat)
+		RunnerTaskRouter.GET("/tasks/poll", v1.PollRunnerTask)
+		RunnerTaskRouter.POST("/tasks/:taskId/logs", v1.AppendRunnerTaskLogs)
+		RunnerTaskRouter.POST("/tasks/:taskId/result", v1.CompleteRunnerTask)
+	}
+
+	// 执行器管理
+	RunnerRouter := Router.Group("/runner").Use(middleware.JWTAuth(), middleware.AdminAuth(), middleware.RequireScope(service.ScopeAdmin))
+	{
+		RunnerRouter.GET("", v1.GetRunners)
+		RunnerRouter.GET("/queue", v1.GetRunnerQueue)
+		RunnerRouter.DELETE("/:id", middleware.Audit("runner", "delete"), v1.DeleteRunner)
+	}
+}
+
+// InitWebhookRouter 初始化Webhook路由
+func InitWebhookRouter(Router *gin.RouterGroup) {
+	// Git平台回调，通过签名或令牌认证
+	WebhookRouter := Router.Group("/webhook")
+	{
+		WebhookRouter.POST("/pipeline/:id", v1.ReceivePipelineWebhook)
+	}
+}
+
+// InitNotificationRouter 初始化通知路由
+func InitNotificationRouter(Router *gin.RouterGroup) {
+	NotificationRouter := Router.Group("/notification").Use(middleware.JWTAuth(), middleware.RequireScope(service.ScopeWriteNotification))
+	{
+		NotificationRouter.POST("/rules", middleware.Audit("notification_rule", "create"), v1.CreateNotificationRule)
+		NotificationRouter.GET("/rules", v1.GetNotificationRules)
+		NotificationRouter.GET("/rules/:id", middleware.Authorize("notification_rule", "id", service.RoleMaintainer), v1.GetNotificationRule)
+		NotificationRouter.PUT("/rules/:id", middleware.Audit("notification_rule", "update"), middleware.Authorize("notification_rule", "id", service.RoleMaintainer), v1.UpdateNotificationRule)
+		NotificationRouter.DELETE("/rules/:id", middleware.Audit("notification_rule", "delete"), middleware.Authorize("notification_rule", "id", service.RoleMaintainer), v1.DeleteNotificationRule)
+		NotificationRouter.POST("/rules/:id/test", middleware.Audit("notification_rule", "test"), middleware.Authorize("notification_rule", "id", service.RoleMaintainer), v1.TestNotificationRule)
+		NotificationRouter.GET("/deliveries", v1.GetNotificationDeliveries)
+		NotificationRouter.POST("/deliveries/:id/retry", middleware.Audit("notification_delivery", "retry"), middleware.Authorize("notification_delivery", "id", service.RoleMaintainer), v1.RetryNotificationDelivery)
+		NotificationRouter.GET("/subscriptions", v1.GetNotificationSubscriptions)
+	}
+}
+
+// InitAuditRouter 初始化审计路由
+func InitAuditRouter(Router *gin.RouterGroup) {
+	AuditRouter := Router.Group("/audit").Use(middleware.JWTAuth(), middleware.AdminAuth(), middleware.RequireScope(service.ScopeAdmin))
+	{
+		AuditRouter.GET("", v1.GetAuditLogs)
+	}
+}
+
+// InitServiceAccountRouter 初始化服务账号路由
+func InitServiceAccountRouter(Router *gin.RouterGroup) {
+	ServiceAccountRouter := Router.Group("/service-account").Use(middleware.JWTAuth(), middleware.AdminAuth(), middleware.SessionOnly())
+	{
+		ServiceAccountRouter.GET("", v1.GetServiceAccounts)
+		ServiceAccountRouter.POST("", middleware.Audit("user", "create"), v1.CreateServiceAccount)
+		ServiceAccountRouter.DELETE("/:id", middleware.Audit("user", "delete"), v1.DeleteServiceAccount)
+		ServiceAccountRouter.GET("/:id/tokens", v1.GetServiceAccountTokens)
+		ServiceAccountRouter.POST("/:id/tokens", middleware.Audit("access_token", "create"), v1.CreateServiceAccountToken)
+		ServiceAccountRouter.DELETE("/:id/tokens/:tokenId", middleware.Audit("access_token", "revoke"), v1.RevokeServiceAccountToken)
+	}
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"gin_pipeline/global"
+	"gin_pipeline/model"
+	"gin_pipeline/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+// AccessTokenPrefix 访问令牌的前缀，用于和JWT区分
+const AccessTokenPrefix = "pat_"
+
+// 访问令牌的授权范围，登录会话不受授权范围限制
+const (
+	ScopeRead              = "read"               // 除管理接口外的所有查询接口
+	ScopeTriggerPipeline   = "trigger:pipeline"   // 触发和取消流水线运行
+	ScopeWritePipeline     = "write:pipeline"     // 修改流水线、DAG、定时任务、Webhook和成员
+	ScopeWriteArtifact     = "write:artifact"     // 上传和删除制品
+	ScopeDeployRelease     = "deploy:release"     // 创建、回滚和删除发布
+	ScopeWriteEnvironment  = "write:environment"  // 修改环境和成员
+	ScopeWriteTemplate     = "write:template"     // 修改构建模板、DAG片段、YAML Schema和模板市场
+	ScopeWriteNotification = "write:notification" // 修改通知规则和订阅
+	ScopeAdmin             = "admin"              // 管理接口，如用户角色、执行器和审计日志，包括这些接口的查询
+)
+
+// AccessTokenScopes 可以授予的授权范围
+var AccessTokenScopes = []string{
+	ScopeRead, ScopeTriggerPipeline, ScopeWritePipeline, ScopeWriteArtifact, ScopeDeployRelease,
+	ScopeWriteEnvironment, ScopeWriteTemplate, ScopeWriteNotification, ScopeAdmin,
+}
+
+// accessTokenTouchInterval 最近使用时间的更新间隔，避免每个请求都写数据库
+const accessTokenTouchInterval = time.Minute
+
+// IsAccessToken 是否为访问令牌
+func IsAccessToken(token string) bool {
+	return strings.HasPrefix(token, AccessTokenPrefix)
+}
+
+// AccessTokenService 访问令牌和服务账号服务
+type AccessTokenService struct{}
+
+// CreatedAccessToken 新创建的访问令牌，包含只返回一次的明文令牌
+type CreatedAccessToken struct {
+	model.AccessToken
+	Token string `json:"token"`
+}
+
+// CreateToken 为用户创建访问令牌，明文令牌只返回这一次
+func (s *AccessTokenService) CreateToken(userID, creatorID uint, name string, scopes []string, expiresAt *time.Time) (*CreatedAccessToken, error) {
+	var user model.User
+	if err := global.DB.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("用户不存在")
+		}
+		return nil, err
+	}
+
+	if len(scopes) == 0 {
+		return nil, errors.New("至少需要一个授权范围")
+	}
+	seen := make(map[string]bool, len(scopes))
+	var normalized model.StringList
+	for _, scope := range scopes {
+		if !model.StringList(AccessTokenScopes).Contains(scope) {
+			return nil, fmt.Errorf("无效的授权范围: %s", scope)
+		}
+		if scope == ScopeAdmin && user.Role != RoleAdmin {
+			return nil, errors.New("只有管理员的令牌可以授予admin范围")
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			normalized = append(normalized, scope)
+		}
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, errors.New("过期时间必须晚于当前时间")
+	}
+
+	random, err := utils.RandomToken(20)
+	if err != nil {
+		return nil, err
+	}
+	token := AccessTokenPrefix + random
+
+	accessToken := model.AccessToken{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: utils.HashToken(token),
+		Prefix:    token[:len(AccessTokenPrefix)+6],
+		Scopes:    normalized,
+		ExpiresAt: expiresAt,
+		CreatorID: creatorID,
+	}
+	if err := global.DB.Create(&accessToken).Error; err != nil {
+		return nil, err
+	}
+	return &CreatedAccessToken{AccessToken: accessToken, Token: token}, nil
+}
+
+// GetTokens 获取用户的访问令牌，包括已撤销和已过期的令牌
+func (s *AccessTokenService) GetTokens(userID uint) ([]model.AccessToken, error) {
+	var tokens []model.AccessToken
+	err := global.DB.Where("user_id = ?", userID).Order("id DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+// RevokeToken 撤销用户的访问令牌，之后的请求立即被拒绝
+func (s *AccessTokenService) RevokeToken(userID, tokenID uint) error {
+	var token model.AccessToken
+	if err := global.DB.Where("user_id = ? AND id = ?", userID, tokenID).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("令牌不存在")
+		}
+		return err
+	}
+	if token.RevokedAt != nil {
+		return nil
+	}
+	return global.DB.Model(&token).Update("revoked_at", time.Now()).Error
+}
+
+// Authenticate 校验访问令牌，返回令牌和所属用户
+// 每次请求都查询数据库，撤销、过期和删除用户立即生效
+func (s *AccessTokenService) Authenticate(token, ip string) (*model.AccessToken, *model.User, error) {
+	var accessToken model.AccessToken
+	if err := global.DB.Where("token_hash = ?", utils.HashToken(token)).First(&accessToken).Error; err != nil {
+		return nil, nil, errors.New("访问令牌无效")
+	}
+	now := time.Now()
+	if accessToken.RevokedAt != nil {
+		return nil, nil, errors.New("访问令牌已撤销")
+	}
+	if accessToken.ExpiresAt != nil && !accessToken.ExpiresAt.After(now) {
+		return nil, nil, errors.New("访问令牌已过期")
+	}
+
+	var user model.User
+	if err := global.DB.First(&user, accessToken.UserID).Error; err != nil {
+		return nil, nil, errors.New("访问令牌所属的用户不存在")
+	}
+
+	// 按间隔更新最近使用时间
+	if accessToken.LastUsedAt == nil || now.Sub(*accessToken.LastUsedAt) >= accessTokenTouchInterval {
+		if err := global.DB.Model(&accessToken).UpdateColumns(map[string]interface{}{
+			"last_used_at": now,
+			"last_used_ip": ip,
+		}).Error; err != nil {
+			global.Log.Warn("更新访问令牌使用时间失败", zap.Uint("token_id", accessToken.ID), zap.Error(err))
+		}
+	}
+	return &accessToken, &user, nil
+}
+
+// HasScope 令牌是否具有指定的授权范围
+func HasScope(token *model.AccessToken, scope string) bool {
+	return token.Scopes.Contains(scope)
+}
+
+// CreateServiceAccount 创建服务账号，服务账号没有可用的密码，只能通过访问令牌调用接口
+func (s *AccessTokenService) CreateServiceAccount(user *model.User) error {
+	if !IsValidRole(user.Role) {
+		return errors.New("无效的角色")
+	}
+	var count int64
+	if err := global.DB.Model(&model.User{}).Where("username = ?", user.Username).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("用户名已存在")
+	}
+
+	random, err := utils.RandomToken(32)
+	if err != nil {
+		return err
+	}
+	hashed, err := utils.HashPassword(random)
+	if err != nil {
+		return err
+	}
+	user.Password = hashed
+	user.IsService = true
+	return global.DB.Create(user).Error
+}
+
+// GetServiceAccounts 获取所有服务账号
+func (s *AccessTokenService) GetServiceAccounts() ([]model.User, error) {
+	var users []model.User
+	err := global.DB.Where("is_service = ?", true).Order("id ASC").Find(&users).Error
+	return users, err
+}
+
+// GetServiceAccount 获取服务账号
+func (s *AccessTokenService) GetServiceAccount(id uint) (*model.User, error) {
+	var user model.User
+	if err := global.DB.Where("is_service = ?", true).First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("服务账号不存在")
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// DeleteServiceAccount 删除服务账号并撤销它的所有令牌
+func (s *AccessTokenService) DeleteServiceAccount(id uint) error {
+	user, err := s.GetServiceAccount(id)
+	if err != nil {
+		return err
+	}
+	return global.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.AccessToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", user.ID).
+			Update("revoked_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Delete(user).Error
+	})
+}
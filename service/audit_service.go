@@ -0,0 +1,189 @@
+package service
+
+import (
+	"encoding/json"
+	"gin_pipeline/global"
+	"gin_pipeline/model"
+	"go.uber.org/zap"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// 审计结果
+const (
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+)
+
+// resourceModels 资源类型到模型的映射，用于记录操作前后的资源和检查权限
+var resourceModels = map[string]func() interface{}{
+	"user":                  func() interface{} { return &model.User{} },
+	"pipeline":              func() interface{} { return &model.Pipeline{} },
+	"pipeline_run":          func() interface{} { return &model.PipelineRun{} },
+	"pipeline_schedule":     func() interface{} { return &model.PipelineSchedule{} },
+	"pipeline_member":       func() interface{} { return &model.PipelineMember{} },
+	"artifact":              func() interface{} { return &model.Artifact{} },
+	"environment":           func() interface{} { return &model.Environment{} },
+	"environment_member":    func() interface{} { return &model.EnvironmentMember{} },
+	"release":               func() interface{} { return &model.Release{} },
+	"build_template":        func() interface{} { return &model.BuildTemplate{} },
+	"dag":                   func() interface{} { return &model.DAG{} },
+	"dag_fragment":          func() interface{} { return &model.DAGFragment{} },
+	"yaml_schema":           func() interface{} { return &model.YAMLSchema{} },
+	"template_category":     func() interface{} { return &model.TemplateCategory{} },
+	"template":              func() interface{} { return &model.Template{} },
+	"template_version":      func() interface{} { return &model.TemplateVersion{} },
+	"runner":                func() interface{} { return &model.Runner{} },
+	"notification_rule":     func() interface{} { return &model.NotificationRule{} },
+	"notification_delivery": func() interface{} { return &model.NotificationDelivery{} },
+	"access_token":          func() interface{} { return &model.AccessToken{} },
+}
+
+// auditIgnoredFields 不参与比较的字段
+var auditIgnoredFields = map[string]bool{
+	"updated_at": true,
+}
+
+// AuditService 审计日志服务
+type AuditService struct{}
+
+// AuditFilter 审计日志筛选条件
+type AuditFilter struct {
+	ActorID      uint
+	ResourceType string
+	ResourceID   string
+	Action       string
+	Outcome      string
+	Since        *time.Time
+	Until        *time.Time
+}
+
+// Snapshot 读取资源的当前状态，资源不存在或类型未登记时返回nil
+// 使用资源的JSON表示，不输出的敏感字段(如密码、密钥)不会进入审计日志
+func (s *AuditService) Snapshot(resourceType, resourceID string) model.JSONMap {
+	newModel, ok := resourceModels[resourceType]
+	if !ok || global.DB == nil {
+		return nil
+	}
+	id, err := strconv.ParseUint(resourceID, 10, 32)
+	if err != nil {
+		return nil
+	}
+
+	resource := newModel()
+	if err := global.DB.First(resource, uint(id)).Error; err != nil {
+		return nil
+	}
+	return toJSONMap(resource)
+}
+
+// toJSONMap 将资源转换为JSON对象
+func toJSONMap(value interface{}) model.JSONMap {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	var result model.JSONMap
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil
+	}
+	return result
+}
+
+// Diff 比较操作前后的资源，返回变化的字段
+func (s *AuditService) Diff(before, after model.JSONMap) model.JSONMap {
+	diff := model.JSONMap{}
+	for field, oldValue := range before {
+		if auditIgnoredFields[field] {
+			continue
+		}
+		newValue, ok := after[field]
+		if !ok || !reflect.DeepEqual(oldValue, newValue) {
+			diff[field] = map[string]interface{}{"before": oldValue, "after": newValue}
+		}
+	}
+	for field, newValue := range after {
+		if auditIgnoredFields[field] {
+			continue
+		}
+		if _, ok := before[field]; !ok {
+			diff[field] = map[string]interface{}{"before": nil, "after": newValue}
+		}
+	}
+	if len(diff) == 0 {
+		return nil
+	}
+	return diff
+}
+
+// Record 写入审计日志，失败时只记录错误，不影响请求本身
+func (s *AuditService) Record(entry *model.AuditLog) {
+	if global.DB == nil {
+		return
+	}
+	entry.Diff = s.Diff(entry.Before, entry.After)
+	if err := global.DB.Create(entry).Error; err != nil {
+		global.Log.Error("写入审计日志失败",
+			zap.String("action", entry.Action),
+			zap.String("resource_type", entry.ResourceType),
+			zap.String("resource_id", entry.ResourceID),
+			zap.Error(err))
+	}
+}
+
+// GetAuditLogs 分页获取审计日志，按时间倒序
+func (s *AuditService) GetAuditLogs(filter AuditFilter, page, pageSize int) ([]model.AuditLog, int64, error) {
+	db := global.DB.Model(&model.AuditLog{})
+	if filter.ActorID != 0 {
+		db = db.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.ResourceType != "" {
+		db = db.Where("resource_type = ?", filter.ResourceType)
+	}
+	if filter.ResourceID != "" {
+		db = db.Where("resource_id = ?", filter.ResourceID)
+	}
+	if filter.Action != "" {
+		db = db.Where("action = ?", filter.Action)
+	}
+	if filter.Outcome != "" {
+		db = db.Where("outcome = ?", filter.Outcome)
+	}
+	if filter.Since != nil {
+		db = db.Where("created_at >= ?", *filter.Since)
+	}
+	if filter.Until != nil {
+		db = db.Where("created_at < ?", *filter.Until)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var logs []model.AuditLog
+	if err := db.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+	return logs, total, nil
+}
+
+// PurgeExpired 删除超过保留天数的审计日志，分批删除以免长时间锁表，返回删除的条数
+func (s *AuditService) PurgeExpired(retentionDays int) (int64, error) {
+	if retentionDays <= 0 {
+		return 0, nil
+	}
+	cutoff := time.Now().AddDate(0, 0, -retentionDays)
+
+	var total int64
+	for {
+		result := global.DB.Exec("DELETE FROM audit_logs WHERE created_at < ? LIMIT 1000", cutoff)
+		if result.Error != nil {
+			return total, result.Error
+		}
+		total += result.RowsAffected
+		if result.RowsAffected < 1000 {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"fmt"
+	"gin_pipeline/global"
+	"gin_pipeline/model"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+// 并发组策略
+const (
+	ConcurrencyQueue  = "queue"  // 排队等待同组运行结束
+	ConcurrencyCancel = "cancel" // 取消同组正在进行的运行
+	ConcurrencySkip   = "skip"   // 同组有运行时跳过新的运行
+)
+
+// resolveConcurrencyGroup 解析流水线的并发组模板，未配置并发组时返回空字符串
+func resolveConcurrencyGroup(pipeline *model.Pipeline, gitBranch string) string {
+	if pipeline.ConcurrencyGroup == "" {
+		return ""
+	}
+	replacer := strings.NewReplacer(
+		"{pipeline}", fmt.Sprint(pipeline.ID),
+		"{branch}", gitBranch,
+	)
+	return replacer.Replace(pipeline.ConcurrencyGroup)
+}
+
+// activeGroupRuns 获取并发组中等待中或运行中的运行
+func activeGroupRuns(group string, excludeID uint) ([]model.PipelineRun, error) {
+	var runs []model.PipelineRun
+	err := global.DB.Where("concurrency_group = ? AND status IN ? AND id <> ?",
+		group, []string{"pending", "running"}, excludeID).
+		Order("id ASC").
+		Find(&runs).Error
+	return runs, err
+}
+
+// liveGroupRuns 过滤掉已停止实例遗留的运行
+// 这些运行会被接管后重新入队或标记为失败，在此之前不应让跳过策略一直跳过新的运行
+func liveGroupRuns(runs []model.PipelineRun) []model.PipelineRun {
+	alive := make(map[string]bool)
+	live := runs[:0]
+	for _, run := range runs {
+		if _, ok := alive[run.Instance]; !ok {
+			alive[run.Instance] = instanceAlive(run.Instance)
+		}
+		if alive[run.Instance] {
+			live = append(live, run)
+		}
+	}
+	return live
+}
+
+// skipSupersededRun 同组已有运行时，将新运行记录为跳过
+func skipSupersededRun(run *model.PipelineRun, active *model.PipelineRun) error {
+	now := time.Now()
+	run.Status = "skipped"
+	run.EndTime = &now
+	run.SupersededBy = active.ID
+
+	global.Log.Info("同组已有运行，跳过新的运行",
+		zap.String("group", run.ConcurrencyGroup),
+		zap.Uint("activeRunID", active.ID))
+	if err := global.DB.Create(run).Error; err != nil {
+		return err
+	}
+	PublishEvent(newPipelineRunFinished(run, "skipped", 0))
+	return nil
+}
+
+// cancelSupersededRuns 取消同组中被新运行取代的运行
+func (s *WorkflowService) cancelSupersededRuns(run *model.PipelineRun) error {
+	runs, err := activeGroupRuns(run.ConcurrencyGroup, run.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, old := range runs {
+		// 只取代先触发的运行
+		if old.ID > run.ID {
+			continue
+		}
+		if err := global.DB.Model(&model.PipelineRun{}).
+			Where("id = ?", old.ID).
+			Update("superseded_by", run.ID).Error; err != nil {
+			return err
+		}
+		if err := s.CancelWorkflow(old.ID); err != nil {
+			return err
+		}
+
+		global.Log.Info("取消被取代的运行",
+			zap.String("group", run.ConcurrencyGroup),
+			zap.Uint("runID", old.ID),
+			zap.Uint("supersededBy", run.ID))
+	}
+	return nil
+}
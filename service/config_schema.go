@@ -0,0 +1,152 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ConfigSchema 任务配置的Schema（JSON Schema的子集）
+// 支持 type、properties、required、enum、items、minimum、maximum 和 additionalProperties
+type ConfigSchema struct {
+	Type                 string                   `json:"type,omitempty"`
+	Description          string                   `json:"description,omitempty"`
+	Properties           map[string]*ConfigSchema `json:"properties,omitempty"`
+	Required             []string                 `json:"required,omitempty"`
+	Enum                 []interface{}            `json:"enum,omitempty"`
+	Items                *ConfigSchema            `json:"items,omitempty"`
+	Minimum              *float64                 `json:"minimum,omitempty"`
+	Maximum              *float64                 `json:"maximum,omitempty"`
+	AdditionalProperties *bool                    `json:"additionalProperties,omitempty"`
+	Default              interface{}              `json:"default,omitempty"`
+}
+
+// Validate 根据Schema验证配置，返回所有错误
+func (s *ConfigSchema) Validate(value interface{}) []string {
+	if s == nil {
+		return nil
+	}
+	return s.validate("config", value)
+}
+
+// validate 递归验证
+func (s *ConfigSchema) validate(path string, value interface{}) []string {
+	var errs []string
+
+	// 参数和片段输入占位符在运行时才替换，整值占位符可能替换为任意类型，不检查取值；
+	// 嵌在文本中的占位符替换后仍是字符串，只检查类型
+	if text, ok := value.(string); ok && hasPlaceholder(text) {
+		if isWholePlaceholder(text) || s.Type == "" || matchSchemaType(s.Type, value) {
+			return nil
+		}
+		return []string{fmt.Sprintf("%s 类型错误，期望 %s", path, s.Type)}
+	}
+
+	if s.Type != "" && !matchSchemaType(s.Type, value) {
+		return []string{fmt.Sprintf("%s 类型错误，期望 %s", path, s.Type)}
+	}
+
+	if len(s.Enum) > 0 {
+		matched := false
+		for _, candidate := range s.Enum {
+			if fmt.Sprint(candidate) == fmt.Sprint(value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			errs = append(errs, fmt.Sprintf("%s 取值必须为 %v 之一", path, s.Enum))
+		}
+	}
+
+	if number, ok := toFloat(value); ok {
+		if s.Minimum != nil && number < *s.Minimum {
+			errs = append(errs, fmt.Sprintf("%s 不能小于 %v", path, *s.Minimum))
+		}
+		if s.Maximum != nil && number > *s.Maximum {
+			errs = append(errs, fmt.Sprintf("%s 不能大于 %v", path, *s.Maximum))
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, key := range s.Required {
+			if _, exists := v[key]; !exists {
+				errs = append(errs, fmt.Sprintf("%s 缺少必要字段: %s", path, key))
+			}
+		}
+
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			propSchema, exists := s.Properties[key]
+			if !exists {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					errs = append(errs, fmt.Sprintf("%s 不允许的字段: %s", path, key))
+				}
+				continue
+			}
+			errs = append(errs, propSchema.validate(path+"."+key, v[key])...)
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range v {
+				errs = append(errs, s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item)...)
+			}
+		}
+	}
+
+	return errs
+}
+
+// matchSchemaType 检查值是否符合Schema类型
+func matchSchemaType(schemaType string, value interface{}) bool {
+	switch schemaType {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := toFloat(value)
+		return ok
+	case "integer":
+		number, ok := toFloat(value)
+		return ok && number == float64(int64(number))
+	case "null":
+		return value == nil
+	}
+	// 未知类型不做限制
+	return true
+}
+
+// toFloat 将数值转换为float64
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	return 0, false
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConfigSchemaValidate(t *testing.T) {
+	closed, one, ten := false, 1.0, 10.0
+	schema := &ConfigSchema{
+		Type:     "object",
+		Required: []string{"chart"},
+		Properties: map[string]*ConfigSchema{
+			"chart":    {Type: "string"},
+			"replicas": {Type: "integer", Minimum: &one, Maximum: &ten},
+			"strategy": {Type: "string", Enum: []interface{}{"rolling", "recreate"}},
+			"wait":     {Type: "boolean"},
+			"values":   {Type: "object", AdditionalProperties: &closed, Properties: map[string]*ConfigSchema{"image": {Type: "string"}}},
+			"hosts":    {Type: "array", Items: &ConfigSchema{Type: "string"}},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		config interface{}
+		want   []string
+	}{
+		{name: "valid", config: map[string]interface{}{"chart": "web", "replicas": float64(3), "strategy": "rolling", "wait": true}},
+		{name: "unknown fields allowed", config: map[string]interface{}{"chart": "web", "extra": 1}},
+		{name: "not an object", config: "web", want: []string{"config 类型错误，期望 object"}},
+		{name: "missing required", config: map[string]interface{}{}, want: []string{"config 缺少必要字段: chart"}},
+		{
+			name:   "wrong types",
+			config: map[string]interface{}{"chart": 1, "replicas": 1.5, "wait": "yes"},
+			want:   []string{"config.chart 类型错误，期望 string", "config.replicas 类型错误，期望 integer", "config.wait 类型错误，期望 boolean"},
+		},
+		{
+			name:   "out of range",
+			config: map[string]interface{}{"chart": "web", "replicas": float64(0)},
+			want:   []string{"config.replicas 不能小于 1"},
+		},
+		{
+			name:   "not in enum",
+			config: map[string]interface{}{"chart": "web", "strategy": "blue-green"},
+			want:   []string{"config.strategy 取值必须为 [rolling recreate] 之一"},
+		},
+		{
+			name:   "closed object",
+			config: map[string]interface{}{"chart": "web", "values": map[string]interface{}{"image": "nginx", "tag": "1"}},
+			want:   []string{"config.values 不允许的字段: tag"},
+		},
+		{
+			name:   "array items",
+			config: map[string]interface{}{"chart": "web", "hosts": []interface{}{"a", 2}},
+			want:   []string{"config.hosts[1] 类型错误，期望 string"},
+		},
+		{
+			// 整值占位符运行时可能替换为任意类型
+			name:   "whole placeholders",
+			config: map[string]interface{}{"chart": "${{ inputs.chart }}", "replicas": "${{ params.replicas }}", "strategy": "${{params.strategy}}"},
+		},
+		{
+			name:   "embedded placeholder in string field",
+			config: map[string]interface{}{"chart": "charts/${{ params.app }}"},
+		},
+		{
+			name:   "embedded placeholder in integer field",
+			config: map[string]interface{}{"chart": "web", "replicas": "x${{ params.replicas }}"},
+			want:   []string{"config.replicas 类型错误，期望 integer"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := schema.Validate(tt.config); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Validate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigSchemaValidateNil(t *testing.T) {
+	var schema *ConfigSchema
+	if got := schema.Validate(map[string]interface{}{"anything": 1}); got != nil {
+		t.Fatalf("nil schema Validate() = %v, want nil", got)
+	}
+}
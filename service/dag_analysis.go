@@ -0,0 +1,375 @@
+package service
+
+import (
+	"errors"
+	"gin_pipeline/global"
+	"gin_pipeline/model"
+	"sort"
+	"time"
+)
+
+// defaultTaskDuration 没有任何历史记录时任务的估计耗时(秒)
+const defaultTaskDuration = 60.0
+
+// DAGNodeEstimate 节点的耗时估计和调度时间
+type DAGNodeEstimate struct {
+	NodeID         string  `json:"node_id"`
+	Duration       float64 `json:"duration"`        // 估计耗时(秒)
+	Samples        int     `json:"samples"`         // 参与估计的历史运行数，为0时使用默认值
+	EarliestStart  float64 `json:"earliest_start"`  // 最早开始时间(秒，相对运行开始)
+	EarliestFinish float64 `json:"earliest_finish"` // 最早完成时间
+	LatestStart    float64 `json:"latest_start"`    // 不延长总耗时的最晚开始时间
+	LatestFinish   float64 `json:"latest_finish"`
+	Slack          float64 `json:"slack"` // 可延迟的时间，关键路径上的节点为0
+	Critical       bool    `json:"critical"`
+}
+
+// ParallelismSegment 一段时间内同时运行的节点
+type ParallelismSegment struct {
+	Start   float64  `json:"start"`
+	End     float64  `json:"end"`
+	Running int      `json:"running"`
+	Nodes   []string `json:"nodes"`
+}
+
+// RunETA 运行中的流水线的预计完成时间
+type RunETA struct {
+	RunID     uint      `json:"run_id"`
+	Status    string    `json:"status"`
+	Remaining float64   `json:"remaining"` // 预计剩余时间(秒)
+	ETA       time.Time `json:"eta"`
+	Pending   []string  `json:"pending"` // 尚未开始的节点
+	Running   []string  `json:"running"` // 正在运行的节点
+}
+
+// DAGAnalysis DAG的关键路径分析
+type DAGAnalysis struct {
+	DAGID         uint                 `json:"dag_id"`
+	SampleRuns    []uint               `json:"sample_runs"`    // 用于估计耗时的成功运行
+	TotalDuration float64              `json:"total_duration"` // 估计总耗时(秒)
+	CriticalPath  []string             `json:"critical_path"`
+	Nodes         []DAGNodeEstimate    `json:"nodes"`
+	Parallelism   []ParallelismSegment `json:"parallelism"`
+	Run           *RunETA              `json:"run,omitempty"`
+}
+
+// AnalyzeDAG 根据最近historyRuns次成功运行估计任务耗时，计算关键路径和并行度；runID不为0时计算该运行的预计完成时间
+func (s *DAGService) AnalyzeDAG(dagID uint, historyRuns int, runID uint) (*DAGAnalysis, error) {
+	dag, err := s.GetDAGByID(dagID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 运行时片段节点会展开，耗时按展开后的节点统计
+	nodes, err := ExpandDAGFragments(dag.NodesData)
+	if err != nil {
+		return nil, err
+	}
+
+	sampleRuns, durations, samples, err := taskDurationHistory(dag.PipelineID, historyRuns)
+	if err != nil {
+		return nil, err
+	}
+	estimates := estimateNodeDurations(nodes, durations, samples)
+
+	analysis, err := criticalPath(nodes, estimates, samples)
+	if err != nil {
+		return nil, err
+	}
+	analysis.DAGID = dag.ID
+	analysis.SampleRuns = sampleRuns
+
+	if runID != 0 {
+		var run model.PipelineRun
+		if err := global.DB.First(&run, runID).Error; err != nil {
+			return nil, err
+		}
+		if run.PipelineID != dag.PipelineID {
+			return nil, errors.New("运行不属于该DAG的流水线")
+		}
+		if len(run.DAGNodes) > 0 {
+			nodes = run.DAGNodes
+		}
+		eta, err := estimateRunETA(&run, nodes, estimateNodeDurations(nodes, durations, samples))
+		if err != nil {
+			return nil, err
+		}
+		analysis.Run = eta
+	}
+
+	return analysis, nil
+}
+
+// taskDurationHistory 获取流水线最近的成功运行中各节点的平均耗时
+func taskDurationHistory(pipelineID uint, historyRuns int) ([]uint, map[string]float64, map[string]int, error) {
+	runIDs := []uint{}
+	if err := global.DB.Model(&model.PipelineRun{}).
+		Where("pipeline_id = ? AND status = ?", pipelineID, "success").
+		Order("id DESC").Limit(historyRuns).
+		Pluck("id", &runIDs).Error; err != nil {
+		return nil, nil, nil, err
+	}
+
+	durations := make(map[string]float64)
+	samples := make(map[string]int)
+	if len(runIDs) == 0 {
+		return runIDs, durations, samples, nil
+	}
+
+	var tasks []model.PipelineRunTask
+	if err := global.DB.Where("run_id IN ? AND status = ?", runIDs, "success").Find(&tasks).Error; err != nil {
+		return nil, nil, nil, err
+	}
+	for _, task := range tasks {
+		durations[task.NodeID] += task.Duration
+		samples[task.NodeID]++
+	}
+	for nodeID, total := range durations {
+		durations[nodeID] = total / float64(samples[nodeID])
+	}
+	return runIDs, durations, samples, nil
+}
+
+// estimateNodeDurations 没有历史记录的节点使用其他节点的平均耗时，都没有时使用默认值
+func estimateNodeDurations(nodes []model.DAGNode, durations map[string]float64, samples map[string]int) map[string]float64 {
+	fallback, known := 0.0, 0
+	for _, node := range nodes {
+		if samples[node.ID] > 0 {
+			fallback += durations[node.ID]
+			known++
+		}
+	}
+	if known > 0 {
+		fallback /= float64(known)
+	} else {
+		fallback = defaultTaskDuration
+	}
+
+	estimates := make(map[string]float64, len(nodes))
+	for _, node := range nodes {
+		if samples[node.ID] > 0 {
+			estimates[node.ID] = durations[node.ID]
+		} else {
+			estimates[node.ID] = fallback
+		}
+	}
+	return estimates
+}
+
+// topologicalOrder 返回节点的拓扑顺序，忽略不存在的依赖
+func topologicalOrder(nodes []model.DAGNode) ([]model.DAGNode, error) {
+	index := make(map[string]int, len(nodes))
+	for i, node := range nodes {
+		index[node.ID] = i
+	}
+
+	inDegree := make([]int, len(nodes))
+	dependents := make([][]int, len(nodes))
+	for i, node := range nodes {
+		for _, dep := range node.Dependencies {
+			if j, ok := index[dep]; ok {
+				inDegree[i]++
+				dependents[j] = append(dependents[j], i)
+			}
+		}
+	}
+
+	var queue []int
+	for i := range nodes {
+		if inDegree[i] == 0 {
+			queue = append(queue, i)
+		}
+	}
+	order := make([]model.DAGNode, 0, len(nodes))
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		order = append(order, nodes[i])
+		for _, j := range dependents[i] {
+			inDegree[j]--
+			if inDegree[j] == 0 {
+				queue = append(queue, j)
+			}
+		}
+	}
+	if len(order) != len(nodes) {
+		return nil, errors.New("DAG中存在环")
+	}
+	return order, nil
+}
+
+// criticalPath 关键路径法：正向计算最早时间，反向计算最晚时间，松弛为0的节点组成关键路径
+func criticalPath(nodes []model.DAGNode, durations map[string]float64, samples map[string]int) (*DAGAnalysis, error) {
+	order, err := topologicalOrder(nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	estimates := make(map[string]*DAGNodeEstimate, len(order))
+	dependents := make(map[string][]string, len(order))
+	total := 0.0
+	for _, node := range order {
+		estimate := &DAGNodeEstimate{NodeID: node.ID, Duration: durations[node.ID], Samples: samples[node.ID]}
+		for _, dep := range node.Dependencies {
+			if depEstimate, ok := estimates[dep]; ok {
+				dependents[dep] = append(dependents[dep], node.ID)
+				if depEstimate.EarliestFinish > estimate.EarliestStart {
+					estimate.EarliestStart = depEstimate.EarliestFinish
+				}
+			}
+		}
+		estimate.EarliestFinish = estimate.EarliestStart + estimate.Duration
+		if estimate.EarliestFinish > total {
+			total = estimate.EarliestFinish
+		}
+		estimates[node.ID] = estimate
+	}
+
+	for i := len(order) - 1; i >= 0; i-- {
+		estimate := estimates[order[i].ID]
+		estimate.LatestFinish = total
+		for _, dependent := range dependents[estimate.NodeID] {
+			if ls := estimates[dependent].LatestStart; ls < estimate.LatestFinish {
+				estimate.LatestFinish = ls
+			}
+		}
+		estimate.LatestStart = estimate.LatestFinish - estimate.Duration
+		estimate.Slack = estimate.LatestStart - estimate.EarliestStart
+		// 浮点误差内视为0
+		if estimate.Slack < 1e-6 {
+			estimate.Slack = 0
+			estimate.Critical = true
+		}
+	}
+
+	analysis := &DAGAnalysis{
+		TotalDuration: total,
+		CriticalPath:  []string{},
+		Nodes:         make([]DAGNodeEstimate, 0, len(nodes)),
+	}
+	for _, node := range nodes {
+		analysis.Nodes = append(analysis.Nodes, *estimates[node.ID])
+	}
+
+	// 从最晚完成的关键节点沿依赖回溯
+	var current *DAGNodeEstimate
+	for _, node := range order {
+		estimate := estimates[node.ID]
+		if estimate.Critical && estimate.EarliestFinish >= total-1e-6 {
+			current = estimate
+			break
+		}
+	}
+	nodeByID := make(map[string]model.DAGNode, len(nodes))
+	for _, node := range nodes {
+		nodeByID[node.ID] = node
+	}
+	for current != nil {
+		analysis.CriticalPath = append([]string{current.NodeID}, analysis.CriticalPath...)
+		var next *DAGNodeEstimate
+		for _, dep := range nodeByID[current.NodeID].Dependencies {
+			if depEstimate, ok := estimates[dep]; ok && depEstimate.Critical && depEstimate.EarliestFinish >= current.EarliestStart-1e-6 {
+				next = depEstimate
+				break
+			}
+		}
+		current = next
+	}
+
+	analysis.Parallelism = parallelismProfile(analysis.Nodes)
+	return analysis, nil
+}
+
+// parallelismProfile 按最早开始时间调度时，各时间段同时运行的节点
+func parallelismProfile(estimates []DAGNodeEstimate) []ParallelismSegment {
+	var points []float64
+	for _, estimate := range estimates {
+		points = append(points, estimate.EarliestStart, estimate.EarliestFinish)
+	}
+	sort.Float64s(points)
+
+	segments := []ParallelismSegment{}
+	for i := 0; i+1 < len(points); i++ {
+		start, end := points[i], points[i+1]
+		if end-start < 1e-6 {
+			continue
+		}
+		nodes := []string{}
+		for _, estimate := range estimates {
+			if estimate.EarliestStart <= start+1e-6 && estimate.EarliestFinish >= end-1e-6 {
+				nodes = append(nodes, estimate.NodeID)
+			}
+		}
+		// 与上一段运行的节点相同时合并
+		if n := len(segments); n > 0 && segments[n-1].End == start && equalStrings(segments[n-1].Nodes, nodes) {
+			segments[n-1].End = end
+			continue
+		}
+		segments = append(segments, ParallelismSegment{Start: start, End: end, Running: len(nodes), Nodes: nodes})
+	}
+	return segments
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// estimateRunETA 根据已完成、运行中和未开始的任务估计运行的剩余时间
+func estimateRunETA(run *model.PipelineRun, nodes []model.DAGNode, durations map[string]float64) (*RunETA, error) {
+	now := time.Now()
+	eta := &RunETA{RunID: run.ID, Status: run.Status, Pending: []string{}, Running: []string{}, ETA: now}
+	if run.Status != "pending" && run.Status != "running" {
+		if run.EndTime != nil {
+			eta.ETA = *run.EndTime
+		}
+		return eta, nil
+	}
+
+	var tasks []model.PipelineRunTask
+	if err := global.DB.Where("run_id = ?", run.ID).Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+	taskByNode := make(map[string]model.PipelineRunTask, len(tasks))
+	for _, task := range tasks {
+		taskByNode[task.NodeID] = task
+	}
+
+	// 已完成的任务剩余为0，运行中的任务按已运行时间扣减
+	remaining := make(map[string]float64, len(nodes))
+	for _, node := range nodes {
+		task, ok := taskByNode[node.ID]
+		switch {
+		case !ok:
+			remaining[node.ID] = durations[node.ID]
+			eta.Pending = append(eta.Pending, node.ID)
+		case task.Status == "running":
+			left := durations[node.ID]
+			if task.StartedAt != nil {
+				left -= now.Sub(*task.StartedAt).Seconds()
+			}
+			if left < 0 {
+				left = 0
+			}
+			remaining[node.ID] = left
+			eta.Running = append(eta.Running, node.ID)
+		default:
+			remaining[node.ID] = 0
+		}
+	}
+
+	schedule, err := criticalPath(nodes, remaining, nil)
+	if err != nil {
+		return nil, err
+	}
+	eta.Remaining = schedule.TotalDuration
+	eta.ETA = now.Add(time.Duration(eta.Remaining * float64(time.Second)))
+	return eta, nil
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"gin_pipeline/model"
+	"reflect"
+	"testing"
+)
+
+func TestEstimateNodeDurations(t *testing.T) {
+	nodes := []model.DAGNode{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+	tests := []struct {
+		name      string
+		durations map[string]float64
+		samples   map[string]int
+		want      map[string]float64
+	}{
+		{
+			name: "no history uses the default",
+			want: map[string]float64{"a": defaultTaskDuration, "b": defaultTaskDuration, "c": defaultTaskDuration},
+		},
+		{
+			name:      "missing nodes use the average of known nodes",
+			durations: map[string]float64{"a": 10, "b": 30},
+			samples:   map[string]int{"a": 2, "b": 1},
+			want:      map[string]float64{"a": 10, "b": 30, "c": 20},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := estimateNodeDurations(nodes, tt.durations, tt.samples); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("estimateNodeDurations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCriticalPath(t *testing.T) {
+	node := func(id string, deps ...string) model.DAGNode {
+		return model.DAGNode{ID: id, Dependencies: deps}
+	}
+
+	tests := []struct {
+		name      string
+		nodes     []model.DAGNode
+		durations map[string]float64
+		wantTotal float64
+		wantPath  []string
+		wantSlack map[string]float64
+		wantErr   bool
+	}{
+		{
+			name:      "chain",
+			nodes:     []model.DAGNode{node("a"), node("b", "a"), node("c", "b")},
+			durations: map[string]float64{"a": 10, "b": 20, "c": 5},
+			wantTotal: 35,
+			wantPath:  []string{"a", "b", "c"},
+			wantSlack: map[string]float64{"a": 0, "b": 0, "c": 0},
+		},
+		{
+			// build -> (unit 30 | lint 5) -> deploy，lint可延迟25秒
+			name:      "diamond",
+			nodes:     []model.DAGNode{node("build"), node("unit", "build"), node("lint", "build"), node("deploy", "unit", "lint")},
+			durations: map[string]float64{"build": 10, "unit": 30, "lint": 5, "deploy": 10},
+			wantTotal: 50,
+			wantPath:  []string{"build", "unit", "deploy"},
+			wantSlack: map[string]float64{"build": 0, "unit": 0, "lint": 25, "deploy": 0},
+		},
+		{
+			name:      "independent branches",
+			nodes:     []model.DAGNode{node("a"), node("b")},
+			durations: map[string]float64{"a": 10, "b": 40},
+			wantTotal: 40,
+			wantPath:  []string{"b"},
+			wantSlack: map[string]float64{"a": 30, "b": 0},
+		},
+		{
+			name:      "unknown dependencies are ignored",
+			nodes:     []model.DAGNode{node("a", "missing")},
+			durations: map[string]float64{"a": 10},
+			wantTotal: 10,
+			wantPath:  []string{"a"},
+			wantSlack: map[string]float64{"a": 0},
+		},
+		{
+			name:    "cycle",
+			nodes:   []model.DAGNode{node("a", "b"), node("b", "a")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analysis, err := criticalPath(tt.nodes, tt.durations, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("criticalPath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if analysis.TotalDuration != tt.wantTotal {
+				t.Errorf("TotalDuration = %v, want %v", analysis.TotalDuration, tt.wantTotal)
+			}
+			if !reflect.DeepEqual(analysis.CriticalPath, tt.wantPath) {
+				t.Errorf("CriticalPath = %v, want %v", analysis.CriticalPath, tt.wantPath)
+			}
+			for _, estimate := range analysis.Nodes {
+				if estimate.Slack != tt.wantSlack[estimate.NodeID] || estimate.Critical != (estimate.Slack == 0) {
+					t.Errorf("node %s slack = %v critical = %v, want slack %v", estimate.NodeID, estimate.Slack, estimate.Critical, tt.wantSlack[estimate.NodeID])
+				}
+			}
+		})
+	}
+}
+
+func TestParallelismProfile(t *testing.T) {
+	nodes := []model.DAGNode{
+		{ID: "build"},
+		{ID: "unit", Dependencies: []string{"build"}},
+		{ID: "lint", Dependencies: []string{"build"}},
+		{ID: "deploy", Dependencies: []string{"unit", "lint"}},
+	}
+	analysis, err := criticalPath(nodes, map[string]float64{"build": 10, "unit": 30, "lint": 5, "deploy": 10}, nil)
+	if err != nil {
+		t.Fatalf("criticalPath() error = %v", err)
+	}
+	want := []ParallelismSegment{
+		{Start: 0, End: 10, Running: 1, Nodes: []string{"build"}},
+		{Start: 10, End: 15, Running: 2, Nodes: []string{"unit", "lint"}},
+		{Start: 15, End: 40, Running: 1, Nodes: []string{"unit"}},
+		{Start: 40, End: 50, Running: 1, Nodes: []string{"deploy"}},
+	}
+	if !reflect.DeepEqual(analysis.Parallelism, want) {
+		t.Fatalf("Parallelism = %+v, want %+v", analysis.Parallelism, want)
+	}
+}
@@ -0,0 +1,288 @@
+package service
+
+import (
+	"fmt"
+	"gin_pipeline/global"
+	"gin_pipeline/model"
+	"strings"
+)
+
+// 诊断级别
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// 诊断代码
+const (
+	DiagEmptyDAG            = "empty_dag"
+	DiagMissingID           = "missing_id"
+	DiagDuplicateID         = "duplicate_id"
+	DiagEmptyName           = "empty_name"
+	DiagSelfDependency      = "self_dependency"
+	DiagUnknownDependency   = "unknown_dependency"
+	DiagDuplicateDependency = "duplicate_dependency"
+	DiagUnknownType         = "unknown_type"
+	DiagInvalidConfig       = "invalid_config"
+	DiagInvalidRunsOn       = "invalid_runs_on"
+	DiagCycle               = "cycle"
+	DiagDisconnected        = "disconnected"
+	DiagInvalidFragment     = "invalid_fragment"
+)
+
+// DAGDiagnostic DAG验证发现的问题
+type DAGDiagnostic struct {
+	Severity string   `json:"severity"` // error, warning
+	NodeID   string   `json:"node_id,omitempty"`
+	Code     string   `json:"code"`
+	Message  string   `json:"message"`
+	Path     []string `json:"path,omitempty"` // 环上的节点，首尾相同，前一个节点依赖后一个节点
+}
+
+// DAGValidationError 包含所有诊断的验证错误
+type DAGValidationError struct {
+	Diagnostics []DAGDiagnostic
+}
+
+// Error 拼接所有错误级别的诊断
+func (e *DAGValidationError) Error() string {
+	var messages []string
+	for _, diag := range e.Diagnostics {
+		if diag.Severity == SeverityError {
+			messages = append(messages, diag.Message)
+		}
+	}
+	return strings.Join(messages, "; ")
+}
+
+// HasDAGErrors 诊断中是否包含错误级别的问题
+func HasDAGErrors(diagnostics []DAGDiagnostic) bool {
+	for _, diag := range diagnostics {
+		if diag.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// builtinConfigSchemas 内置任务类型的配置Schema，未列出的字段不做限制
+var builtinConfigSchemas = map[string]*ConfigSchema{
+	"shell": {
+		Type: "object",
+		Properties: map[string]*ConfigSchema{
+			"command": {Type: "string"},
+			"timeout": {Type: "integer", Minimum: floatPtr(1)},
+		},
+	},
+	"docker": {
+		Type:     "object",
+		Required: []string{"image"},
+		Properties: map[string]*ConfigSchema{
+			"image":   {Type: "string"},
+			"command": {Type: "string"},
+			"timeout": {Type: "integer", Minimum: floatPtr(1)},
+		},
+	},
+	"kubernetes": {
+		Type: "object",
+		Properties: map[string]*ConfigSchema{
+			"namespace": {Type: "string"},
+			"manifest":  {Type: "string"},
+			"timeout":   {Type: "integer", Minimum: floatPtr(1)},
+		},
+	},
+}
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+// nodeConfigSchema 获取任务类型的配置Schema，第二个返回值表示类型是否已知
+func nodeConfigSchema(taskType string) (*ConfigSchema, bool) {
+	if taskType == FragmentNodeType {
+		return fragmentConfigSchema, true
+	}
+	if schema, ok := builtinConfigSchemas[taskType]; ok {
+		return schema, true
+	}
+	if _, pluginType, ok := GetPluginManager().Lookup(taskType); ok {
+		return pluginType.ConfigSchema, true
+	}
+	// 启用远程执行器时，配置的远程任务类型由执行器自行解释配置
+	if global.Config.Runner.Enabled {
+		for _, remoteType := range remoteTaskTypes() {
+			if remoteType == taskType {
+				return nil, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// DiagnoseDAG 检查DAG并返回发现的所有问题
+func DiagnoseDAG(nodes []model.DAGNode) []DAGDiagnostic {
+	diagnostics := []DAGDiagnostic{}
+	report := func(severity, nodeID, code, message string) {
+		diagnostics = append(diagnostics, DAGDiagnostic{Severity: severity, NodeID: nodeID, Code: code, Message: message})
+	}
+
+	if len(nodes) == 0 {
+		report(SeverityError, "", DiagEmptyDAG, "DAG不能为空")
+		return diagnostics
+	}
+
+	// 片段节点展开后再检查，展开失败的片段节点保留原样
+	nodes, fragmentDiagnostics := expandFragmentsForDiagnosis(nodes)
+	diagnostics = append(diagnostics, fragmentDiagnostics...)
+
+	// 节点ID，重复时以第一次出现的节点为准
+	nodeMap := make(map[string]model.DAGNode, len(nodes))
+	duplicates := make(map[int]bool)
+	for i, node := range nodes {
+		if node.ID == "" {
+			report(SeverityError, "", DiagMissingID, fmt.Sprintf("第%d个节点缺少ID", i+1))
+			continue
+		}
+		if _, exists := nodeMap[node.ID]; exists {
+			report(SeverityError, node.ID, DiagDuplicateID, "节点ID重复: "+node.ID)
+			duplicates[i] = true
+			continue
+		}
+		nodeMap[node.ID] = node
+	}
+
+	dependents := make(map[string]int, len(nodeMap))
+	for i, node := range nodes {
+		if node.ID == "" || duplicates[i] {
+			continue
+		}
+
+		if strings.TrimSpace(node.Name) == "" {
+			report(SeverityError, node.ID, DiagEmptyName, fmt.Sprintf("节点 %s 没有名称", node.ID))
+		}
+
+		seen := make(map[string]bool, len(node.Dependencies))
+		for _, depID := range node.Dependencies {
+			switch {
+			case depID == node.ID:
+				report(SeverityError, node.ID, DiagSelfDependency, fmt.Sprintf("节点 %s 依赖自身", node.ID))
+			case seen[depID]:
+				report(SeverityWarning, node.ID, DiagDuplicateDependency, fmt.Sprintf("节点 %s 重复依赖 %s", node.ID, depID))
+			default:
+				if _, exists := nodeMap[depID]; !exists {
+					report(SeverityError, node.ID, DiagUnknownDependency, fmt.Sprintf("节点 %s 的依赖节点不存在: %s", node.ID, depID))
+				} else {
+					dependents[depID]++
+				}
+			}
+			seen[depID] = true
+		}
+
+		// 任务类型和配置
+		// 插件可能只安装在部分实例或执行器上，校验DAG的实例不一定认识所有类型，
+		// 因此未知类型只提示、不阻止保存，运行时找不到执行器的任务会失败
+		schema, known := nodeConfigSchema(node.Type)
+		if node.Type == "" {
+			report(SeverityError, node.ID, DiagUnknownType, fmt.Sprintf("节点 %s 缺少任务类型", node.ID))
+		} else if !known {
+			report(SeverityWarning, node.ID, DiagUnknownType, fmt.Sprintf("节点 %s 的任务类型未知: %q，运行时没有对应的执行器将失败", node.ID, node.Type))
+		} else if schema != nil {
+			config := map[string]interface{}(node.Config)
+			if config == nil {
+				config = map[string]interface{}{}
+			}
+			for _, msg := range schema.Validate(config) {
+				report(SeverityError, node.ID, DiagInvalidConfig, fmt.Sprintf("节点 %s 配置错误: %s", node.ID, msg))
+			}
+		}
+
+		if _, err := ParseLabelSelector(node.RunsOn); err != nil {
+			report(SeverityError, node.ID, DiagInvalidRunsOn, fmt.Sprintf("节点 %s 的runs_on无效: %v", node.ID, err))
+		}
+	}
+
+	// 孤立节点：多个节点时既不依赖其他节点也不被依赖
+	if len(nodeMap) > 1 {
+		checked := make(map[string]bool, len(nodeMap))
+		for _, node := range nodes {
+			if node.ID == "" || checked[node.ID] {
+				continue
+			}
+			checked[node.ID] = true
+			hasDeps := false
+			for _, depID := range node.Dependencies {
+				if _, exists := nodeMap[depID]; exists && depID != node.ID {
+					hasDeps = true
+					break
+				}
+			}
+			if !hasDeps && dependents[node.ID] == 0 {
+				report(SeverityWarning, node.ID, DiagDisconnected, fmt.Sprintf("节点 %s 与其他节点没有连接", node.ID))
+			}
+		}
+	}
+
+	for _, cycle := range findDAGCycles(nodes, nodeMap) {
+		diagnostics = append(diagnostics, DAGDiagnostic{
+			Severity: SeverityError,
+			NodeID:   cycle[0],
+			Code:     DiagCycle,
+			Message:  "DAG中存在环: " + strings.Join(cycle, " -> "),
+			Path:     cycle,
+		})
+	}
+
+	return diagnostics
+}
+
+// findDAGCycles 深度优先查找环，返回每个环经过的节点，忽略自依赖和不存在的依赖
+func findDAGCycles(nodes []model.DAGNode, nodeMap map[string]model.DAGNode) [][]string {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(nodeMap))
+	var stack []string
+	var cycles [][]string
+
+	var dfs func(nodeID string)
+	dfs = func(nodeID string) {
+		state[nodeID] = visiting
+		stack = append(stack, nodeID)
+
+		seen := make(map[string]bool)
+		for _, depID := range nodeMap[nodeID].Dependencies {
+			if depID == nodeID || seen[depID] {
+				continue
+			}
+			seen[depID] = true
+			if _, exists := nodeMap[depID]; !exists {
+				continue
+			}
+			switch state[depID] {
+			case unvisited:
+				dfs(depID)
+			case visiting:
+				// 从栈中截取环
+				for i := len(stack) - 1; i >= 0; i-- {
+					if stack[i] == depID {
+						cycle := append(append([]string{}, stack[i:]...), depID)
+						cycles = append(cycles, cycle)
+						break
+					}
+				}
+			}
+		}
+
+		stack = stack[:len(stack)-1]
+		state[nodeID] = done
+	}
+
+	for _, node := range nodes {
+		if _, exists := nodeMap[node.ID]; exists && state[node.ID] == unvisited {
+			dfs(node.ID)
+		}
+	}
+	return cycles
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"gin_pipeline/model"
+	"reflect"
+	"testing"
+)
+
+func TestDiagnoseDAG(t *testing.T) {
+	shell := func(id string, deps ...string) model.DAGNode {
+		return model.DAGNode{ID: id, Name: id, Type: "shell", Config: model.JSONMap{"command": "true"}, Dependencies: deps}
+	}
+
+	type finding struct {
+		severity string
+		nodeID   string
+		code     string
+	}
+	tests := []struct {
+		name  string
+		nodes []model.DAGNode
+		want  []finding
+	}{
+		{name: "valid chain", nodes: []model.DAGNode{shell("a"), shell("b", "a"), shell("c", "b")}},
+		{name: "empty", want: []finding{{SeverityError, "", DiagEmptyDAG}}},
+		{
+			name:  "missing and duplicate id",
+			nodes: []model.DAGNode{shell(""), shell("a"), shell("a")},
+			want:  []finding{{SeverityError, "", DiagMissingID}, {SeverityError, "a", DiagDuplicateID}},
+		},
+		{
+			name:  "dependency problems",
+			nodes: []model.DAGNode{shell("a", "a"), shell("b", "a", "a", "x")},
+			want: []finding{
+				{SeverityError, "a", DiagSelfDependency},
+				{SeverityWarning, "b", DiagDuplicateDependency},
+				{SeverityError, "b", DiagUnknownDependency},
+			},
+		},
+		{
+			name:  "cycle",
+			nodes: []model.DAGNode{shell("a", "c"), shell("b", "a"), shell("c", "b")},
+			want:  []finding{{SeverityError, "a", DiagCycle}},
+		},
+		{
+			name:  "disconnected and unnamed",
+			nodes: []model.DAGNode{shell("a"), shell("b", "a"), {ID: "c", Type: "shell"}},
+			want:  []finding{{SeverityError, "c", DiagEmptyName}, {SeverityWarning, "c", DiagDisconnected}},
+		},
+		{
+			// 插件可能只安装在部分实例上
+			name:  "unknown type is a warning",
+			nodes: []model.DAGNode{{ID: "a", Name: "a", Type: "helm"}},
+			want:  []finding{{SeverityWarning, "a", DiagUnknownType}},
+		},
+		{
+			name:  "missing type is an error",
+			nodes: []model.DAGNode{{ID: "a", Name: "a"}},
+			want:  []finding{{SeverityError, "a", DiagUnknownType}},
+		},
+		{
+			name:  "invalid config",
+			nodes: []model.DAGNode{{ID: "a", Name: "a", Type: "docker", Config: model.JSONMap{"timeout": "soon"}}},
+			want:  []finding{{SeverityError, "a", DiagInvalidConfig}, {SeverityError, "a", DiagInvalidConfig}},
+		},
+		{
+			name: "placeholders skip value checks",
+			nodes: []model.DAGNode{{ID: "a", Name: "a", Type: "docker",
+				Config: model.JSONMap{"image": "${{ params.image }}", "timeout": "${{ params.timeout }}"}}},
+		},
+		{
+			name:  "invalid runs_on",
+			nodes: []model.DAGNode{{ID: "a", Name: "a", Type: "shell", RunsOn: "linux &&"}},
+			want:  []finding{{SeverityError, "a", DiagInvalidRunsOn}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []finding
+			for _, diag := range DiagnoseDAG(tt.nodes) {
+				got = append(got, finding{diag.Severity, diag.NodeID, diag.Code})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("DiagnoseDAG() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateDAGSeverities(t *testing.T) {
+	s := &DAGService{}
+	// 未知类型只是警告，DAG仍可保存
+	if err := s.ValidateDAG([]model.DAGNode{{ID: "a", Name: "a", Type: "helm"}}); err != nil {
+		t.Fatalf("ValidateDAG(unknown type) error = %v, want nil", err)
+	}
+	if err := s.ValidateDAG([]model.DAGNode{{ID: "a", Type: "shell", Config: model.JSONMap{"command": "true"}}}); err == nil {
+		t.Fatal("ValidateDAG(empty name) error = nil, want an error")
+	}
+}
+
+func TestDiagnoseDAGCyclePath(t *testing.T) {
+	nodes := []model.DAGNode{
+		{ID: "a", Name: "a", Type: "shell", Dependencies: []string{"b"}},
+		{ID: "b", Name: "b", Type: "shell", Dependencies: []string{"a"}},
+	}
+	diagnostics := DiagnoseDAG(nodes)
+	if len(diagnostics) != 1 || diagnostics[0].Code != DiagCycle {
+		t.Fatalf("DiagnoseDAG() = %+v, want one cycle", diagnostics)
+	}
+	if want := []string{"a", "b", "a"}; !reflect.DeepEqual(diagnostics[0].Path, want) {
+		t.Fatalf("cycle path = %v, want %v", diagnostics[0].Path, want)
+	}
+	if !HasDAGErrors(diagnostics) {
+		t.Fatal("HasDAGErrors() = false for a cycle")
+	}
+}
@@ -0,0 +1,339 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"gin_pipeline/global"
+	"gin_pipeline/model"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// 字段变更类型
+const (
+	FieldAdded   = "added"
+	FieldRemoved = "removed"
+	FieldChanged = "changed"
+)
+
+// DAGFieldChange 节点字段的变更
+type DAGFieldChange struct {
+	Path string      `json:"path"` // 字段路径，如 type、config.image
+	Kind string      `json:"kind"` // added, removed, changed
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// DAGNodeRename 节点ID的变更，节点类型和配置相同但ID不同时视为重命名
+type DAGNodeRename struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// DAGNodeChange 两个版本中都存在的节点的变更
+type DAGNodeChange struct {
+	ID                  string           `json:"id"` // 目标版本中的节点ID
+	DependenciesAdded   []string         `json:"dependencies_added,omitempty"`
+	DependenciesRemoved []string         `json:"dependencies_removed,omitempty"`
+	Fields              []DAGFieldChange `json:"fields,omitempty"`
+}
+
+// DAGDiff 两个DAG版本之间的结构差异
+type DAGDiff struct {
+	FromID      uint            `json:"from_id"`
+	FromVersion int             `json:"from_version"`
+	ToID        uint            `json:"to_id"`
+	ToVersion   int             `json:"to_version"`
+	Added       []model.DAGNode `json:"added"`
+	Removed     []model.DAGNode `json:"removed"`
+	Renamed     []DAGNodeRename `json:"renamed"`
+	Changed     []DAGNodeChange `json:"changed"`
+	Text        string          `json:"text"` // 便于评审的文本形式
+}
+
+// Empty 两个版本是否没有差异
+func (d *DAGDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Renamed) == 0 && len(d.Changed) == 0
+}
+
+// DiffDAGs 比较两个DAG版本，from为nil时目标版本的所有节点都视为新增
+func DiffDAGs(from, to *model.DAG) *DAGDiff {
+	diff := &DAGDiff{
+		ToID:      to.ID,
+		ToVersion: to.Version,
+		Added:     []model.DAGNode{},
+		Removed:   []model.DAGNode{},
+		Renamed:   []DAGNodeRename{},
+		Changed:   []DAGNodeChange{},
+	}
+
+	var fromNodes []model.DAGNode
+	if from != nil {
+		diff.FromID = from.ID
+		diff.FromVersion = from.Version
+		fromNodes = from.NodesData
+	}
+
+	oldByID := make(map[string]model.DAGNode, len(fromNodes))
+	for _, node := range fromNodes {
+		oldByID[node.ID] = node
+	}
+	newByID := make(map[string]model.DAGNode, len(to.NodesData))
+	for _, node := range to.NodesData {
+		newByID[node.ID] = node
+	}
+
+	// 找出只存在于一边的节点，类型和配置相同的一对视为重命名
+	var removed, added []model.DAGNode
+	for _, node := range fromNodes {
+		if _, ok := newByID[node.ID]; !ok {
+			removed = append(removed, node)
+		}
+	}
+	for _, node := range to.NodesData {
+		if _, ok := oldByID[node.ID]; !ok {
+			added = append(added, node)
+		}
+	}
+
+	renamedTo := make(map[string]string) // 旧ID -> 新ID
+	renamedFrom := make(map[string]string)
+	for _, oldNode := range removed {
+		for _, newNode := range added {
+			if _, used := renamedFrom[newNode.ID]; used {
+				continue
+			}
+			if oldNode.Type == newNode.Type && reflect.DeepEqual(normalizeJSON(oldNode.Config), normalizeJSON(newNode.Config)) {
+				renamedTo[oldNode.ID] = newNode.ID
+				renamedFrom[newNode.ID] = oldNode.ID
+				diff.Renamed = append(diff.Renamed, DAGNodeRename{From: oldNode.ID, To: newNode.ID})
+				break
+			}
+		}
+	}
+	for _, node := range removed {
+		if _, ok := renamedTo[node.ID]; !ok {
+			diff.Removed = append(diff.Removed, node)
+		}
+	}
+	for _, node := range added {
+		if _, ok := renamedFrom[node.ID]; !ok {
+			diff.Added = append(diff.Added, node)
+		}
+	}
+
+	// 比较两边都存在的节点，依赖中的旧ID按重命名映射后再比较
+	for _, newNode := range to.NodesData {
+		oldID := newNode.ID
+		if renamed, ok := renamedFrom[newNode.ID]; ok {
+			oldID = renamed
+		}
+		oldNode, ok := oldByID[oldID]
+		if !ok {
+			continue
+		}
+
+		oldDeps := make([]string, 0, len(oldNode.Dependencies))
+		for _, dep := range oldNode.Dependencies {
+			if renamed, ok := renamedTo[dep]; ok {
+				dep = renamed
+			}
+			oldDeps = append(oldDeps, dep)
+		}
+
+		change := DAGNodeChange{ID: newNode.ID}
+		change.DependenciesAdded, change.DependenciesRemoved = diffStringSets(oldDeps, newNode.Dependencies)
+		change.Fields = diffNodeFields(oldNode, newNode)
+		if len(change.DependenciesAdded) > 0 || len(change.DependenciesRemoved) > 0 || len(change.Fields) > 0 {
+			diff.Changed = append(diff.Changed, change)
+		}
+	}
+
+	diff.Text = renderDAGDiff(diff)
+	return diff
+}
+
+// DiffDAGsByID 比较两个已存储的DAG版本
+func (s *DAGService) DiffDAGsByID(fromID, toID uint) (*DAGDiff, error) {
+	from, err := s.GetDAGByID(fromID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.GetDAGByID(toID)
+	if err != nil {
+		return nil, err
+	}
+	return DiffDAGs(from, to), nil
+}
+
+// ActivationDiff 激活指定DAG将带来的变化，与流水线当前的活动DAG比较
+func (s *DAGService) ActivationDiff(dagID uint) (*DAGDiff, error) {
+	to, err := s.GetDAGByID(dagID)
+	if err != nil {
+		return nil, err
+	}
+
+	var active model.DAG
+	result := global.DB.Where("pipeline_id = ? AND is_active = ?", to.PipelineID, true).
+		Order("version DESC").Limit(1).Find(&active)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return DiffDAGs(nil, to), nil
+	}
+	return DiffDAGs(&active, to), nil
+}
+
+// diffNodeFields 比较节点的名称、类型、执行器标签和配置
+func diffNodeFields(oldNode, newNode model.DAGNode) []DAGFieldChange {
+	var changes []DAGFieldChange
+	for _, field := range []struct {
+		path     string
+		old, new string
+	}{
+		{"name", oldNode.Name, newNode.Name},
+		{"type", oldNode.Type, newNode.Type},
+		{"runs_on", oldNode.RunsOn, newNode.RunsOn},
+	} {
+		if field.old != field.new {
+			changes = append(changes, DAGFieldChange{Path: field.path, Kind: FieldChanged, Old: field.old, New: field.new})
+		}
+	}
+	return append(changes, diffValues("config", normalizeJSON(oldNode.Config), normalizeJSON(newNode.Config))...)
+}
+
+// diffValues 递归比较两个JSON值，对象逐个键比较，其他类型整体比较
+func diffValues(path string, oldValue, newValue interface{}) []DAGFieldChange {
+	oldMap, oldIsMap := oldValue.(map[string]interface{})
+	newMap, newIsMap := newValue.(map[string]interface{})
+	if !oldIsMap || !newIsMap {
+		switch {
+		case reflect.DeepEqual(oldValue, newValue):
+			return nil
+		case oldValue == nil:
+			return []DAGFieldChange{{Path: path, Kind: FieldAdded, New: newValue}}
+		case newValue == nil:
+			return []DAGFieldChange{{Path: path, Kind: FieldRemoved, Old: oldValue}}
+		default:
+			return []DAGFieldChange{{Path: path, Kind: FieldChanged, Old: oldValue, New: newValue}}
+		}
+	}
+
+	keys := make(map[string]bool, len(oldMap)+len(newMap))
+	for key := range oldMap {
+		keys[key] = true
+	}
+	for key := range newMap {
+		keys[key] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	var changes []DAGFieldChange
+	for _, key := range sorted {
+		changes = append(changes, diffValues(path+"."+key, oldMap[key], newMap[key])...)
+	}
+	return changes
+}
+
+// normalizeJSON 通过JSON编解码统一数值等类型，使来自请求和数据库的配置可以直接比较
+func normalizeJSON(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return value
+	}
+	if m, ok := normalized.(map[string]interface{}); ok && len(m) == 0 {
+		return nil
+	}
+	return normalized
+}
+
+// diffStringSets 返回新增和删除的元素
+func diffStringSets(oldItems, newItems []string) (added, removed []string) {
+	oldSet := make(map[string]bool, len(oldItems))
+	for _, item := range oldItems {
+		oldSet[item] = true
+	}
+	newSet := make(map[string]bool, len(newItems))
+	for _, item := range newItems {
+		newSet[item] = true
+		if !oldSet[item] {
+			added = append(added, item)
+		}
+	}
+	for _, item := range oldItems {
+		if !newSet[item] {
+			removed = append(removed, item)
+		}
+	}
+	return added, removed
+}
+
+// renderDAGDiff 将差异渲染为文本
+func renderDAGDiff(diff *DAGDiff) string {
+	var b strings.Builder
+	if diff.FromID == 0 {
+		fmt.Fprintf(&b, "(无) -> v%d (#%d)\n", diff.ToVersion, diff.ToID)
+	} else {
+		fmt.Fprintf(&b, "v%d (#%d) -> v%d (#%d)\n", diff.FromVersion, diff.FromID, diff.ToVersion, diff.ToID)
+	}
+	if diff.Empty() {
+		b.WriteString("没有变化\n")
+		return b.String()
+	}
+
+	for _, node := range diff.Added {
+		fmt.Fprintf(&b, "+ %s (%s)", node.ID, node.Type)
+		if len(node.Dependencies) > 0 {
+			fmt.Fprintf(&b, " 依赖 %s", strings.Join(node.Dependencies, ", "))
+		}
+		b.WriteString("\n")
+	}
+	for _, node := range diff.Removed {
+		fmt.Fprintf(&b, "- %s (%s)\n", node.ID, node.Type)
+	}
+	for _, rename := range diff.Renamed {
+		fmt.Fprintf(&b, "> %s 重命名为 %s\n", rename.From, rename.To)
+	}
+	for _, change := range diff.Changed {
+		fmt.Fprintf(&b, "~ %s\n", change.ID)
+		for _, dep := range change.DependenciesAdded {
+			fmt.Fprintf(&b, "    + 依赖 %s\n", dep)
+		}
+		for _, dep := range change.DependenciesRemoved {
+			fmt.Fprintf(&b, "    - 依赖 %s\n", dep)
+		}
+		for _, field := range change.Fields {
+			switch field.Kind {
+			case FieldAdded:
+				fmt.Fprintf(&b, "    + %s: %s\n", field.Path, formatDiffValue(field.New))
+			case FieldRemoved:
+				fmt.Fprintf(&b, "    - %s: %s\n", field.Path, formatDiffValue(field.Old))
+			default:
+				fmt.Fprintf(&b, "    ~ %s: %s -> %s\n", field.Path, formatDiffValue(field.Old), formatDiffValue(field.New))
+			}
+		}
+	}
+	return b.String()
+}
+
+// formatDiffValue 以JSON形式显示值
+func formatDiffValue(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
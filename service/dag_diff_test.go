@@ -0,0 +1,106 @@
+package service
+
+import (
+	"gin_pipeline/model"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffDAGs(t *testing.T) {
+	build := model.DAGNode{ID: "build", Name: "构建", Type: "shell", Config: model.JSONMap{"command": "make"}}
+	test := model.DAGNode{ID: "test", Name: "测试", Type: "shell", Config: model.JSONMap{"command": "make test"}, Dependencies: []string{"build"}}
+	deploy := model.DAGNode{ID: "deploy", Name: "部署", Type: "docker", Config: model.JSONMap{"image": "app:1"}, Dependencies: []string{"test"}}
+
+	ids := func(nodes []model.DAGNode) []string {
+		var result []string
+		for _, node := range nodes {
+			result = append(result, node.ID)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name        string
+		from        []model.DAGNode
+		to          []model.DAGNode
+		wantAdded   []string
+		wantRemoved []string
+		wantRenamed []DAGNodeRename
+		wantChanged []DAGNodeChange
+	}{
+		{name: "identical", from: []model.DAGNode{build, test}, to: []model.DAGNode{build, test}},
+		{
+			name:      "added node",
+			from:      []model.DAGNode{build, test},
+			to:        []model.DAGNode{build, test, deploy},
+			wantAdded: []string{"deploy"},
+		},
+		{
+			name:        "removed node and dependency",
+			from:        []model.DAGNode{build, test, deploy},
+			to:          []model.DAGNode{build, {ID: "deploy", Name: "部署", Type: "docker", Config: model.JSONMap{"image": "app:1"}, Dependencies: []string{"build"}}},
+			wantRemoved: []string{"test"},
+			wantChanged: []DAGNodeChange{{ID: "deploy", DependenciesAdded: []string{"build"}, DependenciesRemoved: []string{"test"}}},
+		},
+		{
+			// 依赖跟随重命名，不视为依赖变更
+			name:        "renamed node",
+			from:        []model.DAGNode{build, test},
+			to:          []model.DAGNode{{ID: "compile", Name: "构建", Type: "shell", Config: model.JSONMap{"command": "make"}}, {ID: "test", Name: "测试", Type: "shell", Config: model.JSONMap{"command": "make test"}, Dependencies: []string{"compile"}}},
+			wantRenamed: []DAGNodeRename{{From: "build", To: "compile"}},
+		},
+		{
+			name: "field and nested config changes",
+			from: []model.DAGNode{{ID: "deploy", Name: "部署", Type: "docker", RunsOn: "linux",
+				Config: model.JSONMap{"image": "app:1", "env": map[string]interface{}{"A": "1", "B": "2"}, "timeout": 60}}},
+			to: []model.DAGNode{{ID: "deploy", Name: "发布", Type: "docker", RunsOn: "linux && docker",
+				Config: model.JSONMap{"image": "app:2", "env": map[string]interface{}{"A": "1", "C": "3"}, "timeout": float64(60)}}},
+			wantChanged: []DAGNodeChange{{ID: "deploy", Fields: []DAGFieldChange{
+				{Path: "name", Kind: FieldChanged, Old: "部署", New: "发布"},
+				{Path: "runs_on", Kind: FieldChanged, Old: "linux", New: "linux && docker"},
+				{Path: "config.env.B", Kind: FieldRemoved, Old: "2"},
+				{Path: "config.env.C", Kind: FieldAdded, New: "3"},
+				{Path: "config.image", Kind: FieldChanged, Old: "app:1", New: "app:2"},
+			}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := DiffDAGs(&model.DAG{ID: 1, Version: 1, NodesData: tt.from}, &model.DAG{ID: 2, Version: 2, NodesData: tt.to})
+			if got := ids(diff.Added); !reflect.DeepEqual(got, tt.wantAdded) {
+				t.Errorf("Added = %v, want %v", got, tt.wantAdded)
+			}
+			if got := ids(diff.Removed); !reflect.DeepEqual(got, tt.wantRemoved) {
+				t.Errorf("Removed = %v, want %v", got, tt.wantRemoved)
+			}
+			wantEmpty := tt.wantAdded == nil && tt.wantRemoved == nil && tt.wantRenamed == nil && tt.wantChanged == nil
+			if tt.wantRenamed == nil {
+				tt.wantRenamed = []DAGNodeRename{}
+			}
+			if tt.wantChanged == nil {
+				tt.wantChanged = []DAGNodeChange{}
+			}
+			if !reflect.DeepEqual(diff.Renamed, tt.wantRenamed) {
+				t.Errorf("Renamed = %v, want %v", diff.Renamed, tt.wantRenamed)
+			}
+			if !reflect.DeepEqual(diff.Changed, tt.wantChanged) {
+				t.Errorf("Changed = %+v, want %+v", diff.Changed, tt.wantChanged)
+			}
+			if diff.Empty() != wantEmpty {
+				t.Errorf("Empty() = %v, want %v", diff.Empty(), wantEmpty)
+			}
+		})
+	}
+}
+
+func TestDiffDAGsFromNothing(t *testing.T) {
+	to := &model.DAG{ID: 5, Version: 1, NodesData: []model.DAGNode{{ID: "build", Type: "shell"}}}
+	diff := DiffDAGs(nil, to)
+	if len(diff.Added) != 1 || diff.FromID != 0 {
+		t.Fatalf("DiffDAGs(nil) = %+v, want every node added", diff)
+	}
+	if !strings.HasPrefix(diff.Text, "(无) -> v1 (#5)") {
+		t.Fatalf("Text = %q", diff.Text)
+	}
+}
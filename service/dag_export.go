@@ -0,0 +1,295 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"gin_pipeline/model"
+	"html"
+	"sort"
+	"strings"
+)
+
+// DAG导出格式
+const (
+	DAGExportDOT     = "dot"
+	DAGExportMermaid = "mermaid"
+	DAGExportSVG     = "svg"
+)
+
+// dagStatusColor 任务状态对应的填充色和边框色
+type dagStatusColor struct {
+	Fill   string
+	Stroke string
+}
+
+var dagStatusColors = map[string]dagStatusColor{
+	"pending":  {"#f3f4f6", "#9ca3af"},
+	"running":  {"#dbeafe", "#3b82f6"},
+	"success":  {"#d1fae5", "#10b981"},
+	"failed":   {"#fee2e2", "#ef4444"},
+	"canceled": {"#fef3c7", "#f59e0b"},
+	"skipped":  {"#fef3c7", "#f59e0b"},
+}
+
+// defaultNodeColor 未指定运行或没有状态的节点颜色
+var defaultNodeColor = dagStatusColor{"#ffffff", "#4b5563"}
+
+// nodeColor 获取节点颜色，statuses为空时使用默认颜色
+func nodeColor(statuses map[string]string, nodeID string) dagStatusColor {
+	if color, ok := dagStatusColors[statuses[nodeID]]; ok {
+		return color
+	}
+	return defaultNodeColor
+}
+
+// ExportDAG 将DAG导出为指定格式，statuses为节点ID到任务状态的映射，用于按运行结果着色
+func ExportDAG(dag *model.DAG, format string, statuses map[string]string) ([]byte, string, error) {
+	switch format {
+	case DAGExportDOT:
+		return exportDOT(dag, statuses), "text/vnd.graphviz; charset=utf-8", nil
+	case DAGExportMermaid:
+		return exportMermaid(dag, statuses), "text/plain; charset=utf-8", nil
+	case DAGExportSVG:
+		return exportSVG(dag, statuses), "image/svg+xml", nil
+	default:
+		return nil, "", fmt.Errorf("不支持的导出格式: %s", format)
+	}
+}
+
+// dagEdges 返回DAG的边，忽略指向不存在节点的依赖
+func dagEdges(nodes []model.DAGNode) [][2]int {
+	index := make(map[string]int, len(nodes))
+	for i, node := range nodes {
+		index[node.ID] = i
+	}
+
+	var edges [][2]int
+	for i, node := range nodes {
+		for _, dep := range node.Dependencies {
+			if from, ok := index[dep]; ok {
+				edges = append(edges, [2]int{from, i})
+			}
+		}
+	}
+	return edges
+}
+
+// nodeLabelName 节点显示名称，未设置名称时使用ID
+func nodeLabelName(node model.DAGNode) string {
+	if node.Name != "" {
+		return node.Name
+	}
+	return node.ID
+}
+
+// exportDOT 导出为Graphviz DOT
+func exportDOT(dag *model.DAG, statuses map[string]string) []byte {
+	quote := func(s string) string {
+		s = strings.ReplaceAll(s, `\`, `\\`)
+		s = strings.ReplaceAll(s, "\n", `\n`)
+		return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "digraph %s {\n", quote(dag.Name))
+	buf.WriteString("  rankdir=LR;\n")
+	buf.WriteString("  node [shape=box, style=\"rounded,filled\", fontname=\"Helvetica\"];\n")
+	for _, node := range dag.NodesData {
+		color := nodeColor(statuses, node.ID)
+		label := nodeLabelName(node) + "\n" + node.Type
+		if status := statuses[node.ID]; status != "" {
+			label += " · " + status
+		}
+		fmt.Fprintf(&buf, "  %s [label=%s, fillcolor=%s, color=%s];\n",
+			quote(node.ID), quote(label), quote(color.Fill), quote(color.Stroke))
+	}
+	for _, edge := range dagEdges(dag.NodesData) {
+		fmt.Fprintf(&buf, "  %s -> %s;\n", quote(dag.NodesData[edge[0]].ID), quote(dag.NodesData[edge[1]].ID))
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes()
+}
+
+// exportMermaid 导出为Mermaid流程图，节点ID可能包含Mermaid不支持的字符，统一使用序号作为标识
+func exportMermaid(dag *model.DAG, statuses map[string]string) []byte {
+	escape := func(s string) string {
+		s = strings.ReplaceAll(s, `"`, "#quot;")
+		s = strings.ReplaceAll(s, "<", "#lt;")
+		return strings.ReplaceAll(s, ">", "#gt;")
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("flowchart LR\n")
+	usedStatuses := make(map[string]bool)
+	for i, node := range dag.NodesData {
+		label := escape(nodeLabelName(node)) + "<br/><small>" + escape(node.Type) + "</small>"
+		fmt.Fprintf(&buf, "  n%d[\"%s\"]\n", i, label)
+		if status := statuses[node.ID]; status != "" {
+			if _, ok := dagStatusColors[status]; ok {
+				fmt.Fprintf(&buf, "  class n%d %s\n", i, status)
+				usedStatuses[status] = true
+			}
+		}
+	}
+	for _, edge := range dagEdges(dag.NodesData) {
+		fmt.Fprintf(&buf, "  n%d --> n%d\n", edge[0], edge[1])
+	}
+
+	names := make([]string, 0, len(usedStatuses))
+	for status := range usedStatuses {
+		names = append(names, status)
+	}
+	sort.Strings(names)
+	for _, status := range names {
+		color := dagStatusColors[status]
+		fmt.Fprintf(&buf, "  classDef %s fill:%s,stroke:%s\n", status, color.Fill, color.Stroke)
+	}
+	return buf.Bytes()
+}
+
+// SVG布局尺寸
+const (
+	svgNodeWidth  = 180
+	svgNodeHeight = 56
+	svgGapX       = 80
+	svgGapY       = 28
+	svgMargin     = 24
+	svgMaxLabel   = 22
+)
+
+// layoutDAG 分层布局：节点所在层为从根节点出发的最长路径长度，层内按上一层相邻节点的平均位置排序以减少交叉
+func layoutDAG(nodes []model.DAGNode, edges [][2]int) [][]int {
+	parents := make([][]int, len(nodes))
+	for _, edge := range edges {
+		parents[edge[1]] = append(parents[edge[1]], edge[0])
+	}
+
+	// 计算层级，state用于在存在环时终止递归
+	layer := make([]int, len(nodes))
+	state := make([]int, len(nodes)) // 0未访问 1访问中 2已完成
+	var visit func(i int) int
+	visit = func(i int) int {
+		if state[i] == 2 || state[i] == 1 {
+			return layer[i]
+		}
+		state[i] = 1
+		for _, p := range parents[i] {
+			if l := visit(p) + 1; l > layer[i] {
+				layer[i] = l
+			}
+		}
+		state[i] = 2
+		return layer[i]
+	}
+
+	depth := 0
+	for i := range nodes {
+		if l := visit(i); l+1 > depth {
+			depth = l + 1
+		}
+	}
+
+	layers := make([][]int, depth)
+	for i := range nodes {
+		layers[layer[i]] = append(layers[layer[i]], i)
+	}
+
+	position := make([]float64, len(nodes))
+	for l, members := range layers {
+		if l > 0 {
+			weight := make(map[int]float64, len(members))
+			for _, i := range members {
+				if len(parents[i]) == 0 {
+					weight[i] = position[i]
+					continue
+				}
+				sum := 0.0
+				for _, p := range parents[i] {
+					sum += position[p]
+				}
+				weight[i] = sum / float64(len(parents[i]))
+			}
+			sort.SliceStable(members, func(a, b int) bool {
+				return weight[members[a]] < weight[members[b]]
+			})
+		}
+		for order, i := range members {
+			position[i] = float64(order)
+		}
+	}
+	return layers
+}
+
+// truncateLabel 截断过长的标签
+func truncateLabel(s string) string {
+	runes := []rune(s)
+	if len(runes) <= svgMaxLabel {
+		return s
+	}
+	return string(runes[:svgMaxLabel-1]) + "…"
+}
+
+// exportSVG 导出为SVG图片
+func exportSVG(dag *model.DAG, statuses map[string]string) []byte {
+	nodes := dag.NodesData
+	edges := dagEdges(nodes)
+	layers := layoutDAG(nodes, edges)
+
+	maxRows := 0
+	for _, members := range layers {
+		if len(members) > maxRows {
+			maxRows = len(members)
+		}
+	}
+	width := svgMargin*2 + len(layers)*svgNodeWidth + max(len(layers)-1, 0)*svgGapX
+	height := svgMargin*2 + maxRows*svgNodeHeight + max(maxRows-1, 0)*svgGapY
+	if len(nodes) == 0 {
+		width, height = svgMargin*2+svgNodeWidth, svgMargin*2+svgNodeHeight
+	}
+
+	// 每层垂直居中
+	xs := make([]int, len(nodes))
+	ys := make([]int, len(nodes))
+	for l, members := range layers {
+		columnHeight := len(members)*svgNodeHeight + (len(members)-1)*svgGapY
+		top := (height - columnHeight) / 2
+		for order, i := range members {
+			xs[i] = svgMargin + l*(svgNodeWidth+svgGapX)
+			ys[i] = top + order*(svgNodeHeight+svgGapY)
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="Helvetica, Arial, sans-serif">`+"\n",
+		width, height, width, height)
+	fmt.Fprintf(&buf, "  <title>%s</title>\n", html.EscapeString(dag.Name))
+	buf.WriteString(`  <defs><marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="8" markerHeight="8" orient="auto-start-reverse"><path d="M 0 0 L 10 5 L 0 10 z" fill="#6b7280"/></marker></defs>` + "\n")
+	fmt.Fprintf(&buf, `  <rect width="%d" height="%d" fill="#ffffff"/>`+"\n", width, height)
+
+	for _, edge := range edges {
+		from, to := edge[0], edge[1]
+		x1, y1 := xs[from]+svgNodeWidth, ys[from]+svgNodeHeight/2
+		x2, y2 := xs[to], ys[to]+svgNodeHeight/2
+		mid := (x1 + x2) / 2
+		fmt.Fprintf(&buf, `  <path d="M %d %d C %d %d, %d %d, %d %d" fill="none" stroke="#6b7280" stroke-width="1.5" marker-end="url(#arrow)"/>`+"\n",
+			x1, y1, mid, y1, mid, y2, x2, y2)
+	}
+
+	for i, node := range nodes {
+		color := nodeColor(statuses, node.ID)
+		subtitle := node.Type
+		if status := statuses[node.ID]; status != "" {
+			subtitle += " · " + status
+		}
+		fmt.Fprintf(&buf, `  <g><title>%s</title>`, html.EscapeString(node.ID))
+		fmt.Fprintf(&buf, `<rect x="%d" y="%d" width="%d" height="%d" rx="8" fill="%s" stroke="%s" stroke-width="1.5"/>`,
+			xs[i], ys[i], svgNodeWidth, svgNodeHeight, color.Fill, color.Stroke)
+		fmt.Fprintf(&buf, `<text x="%d" y="%d" text-anchor="middle" font-size="14" fill="#111827">%s</text>`,
+			xs[i]+svgNodeWidth/2, ys[i]+24, html.EscapeString(truncateLabel(nodeLabelName(node))))
+		fmt.Fprintf(&buf, `<text x="%d" y="%d" text-anchor="middle" font-size="11" fill="#6b7280">%s</text></g>`+"\n",
+			xs[i]+svgNodeWidth/2, ys[i]+42, html.EscapeString(truncateLabel(subtitle)))
+	}
+
+	buf.WriteString("</svg>\n")
+	return buf.Bytes()
+}
@@ -0,0 +1,463 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"gin_pipeline/global"
+	"gin_pipeline/model"
+	"gorm.io/gorm/clause"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// FragmentNodeType 引用DAG片段的节点类型
+//
+// 节点配置：
+//
+//	fragment: build-scan-push   # 片段名称
+//	version: 2                  # 可选，固定版本；不指定时跟随最新版本
+//	inputs:                     # 片段输入，可以使用 ${{ params.name }} 引用流水线参数
+//	  image: registry.example.com/app
+//
+// 展开后片段中的节点ID为 <节点ID>.<片段内节点ID>；片段的入口节点继承引用节点的依赖，
+// 依赖引用节点的节点改为依赖片段的所有出口节点。
+const FragmentNodeType = "fragment"
+
+// maxFragmentDepth 片段嵌套的最大深度
+const maxFragmentDepth = 5
+
+// maxFragmentCreateAttempts 创建片段时版本号冲突的最大重试次数
+const maxFragmentCreateAttempts = 5
+
+// fragmentNamePattern 片段名称格式
+var fragmentNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// inputPlaceholder 片段节点配置中的输入占位符，如 ${{ inputs.image }}
+var inputPlaceholder = regexp.MustCompile(`\$\{\{\s*inputs\.([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// fragmentConfigSchema fragment节点的配置Schema
+var fragmentConfigSchema = &ConfigSchema{
+	Type:     "object",
+	Required: []string{"fragment"},
+	Properties: map[string]*ConfigSchema{
+		"fragment": {Type: "string"},
+		"version":  {Type: "integer", Minimum: floatPtr(1)},
+		"inputs":   {Type: "object"},
+	},
+}
+
+// DAGFragmentService DAG片段服务
+type DAGFragmentService struct{}
+
+// CreateFragment 创建片段的新版本，版本号为同名片段的最大版本加1
+func (s *DAGFragmentService) CreateFragment(fragment *model.DAGFragment) error {
+	if !fragmentNamePattern.MatchString(fragment.Name) {
+		return fmt.Errorf("片段名称 %q 只能包含字母、数字、下划线和连字符", fragment.Name)
+	}
+	if err := ValidatePipelineParameters(fragment.Inputs); err != nil {
+		return err
+	}
+	if err := validateFragmentPlaceholders(fragment); err != nil {
+		return err
+	}
+
+	// 片段自身作为DAG校验，引用的其他片段一并展开
+	nodes, err := expandFragments(fragment.Nodes, newFragmentResolver(), []string{fragment.Name})
+	if err != nil {
+		return err
+	}
+	diagnostics := diagnoseFragmentNodes(nodes)
+	if HasDAGErrors(diagnostics) {
+		return &DAGValidationError{Diagnostics: diagnostics}
+	}
+
+	// 并发创建同名片段时版本号可能冲突，通过唯一索引检测并重新分配版本号
+	for attempt := 0; attempt < maxFragmentCreateAttempts; attempt++ {
+		var maxVersion int
+		if err := global.DB.Model(&model.DAGFragment{}).
+			Where("name = ?", fragment.Name).
+			Select("COALESCE(MAX(version), 0)").
+			Scan(&maxVersion).Error; err != nil {
+			return err
+		}
+		fragment.Version = maxVersion + 1
+		result := global.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(fragment)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 1 {
+			return nil
+		}
+	}
+	return fmt.Errorf("片段 %s 正在被并发修改，请稍后重试", fragment.Name)
+}
+
+// GetFragments 获取所有片段的最新版本
+func (s *DAGFragmentService) GetFragments() ([]model.DAGFragment, error) {
+	var fragments []model.DAGFragment
+	latest := global.DB.Model(&model.DAGFragment{}).Select("name, MAX(version) AS version").Group("name")
+	if err := global.DB.Joins("JOIN (?) AS latest ON latest.name = dag_fragments.name AND latest.version = dag_fragments.version", latest).
+		Order("dag_fragments.name").
+		Find(&fragments).Error; err != nil {
+		return nil, err
+	}
+	return fragments, nil
+}
+
+// GetFragmentVersions 获取片段的所有版本
+func (s *DAGFragmentService) GetFragmentVersions(name string) ([]model.DAGFragment, error) {
+	var fragments []model.DAGFragment
+	if err := global.DB.Where("name = ?", name).Order("version DESC").Find(&fragments).Error; err != nil {
+		return nil, err
+	}
+	return fragments, nil
+}
+
+// GetFragment 获取片段的指定版本，version为0时获取最新版本
+func (s *DAGFragmentService) GetFragment(name string, version int) (*model.DAGFragment, error) {
+	var fragment model.DAGFragment
+	query := global.DB.Where("name = ?", name)
+	if version > 0 {
+		query = query.Where("version = ?", version)
+	} else {
+		query = query.Order("version DESC")
+	}
+	if err := query.First(&fragment).Error; err != nil {
+		return nil, err
+	}
+	return &fragment, nil
+}
+
+// diagnoseFragmentNodes 检查片段的节点，配置中仍有未替换的输入的节点只检查结构，
+// 其配置在流水线引用片段、输入确定后再随流水线DAG一起检查
+func diagnoseFragmentNodes(nodes []model.DAGNode) []DAGDiagnostic {
+	unresolved := make(map[string]bool)
+	for _, node := range nodes {
+		if valueHasPlaceholder(map[string]interface{}(node.Config)) {
+			unresolved[node.ID] = true
+		}
+	}
+
+	diagnostics := []DAGDiagnostic{}
+	for _, diag := range DiagnoseDAG(nodes) {
+		if diag.Code == DiagInvalidConfig && unresolved[diag.NodeID] {
+			continue
+		}
+		diagnostics = append(diagnostics, diag)
+	}
+	return diagnostics
+}
+
+// valueHasPlaceholder 配置中是否包含参数或输入占位符
+func valueHasPlaceholder(value interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		return hasPlaceholder(v)
+	case map[string]interface{}:
+		for _, item := range v {
+			if valueHasPlaceholder(item) {
+				return true
+			}
+		}
+	case model.JSONMap:
+		return valueHasPlaceholder(map[string]interface{}(v))
+	case []interface{}:
+		for _, item := range v {
+			if valueHasPlaceholder(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// validateFragmentPlaceholders 检查节点配置中引用的输入都已声明
+func validateFragmentPlaceholders(fragment *model.DAGFragment) error {
+	declared := make(map[string]bool, len(fragment.Inputs))
+	for _, input := range fragment.Inputs {
+		declared[input.Name] = true
+	}
+
+	var check func(value interface{}) error
+	check = func(value interface{}) error {
+		switch v := value.(type) {
+		case string:
+			for _, match := range inputPlaceholder.FindAllStringSubmatch(v, -1) {
+				if !declared[match[1]] {
+					return fmt.Errorf("引用了未声明的输入: %s", match[1])
+				}
+			}
+		case map[string]interface{}:
+			for _, item := range v {
+				if err := check(item); err != nil {
+					return err
+				}
+			}
+		case model.JSONMap:
+			return check(map[string]interface{}(v))
+		case []interface{}:
+			for _, item := range v {
+				if err := check(item); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	for _, node := range fragment.Nodes {
+		if err := check(node.Config); err != nil {
+			return fmt.Errorf("节点 %s %v", node.ID, err)
+		}
+	}
+	return nil
+}
+
+// fragmentResolver 在一次展开中缓存已加载的片段
+type fragmentResolver struct {
+	cache map[string]*model.DAGFragment
+}
+
+func newFragmentResolver() *fragmentResolver {
+	return &fragmentResolver{cache: make(map[string]*model.DAGFragment)}
+}
+
+// resolve 加载片段，version为0时为最新版本
+func (r *fragmentResolver) resolve(name string, version int) (*model.DAGFragment, error) {
+	key := fmt.Sprintf("%s@%d", name, version)
+	if fragment, ok := r.cache[key]; ok {
+		return fragment, nil
+	}
+	fragment, err := new(DAGFragmentService).GetFragment(name, version)
+	if err != nil {
+		if version > 0 {
+			return nil, fmt.Errorf("片段 %s 的版本 %d 不存在", name, version)
+		}
+		return nil, fmt.Errorf("片段 %s 不存在", name)
+	}
+	r.cache[key] = fragment
+	return fragment, nil
+}
+
+// HasFragmentNodes DAG中是否包含片段节点
+func HasFragmentNodes(nodes []model.DAGNode) bool {
+	for _, node := range nodes {
+		if node.Type == FragmentNodeType {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpandDAGFragments 将DAG中的片段节点展开为片段中的节点
+func ExpandDAGFragments(nodes []model.DAGNode) ([]model.DAGNode, error) {
+	if !HasFragmentNodes(nodes) {
+		return nodes, nil
+	}
+	return expandFragments(nodes, newFragmentResolver(), nil)
+}
+
+// expandFragmentsForDiagnosis 展开片段节点，无法展开的片段节点保留原样并返回对应的诊断
+func expandFragmentsForDiagnosis(nodes []model.DAGNode) ([]model.DAGNode, []DAGDiagnostic) {
+	if !HasFragmentNodes(nodes) {
+		return nodes, nil
+	}
+
+	resolver := newFragmentResolver()
+	var diagnostics []DAGDiagnostic
+	var failed []string
+	for _, node := range nodes {
+		if node.Type != FragmentNodeType {
+			continue
+		}
+		if _, _, err := expandFragmentNode(node, resolver, nil); err != nil {
+			diagnostics = append(diagnostics, DAGDiagnostic{
+				Severity: SeverityError,
+				NodeID:   node.ID,
+				Code:     DiagInvalidFragment,
+				Message:  fmt.Sprintf("节点 %s 的片段无法展开: %v", node.ID, err),
+			})
+			failed = append(failed, node.ID)
+		}
+	}
+
+	expanded, err := expandFragments(nodes, resolver, nil, failed...)
+	if err != nil {
+		return nodes, diagnostics
+	}
+	return expanded, diagnostics
+}
+
+// expandFragments 展开一层节点中的片段，stack为正在展开的片段名称，用于检测循环引用；skip中的节点不展开
+func expandFragments(nodes []model.DAGNode, resolver *fragmentResolver, stack []string, skip ...string) ([]model.DAGNode, error) {
+	if len(stack) > maxFragmentDepth {
+		return nil, fmt.Errorf("片段嵌套超过%d层: %s", maxFragmentDepth, strings.Join(stack, " -> "))
+	}
+
+	skipped := make(map[string]bool, len(skip))
+	for _, id := range skip {
+		skipped[id] = true
+	}
+
+	var result []model.DAGNode
+	exits := make(map[string][]string) // 片段节点ID -> 展开后的出口节点ID
+	for _, node := range nodes {
+		if node.Type != FragmentNodeType || skipped[node.ID] {
+			result = append(result, node)
+			continue
+		}
+
+		expanded, exitIDs, err := expandFragmentNode(node, resolver, stack)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, expanded...)
+		exits[node.ID] = exitIDs
+	}
+
+	// 依赖片段节点的节点改为依赖片段的出口节点
+	if len(exits) > 0 {
+		for i := range result {
+			var dependencies []string
+			for _, dep := range result[i].Dependencies {
+				if exitIDs, ok := exits[dep]; ok {
+					dependencies = append(dependencies, exitIDs...)
+				} else {
+					dependencies = append(dependencies, dep)
+				}
+			}
+			result[i].Dependencies = dependencies
+		}
+	}
+	return result, nil
+}
+
+// expandFragmentNode 展开单个片段节点，返回展开后的节点和出口节点ID
+func expandFragmentNode(node model.DAGNode, resolver *fragmentResolver, stack []string) ([]model.DAGNode, []string, error) {
+	name := configString(node.Config, "fragment", "")
+	if name == "" {
+		return nil, nil, errors.New("未指定片段名称")
+	}
+	for _, parent := range stack {
+		if parent == name {
+			return nil, nil, fmt.Errorf("片段循环引用: %s -> %s", strings.Join(stack, " -> "), name)
+		}
+	}
+
+	fragment, err := resolver.resolve(name, configInt(node.Config, "version", 0))
+	if err != nil {
+		return nil, nil, err
+	}
+	// 空片段展开后没有出口节点，依赖片段节点的节点会失去依赖而提前执行
+	if len(fragment.Nodes) == 0 {
+		return nil, nil, fmt.Errorf("片段 %s 的版本 %d 没有节点", name, fragment.Version)
+	}
+
+	supplied, _ := node.Config["inputs"].(map[string]interface{})
+	inputs, err := resolveFragmentInputs(fragment.Inputs, supplied)
+	if err != nil {
+		return nil, nil, fmt.Errorf("片段 %s: %v", name, err)
+	}
+
+	// 先展开片段内部嵌套的片段
+	inner, err := expandFragments(fragment.Nodes, resolver, append(append([]string{}, stack...), name))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	innerIDs := make(map[string]bool, len(inner))
+	dependedOn := make(map[string]bool, len(inner))
+	for _, innerNode := range inner {
+		innerIDs[innerNode.ID] = true
+	}
+	for _, innerNode := range inner {
+		for _, dep := range innerNode.Dependencies {
+			dependedOn[dep] = true
+		}
+	}
+
+	prefix := node.ID + "."
+	expanded := make([]model.DAGNode, 0, len(inner))
+	var exitIDs []string
+	for _, innerNode := range inner {
+		var dependencies []string
+		for _, dep := range innerNode.Dependencies {
+			if innerIDs[dep] {
+				dependencies = append(dependencies, prefix+dep)
+			}
+		}
+		// 入口节点继承片段节点的依赖
+		if len(dependencies) == 0 {
+			dependencies = append(dependencies, node.Dependencies...)
+		}
+
+		runsOn := innerNode.RunsOn
+		if runsOn == "" {
+			runsOn = node.RunsOn
+		}
+		nodeName := innerNode.Name
+		if nodeName == "" {
+			nodeName = innerNode.ID
+		}
+		if node.Name != "" {
+			nodeName = node.Name + " / " + nodeName
+		}
+
+		config, _ := interpolateValue(map[string]interface{}(innerNode.Config), inputPlaceholder, inputs).(map[string]interface{})
+		expanded = append(expanded, model.DAGNode{
+			ID:           prefix + innerNode.ID,
+			Name:         nodeName,
+			Type:         innerNode.Type,
+			Config:       model.JSONMap(config),
+			Dependencies: dependencies,
+			RunsOn:       runsOn,
+		})
+		if !dependedOn[innerNode.ID] {
+			exitIDs = append(exitIDs, prefix+innerNode.ID)
+		}
+	}
+	return expanded, exitIDs, nil
+}
+
+// resolveFragmentInputs 校验片段输入并补全默认值，引用流水线参数或外层片段输入的输入在之后才替换，不做类型转换
+func resolveFragmentInputs(declared []model.PipelineParameter, supplied map[string]interface{}) (model.JSONMap, error) {
+	deferred := make(map[string]interface{})
+	immediate := make(map[string]interface{}, len(supplied))
+	for name, value := range supplied {
+		if text, ok := value.(string); ok && hasPlaceholder(text) {
+			deferred[name] = value
+		} else {
+			immediate[name] = value
+		}
+	}
+
+	params := make([]model.PipelineParameter, 0, len(declared))
+	known := make(map[string]bool, len(declared))
+	for _, param := range declared {
+		known[param.Name] = true
+		if _, ok := deferred[param.Name]; !ok {
+			params = append(params, param)
+		}
+	}
+
+	var unknown []string
+	for name := range deferred {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("未声明的输入: %s", strings.Join(unknown, ", "))
+	}
+
+	resolved, err := ResolveTriggerParameters(params, immediate)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range deferred {
+		resolved[name] = value
+	}
+	return resolved, nil
+}
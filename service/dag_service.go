@@ -1,7 +1,6 @@
 package service
 
 import (
-	"errors"
 	"gin_pipeline/global"
 	"gin_pipeline/model"
 	"go.uber.org/zap"
@@ -128,67 +127,54 @@ func (s *DAGService) CreateDAGVersion(dagID uint, creatorID uint) (*model.DAG, e
 	return &newDag, nil
 }
 
-// ValidateDAG 验证DAG是否有效（无环）
-func (s *DAGService) ValidateDAG(nodes []model.DAGNode) error {
-	if len(nodes) == 0 {
-		return errors.New("DAG不能为空")
+// CreateDAGFromStages 将流水线的阶段/作业转换为新的DAG版本
+func (s *DAGService) CreateDAGFromStages(pipelineID uint, creatorID uint) (*model.DAG, error) {
+	var pipeline model.Pipeline
+	if err := global.DB.First(&pipeline, pipelineID).Error; err != nil {
+		return nil, err
 	}
 
-	// 构建节点映射
-	nodeMap := make(map[string]model.DAGNode)
-	for _, node := range nodes {
-		nodeMap[node.ID] = node
+	compiled, err := CompilePipelineStages(pipelineID)
+	if err != nil {
+		return nil, err
 	}
 
-	// 检查所有依赖是否存在
-	for _, node := range nodes {
-		for _, depID := range node.Dependencies {
-			if _, exists := nodeMap[depID]; !exists {
-				return errors.New("依赖节点不存在: " + depID)
-			}
-		}
+	if err := s.ValidateDAG(compiled.NodesData); err != nil {
+		return nil, err
 	}
 
-	// 检测环
-	visited := make(map[string]bool)
-	path := make(map[string]bool)
-
-	var dfs func(string) bool
-	dfs = func(nodeID string) bool {
-		// 如果节点已经在当前路径中，说明有环
-		if path[nodeID] {
-			return true
-		}
-
-		// 如果节点已经被访问过且没有环，则跳过
-		if visited[nodeID] {
-			return false
-		}
-
-		// 标记节点为已访问，并添加到当前路径
-		visited[nodeID] = true
-		path[nodeID] = true
-
-		// 递归检查所有依赖节点
-		node := nodeMap[nodeID]
-		for _, depID := range node.Dependencies {
-			if dfs(depID) {
-				return true
-			}
-		}
+	// 获取当前最高版本
+	var maxVersion int
+	if err := global.DB.Model(&model.DAG{}).
+		Where("pipeline_id = ?", pipelineID).
+		Select("COALESCE(MAX(version), 0)").
+		Scan(&maxVersion).Error; err != nil {
+		return nil, err
+	}
 
-		// 从当前路径中移除节点
-		path[nodeID] = false
-		return false
+	newDag := model.DAG{
+		Name:        pipeline.Name,
+		Description: "由流水线阶段生成",
+		Version:     maxVersion + 1,
+		PipelineID:  pipelineID,
+		NodesData:   compiled.NodesData,
+		CreatorID:   creatorID,
+		IsActive:    false, // 默认不激活
 	}
 
-	// 对每个节点进行DFS
-	for _, node := range nodes {
-		if dfs(node.ID) {
-			return errors.New("DAG中存在环")
-		}
+	if err := global.DB.Create(&newDag).Error; err != nil {
+		return nil, err
 	}
 
+	return &newDag, nil
+}
+
+// ValidateDAG 验证DAG是否有效，存在错误级别的问题时返回 *DAGValidationError
+func (s *DAGService) ValidateDAG(nodes []model.DAGNode) error {
+	diagnostics := DiagnoseDAG(nodes)
+	if HasDAGErrors(diagnostics) {
+		return &DAGValidationError{Diagnostics: diagnostics}
+	}
 	return nil
 }
 
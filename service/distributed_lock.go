@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"gin_pipeline/global"
+	"gin_pipeline/utils"
+	"github.com/go-redis/redis/v8"
+	"sync"
+	"time"
+)
+
+// ErrLockNotAcquired 在等待时间内未能获取锁
+var ErrLockNotAcquired = errors.New("获取锁超时")
+
+// releaseLockScript 仅当锁仍由自己持有时才删除
+var releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// renewLockScript 仅当锁仍由自己持有时才续期
+var renewLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+// localLocks 未配置Redis时使用的进程内锁，仅在单实例部署下有效
+var localLocks = struct {
+	holders map[string]string
+	mutex   sync.Mutex
+}{holders: make(map[string]string)}
+
+// DistributedLock 基于Redis的分布式锁，未配置Redis时退化为进程内锁
+type DistributedLock struct {
+	key   string
+	token string
+	ttl   time.Duration
+}
+
+// TryLock 尝试获取锁，不等待
+func TryLock(ctx context.Context, key string, ttl time.Duration) (*DistributedLock, bool, error) {
+	token, err := utils.RandomToken(16)
+	if err != nil {
+		return nil, false, err
+	}
+	lock := &DistributedLock{key: "lock:" + key, token: token, ttl: ttl}
+
+	if global.Redis == nil {
+		localLocks.mutex.Lock()
+		defer localLocks.mutex.Unlock()
+		if _, held := localLocks.holders[lock.key]; held {
+			return nil, false, nil
+		}
+		localLocks.holders[lock.key] = token
+		return lock, true, nil
+	}
+
+	ok, err := global.Redis.SetNX(ctx, lock.key, token, ttl).Result()
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	return lock, true, nil
+}
+
+// AcquireLock 获取锁，最多等待wait时间
+func AcquireLock(ctx context.Context, key string, ttl, wait time.Duration) (*DistributedLock, error) {
+	deadline := time.Now().Add(wait)
+	for {
+		lock, ok, err := TryLock(ctx, key, ttl)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return lock, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, ErrLockNotAcquired
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// Renew 续期锁，返回锁是否仍由自己持有
+func (l *DistributedLock) Renew(ctx context.Context) (bool, error) {
+	if global.Redis == nil {
+		localLocks.mutex.Lock()
+		defer localLocks.mutex.Unlock()
+		return localLocks.holders[l.key] == l.token, nil
+	}
+
+	result, err := renewLockScript.Run(ctx, global.Redis, []string{l.key}, l.token, l.ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return result == 1, nil
+}
+
+// Release 释放锁
+func (l *DistributedLock) Release(ctx context.Context) error {
+	if global.Redis == nil {
+		localLocks.mutex.Lock()
+		defer localLocks.mutex.Unlock()
+		if localLocks.holders[l.key] == l.token {
+			delete(localLocks.holders, l.key)
+		}
+		return nil
+	}
+
+	return releaseLockScript.Run(ctx, global.Redis, []string{l.key}, l.token).Err()
+}
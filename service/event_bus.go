@@ -0,0 +1,289 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"gin_pipeline/global"
+	"go.uber.org/zap"
+	"os"
+	"sync"
+	"time"
+)
+
+// Event 领域事件
+type Event interface {
+	EventName() string
+}
+
+// allEvents 订阅所有事件时使用的事件名
+const allEvents = "*"
+
+// EventBus 进程内事件总线
+// 每个订阅者有独立的队列和协程，按发布顺序处理事件，队列满时发布方最多等待publish_timeout，
+// 超时后丢弃该订阅者的事件并计入pipeline_events_dropped_total，避免慢订阅者拖住发布方；
+// 启用Redis转发后，事件同时发布到Redis频道，其他实例上选择接收远程事件的订阅者也会收到
+type EventBus struct {
+	subscribers map[string][]*eventSubscriber
+	nextID      uint64
+	instanceID  string
+	channel     string // Redis频道，为空时不转发
+	mutex       sync.RWMutex
+}
+
+// eventSubscriber 事件订阅者
+type eventSubscriber struct {
+	id      uint64
+	name    string // 订阅者名称，用于日志
+	remote  bool   // 是否接收其他实例发布的事件
+	handler func(Event)
+	queue   chan Event
+	done    chan struct{}
+}
+
+// eventEnvelope 通过Redis转发的事件
+type eventEnvelope struct {
+	Instance string          `json:"instance"`
+	Name     string          `json:"name"`
+	Payload  json.RawMessage `json:"payload"`
+}
+
+// SubscribeOption 订阅选项
+type SubscribeOption func(*eventSubscriber)
+
+// WithRemoteEvents 同时接收其他实例发布的事件，用于实时推送等需要全局视图的订阅者
+// 通知、指标等每个事件只应处理一次的订阅者不应使用
+func WithRemoteEvents() SubscribeOption {
+	return func(s *eventSubscriber) {
+		s.remote = true
+	}
+}
+
+var eventBus = &EventBus{
+	subscribers: make(map[string][]*eventSubscriber),
+	instanceID:  newInstanceID(),
+}
+
+// eventDecoders 事件名到解码函数的映射，用于还原其他实例转发的事件
+var eventDecoders = map[string]func([]byte) (Event, error){}
+
+// registerEventType 注册可以通过Redis转发的事件类型
+func registerEventType[T Event]() {
+	var zero T
+	eventDecoders[zero.EventName()] = func(data []byte) (Event, error) {
+		var event T
+		err := json.Unmarshal(data, &event)
+		return event, err
+	}
+}
+
+// GetEventBus 获取全局事件总线
+func GetEventBus() *EventBus {
+	return eventBus
+}
+
+// newInstanceID 生成本实例的标识，用于忽略自己转发的事件
+func newInstanceID() string {
+	hostname, _ := os.Hostname()
+	buf := make([]byte, 4)
+	rand.Read(buf)
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), hex.EncodeToString(buf))
+}
+
+// Subscribe 订阅指定类型的事件，返回取消订阅的函数
+func Subscribe[T Event](name string, handler func(T), opts ...SubscribeOption) func() {
+	var zero T
+	return eventBus.subscribe(zero.EventName(), name, func(event Event) {
+		if typed, ok := event.(T); ok {
+			handler(typed)
+		}
+	}, opts...)
+}
+
+// SubscribeAll 订阅所有事件，返回取消订阅的函数
+func SubscribeAll(name string, handler func(Event), opts ...SubscribeOption) func() {
+	return eventBus.subscribe(allEvents, name, handler, opts...)
+}
+
+// PublishEvent 发布事件
+func PublishEvent(event Event) {
+	eventBus.Publish(event)
+}
+
+// subscribe 添加订阅者并启动其处理协程
+func (b *EventBus) subscribe(eventName, name string, handler func(Event), opts ...SubscribeOption) func() {
+	queueSize := global.Config.EventBus.QueueSize
+	if queueSize <= 0 {
+		queueSize = 1024
+	}
+
+	b.mutex.Lock()
+	b.nextID++
+	sub := &eventSubscriber{
+		id:      b.nextID,
+		name:    name,
+		handler: handler,
+		queue:   make(chan Event, queueSize),
+		done:    make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(sub)
+	}
+	b.subscribers[eventName] = append(b.subscribers[eventName], sub)
+	b.mutex.Unlock()
+
+	go sub.run()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mutex.Lock()
+			subs := b.subscribers[eventName]
+			for i, s := range subs {
+				if s.id == sub.id {
+					b.subscribers[eventName] = append(subs[:i:i], subs[i+1:]...)
+					break
+				}
+			}
+			b.mutex.Unlock()
+			close(sub.done)
+		})
+	}
+}
+
+// run 按顺序处理队列中的事件
+func (s *eventSubscriber) run() {
+	for {
+		select {
+		case event := <-s.queue:
+			s.handle(event)
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// handle 处理单个事件，订阅者的panic不影响其他事件
+func (s *eventSubscriber) handle(event Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			global.Log.Error("事件处理失败",
+				zap.String("subscriber", s.name),
+				zap.String("event", event.EventName()),
+				zap.Any("panic", r))
+		}
+	}()
+	s.handler(event)
+}
+
+// Publish 发布事件给本实例的订阅者，启用转发时同时发布到Redis
+func (b *EventBus) Publish(event Event) {
+	b.dispatch(event, false)
+
+	b.mutex.RLock()
+	channel := b.channel
+	b.mutex.RUnlock()
+	if channel == "" || global.Redis == nil {
+		return
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		global.Log.Error("序列化事件失败", zap.String("event", event.EventName()), zap.Error(err))
+		return
+	}
+	data, _ := json.Marshal(eventEnvelope{Instance: b.instanceID, Name: event.EventName(), Payload: payload})
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := global.Redis.Publish(ctx, channel, data).Err(); err != nil {
+		global.Log.Error("转发事件失败", zap.String("event", event.EventName()), zap.Error(err))
+	}
+}
+
+// dispatch 将事件放入订阅者的队列，remote表示事件来自其他实例
+func (b *EventBus) dispatch(event Event, remote bool) {
+	b.mutex.RLock()
+	var targets []*eventSubscriber
+	for _, sub := range b.subscribers[event.EventName()] {
+		if !remote || sub.remote {
+			targets = append(targets, sub)
+		}
+	}
+	for _, sub := range b.subscribers[allEvents] {
+		if !remote || sub.remote {
+			targets = append(targets, sub)
+		}
+	}
+	b.mutex.RUnlock()
+
+	for _, sub := range targets {
+		if !sub.offer(event) {
+			eventsDroppedTotal.WithLabelValues(sub.name, event.EventName()).Inc()
+			global.Log.Warn("订阅者队列已满，丢弃事件", zap.String("subscriber", sub.name), zap.String("event", event.EventName()))
+		}
+	}
+}
+
+// offer 将事件放入订阅者队列，队列满时最多等待publish_timeout，返回false表示事件被丢弃
+func (sub *eventSubscriber) offer(event Event) bool {
+	select {
+	case sub.queue <- event:
+		return true
+	case <-sub.done:
+		return true
+	default:
+	}
+
+	timeout := time.Duration(global.Config.EventBus.PublishTimeout) * time.Millisecond
+	if timeout <= 0 {
+		timeout = 100 * time.Millisecond
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case sub.queue <- event:
+		return true
+	case <-sub.done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// EnableRedis 通过Redis频道在实例间转发事件，并接收其他实例的事件
+func (b *EventBus) EnableRedis(channel string) {
+	if global.Redis == nil {
+		return
+	}
+
+	b.mutex.Lock()
+	b.channel = channel
+	b.mutex.Unlock()
+
+	// 断线后由客户端自动重新订阅
+	pubsub := global.Redis.Subscribe(context.Background(), channel)
+	go func() {
+		for msg := range pubsub.Channel() {
+			var envelope eventEnvelope
+			if err := json.Unmarshal([]byte(msg.Payload), &envelope); err != nil {
+				global.Log.Warn("解析转发的事件失败", zap.Error(err))
+				continue
+			}
+			if envelope.Instance == b.instanceID {
+				continue
+			}
+			decode, ok := eventDecoders[envelope.Name]
+			if !ok {
+				continue
+			}
+			event, err := decode(envelope.Payload)
+			if err != nil {
+				global.Log.Warn("解析转发的事件失败", zap.String("event", envelope.Name), zap.Error(err))
+				continue
+			}
+			b.dispatch(event, true)
+		}
+	}()
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"gin_pipeline/global"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testEvent struct{ Seq int }
+
+func (e testEvent) EventName() string { return "test.event" }
+
+func TestEventBusDeliversInOrder(t *testing.T) {
+	bus := &EventBus{subscribers: make(map[string][]*eventSubscriber)}
+	received := make(chan int, 5)
+	unsubscribe := bus.subscribe("test.event", "ordered", func(event Event) {
+		seq := event.(testEvent).Seq
+		// 处理函数的panic不影响后续事件
+		if seq == 2 {
+			panic("boom")
+		}
+		received <- seq
+	})
+	defer unsubscribe()
+	other := bus.subscribe("other.event", "other", func(event Event) {
+		t.Errorf("other subscriber received %v", event)
+	})
+	defer other()
+
+	for seq := 1; seq <= 5; seq++ {
+		bus.Publish(testEvent{Seq: seq})
+	}
+	var got []int
+	for len(got) < 4 {
+		select {
+		case seq := <-received:
+			got = append(got, seq)
+		case <-time.After(time.Second):
+			t.Fatalf("received %v, want [1 3 4 5]", got)
+		}
+	}
+	if !reflect.DeepEqual(got, []int{1, 3, 4, 5}) {
+		t.Fatalf("received %v, want [1 3 4 5]", got)
+	}
+}
+
+func TestEventBusDropsWhenQueueIsFull(t *testing.T) {
+	previous := global.Config.EventBus
+	t.Cleanup(func() { global.Config.EventBus = previous })
+	global.Config.EventBus.QueueSize = 1
+	global.Config.EventBus.PublishTimeout = 20
+
+	bus := &EventBus{subscribers: make(map[string][]*eventSubscriber)}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	received := make(chan int, 3)
+	unsubscribe := bus.subscribe("test.event", "slow", func(event Event) {
+		seq := event.(testEvent).Seq
+		if seq == 1 {
+			close(started)
+			<-release
+		}
+		received <- seq
+	})
+	defer unsubscribe()
+
+	// 第一个事件阻塞在处理函数中，第二个事件占满队列，第三个事件等待超时后丢弃
+	bus.Publish(testEvent{Seq: 1})
+	<-started
+	bus.Publish(testEvent{Seq: 2})
+
+	sub := bus.subscribers["test.event"][0]
+	begin := time.Now()
+	if sub.offer(testEvent{Seq: 3}) {
+		t.Fatal("offer() to a full queue = true, want dropped")
+	}
+	if waited := time.Since(begin); waited < 20*time.Millisecond {
+		t.Fatalf("offer() returned after %v, want to wait for the publish timeout", waited)
+	}
+
+	close(release)
+	var got []int
+	for len(got) < 2 {
+		select {
+		case seq := <-received:
+			got = append(got, seq)
+		case <-time.After(time.Second):
+			t.Fatalf("received %v, want [1 2]", got)
+		}
+	}
+	if !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Fatalf("received %v, want [1 2]", got)
+	}
+}
+
+func TestEventBusOfferAfterUnsubscribe(t *testing.T) {
+	previous := global.Config.EventBus
+	t.Cleanup(func() { global.Config.EventBus = previous })
+	global.Config.EventBus.QueueSize = 1
+	global.Config.EventBus.PublishTimeout = 1000
+
+	bus := &EventBus{subscribers: make(map[string][]*eventSubscriber)}
+	block := make(chan struct{})
+	defer close(block)
+	unsubscribe := bus.subscribe("test.event", "gone", func(Event) { <-block })
+	sub := bus.subscribers["test.event"][0]
+	sub.queue <- testEvent{Seq: 1}
+	unsubscribe()
+
+	// 已退订的订阅者不再消费队列，发布方不应等待
+	begin := time.Now()
+	if !sub.offer(testEvent{Seq: 2}) {
+		t.Fatal("offer() after unsubscribe = false, want true")
+	}
+	if waited := time.Since(begin); waited > 500*time.Millisecond {
+		t.Fatalf("offer() after unsubscribe waited %v", waited)
+	}
+}
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"gin_pipeline/model"
+	"time"
+)
+
+// 事件名
+const (
+	EventNamePipelineRunCreated  = "pipeline_run.created"
+	EventNamePipelineRunStarted  = "pipeline_run.started"
+	EventNamePipelineRunFinished = "pipeline_run.finished"
+	EventNameTaskStarted         = "task.started"
+	EventNameTaskFinished        = "task.finished"
+	EventNameReleaseStarted      = "release.started"
+	EventNameReleaseDeployed     = "release.deployed"
+	EventNameArtifactCreated     = "artifact.created"
+)
+
+func init() {
+	registerEventType[PipelineRunCreated]()
+	registerEventType[PipelineRunStarted]()
+	registerEventType[PipelineRunFinished]()
+	registerEventType[TaskStarted]()
+	registerEventType[TaskFinished]()
+	registerEventType[ReleaseStarted]()
+	registerEventType[ReleaseDeployed]()
+	registerEventType[ArtifactCreated]()
+}
+
+// PipelineRunCreated 运行已创建并进入调度队列
+type PipelineRunCreated struct {
+	RunID       uint      `json:"run_id"`
+	PipelineID  uint      `json:"pipeline_id"`
+	TriggerType string    `json:"trigger_type"`
+	TriggerBy   uint      `json:"trigger_by"`
+	GitBranch   string    `json:"git_branch"`
+	GitCommit   string    `json:"git_commit"`
+	Time        time.Time `json:"time"`
+}
+
+// EventName 事件名
+func (PipelineRunCreated) EventName() string { return EventNamePipelineRunCreated }
+
+// PipelineRunStarted 运行出队并开始执行
+type PipelineRunStarted struct {
+	RunID       uint      `json:"run_id"`
+	PipelineID  uint      `json:"pipeline_id"`
+	TriggerType string    `json:"trigger_type"`
+	GitBranch   string    `json:"git_branch"`
+	Time        time.Time `json:"time"`
+}
+
+// EventName 事件名
+func (PipelineRunStarted) EventName() string { return EventNamePipelineRunStarted }
+
+// PipelineRunFinished 运行结束，包括成功、失败、取消和跳过
+type PipelineRunFinished struct {
+	RunID        uint              `json:"run_id"`
+	PipelineID   uint              `json:"pipeline_id"`
+	Status       string            `json:"status"` // success, failed, canceled, skipped
+	TriggerType  string            `json:"trigger_type"`
+	GitBranch    string            `json:"git_branch"`
+	GitCommit    string            `json:"git_commit"`
+	Duration     float64           `json:"duration"` // 执行耗时(秒)，未执行时为0
+	TaskStatuses map[string]string `json:"task_statuses,omitempty"`
+	Time         time.Time         `json:"time"`
+}
+
+// EventName 事件名
+func (PipelineRunFinished) EventName() string { return EventNamePipelineRunFinished }
+
+// newPipelineRunFinished 根据运行记录创建运行结束事件
+func newPipelineRunFinished(run *model.PipelineRun, status string, duration time.Duration) PipelineRunFinished {
+	event := PipelineRunFinished{
+		RunID:       run.ID,
+		PipelineID:  run.PipelineID,
+		Status:      status,
+		TriggerType: run.TriggerType,
+		GitBranch:   run.GitBranch,
+		GitCommit:   run.GitCommit,
+		Duration:    duration.Seconds(),
+		Time:        time.Now(),
+	}
+	if len(run.TaskStatuses) > 0 {
+		event.TaskStatuses = make(map[string]string, len(run.TaskStatuses))
+		for taskID, status := range run.TaskStatuses {
+			if s, ok := status.(string); ok {
+				event.TaskStatuses[taskID] = s
+			}
+		}
+	}
+	return event
+}
+
+// TaskStarted 任务开始执行
+type TaskStarted struct {
+	RunID    uint      `json:"run_id"`
+	TaskID   string    `json:"task_id"`
+	TaskName string    `json:"task_name"`
+	TaskType string    `json:"task_type"`
+	Time     time.Time `json:"time"`
+}
+
+// EventName 事件名
+func (TaskStarted) EventName() string { return EventNameTaskStarted }
+
+// TaskFinished 任务执行结束
+type TaskFinished struct {
+	RunID    uint      `json:"run_id"`
+	TaskID   string    `json:"task_id"`
+	TaskName string    `json:"task_name"`
+	TaskType string    `json:"task_type"`
+	Status   string    `json:"status"` // success, failed
+	Error    string    `json:"error,omitempty"`
+	Duration float64   `json:"duration"` // 执行耗时(秒)
+	Time     time.Time `json:"time"`
+}
+
+// EventName 事件名
+func (TaskFinished) EventName() string { return EventNameTaskFinished }
+
+// ReleaseStarted 发布开始部署
+type ReleaseStarted struct {
+	ReleaseID   uint      `json:"release_id"`
+	PipelineID  uint      `json:"pipeline_id"`
+	Environment string    `json:"environment"`
+	Version     string    `json:"version"`
+	Time        time.Time `json:"time"`
+}
+
+// EventName 事件名
+func (ReleaseStarted) EventName() string { return EventNameReleaseStarted }
+
+// ReleaseDeployed 发布部署结束
+type ReleaseDeployed struct {
+	ReleaseID   uint      `json:"release_id"`
+	PipelineID  uint      `json:"pipeline_id"`
+	ArtifactID  uint      `json:"artifact_id"`
+	Environment string    `json:"environment"`
+	Version     string    `json:"version"`
+	Description string    `json:"description"`
+	Status      string    `json:"status"` // success, failed
+	IsRollback  bool      `json:"is_rollback"`
+	DeployedBy  uint      `json:"deployed_by"`
+	Time        time.Time `json:"time"`
+}
+
+// EventName 事件名
+func (ReleaseDeployed) EventName() string { return EventNameReleaseDeployed }
+
+// NewReleaseStarted 根据发布记录创建发布开始事件，需要预加载制品
+func NewReleaseStarted(release *model.Release) ReleaseStarted {
+	return ReleaseStarted{
+		ReleaseID:   release.ID,
+		PipelineID:  release.Artifact.PipelineID,
+		Environment: release.Environment,
+		Version:     release.Version,
+		Time:        time.Now(),
+	}
+}
+
+// NewReleaseDeployed 根据发布记录创建发布结束事件，需要预加载制品
+func NewReleaseDeployed(release *model.Release) ReleaseDeployed {
+	return ReleaseDeployed{
+		ReleaseID:   release.ID,
+		PipelineID:  release.Artifact.PipelineID,
+		ArtifactID:  release.ArtifactID,
+		Environment: release.Environment,
+		Version:     release.Version,
+		Description: release.Description,
+		Status:      release.Status,
+		IsRollback:  release.IsRollback,
+		DeployedBy:  release.DeployedBy,
+		Time:        time.Now(),
+	}
+}
+
+// ArtifactCreated 制品已上传
+type ArtifactCreated struct {
+	ArtifactID uint      `json:"artifact_id"`
+	PipelineID uint      `json:"pipeline_id"`
+	RunID      uint      `json:"run_id"`
+	Name       string    `json:"name"`
+	Type       string    `json:"type"`
+	Version    string    `json:"version"`
+	Size       int64     `json:"size"`
+	CreatedBy  uint      `json:"created_by"`
+	Time       time.Time `json:"time"`
+}
+
+// EventName 事件名
+func (ArtifactCreated) EventName() string { return EventNameArtifactCreated }
+
+// NewArtifactCreated 根据制品记录创建制品上传事件
+func NewArtifactCreated(artifact *model.Artifact) ArtifactCreated {
+	return ArtifactCreated{
+		ArtifactID: artifact.ID,
+		PipelineID: artifact.PipelineID,
+		RunID:      artifact.PipelineRunID,
+		Name:       artifact.Name,
+		Type:       artifact.Type,
+		Version:    artifact.Version,
+		Size:       artifact.Size,
+		CreatedBy:  artifact.CreatedBy,
+		Time:       time.Now(),
+	}
+}
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// LabelSelector 执行器标签表达式，如 `linux && docker && !gpu`
+// 支持 &&、||、! 和括号，优先级为 ! > && > ||
+type LabelSelector struct {
+	expr string
+	root labelExpr
+}
+
+// labelExpr 标签表达式节点
+type labelExpr interface {
+	match(labels map[string]bool) bool
+}
+
+type labelTerm string
+
+type labelNot struct{ expr labelExpr }
+
+type labelAnd struct{ left, right labelExpr }
+
+type labelOr struct{ left, right labelExpr }
+
+func (t labelTerm) match(labels map[string]bool) bool { return labels[string(t)] }
+
+func (n labelNot) match(labels map[string]bool) bool { return !n.expr.match(labels) }
+
+func (a labelAnd) match(labels map[string]bool) bool {
+	return a.left.match(labels) && a.right.match(labels)
+}
+
+func (o labelOr) match(labels map[string]bool) bool {
+	return o.left.match(labels) || o.right.match(labels)
+}
+
+// ParseLabelSelector 解析标签表达式，空表达式匹配所有执行器
+func ParseLabelSelector(expr string) (*LabelSelector, error) {
+	p := &labelParser{tokens: tokenizeLabelExpr(expr)}
+	if len(p.tokens) == 0 {
+		return &LabelSelector{}, nil
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("标签表达式 %q 在 %q 处有多余内容", expr, p.tokens[p.pos])
+	}
+	return &LabelSelector{expr: strings.TrimSpace(expr), root: root}, nil
+}
+
+// String 返回原始表达式
+func (s *LabelSelector) String() string {
+	return s.expr
+}
+
+// Matches 判断标签集合是否满足表达式
+func (s *LabelSelector) Matches(labels map[string]bool) bool {
+	if s == nil || s.root == nil {
+		return true
+	}
+	return s.root.match(labels)
+}
+
+// RequiredLabels 返回表达式中以肯定形式出现的标签，用于统计各标签的排队数
+func (s *LabelSelector) RequiredLabels() []string {
+	if s == nil || s.root == nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var result []string
+	var walk func(labelExpr, bool)
+	walk = func(e labelExpr, negated bool) {
+		switch v := e.(type) {
+		case labelTerm:
+			if !negated && !seen[string(v)] {
+				seen[string(v)] = true
+				result = append(result, string(v))
+			}
+		case labelNot:
+			walk(v.expr, !negated)
+		case labelAnd:
+			walk(v.left, negated)
+			walk(v.right, negated)
+		case labelOr:
+			walk(v.left, negated)
+			walk(v.right, negated)
+		}
+	}
+	walk(s.root, false)
+	return result
+}
+
+// ParseRunnerLabels 将逗号分隔的执行器标签转换为集合
+func ParseRunnerLabels(labels string) map[string]bool {
+	result := make(map[string]bool)
+	for _, label := range strings.Split(labels, ",") {
+		if label = strings.TrimSpace(label); label != "" {
+			result[label] = true
+		}
+	}
+	return result
+}
+
+// tokenizeLabelExpr 将表达式拆分为标记
+func tokenizeLabelExpr(expr string) []string {
+	var tokens []string
+	runes := []rune(expr)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')' || r == '!':
+			tokens = append(tokens, string(r))
+			i++
+		case (r == '&' || r == '|') && i+1 < len(runes) && runes[i+1] == r:
+			tokens = append(tokens, string([]rune{r, r}))
+			i += 2
+		default:
+			start := i
+			for i < len(runes) && isLabelRune(runes[i]) {
+				i++
+			}
+			if i == start {
+				// 非法字符单独作为标记，由解析器报错
+				tokens = append(tokens, string(r))
+				i++
+				continue
+			}
+			tokens = append(tokens, string(runes[start:i]))
+		}
+	}
+	return tokens
+}
+
+// isLabelRune 标签允许的字符
+func isLabelRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("-_.:/=", r)
+}
+
+// labelParser 递归下降解析器
+type labelParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *labelParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *labelParser) parseOr() (labelExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "||" {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = labelOr{left, right}
+	}
+	return left, nil
+}
+
+func (p *labelParser) parseAnd() (labelExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "&&" {
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = labelAnd{left, right}
+	}
+	return left, nil
+}
+
+func (p *labelParser) parseUnary() (labelExpr, error) {
+	token := p.peek()
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("标签表达式不完整")
+	case token == "!":
+		p.pos++
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return labelNot{expr}, nil
+	case token == "(":
+		p.pos++
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("标签表达式缺少右括号")
+		}
+		p.pos++
+		return expr, nil
+	case isLabelRune([]rune(token)[0]):
+		p.pos++
+		return labelTerm(token), nil
+	}
+	return nil, fmt.Errorf("标签表达式中存在非法标记 %q", token)
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLabelSelector(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr bool
+	}{
+		{expr: ""},
+		{expr: "   "},
+		{expr: "linux"},
+		{expr: "linux && docker && !gpu"},
+		{expr: "(linux || darwin) && arch=arm64"},
+		{expr: "!!linux"},
+		{expr: "region/cn-north-1 && zone:a"},
+		{expr: "linux &&", wantErr: true},
+		{expr: "&& linux", wantErr: true},
+		{expr: "(linux", wantErr: true},
+		{expr: "linux)", wantErr: true},
+		{expr: "linux docker", wantErr: true},
+		{expr: "linux & docker", wantErr: true},
+		{expr: "linux || !", wantErr: true},
+		{expr: "linux @ docker", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := ParseLabelSelector(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLabelSelector(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLabelSelectorMatches(t *testing.T) {
+	tests := []struct {
+		expr   string
+		labels string
+		want   bool
+	}{
+		{expr: "", labels: "", want: true},
+		{expr: "", labels: "linux", want: true},
+		{expr: "linux", labels: "linux,docker", want: true},
+		{expr: "linux", labels: "darwin", want: false},
+		{expr: "linux && docker", labels: "linux", want: false},
+		{expr: "linux && !gpu", labels: "linux,docker", want: true},
+		{expr: "linux && !gpu", labels: "linux,gpu", want: false},
+		{expr: "linux || darwin", labels: "darwin", want: true},
+		// && 的优先级高于 ||
+		{expr: "windows || linux && gpu", labels: "windows", want: true},
+		{expr: "(windows || linux) && gpu", labels: "windows", want: false},
+		{expr: "!(linux && gpu)", labels: "linux", want: true},
+		{expr: "arch=arm64", labels: " linux , arch=arm64 ", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr+"|"+tt.labels, func(t *testing.T) {
+			selector, err := ParseLabelSelector(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseLabelSelector(%q) error = %v", tt.expr, err)
+			}
+			if got := selector.Matches(ParseRunnerLabels(tt.labels)); got != tt.want {
+				t.Fatalf("%q.Matches(%q) = %v, want %v", tt.expr, tt.labels, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLabelSelectorRequiredLabels(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{expr: "", want: nil},
+		{expr: "linux && docker && !gpu", want: []string{"linux", "docker"}},
+		{expr: "!(linux && !docker)", want: []string{"docker"}},
+		{expr: "linux || linux && docker", want: []string{"linux", "docker"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			selector, err := ParseLabelSelector(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseLabelSelector(%q) error = %v", tt.expr, err)
+			}
+			if got := selector.RequiredLabels(); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("RequiredLabels(%q) = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
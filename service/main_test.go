@@ -0,0 +1,14 @@
+package service
+
+import (
+	"gin_pipeline/global"
+	"os"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	global.Log = zap.NewNop().Sugar()
+	os.Exit(m.Run())
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"errors"
+	"gin_pipeline/global"
+	"gin_pipeline/model"
+	"gorm.io/gorm"
+)
+
+// MemberService 流水线和环境成员服务
+type MemberService struct{}
+
+// checkMemberUser 检查要加入的用户是否存在
+func (s *MemberService) checkMemberUser(userID uint) error {
+	var user model.User
+	if err := global.DB.Select("id").First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("用户不存在")
+		}
+		return err
+	}
+	return nil
+}
+
+// GetPipelineMembers 获取流水线成员
+func (s *MemberService) GetPipelineMembers(pipelineID uint) ([]model.PipelineMember, error) {
+	var members []model.PipelineMember
+	err := global.DB.Preload("User").Where("pipeline_id = ?", pipelineID).Order("id ASC").Find(&members).Error
+	return members, err
+}
+
+// AddPipelineMember 添加流水线成员
+func (s *MemberService) AddPipelineMember(member *model.PipelineMember) error {
+	if !IsValidMemberRole(member.Role) {
+		return errors.New("无效的成员角色")
+	}
+	if err := s.checkMemberUser(member.UserID); err != nil {
+		return err
+	}
+	var count int64
+	if err := global.DB.Model(&model.PipelineMember{}).
+		Where("pipeline_id = ? AND user_id = ?", member.PipelineID, member.UserID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("用户已是流水线成员")
+	}
+	if err := global.DB.Create(member).Error; err != nil {
+		return err
+	}
+	return global.DB.Preload("User").First(member, member.ID).Error
+}
+
+// getPipelineMember 获取流水线的成员，成员不属于该流水线时视为不存在
+func (s *MemberService) getPipelineMember(pipelineID, memberID uint) (*model.PipelineMember, error) {
+	var member model.PipelineMember
+	if err := global.DB.Where("pipeline_id = ? AND id = ?", pipelineID, memberID).First(&member).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("成员不存在")
+		}
+		return nil, err
+	}
+	return &member, nil
+}
+
+// UpdatePipelineMember 修改流水线成员的角色
+func (s *MemberService) UpdatePipelineMember(pipelineID, memberID uint, role string) (*model.PipelineMember, error) {
+	if !IsValidMemberRole(role) {
+		return nil, errors.New("无效的成员角色")
+	}
+	member, err := s.getPipelineMember(pipelineID, memberID)
+	if err != nil {
+		return nil, err
+	}
+	if err := global.DB.Model(member).Update("role", role).Error; err != nil {
+		return nil, err
+	}
+	if err := global.DB.Preload("User").First(member, member.ID).Error; err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// RemovePipelineMember 移除流水线成员
+func (s *MemberService) RemovePipelineMember(pipelineID, memberID uint) error {
+	member, err := s.getPipelineMember(pipelineID, memberID)
+	if err != nil {
+		return err
+	}
+	return global.DB.Delete(member).Error
+}
+
+// GetEnvironmentMembers 获取环境成员
+func (s *MemberService) GetEnvironmentMembers(environmentID uint) ([]model.EnvironmentMember, error) {
+	var members []model.EnvironmentMember
+	err := global.DB.Preload("User").Where("environment_id = ?", environmentID).Order("id ASC").Find(&members).Error
+	return members, err
+}
+
+// AddEnvironmentMember 添加环境成员
+func (s *MemberService) AddEnvironmentMember(member *model.EnvironmentMember) error {
+	if !IsValidMemberRole(member.Role) {
+		return errors.New("无效的成员角色")
+	}
+	if err := s.checkMemberUser(member.UserID); err != nil {
+		return err
+	}
+	var count int64
+	if err := global.DB.Model(&model.EnvironmentMember{}).
+		Where("environment_id = ? AND user_id = ?", member.EnvironmentID, member.UserID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("用户已是环境成员")
+	}
+	if err := global.DB.Create(member).Error; err != nil {
+		return err
+	}
+	return global.DB.Preload("User").First(member, member.ID).Error
+}
+
+// getEnvironmentMember 获取环境的成员，成员不属于该环境时视为不存在
+func (s *MemberService) getEnvironmentMember(environmentID, memberID uint) (*model.EnvironmentMember, error) {
+	var member model.EnvironmentMember
+	if err := global.DB.Where("environment_id = ? AND id = ?", environmentID, memberID).First(&member).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("成员不存在")
+		}
+		return nil, err
+	}
+	return &member, nil
+}
+
+// UpdateEnvironmentMember 修改环境成员的角色和生产环境部署权限
+func (s *MemberService) UpdateEnvironmentMember(environmentID, memberID uint, role string, canDeployProduction bool) (*model.EnvironmentMember, error) {
+	if !IsValidMemberRole(role) {
+		return nil, errors.New("无效的成员角色")
+	}
+	member, err := s.getEnvironmentMember(environmentID, memberID)
+	if err != nil {
+		return nil, err
+	}
+	if err := global.DB.Model(member).Updates(map[string]interface{}{
+		"role":                  role,
+		"can_deploy_production": canDeployProduction,
+	}).Error; err != nil {
+		return nil, err
+	}
+	if err := global.DB.Preload("User").First(member, member.ID).Error; err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// RemoveEnvironmentMember 移除环境成员
+func (s *MemberService) RemoveEnvironmentMember(environmentID, memberID uint) error {
+	member, err := s.getEnvironmentMember(environmentID, memberID)
+	if err != nil {
+		return err
+	}
+	return global.DB.Delete(member).Error
+}
+
+// UpdateUserRole 修改用户的全局角色
+func (s *MemberService) UpdateUserRole(userID uint, role string) (*model.User, error) {
+	if !IsValidRole(role) {
+		return nil, errors.New("无效的角色")
+	}
+	var user model.User
+	if err := global.DB.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	if err := global.DB.Model(&user).Update("role", role).Error; err != nil {
+		return nil, err
+	}
+	user.Role = role
+	return &user, nil
+}
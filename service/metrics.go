@@ -0,0 +1,138 @@
+package service
+
+import (
+	"gin_pipeline/global"
+	"gin_pipeline/model"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 工作流指标
+var (
+	runsStartedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "pipeline",
+		Name:      "runs_started_total",
+		Help:      "开始执行的流水线运行数，metrics.pipeline_label关闭时pipeline_id为空",
+	}, []string{"pipeline_id", "trigger_type"})
+
+	runsFinishedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "pipeline",
+		Name:      "runs_finished_total",
+		Help:      "结束的流水线运行数，按最终状态区分，metrics.pipeline_label关闭时pipeline_id为空",
+	}, []string{"pipeline_id", "status"})
+
+	runDurationSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "pipeline",
+		Name:      "run_duration_seconds",
+		Help:      "流水线运行从出队到结束的耗时",
+		Buckets:   []float64{10, 30, 60, 120, 300, 600, 1200, 1800, 3600, 7200},
+	}, []string{"status"})
+
+	taskDurationSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "pipeline",
+		Name:      "task_duration_seconds",
+		Help:      "任务执行耗时，按任务类型和结果区分",
+		Buckets:   []float64{1, 5, 10, 30, 60, 120, 300, 600, 1800, 3600},
+	}, []string{"type", "status"})
+
+	eventsDroppedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "pipeline",
+		Name:      "events_dropped_total",
+		Help:      "订阅者队列已满而被丢弃的事件数",
+	}, []string{"subscriber", "event"})
+
+	tasksRunning = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "pipeline",
+		Name:      "tasks_running",
+		Help:      "正在执行的任务数",
+	}, []string{"type"})
+
+	executorErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "pipeline",
+		Name:      "executor_errors_total",
+		Help:      "任务执行器返回的错误数，不包括取消",
+	}, []string{"type"})
+
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: "pipeline",
+		Name:      "run_queue_depth",
+		Help:      "本实例调度队列中等待执行的运行数，每个实例只统计自己的队列，全局数量需要对所有实例求和",
+	}, func() float64 {
+		queued, _ := GetRunScheduler().Stats()
+		return float64(queued)
+	})
+
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: "pipeline",
+		Name:      "runs_running",
+		Help:      "本实例正在执行的运行数，每个实例只统计自己执行的运行，全局数量需要对所有实例求和",
+	}, func() float64 {
+		_, running := GetRunScheduler().Stats()
+		return float64(running)
+	})
+)
+
+// RegisterMetricsSubscribers 订阅运行事件以记录运行指标
+func RegisterMetricsSubscribers() {
+	Subscribe("metrics", func(event PipelineRunStarted) {
+		runsStartedTotal.WithLabelValues(pipelineLabel(event.PipelineID), event.TriggerType).Inc()
+	})
+	// 未执行的运行(排队中取消、被跳过)不记录耗时
+	Subscribe("metrics", func(event PipelineRunFinished) {
+		runsFinishedTotal.WithLabelValues(pipelineLabel(event.PipelineID), event.Status).Inc()
+		if event.Duration > 0 {
+			runDurationSeconds.WithLabelValues(event.Status).Observe(event.Duration)
+		}
+	})
+}
+
+// pipelineLabel 运行计数的pipeline_id标签值，未开启metrics.pipeline_label时为空，避免标签基数随流水线数量增长
+func pipelineLabel(pipelineID uint) string {
+	if !global.Config.Metrics.PipelineLabel {
+		return ""
+	}
+	return strconv.FormatUint(uint64(pipelineID), 10)
+}
+
+// artifactStorageCollector 统计制品占用的存储空间，结果缓存一段时间以免每次抓取都查询数据库
+type artifactStorageCollector struct {
+	desc      *prometheus.Desc
+	ttl       time.Duration
+	mutex     sync.Mutex
+	bytes     float64
+	updatedAt time.Time
+}
+
+// NewArtifactStorageCollector 创建制品存储指标收集器
+func NewArtifactStorageCollector(ttl time.Duration) prometheus.Collector {
+	return &artifactStorageCollector{
+		desc: prometheus.NewDesc("pipeline_artifact_storage_bytes", "制品占用的存储空间(字节)", nil, nil),
+		ttl:  ttl,
+	}
+}
+
+// Describe 实现prometheus.Collector接口
+func (c *artifactStorageCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect 实现prometheus.Collector接口
+func (c *artifactStorageCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if time.Since(c.updatedAt) >= c.ttl {
+		var bytes int64
+		if err := global.DB.Model(&model.Artifact{}).Select("COALESCE(SUM(size), 0)").Scan(&bytes).Error; err != nil {
+			global.Log.Error("统计制品存储空间失败", zap.Error(err))
+		} else {
+			c.bytes = float64(bytes)
+			c.updatedAt = time.Now()
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, c.bytes)
+}
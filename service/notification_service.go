@@ -0,0 +1,862 @@
+package service
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gin_pipeline/global"
+	"gin_pipeline/model"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"io"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"text/template"
+	"time"
+)
+
+// 通知事件
+// 系统目前没有发布审批流程，暂不提供"等待审批"事件，引入审批后再增加
+const (
+	EventRunFailed        = "run.failed"
+	EventRunFixed         = "run.fixed"
+	EventReleaseSucceeded = "release.succeeded"
+	EventReleaseFailed    = "release.failed"
+	EventTest             = "test" // 测试通知规则时发送
+)
+
+// NotificationEvents 可以订阅的事件
+var NotificationEvents = []string{EventRunFailed, EventRunFixed, EventReleaseSucceeded, EventReleaseFailed}
+
+// defaultSubscriptionEvents 订阅未指定事件时接收的事件
+var defaultSubscriptionEvents = model.StringList{EventRunFailed, EventRunFixed}
+
+// 通知渠道
+const (
+	ChannelWebhook = "webhook"
+	ChannelEmail   = "email"
+	ChannelChat    = "chat"
+)
+
+// 聊天消息格式
+const (
+	ChatFormatSlack    = "slack"
+	ChatFormatDingTalk = "dingtalk"
+	ChatFormatFeishu   = "feishu"
+)
+
+// 投递状态
+const (
+	DeliveryPending = "pending"
+	DeliverySuccess = "success"
+	DeliveryFailed  = "failed"
+)
+
+// errDeliveryAborted 不再重试的投递错误
+var errDeliveryAborted = errors.New("投递已终止")
+
+// NotificationEvent 通知事件，也是webhook模板的数据
+type NotificationEvent struct {
+	Event        string    `json:"event"`
+	Title        string    `json:"title"`
+	Message      string    `json:"message"`
+	PipelineID   uint      `json:"pipeline_id"`
+	PipelineName string    `json:"pipeline_name"`
+	RunID        uint      `json:"run_id,omitempty"`
+	Status       string    `json:"status,omitempty"`
+	GitBranch    string    `json:"git_branch,omitempty"`
+	GitCommit    string    `json:"git_commit,omitempty"`
+	TriggerType  string    `json:"trigger_type,omitempty"`
+	FailedTasks  []string  `json:"failed_tasks,omitempty"`
+	Duration     int       `json:"duration,omitempty"` // 运行时长(秒)
+	Environment  string    `json:"environment,omitempty"`
+	ReleaseID    uint      `json:"release_id,omitempty"`
+	Version      string    `json:"version,omitempty"`
+	Time         time.Time `json:"time"`
+}
+
+// text 聊天和邮件使用的纯文本内容
+func (e *NotificationEvent) text() string {
+	var b strings.Builder
+	b.WriteString(e.Title)
+	if e.Message != "" {
+		b.WriteString("\n")
+		b.WriteString(e.Message)
+	}
+	return b.String()
+}
+
+// NotificationService 通知服务
+type NotificationService struct{}
+
+// templateFuncs webhook模板可用的函数，json 将值编码为JSON，用于在模板中安全地嵌入字符串
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// ValidateRule 校验通知规则，并补全聊天消息格式
+func (s *NotificationService) ValidateRule(rule *model.NotificationRule) error {
+	if len(rule.Events) == 0 {
+		return errors.New("至少需要一个事件")
+	}
+	for _, event := range rule.Events {
+		if !isNotificationEvent(event) {
+			return fmt.Errorf("未知的事件: %s", event)
+		}
+	}
+
+	switch rule.Channel {
+	case ChannelWebhook, ChannelChat:
+		if err := validateNotificationURL(rule.URL); err != nil {
+			return err
+		}
+	case ChannelEmail:
+		if len(rule.Recipients) == 0 {
+			return errors.New("邮件渠道至少需要一个收件人")
+		}
+		for _, recipient := range rule.Recipients {
+			if _, err := mail.ParseAddress(recipient); err != nil {
+				return fmt.Errorf("无效的收件人: %s", recipient)
+			}
+		}
+	default:
+		return fmt.Errorf("不支持的通知渠道: %s", rule.Channel)
+	}
+
+	if rule.Channel == ChannelChat {
+		if rule.ChatFormat == "" {
+			rule.ChatFormat = ChatFormatSlack
+		}
+		if rule.ChatFormat != ChatFormatSlack && rule.ChatFormat != ChatFormatDingTalk && rule.ChatFormat != ChatFormatFeishu {
+			return fmt.Errorf("不支持的消息格式: %s", rule.ChatFormat)
+		}
+	}
+
+	// 用示例事件渲染模板，确保能生成合法的JSON
+	if rule.Channel == ChannelWebhook && rule.Template != "" {
+		if _, err := renderWebhookPayload(rule.Template, sampleNotificationEvent()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isNotificationEvent 是否是可以订阅的事件
+func isNotificationEvent(event string) bool {
+	for _, e := range NotificationEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// validateNotificationURL 校验webhook地址，地址是IP时同时检查是否为内网地址；
+// 域名在发送时按解析出的地址检查，避免解析结果变化后绕过校验
+func validateNotificationURL(rawURL string) error {
+	if rawURL == "" {
+		return errors.New("地址不能为空")
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("无效的地址: %s", rawURL)
+	}
+	if global.Config.Notification.AllowPrivateNetwork {
+		return nil
+	}
+	host := parsed.Hostname()
+	if ip := net.ParseIP(host); (ip != nil && privateNetworkIP(ip)) || strings.EqualFold(host, "localhost") {
+		return fmt.Errorf("不允许发送到内网地址: %s", host)
+	}
+	return nil
+}
+
+// errPrivateNetwork 通知地址解析到了内网地址
+var errPrivateNetwork = errors.New("不允许发送到内网地址")
+
+// privateNetworkIP 是否为回环、链路本地、内网或未指定地址
+func privateNetworkIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified()
+}
+
+// notificationTransport 发送通知的连接在建立时检查解析出的地址，重定向和DNS重绑定也无法访问内网服务；
+// 不使用环境变量中的代理，否则检查的是代理的地址
+var notificationTransport = &http.Transport{
+	DialContext: (&net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			if global.Config.Notification.AllowPrivateNetwork {
+				return nil
+			}
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || privateNetworkIP(ip) {
+				return fmt.Errorf("%w: %s", errPrivateNetwork, host)
+			}
+			return nil
+		},
+	}).DialContext,
+	TLSHandshakeTimeout: 10 * time.Second,
+	MaxIdleConns:        20,
+	IdleConnTimeout:     90 * time.Second,
+}
+
+// sampleNotificationEvent 用于校验模板和测试规则的示例事件
+func sampleNotificationEvent() *NotificationEvent {
+	return &NotificationEvent{
+		Event:        EventTest,
+		Title:        "测试通知",
+		Message:      "这是一条测试通知",
+		PipelineID:   1,
+		PipelineName: "example",
+		RunID:        1,
+		Status:       "failed",
+		GitBranch:    "main",
+		FailedTasks:  []string{"build"},
+		Environment:  "production",
+		Time:         time.Now(),
+	}
+}
+
+// CreateRule 创建通知规则
+func (s *NotificationService) CreateRule(rule *model.NotificationRule) error {
+	if err := s.ValidateRule(rule); err != nil {
+		return err
+	}
+	if rule.PipelineID != 0 {
+		if err := global.DB.First(&model.Pipeline{}, rule.PipelineID).Error; err != nil {
+			return fmt.Errorf("流水线不存在: %w", err)
+		}
+	}
+	return global.DB.Create(rule).Error
+}
+
+// GetRules 获取通知规则，pipelineID不为0时只返回作用于该流水线的规则
+func (s *NotificationService) GetRules(pipelineID uint) ([]model.NotificationRule, error) {
+	db := global.DB.Order("id ASC")
+	if pipelineID != 0 {
+		db = db.Where("pipeline_id = 0 OR pipeline_id = ?", pipelineID)
+	}
+	var rules []model.NotificationRule
+	if err := db.Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// GetRule 获取通知规则
+func (s *NotificationService) GetRule(id uint) (*model.NotificationRule, error) {
+	var rule model.NotificationRule
+	if err := global.DB.First(&rule, id).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// UpdateRule 校验并保存修改后的通知规则
+func (s *NotificationService) UpdateRule(rule *model.NotificationRule) error {
+	if err := s.ValidateRule(rule); err != nil {
+		return err
+	}
+	return global.DB.Save(rule).Error
+}
+
+// DeleteRule 删除通知规则
+func (s *NotificationService) DeleteRule(id uint) error {
+	result := global.DB.Delete(&model.NotificationRule{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("通知规则不存在")
+	}
+	return nil
+}
+
+// TestRule 使用示例事件立即发送一次，返回投递记录
+func (s *NotificationService) TestRule(id uint) (*model.NotificationDelivery, error) {
+	rule, err := s.GetRule(id)
+	if err != nil {
+		return nil, err
+	}
+
+	event := sampleNotificationEvent()
+	if rule.PipelineID != 0 {
+		event.PipelineID = rule.PipelineID
+	}
+	delivery, err := s.buildRuleDelivery(rule, event)
+	if err != nil {
+		return nil, err
+	}
+	if err := global.DB.Create(delivery).Error; err != nil {
+		return nil, err
+	}
+
+	s.attempt(delivery)
+	if err := global.DB.First(delivery, delivery.ID).Error; err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// Subscribe 订阅流水线，已订阅时更新订阅的事件
+func (s *NotificationService) Subscribe(userID, pipelineID uint, events []string) (*model.NotificationSubscription, error) {
+	for _, event := range events {
+		if !isNotificationEvent(event) {
+			return nil, fmt.Errorf("未知的事件: %s", event)
+		}
+	}
+	if err := global.DB.First(&model.Pipeline{}, pipelineID).Error; err != nil {
+		return nil, fmt.Errorf("流水线不存在: %w", err)
+	}
+
+	var subscription model.NotificationSubscription
+	err := global.DB.Where("user_id = ? AND pipeline_id = ?", userID, pipelineID).First(&subscription).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	subscription.UserID = userID
+	subscription.PipelineID = pipelineID
+	subscription.Events = events
+	if err := global.DB.Save(&subscription).Error; err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// Unsubscribe 取消订阅流水线
+func (s *NotificationService) Unsubscribe(userID, pipelineID uint) error {
+	result := global.DB.Where("user_id = ? AND pipeline_id = ?", userID, pipelineID).
+		Delete(&model.NotificationSubscription{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("未订阅该流水线")
+	}
+	return nil
+}
+
+// GetSubscriptions 获取用户的订阅
+func (s *NotificationService) GetSubscriptions(userID uint) ([]model.NotificationSubscription, error) {
+	var subscriptions []model.NotificationSubscription
+	if err := global.DB.Where("user_id = ?", userID).Order("id ASC").Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// DeliveryFilter 投递记录的筛选条件
+type DeliveryFilter struct {
+	RuleID     uint
+	PipelineID uint
+	Status     string
+}
+
+// GetDeliveries 分页获取投递记录，按时间倒序
+func (s *NotificationService) GetDeliveries(filter DeliveryFilter, page, pageSize int) ([]model.NotificationDelivery, int64, error) {
+	db := global.DB.Model(&model.NotificationDelivery{})
+	if filter.RuleID != 0 {
+		db = db.Where("rule_id = ?", filter.RuleID)
+	}
+	if filter.PipelineID != 0 {
+		db = db.Where("pipeline_id = ?", filter.PipelineID)
+	}
+	if filter.Status != "" {
+		db = db.Where("status = ?", filter.Status)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var deliveries []model.NotificationDelivery
+	if err := db.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&deliveries).Error; err != nil {
+		return nil, 0, err
+	}
+	return deliveries, total, nil
+}
+
+// RetryDelivery 重新发送失败的投递，重置尝试次数
+func (s *NotificationService) RetryDelivery(id uint) (*model.NotificationDelivery, error) {
+	var delivery model.NotificationDelivery
+	if err := global.DB.First(&delivery, id).Error; err != nil {
+		return nil, err
+	}
+	if delivery.Status != DeliveryFailed {
+		return nil, errors.New("只能重试失败的投递")
+	}
+
+	// 截断到毫秒，与数据库的精度一致，保证随后的认领条件成立
+	now := time.Now().Truncate(time.Millisecond)
+	if err := global.DB.Model(&delivery).Updates(map[string]interface{}{
+		"status":          DeliveryPending,
+		"attempts":        0,
+		"next_attempt_at": now,
+	}).Error; err != nil {
+		return nil, err
+	}
+	delivery.Attempts = 0
+
+	s.attempt(&delivery)
+	if err := global.DB.First(&delivery, id).Error; err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// Notify 根据通知规则和用户订阅创建投递记录并异步发送
+func (s *NotificationService) Notify(event *NotificationEvent) {
+	if !global.Config.Notification.Enabled || global.DB == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	s.fillPipelineName(event)
+
+	deliveries := append(s.ruleDeliveries(event), s.subscriptionDeliveries(event)...)
+	if len(deliveries) == 0 {
+		return
+	}
+	if err := global.DB.Create(&deliveries).Error; err != nil {
+		global.Log.Error("保存通知投递记录失败", zap.String("event", event.Event), zap.Error(err))
+		return
+	}
+
+	for i := range deliveries {
+		if deliveries[i].Status == DeliveryPending {
+			go s.attempt(&deliveries[i])
+		}
+	}
+}
+
+// ruleDeliveries 匹配事件的通知规则生成的投递，生成失败的记为失败的投递
+func (s *NotificationService) ruleDeliveries(event *NotificationEvent) []model.NotificationDelivery {
+	var rules []model.NotificationRule
+	if err := global.DB.Where("enabled = ? AND (pipeline_id = 0 OR pipeline_id = ?)", true, event.PipelineID).
+		Find(&rules).Error; err != nil {
+		global.Log.Error("获取通知规则失败", zap.Error(err))
+		return nil
+	}
+
+	var deliveries []model.NotificationDelivery
+	for i := range rules {
+		rule := &rules[i]
+		// 限定了环境的规则只匹配该环境的事件
+		if !rule.Events.Contains(event.Event) || (rule.Environment != "" && rule.Environment != event.Environment) {
+			continue
+		}
+		delivery, err := s.buildRuleDelivery(rule, event)
+		if err != nil {
+			global.Log.Error("生成通知内容失败", zap.Uint("ruleID", rule.ID), zap.Error(err))
+			delivery = newDelivery(event, rule.Channel, rule.URL)
+			delivery.RuleID = rule.ID
+			delivery.Status = DeliveryFailed
+			delivery.LastError = truncateError(err.Error())
+			delivery.NextAttemptAt = nil
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+	return deliveries
+}
+
+// subscriptionDeliveries 订阅了流水线的用户的邮件投递
+func (s *NotificationService) subscriptionDeliveries(event *NotificationEvent) []model.NotificationDelivery {
+	if event.PipelineID == 0 {
+		return nil
+	}
+	var subscriptions []model.NotificationSubscription
+	if err := global.DB.Where("pipeline_id = ?", event.PipelineID).Find(&subscriptions).Error; err != nil {
+		global.Log.Error("获取流水线订阅失败", zap.Error(err))
+		return nil
+	}
+
+	var deliveries []model.NotificationDelivery
+	for _, subscription := range subscriptions {
+		events := subscription.Events
+		if len(events) == 0 {
+			events = defaultSubscriptionEvents
+		}
+		if !events.Contains(event.Event) {
+			continue
+		}
+		var user model.User
+		if err := global.DB.Select("id", "email").First(&user, subscription.UserID).Error; err != nil || user.Email == "" {
+			continue
+		}
+		delivery := newDelivery(event, ChannelEmail, user.Email)
+		delivery.SubscriptionID = subscription.ID
+		delivery.Subject = event.Title
+		delivery.Payload = event.text()
+		deliveries = append(deliveries, *delivery)
+	}
+	return deliveries
+}
+
+// newDelivery 创建待发送的投递记录
+func newDelivery(event *NotificationEvent, channel, target string) *model.NotificationDelivery {
+	now := time.Now().Truncate(time.Millisecond)
+	return &model.NotificationDelivery{
+		Event:         event.Event,
+		Channel:       channel,
+		Target:        target,
+		PipelineID:    event.PipelineID,
+		RunID:         event.RunID,
+		ReleaseID:     event.ReleaseID,
+		Status:        DeliveryPending,
+		NextAttemptAt: &now,
+	}
+}
+
+// buildRuleDelivery 按规则的渠道渲染通知内容
+func (s *NotificationService) buildRuleDelivery(rule *model.NotificationRule, event *NotificationEvent) (*model.NotificationDelivery, error) {
+	var delivery *model.NotificationDelivery
+	switch rule.Channel {
+	case ChannelWebhook:
+		payload, err := renderWebhookPayload(rule.Template, event)
+		if err != nil {
+			return nil, err
+		}
+		delivery = newDelivery(event, ChannelWebhook, rule.URL)
+		delivery.Payload = payload
+	case ChannelChat:
+		payload, err := renderChatPayload(rule.ChatFormat, event)
+		if err != nil {
+			return nil, err
+		}
+		delivery = newDelivery(event, ChannelChat, rule.URL)
+		delivery.Payload = payload
+	case ChannelEmail:
+		delivery = newDelivery(event, ChannelEmail, strings.Join(rule.Recipients, ","))
+		delivery.Subject = event.Title
+		delivery.Payload = event.text()
+	default:
+		return nil, fmt.Errorf("不支持的通知渠道: %s", rule.Channel)
+	}
+	delivery.RuleID = rule.ID
+	return delivery, nil
+}
+
+// renderWebhookPayload 渲染webhook请求体，模板为空时发送事件本身
+func renderWebhookPayload(tmpl string, event *NotificationEvent) (string, error) {
+	if tmpl == "" {
+		data, err := json.Marshal(event)
+		return string(data), err
+	}
+
+	parsed, err := template.New("webhook").Funcs(templateFuncs).Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("模板解析失败: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := parsed.Execute(&buf, event); err != nil {
+		return "", fmt.Errorf("模板渲染失败: %v", err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		return "", errors.New("模板渲染的结果不是合法的JSON")
+	}
+	return buf.String(), nil
+}
+
+// renderChatPayload 渲染聊天机器人的文本消息
+func renderChatPayload(format string, event *NotificationEvent) (string, error) {
+	text := event.text()
+	var payload interface{}
+	switch format {
+	case ChatFormatDingTalk:
+		payload = map[string]interface{}{"msgtype": "text", "text": map[string]string{"content": text}}
+	case ChatFormatFeishu:
+		payload = map[string]interface{}{"msg_type": "text", "content": map[string]string{"text": text}}
+	default:
+		payload = map[string]string{"text": text}
+	}
+	data, err := json.Marshal(payload)
+	return string(data), err
+}
+
+// RetryDueDeliveries 重试到期的投递
+func (s *NotificationService) RetryDueDeliveries() error {
+	var due []model.NotificationDelivery
+	if err := global.DB.Where("status = ? AND next_attempt_at IS NOT NULL AND next_attempt_at <= ?", DeliveryPending, time.Now()).
+		Order("id ASC").
+		Limit(100).
+		Find(&due).Error; err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for i := range due {
+		wg.Add(1)
+		go func(delivery *model.NotificationDelivery) {
+			defer wg.Done()
+			s.attempt(delivery)
+		}(&due[i])
+	}
+	wg.Wait()
+	return nil
+}
+
+// attempt 认领并发送一次投递，根据结果更新状态和下次重试时间
+func (s *NotificationService) attempt(delivery *model.NotificationDelivery) {
+	timeout := notificationTimeout()
+	now := time.Now()
+
+	// 条件更新认领投递，避免多个实例或重试循环同时发送
+	lease := now.Add(2 * timeout)
+	result := global.DB.Model(&model.NotificationDelivery{}).
+		Where("id = ? AND status = ? AND attempts = ? AND next_attempt_at <= ?", delivery.ID, DeliveryPending, delivery.Attempts, now).
+		Update("next_attempt_at", lease)
+	if result.Error != nil {
+		global.Log.Error("认领通知投递失败", zap.Uint("deliveryID", delivery.ID), zap.Error(result.Error))
+		return
+	}
+	if result.RowsAffected == 0 {
+		return
+	}
+
+	err := s.send(delivery, timeout)
+
+	attempts := delivery.Attempts + 1
+	updates := map[string]interface{}{"attempts": attempts}
+	finished := time.Now()
+	switch {
+	case err == nil:
+		updates["status"] = DeliverySuccess
+		updates["last_error"] = ""
+		updates["next_attempt_at"] = nil
+		updates["delivered_at"] = finished
+	case errors.Is(err, errDeliveryAborted) || attempts >= notificationMaxAttempts():
+		updates["status"] = DeliveryFailed
+		updates["last_error"] = truncateError(err.Error())
+		updates["next_attempt_at"] = nil
+	default:
+		updates["last_error"] = truncateError(err.Error())
+		updates["next_attempt_at"] = finished.Add(notificationBackoff(attempts))
+	}
+	if err != nil {
+		global.Log.Warn("发送通知失败",
+			zap.Uint("deliveryID", delivery.ID),
+			zap.String("channel", delivery.Channel),
+			zap.Int("attempts", attempts),
+			zap.Error(err))
+	}
+
+	if err := global.DB.Model(&model.NotificationDelivery{}).Where("id = ?", delivery.ID).Updates(updates).Error; err != nil {
+		global.Log.Error("更新通知投递状态失败", zap.Uint("deliveryID", delivery.ID), zap.Error(err))
+	}
+}
+
+// send 按渠道发送投递
+func (s *NotificationService) send(delivery *model.NotificationDelivery, timeout time.Duration) error {
+	switch delivery.Channel {
+	case ChannelWebhook, ChannelChat:
+		var secret string
+		if delivery.Channel == ChannelWebhook && delivery.RuleID != 0 {
+			rule, err := s.GetRule(delivery.RuleID)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: 通知规则已删除", errDeliveryAborted)
+			}
+			if err != nil {
+				return err
+			}
+			secret = rule.Secret
+		}
+		return postNotification(delivery, secret, timeout)
+	case ChannelEmail:
+		return sendMail(strings.Split(delivery.Target, ","), delivery.Subject, delivery.Payload, timeout)
+	default:
+		return fmt.Errorf("%w: 不支持的通知渠道 %s", errDeliveryAborted, delivery.Channel)
+	}
+}
+
+// postNotification 发送webhook请求，配置了密钥时携带HMAC-SHA256签名
+func postNotification(delivery *model.NotificationDelivery, secret string, timeout time.Duration) error {
+	req, err := http.NewRequest(http.MethodPost, delivery.Target, strings.NewReader(delivery.Payload))
+	if err != nil {
+		return fmt.Errorf("%w: %v", errDeliveryAborted, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Pipeline-Event", delivery.Event)
+	req.Header.Set("X-Pipeline-Delivery", fmt.Sprint(delivery.ID))
+	if secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(delivery.Payload))
+		req.Header.Set("X-Pipeline-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	client := &http.Client{Timeout: timeout, Transport: notificationTransport}
+	resp, err := client.Do(req)
+	if errors.Is(err, errPrivateNetwork) {
+		return fmt.Errorf("%w: %v", errDeliveryAborted, err)
+	}
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// notificationTimeout 单次投递的超时时间
+func notificationTimeout() time.Duration {
+	if t := global.Config.Notification.Timeout; t > 0 {
+		return time.Duration(t) * time.Second
+	}
+	return 10 * time.Second
+}
+
+// notificationMaxAttempts 每次投递最多尝试次数
+func notificationMaxAttempts() int {
+	if n := global.Config.Notification.MaxAttempts; n > 0 {
+		return n
+	}
+	return 5
+}
+
+// notificationBackoff 第n次失败后的重试间隔，每次翻倍，最长1小时
+func notificationBackoff(attempts int) time.Duration {
+	interval := time.Duration(global.Config.Notification.RetryInterval) * time.Second
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	for i := 1; i < attempts && interval < time.Hour; i++ {
+		interval *= 2
+	}
+	if interval > time.Hour {
+		interval = time.Hour
+	}
+	return interval
+}
+
+// truncateError 截断错误信息以适应字段长度
+func truncateError(msg string) string {
+	runes := []rune(msg)
+	if len(runes) > 500 {
+		return string(runes[:500])
+	}
+	return msg
+}
+
+// RegisterNotificationSubscribers 订阅运行和发布结束事件以发送通知
+func RegisterNotificationSubscribers() {
+	notificationService := new(NotificationService)
+	Subscribe("notification", notificationService.NotifyRunFinished)
+	Subscribe("notification", notificationService.NotifyReleaseFinished)
+}
+
+// NotifyRunFinished 运行结束后发送运行失败或修复的通知
+// 修复指同一流水线同一分支上一次结束的运行失败而本次成功
+func (s *NotificationService) NotifyRunFinished(run PipelineRunFinished) {
+	var event string
+	switch run.Status {
+	case "failed":
+		event = EventRunFailed
+	case "success":
+		var previous model.PipelineRun
+		result := global.DB.Select("id", "status").
+			Where("pipeline_id = ? AND git_branch = ? AND id < ? AND status IN ?", run.PipelineID, run.GitBranch, run.RunID, []string{"success", "failed"}).
+			Order("id DESC").Limit(1).Find(&previous)
+		if result.Error != nil {
+			global.Log.Error("获取上一次运行失败", zap.Error(result.Error))
+			return
+		}
+		if result.RowsAffected == 0 || previous.Status != "failed" {
+			return
+		}
+		event = EventRunFixed
+	default:
+		return
+	}
+
+	var failedTasks []string
+	for taskID, status := range run.TaskStatuses {
+		if status == "failed" {
+			failedTasks = append(failedTasks, taskID)
+		}
+	}
+	sort.Strings(failedTasks)
+
+	notification := &NotificationEvent{
+		Event:       event,
+		PipelineID:  run.PipelineID,
+		RunID:       run.RunID,
+		Status:      run.Status,
+		GitBranch:   run.GitBranch,
+		GitCommit:   run.GitCommit,
+		TriggerType: run.TriggerType,
+		FailedTasks: failedTasks,
+		Duration:    int(run.Duration),
+	}
+	s.fillPipelineName(notification)
+	if event == EventRunFailed {
+		notification.Title = fmt.Sprintf("流水线 %s 运行 #%d 失败", notification.PipelineName, run.RunID)
+		if len(failedTasks) > 0 {
+			notification.Message = "失败的任务: " + strings.Join(failedTasks, ", ")
+		}
+	} else {
+		notification.Title = fmt.Sprintf("流水线 %s 运行 #%d 已恢复成功", notification.PipelineName, run.RunID)
+	}
+	if run.GitBranch != "" {
+		notification.Message = strings.TrimSpace(notification.Message + "\n分支: " + run.GitBranch)
+	}
+	s.Notify(notification)
+}
+
+// NotifyReleaseFinished 发布结束后发送发布结果的通知
+func (s *NotificationService) NotifyReleaseFinished(release ReleaseDeployed) {
+	var event, result string
+	switch release.Status {
+	case "success":
+		event, result = EventReleaseSucceeded, "成功"
+	case "failed":
+		event, result = EventReleaseFailed, "失败"
+	default:
+		return
+	}
+
+	notification := &NotificationEvent{
+		Event:       event,
+		PipelineID:  release.PipelineID,
+		Status:      release.Status,
+		Environment: release.Environment,
+		ReleaseID:   release.ReleaseID,
+		Version:     release.Version,
+	}
+	notification.Title = fmt.Sprintf("版本 %s 发布到 %s %s", release.Version, release.Environment, result)
+	if release.IsRollback {
+		notification.Message = release.Description
+	}
+	s.Notify(notification)
+}
+
+// fillPipelineName 补全事件的流水线名称
+func (s *NotificationService) fillPipelineName(event *NotificationEvent) {
+	if event.PipelineID == 0 || event.PipelineName != "" {
+		return
+	}
+	var pipeline model.Pipeline
+	if err := global.DB.Select("id", "name").First(&pipeline, event.PipelineID).Error; err == nil {
+		event.PipelineName = pipeline.Name
+	}
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"errors"
+	"gin_pipeline/global"
+	"gin_pipeline/model"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestValidateNotificationURL(t *testing.T) {
+	previous := global.Config.Notification
+	t.Cleanup(func() { global.Config.Notification = previous })
+
+	tests := []struct {
+		url          string
+		allowPrivate bool
+		wantErr      bool
+	}{
+		{url: "https://hooks.example.com/notify"},
+		{url: "", wantErr: true},
+		{url: "ftp://example.com/file", wantErr: true},
+		{url: "http://127.0.0.1:8080/hook", wantErr: true},
+		{url: "http://localhost/hook", wantErr: true},
+		{url: "http://169.254.169.254/latest/meta-data", wantErr: true},
+		{url: "http://10.1.2.3/hook", wantErr: true},
+		{url: "http://[::1]/hook", wantErr: true},
+		{url: "http://10.1.2.3/hook", allowPrivate: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			global.Config.Notification.AllowPrivateNetwork = tt.allowPrivate
+			if err := validateNotificationURL(tt.url); (err != nil) != tt.wantErr {
+				t.Fatalf("validateNotificationURL(%q) = %v, wantErr %v", tt.url, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPostNotificationRejectsPrivateNetwork(t *testing.T) {
+	previous := global.Config.Notification
+	t.Cleanup(func() { global.Config.Notification = previous })
+
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+	delivery := &model.NotificationDelivery{Target: server.URL, Payload: "{}"}
+
+	global.Config.Notification.AllowPrivateNetwork = false
+	err := postNotification(delivery, "", time.Second)
+	if !errors.Is(err, errDeliveryAborted) {
+		t.Fatalf("postNotification to loopback = %v, want aborted", err)
+	}
+	if called {
+		t.Fatal("request reached the loopback server")
+	}
+
+	global.Config.Notification.AllowPrivateNetwork = true
+	if err := postNotification(delivery, "", time.Second); err != nil || !called {
+		t.Fatalf("postNotification with private network allowed = %v", err)
+	}
+}
@@ -0,0 +1,678 @@
+package service
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gin_pipeline/global"
+	"gin_pipeline/model"
+	"gin_pipeline/utils"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 账号来源
+const (
+	ProviderLocal = "local"
+	ProviderOIDC  = "oidc"
+)
+
+// OIDC登录的时间限制
+const (
+	OIDCStateTTL       = 10 * time.Minute // 从跳转到身份提供方到回调的最长时间
+	oidcDiscoveryTTL   = time.Hour        // 发现文档和签名公钥的缓存时间
+	oidcRequestTimeout = 10 * time.Second
+)
+
+// oidcProvider 身份提供方的端点，从发现文档读取
+type oidcProvider struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	UserinfoEndpoint      string   `json:"userinfo_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	TokenAuthMethods      []string `json:"token_endpoint_auth_methods_supported"`
+}
+
+// oidcLoginState 跳转到身份提供方前保存的一次性状态
+type oidcLoginState struct {
+	Verifier   string    `json:"verifier"` // PKCE code_verifier
+	Nonce      string    `json:"nonce"`
+	LinkUserID uint      `json:"link_user_id,omitempty"` // 已登录用户发起关联时的用户ID
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// OIDCIdentity 身份提供方返回的用户信息
+type OIDCIdentity struct {
+	Subject       string
+	Username      string
+	Name          string
+	Email         string
+	EmailVerified bool
+	Groups        []string
+}
+
+// oidcCache 发现文档、签名公钥和未配置Redis时的登录状态
+var oidcCache = struct {
+	provider          *oidcProvider
+	providerFetchedAt time.Time
+	keys              map[string]*rsa.PublicKey
+	keysFetchedAt     time.Time
+	states            map[string]oidcLoginState
+	mutex             sync.Mutex
+}{states: make(map[string]oidcLoginState)}
+
+var oidcHTTPClient = &http.Client{Timeout: oidcRequestTimeout}
+
+// OIDCService OIDC单点登录服务，使用授权码模式和PKCE
+type OIDCService struct{}
+
+// PasswordLoginDisabled 是否禁用了本地密码登录，只有启用OIDC时才生效，避免所有人都无法登录
+func PasswordLoginDisabled() bool {
+	return global.Config.OIDC.Enabled && global.Config.OIDC.DisablePasswordLogin
+}
+
+// provider 获取身份提供方的端点，按缓存时间重新发现
+func (s *OIDCService) provider(ctx context.Context) (*oidcProvider, error) {
+	oidcCache.mutex.Lock()
+	defer oidcCache.mutex.Unlock()
+	if oidcCache.provider != nil && time.Since(oidcCache.providerFetchedAt) < oidcDiscoveryTTL {
+		return oidcCache.provider, nil
+	}
+
+	issuer := strings.TrimSuffix(global.Config.OIDC.Issuer, "/")
+	if issuer == "" {
+		return nil, errors.New("未配置OIDC issuer")
+	}
+	var provider oidcProvider
+	if err := s.getJSON(ctx, issuer+"/.well-known/openid-configuration", "", &provider); err != nil {
+		return nil, fmt.Errorf("获取OIDC发现文档失败: %w", err)
+	}
+	if strings.TrimSuffix(provider.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("发现文档的issuer不匹配: %s", provider.Issuer)
+	}
+	if provider.AuthorizationEndpoint == "" || provider.TokenEndpoint == "" || provider.JWKSURI == "" {
+		return nil, errors.New("发现文档缺少必要的端点")
+	}
+
+	oidcCache.provider = &provider
+	oidcCache.providerFetchedAt = time.Now()
+	oidcCache.keys = nil
+	return &provider, nil
+}
+
+// AuthURL 生成跳转到身份提供方的授权地址，同时返回state供调用方绑定到浏览器
+// linkUserID 不为0时，回调将身份关联到该用户，用于已登录的本地用户关联单点登录账号
+func (s *OIDCService) AuthURL(ctx context.Context, linkUserID uint) (string, string, error) {
+	provider, err := s.provider(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := utils.RandomToken(16)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := utils.RandomToken(16)
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := utils.RandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	loginState := oidcLoginState{Verifier: verifier, Nonce: nonce, LinkUserID: linkUserID, CreatedAt: time.Now()}
+	if err := s.saveState(ctx, state, loginState); err != nil {
+		return "", "", err
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {global.Config.OIDC.ClientID},
+		"redirect_uri":          {global.Config.OIDC.RedirectURL},
+		"scope":                 {strings.Join(s.scopes(), " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(provider.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return provider.AuthorizationEndpoint + separator + query.Encode(), state, nil
+}
+
+// scopes 请求的scope，总是包含openid
+func (s *OIDCService) scopes() []string {
+	scopes := []string{"openid"}
+	for _, scope := range global.Config.OIDC.Scopes {
+		if scope != "" && scope != "openid" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// saveState 保存登录状态，未配置Redis时保存在进程内，仅在单实例部署下有效
+func (s *OIDCService) saveState(ctx context.Context, state string, loginState oidcLoginState) error {
+	if global.Redis == nil {
+		oidcCache.mutex.Lock()
+		defer oidcCache.mutex.Unlock()
+		for key, value := range oidcCache.states {
+			if time.Since(value.CreatedAt) > OIDCStateTTL {
+				delete(oidcCache.states, key)
+			}
+		}
+		oidcCache.states[state] = loginState
+		return nil
+	}
+
+	data, err := json.Marshal(loginState)
+	if err != nil {
+		return err
+	}
+	return global.Redis.Set(ctx, "oidc:state:"+state, data, OIDCStateTTL).Err()
+}
+
+// takeState 取出并删除登录状态，每个state只能使用一次
+func (s *OIDCService) takeState(ctx context.Context, state string) (*oidcLoginState, error) {
+	invalid := errors.New("登录状态无效或已过期，请重新登录")
+	if state == "" {
+		return nil, invalid
+	}
+
+	if global.Redis == nil {
+		oidcCache.mutex.Lock()
+		defer oidcCache.mutex.Unlock()
+		loginState, ok := oidcCache.states[state]
+		delete(oidcCache.states, state)
+		if !ok || time.Since(loginState.CreatedAt) > OIDCStateTTL {
+			return nil, invalid
+		}
+		return &loginState, nil
+	}
+
+	data, err := takeKeyScript.Run(ctx, global.Redis, []string{"oidc:state:" + state}).Text()
+	if errors.Is(err, redis.Nil) {
+		return nil, invalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	var loginState oidcLoginState
+	if err := json.Unmarshal([]byte(data), &loginState); err != nil {
+		return nil, err
+	}
+	return &loginState, nil
+}
+
+// Login 处理身份提供方的回调：校验state，用授权码和code_verifier换取令牌，校验ID Token，
+// 然后关联或创建本地用户并按用户组同步角色
+func (s *OIDCService) Login(ctx context.Context, code, state string) (*model.User, error) {
+	identity, loginState, err := s.authenticate(ctx, code, state)
+	if err != nil {
+		return nil, err
+	}
+	return s.provision(identity, loginState.LinkUserID)
+}
+
+// authenticate 校验回调并换取身份信息
+func (s *OIDCService) authenticate(ctx context.Context, code, state string) (*OIDCIdentity, *oidcLoginState, error) {
+	loginState, err := s.takeState(ctx, state)
+	if err != nil {
+		return nil, nil, err
+	}
+	if code == "" {
+		return nil, nil, errors.New("缺少授权码")
+	}
+	provider, err := s.provider(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tokens, err := s.exchange(ctx, provider, code, loginState.Verifier)
+	if err != nil {
+		return nil, nil, err
+	}
+	claims, err := s.verifyIDToken(ctx, provider, tokens.IDToken, loginState.Nonce)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// ID Token中没有用户组等信息时从userinfo端点补充
+	if provider.UserinfoEndpoint != "" && tokens.AccessToken != "" && s.missingClaims(claims) {
+		var userinfo map[string]interface{}
+		if err := s.getJSON(ctx, provider.UserinfoEndpoint, tokens.AccessToken, &userinfo); err != nil {
+			return nil, nil, fmt.Errorf("获取用户信息失败: %w", err)
+		}
+		if userinfo["sub"] != claims["sub"] {
+			return nil, nil, errors.New("用户信息与ID Token的sub不一致")
+		}
+		for key, value := range userinfo {
+			if _, ok := claims[key]; !ok {
+				claims[key] = value
+			}
+		}
+	}
+
+	identity, err := s.identity(claims)
+	if err != nil {
+		return nil, nil, err
+	}
+	return identity, loginState, nil
+}
+
+// oidcTokenResponse 令牌端点的响应
+type oidcTokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// exchange 用授权码换取令牌
+func (s *OIDCService) exchange(ctx context.Context, provider *oidcProvider, code, verifier string) (*oidcTokenResponse, error) {
+	config := global.Config.OIDC
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {config.RedirectURL},
+		"code_verifier": {verifier},
+	}
+	// 身份提供方支持client_secret_basic时优先使用，否则在表单中提交
+	useBasic := config.ClientSecret != "" && (len(provider.TokenAuthMethods) == 0 || model.StringList(provider.TokenAuthMethods).Contains("client_secret_basic"))
+	if !useBasic {
+		form.Set("client_id", config.ClientID)
+		if config.ClientSecret != "" {
+			form.Set("client_secret", config.ClientSecret)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if useBasic {
+		req.SetBasicAuth(url.QueryEscape(config.ClientID), url.QueryEscape(config.ClientSecret))
+	}
+
+	resp, err := oidcHTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求令牌端点失败: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens oidcTokenResponse
+	if err := json.Unmarshal(body, &tokens); err != nil {
+		return nil, fmt.Errorf("解析令牌响应失败: HTTP %d", resp.StatusCode)
+	}
+	if tokens.Error != "" {
+		return nil, fmt.Errorf("换取令牌失败: %s %s", tokens.Error, tokens.ErrorDescription)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("换取令牌失败: HTTP %d", resp.StatusCode)
+	}
+	if tokens.IDToken == "" {
+		return nil, errors.New("令牌响应中没有ID Token")
+	}
+	return &tokens, nil
+}
+
+// verifyIDToken 校验ID Token的签名、签发方、受众、有效期和nonce
+func (s *OIDCService) verifyIDToken(ctx context.Context, provider *oidcProvider, rawIDToken, nonce string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("不支持的签名算法: %v", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		return s.publicKey(ctx, provider, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ID Token校验失败: %w", err)
+	}
+
+	if issuer, _ := claims["iss"].(string); strings.TrimSuffix(issuer, "/") != strings.TrimSuffix(provider.Issuer, "/") {
+		return nil, errors.New("ID Token的签发方不匹配")
+	}
+	if !audienceContains(claims["aud"], global.Config.OIDC.ClientID) {
+		return nil, errors.New("ID Token的受众不匹配")
+	}
+	if _, ok := claims["exp"]; !ok {
+		return nil, errors.New("ID Token缺少过期时间")
+	}
+	if claimNonce, _ := claims["nonce"].(string); claimNonce != nonce {
+		return nil, errors.New("ID Token的nonce不匹配")
+	}
+	if subject, _ := claims["sub"].(string); subject == "" {
+		return nil, errors.New("ID Token缺少sub")
+	}
+	return claims, nil
+}
+
+// audienceContains aud可以是字符串或字符串数组
+func audienceContains(aud interface{}, clientID string) bool {
+	switch value := aud.(type) {
+	case string:
+		return value == clientID
+	case []interface{}:
+		for _, item := range value {
+			if item == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// jsonWebKey JWKS中的公钥
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// publicKey 按kid获取签名公钥，找不到时重新获取JWKS以支持身份提供方轮换密钥
+func (s *OIDCService) publicKey(ctx context.Context, provider *oidcProvider, kid string) (*rsa.PublicKey, error) {
+	oidcCache.mutex.Lock()
+	defer oidcCache.mutex.Unlock()
+
+	if key := s.lookupKey(kid); key != nil && time.Since(oidcCache.keysFetchedAt) < oidcDiscoveryTTL {
+		return key, nil
+	}
+
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := s.getJSON(ctx, provider.JWKSURI, "", &jwks); err != nil {
+		return nil, fmt.Errorf("获取签名公钥失败: %w", err)
+	}
+	keys := make(map[string]*rsa.PublicKey)
+	for _, jwk := range jwks.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		key, err := parseRSAKey(jwk)
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	oidcCache.keys = keys
+	oidcCache.keysFetchedAt = time.Now()
+
+	if key := s.lookupKey(kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("找不到签名公钥: %s", kid)
+}
+
+// lookupKey 在缓存中查找公钥，ID Token没有kid且只有一个公钥时使用该公钥，调用方需持有锁
+func (s *OIDCService) lookupKey(kid string) *rsa.PublicKey {
+	if key, ok := oidcCache.keys[kid]; ok {
+		return key
+	}
+	if kid == "" && len(oidcCache.keys) == 1 {
+		for _, key := range oidcCache.keys {
+			return key
+		}
+	}
+	return nil
+}
+
+// parseRSAKey 将JWK转换为RSA公钥
+func parseRSAKey(jwk jsonWebKey) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+	if err != nil {
+		return nil, err
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+		return nil, errors.New("无效的公钥指数")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
+
+// getJSON 请求JSON接口，accessToken不为空时以Bearer方式携带
+func (s *OIDCService) getJSON(ctx context.Context, endpoint, accessToken string, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	resp, err := oidcHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(result)
+}
+
+// missingClaims ID Token中是否缺少用户名或用户组
+func (s *OIDCService) missingClaims(claims jwt.MapClaims) bool {
+	config := global.Config.OIDC
+	for _, claim := range []string{config.UsernameClaim, config.GroupsClaim, "email"} {
+		if claim == "" {
+			continue
+		}
+		if _, ok := claims[claim]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// identity 从claims中提取用户信息
+func (s *OIDCService) identity(claims jwt.MapClaims) (*OIDCIdentity, error) {
+	config := global.Config.OIDC
+	stringClaim := func(name string) string {
+		value, _ := claims[name].(string)
+		return value
+	}
+
+	identity := &OIDCIdentity{
+		Subject: stringClaim("sub"),
+		Name:    stringClaim("name"),
+		Email:   stringClaim("email"),
+	}
+	// 部分身份提供方以字符串返回email_verified
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = verified
+	case string:
+		identity.EmailVerified = verified == "true"
+	}
+	if config.UsernameClaim != "" {
+		identity.Username = stringClaim(config.UsernameClaim)
+	}
+	if identity.Username == "" {
+		identity.Username = identity.Email
+	}
+	if identity.Username == "" {
+		return nil, errors.New("身份提供方没有返回用户名")
+	}
+	if len(identity.Username) > 50 {
+		return nil, errors.New("用户名过长")
+	}
+	if len(identity.Name) > 50 {
+		identity.Name = identity.Name[:50]
+	}
+
+	if config.GroupsClaim != "" {
+		switch groups := claims[config.GroupsClaim].(type) {
+		case []interface{}:
+			for _, group := range groups {
+				if name, ok := group.(string); ok {
+					identity.Groups = append(identity.Groups, name)
+				}
+			}
+		case string:
+			identity.Groups = strings.Fields(strings.ReplaceAll(groups, ",", " "))
+		}
+	}
+	return identity, nil
+}
+
+// MapRole 按用户组映射角色，匹配多个时取最高的角色，没有匹配时返回false
+func (s *OIDCService) MapRole(groups []string) (string, bool) {
+	role := ""
+	for _, mapping := range global.Config.OIDC.RoleMappings {
+		if !IsValidRole(mapping.Role) || !model.StringList(groups).Contains(mapping.Group) {
+			continue
+		}
+		if role == "" || !RoleAtLeast(role, mapping.Role) {
+			role = mapping.Role
+		}
+	}
+	return role, role != ""
+}
+
+// defaultRole 没有匹配用户组时的角色
+func (s *OIDCService) defaultRole() string {
+	if IsValidRole(global.Config.OIDC.DefaultRole) {
+		return global.Config.OIDC.DefaultRole
+	}
+	return RoleViewer
+}
+
+// provision 关联或创建本地用户
+// 先按sub查找已关联的用户；未关联时只通过已登录用户发起的关联流程，或在开启后按已验证的邮箱关联本地账号，
+// 不按用户名关联，避免身份提供方中的同名用户接管本地账号；关联后本地密码不能再用于登录
+func (s *OIDCService) provision(identity *OIDCIdentity, linkUserID uint) (*model.User, error) {
+	var user model.User
+	err := global.DB.Where("provider = ? AND external_id = ?", ProviderOIDC, identity.Subject).First(&user).Error
+	switch {
+	case err == nil:
+		if linkUserID != 0 && user.ID != linkUserID {
+			return nil, errors.New("该单点登录账号已关联其他用户")
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		linked, err := s.linkAccount(identity, linkUserID)
+		if err != nil {
+			return nil, err
+		}
+		user = *linked
+	default:
+		return nil, err
+	}
+
+	if user.IsService {
+		return nil, errors.New("服务账号不能登录")
+	}
+	// 配置了用户组映射时，角色以身份提供方为准
+	if len(global.Config.OIDC.RoleMappings) > 0 {
+		if role, ok := s.MapRole(identity.Groups); ok {
+			user.Role = role
+		} else {
+			user.Role = s.defaultRole()
+		}
+	}
+	if identity.Name != "" {
+		user.Name = identity.Name
+	}
+	if identity.Email != "" {
+		user.Email = identity.Email
+	}
+	user.LastLogin = time.Now()
+
+	if err := global.DB.Save(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// linkAccount 为尚未关联的身份找到要关联的本地账号，没有时按配置创建用户
+func (s *OIDCService) linkAccount(identity *OIDCIdentity, linkUserID uint) (*model.User, error) {
+	config := global.Config.OIDC
+	var user model.User
+
+	// 已登录用户发起的关联
+	if linkUserID != 0 {
+		if err := global.DB.First(&user, linkUserID).Error; err != nil {
+			return nil, errors.New("要关联的用户不存在")
+		}
+		if user.IsService {
+			return nil, errors.New("服务账号不能关联单点登录")
+		}
+		if user.ExternalID != "" {
+			return nil, errors.New("账号已关联其他单点登录身份")
+		}
+		user.Provider = ProviderOIDC
+		user.ExternalID = identity.Subject
+		return &user, nil
+	}
+
+	// 按身份提供方验证过的邮箱关联，邮箱对应多个账号时不关联
+	if config.LinkVerifiedEmail && identity.Email != "" && identity.EmailVerified {
+		var users []model.User
+		if err := global.DB.Where("email = ? AND external_id = ? AND is_service = ?", identity.Email, "", false).
+			Limit(2).Find(&users).Error; err != nil {
+			return nil, err
+		}
+		if len(users) == 1 {
+			user = users[0]
+			user.Provider = ProviderOIDC
+			user.ExternalID = identity.Subject
+			return &user, nil
+		}
+	}
+
+	var count int64
+	if err := global.DB.Model(&model.User{}).Where("username = ?", identity.Username).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, errors.New("用户名已存在，请使用原账号登录后关联单点登录，或联系管理员")
+	}
+	if !config.AutoProvision {
+		return nil, errors.New("用户未开通，请联系管理员")
+	}
+
+	random, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	hashed, err := utils.HashPassword(random)
+	if err != nil {
+		return nil, err
+	}
+	return &model.User{
+		Username:   identity.Username,
+		Password:   hashed,
+		Role:       s.defaultRole(),
+		Provider:   ProviderOIDC,
+		ExternalID: identity.Subject,
+	}, nil
+}
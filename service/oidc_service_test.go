@@ -0,0 +1,395 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"gin_pipeline/config"
+	"gin_pipeline/global"
+	"github.com/dgrijalva/jwt-go"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeIdentityProvider 用于测试的OIDC身份提供方
+type fakeIdentityProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mutex     sync.Mutex
+	challenge string                     // 授权请求中的code_challenge
+	nonce     string                     // 授权请求中的nonce
+	claims    func(claims jwt.MapClaims) // 签发ID Token前修改claims
+	issuer    string                     // 发现文档中的issuer，为空时使用服务地址
+	tokenHits int
+}
+
+func newFakeIdentityProvider(t *testing.T) *fakeIdentityProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &fakeIdentityProvider{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		issuer := idp.issuer
+		if issuer == "" {
+			issuer = idp.server.URL
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                 issuer,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test-key",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", idp.token)
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+// token 校验授权码和PKCE后签发ID Token
+func (idp *fakeIdentityProvider) token(w http.ResponseWriter, r *http.Request) {
+	idp.mutex.Lock()
+	defer idp.mutex.Unlock()
+	idp.tokenHits++
+
+	fail := func(code string) {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": code})
+	}
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		fail("invalid_request")
+		return
+	}
+	if r.PostForm.Get("code") != "test-code" {
+		fail("invalid_grant")
+		return
+	}
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != idp.challenge {
+		fail("invalid_grant")
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":                idp.server.URL,
+		"aud":                "pipeline",
+		"sub":                "user-1",
+		"exp":                time.Now().Add(time.Minute).Unix(),
+		"iat":                time.Now().Unix(),
+		"nonce":              idp.nonce,
+		"preferred_username": "alice",
+		"email":              "alice@example.com",
+		"email_verified":     true,
+		"groups":             []string{"developers", "platform-admins"},
+	}
+	if idp.claims != nil {
+		idp.claims(claims)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test-key"
+	idToken, err := token.SignedString(idp.key)
+	if err != nil {
+		fail("server_error")
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]string{"access_token": "access", "id_token": idToken})
+}
+
+// authorize 模拟浏览器访问授权地址，记录PKCE参数并返回state
+func (idp *fakeIdentityProvider) authorize(t *testing.T, authURL string) string {
+	t.Helper()
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(authURL, idp.server.URL+"/authorize?") {
+		t.Fatalf("unexpected authorization endpoint: %s", authURL)
+	}
+	query := parsed.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		t.Fatalf("authorization request without PKCE: %s", authURL)
+	}
+	if query.Get("client_id") != "pipeline" || query.Get("response_type") != "code" {
+		t.Fatalf("unexpected authorization request: %s", authURL)
+	}
+
+	idp.mutex.Lock()
+	defer idp.mutex.Unlock()
+	idp.challenge = query.Get("code_challenge")
+	idp.nonce = query.Get("nonce")
+	return query.Get("state")
+}
+
+// setupOIDC 使用测试身份提供方的配置，清空发现文档和公钥缓存
+func setupOIDC(t *testing.T, idp *fakeIdentityProvider) {
+	t.Helper()
+	previous, previousRedis := global.Config.OIDC, global.Redis
+	global.Redis = nil
+	global.Config.OIDC = config.OIDC{
+		Enabled:       true,
+		Issuer:        idp.server.URL,
+		ClientID:      "pipeline",
+		RedirectURL:   "http://localhost/api/v1/user/oidc/callback",
+		UsernameClaim: "preferred_username",
+		GroupsClaim:   "groups",
+	}
+	resetOIDCCache()
+	t.Cleanup(func() {
+		global.Config.OIDC, global.Redis = previous, previousRedis
+		resetOIDCCache()
+	})
+}
+
+func resetOIDCCache() {
+	oidcCache.mutex.Lock()
+	defer oidcCache.mutex.Unlock()
+	oidcCache.provider = nil
+	oidcCache.keys = nil
+	oidcCache.states = make(map[string]oidcLoginState)
+}
+
+func TestOIDCAuthenticate(t *testing.T) {
+	tests := []struct {
+		name    string
+		claims  func(claims jwt.MapClaims)
+		state   func(state string) string
+		tamper  func(state string) // 修改保存的登录状态
+		wantErr string
+	}{
+		{name: "success"},
+		{
+			name:    "unknown state",
+			state:   func(string) string { return "forged" },
+			wantErr: "登录状态无效",
+		},
+		{
+			name:    "empty state",
+			state:   func(string) string { return "" },
+			wantErr: "登录状态无效",
+		},
+		{
+			name: "wrong code verifier",
+			tamper: func(state string) {
+				loginState := oidcCache.states[state]
+				loginState.Verifier = "attacker-verifier"
+				oidcCache.states[state] = loginState
+			},
+			wantErr: "invalid_grant",
+		},
+		{
+			name:    "nonce mismatch",
+			claims:  func(claims jwt.MapClaims) { claims["nonce"] = "other" },
+			wantErr: "nonce不匹配",
+		},
+		{
+			name:    "wrong audience",
+			claims:  func(claims jwt.MapClaims) { claims["aud"] = "other-client" },
+			wantErr: "受众不匹配",
+		},
+		{
+			name:   "audience list",
+			claims: func(claims jwt.MapClaims) { claims["aud"] = []string{"other-client", "pipeline"} },
+		},
+		{
+			name:    "wrong issuer",
+			claims:  func(claims jwt.MapClaims) { claims["iss"] = "https://evil.example.com" },
+			wantErr: "签发方不匹配",
+		},
+		{
+			name:    "expired",
+			claims:  func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Minute).Unix() },
+			wantErr: "ID Token校验失败",
+		},
+		{
+			name:    "missing sub",
+			claims:  func(claims jwt.MapClaims) { delete(claims, "sub") },
+			wantErr: "缺少sub",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newFakeIdentityProvider(t)
+			idp.claims = tt.claims
+			setupOIDC(t, idp)
+			ctx := context.Background()
+
+			authURL, state, err := new(OIDCService).AuthURL(ctx, 42)
+			if err != nil {
+				t.Fatalf("AuthURL: %v", err)
+			}
+			if got := idp.authorize(t, authURL); got != state {
+				t.Fatalf("state in URL = %q, want %q", got, state)
+			}
+			if tt.tamper != nil {
+				tt.tamper(state)
+			}
+			if tt.state != nil {
+				state = tt.state(state)
+			}
+
+			identity, loginState, err := new(OIDCService).authenticate(ctx, "test-code", state)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("authenticate error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("authenticate: %v", err)
+			}
+			if identity.Subject != "user-1" || identity.Username != "alice" || !identity.EmailVerified {
+				t.Fatalf("unexpected identity: %+v", identity)
+			}
+			if strings.Join(identity.Groups, ",") != "developers,platform-admins" {
+				t.Fatalf("groups = %v", identity.Groups)
+			}
+			if loginState.LinkUserID != 42 {
+				t.Fatalf("link user = %d, want 42", loginState.LinkUserID)
+			}
+		})
+	}
+}
+
+func TestOIDCStateSingleUse(t *testing.T) {
+	idp := newFakeIdentityProvider(t)
+	setupOIDC(t, idp)
+	ctx := context.Background()
+
+	authURL, state, err := new(OIDCService).AuthURL(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp.authorize(t, authURL)
+	if _, _, err := new(OIDCService).authenticate(ctx, "test-code", state); err != nil {
+		t.Fatalf("first callback: %v", err)
+	}
+	if _, _, err := new(OIDCService).authenticate(ctx, "test-code", state); err == nil {
+		t.Fatal("replayed state was accepted")
+	}
+	if idp.tokenHits != 1 {
+		t.Fatalf("token endpoint called %d times, want 1", idp.tokenHits)
+	}
+}
+
+func TestOIDCDiscoveryIssuerMismatch(t *testing.T) {
+	idp := newFakeIdentityProvider(t)
+	idp.issuer = "https://evil.example.com"
+	setupOIDC(t, idp)
+
+	if _, _, err := new(OIDCService).AuthURL(context.Background(), 0); err == nil || !strings.Contains(err.Error(), "issuer不匹配") {
+		t.Fatalf("AuthURL error = %v, want issuer mismatch", err)
+	}
+}
+
+func TestOIDCMapRole(t *testing.T) {
+	previous := global.Config.OIDC
+	t.Cleanup(func() { global.Config.OIDC = previous })
+	global.Config.OIDC.RoleMappings = []config.OIDCRoleMapping{
+		{Group: "developers", Role: RoleDeveloper},
+		{Group: "platform-admins", Role: RoleAdmin},
+		{Group: "release", Role: RoleMaintainer},
+		{Group: "broken", Role: "superuser"},
+	}
+
+	tests := []struct {
+		name   string
+		groups []string
+		want   string
+		ok     bool
+	}{
+		{name: "no groups", groups: nil, want: "", ok: false},
+		{name: "unmapped group", groups: []string{"qa"}, want: "", ok: false},
+		{name: "single mapping", groups: []string{"developers"}, want: RoleDeveloper, ok: true},
+		{name: "highest role wins", groups: []string{"developers", "platform-admins", "release"}, want: RoleAdmin, ok: true},
+		{name: "order does not matter", groups: []string{"release", "developers"}, want: RoleMaintainer, ok: true},
+		{name: "invalid role ignored", groups: []string{"broken"}, want: "", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			role, ok := new(OIDCService).MapRole(tt.groups)
+			if role != tt.want || ok != tt.ok {
+				t.Fatalf("MapRole(%v) = %q, %v, want %q, %v", tt.groups, role, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestOIDCIdentity(t *testing.T) {
+	previous := global.Config.OIDC
+	t.Cleanup(func() { global.Config.OIDC = previous })
+	global.Config.OIDC.UsernameClaim = "preferred_username"
+	global.Config.OIDC.GroupsClaim = "groups"
+
+	tests := []struct {
+		name         string
+		claims       jwt.MapClaims
+		wantUsername string
+		wantVerified bool
+		wantGroups   string
+		wantErr      bool
+	}{
+		{
+			name:         "username claim",
+			claims:       jwt.MapClaims{"sub": "1", "preferred_username": "bob", "email_verified": true, "groups": []interface{}{"a", "b"}},
+			wantUsername: "bob", wantVerified: true, wantGroups: "a,b",
+		},
+		{
+			name:         "email fallback and string verified",
+			claims:       jwt.MapClaims{"sub": "1", "email": "bob@example.com", "email_verified": "true", "groups": "a, b"},
+			wantUsername: "bob@example.com", wantVerified: true, wantGroups: "a,b",
+		},
+		{
+			name:         "unverified email",
+			claims:       jwt.MapClaims{"sub": "1", "email": "bob@example.com"},
+			wantUsername: "bob@example.com",
+		},
+		{
+			name:    "no username",
+			claims:  jwt.MapClaims{"sub": "1"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := new(OIDCService).identity(tt.claims)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if identity.Username != tt.wantUsername || identity.EmailVerified != tt.wantVerified || strings.Join(identity.Groups, ",") != tt.wantGroups {
+				t.Fatalf("unexpected identity: %+v", identity)
+			}
+		})
+	}
+}
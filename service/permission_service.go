@@ -0,0 +1,337 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"gin_pipeline/global"
+	"gin_pipeline/model"
+	"gorm.io/gorm"
+)
+
+// 角色，从低到高依次包含前一个角色的权限
+// 全局角色保存在用户上，决定共享资源的权限和能否创建流水线、环境；
+// 流水线和环境上的角色来自成员列表，创建者视为maintainer，其他用户只能查看
+const (
+	RoleViewer     = "viewer"     // 查看
+	RoleDeveloper  = "developer"  // 触发和取消运行、上传制品、部署到非生产环境
+	RoleMaintainer = "maintainer" // 修改配置、删除、管理成员
+	RoleAdmin      = "admin"      // 所有权限
+	roleLegacyUser = "user"       // 旧版本的普通用户角色，按developer处理
+)
+
+// ProductionEnvironment 生产环境的类型，部署需要单独授权
+const ProductionEnvironment = "production"
+
+var roleRanks = map[string]int{
+	RoleViewer:     1,
+	RoleDeveloper:  2,
+	roleLegacyUser: 2,
+	RoleMaintainer: 3,
+	RoleAdmin:      4,
+}
+
+// ErrForbidden 权限不足
+var ErrForbidden = errors.New("权限不足")
+
+// IsValidRole 是否为可以分配的全局角色
+func IsValidRole(role string) bool {
+	return role != roleLegacyUser && roleRanks[role] > 0
+}
+
+// IsValidMemberRole 是否为可以分配给成员的角色
+func IsValidMemberRole(role string) bool {
+	return role == RoleViewer || role == RoleDeveloper || role == RoleMaintainer
+}
+
+// RoleAtLeast 角色是否不低于要求的角色
+func RoleAtLeast(role, required string) bool {
+	return roleRanks[role] >= roleRanks[required]
+}
+
+// Actor 发起操作的用户
+type Actor struct {
+	ID   uint
+	Role string // 全局角色
+}
+
+// IsAdmin 是否为管理员
+func (a Actor) IsAdmin() bool {
+	return a.Role == RoleAdmin
+}
+
+// forbidden 返回说明缺少什么权限的错误
+func forbidden(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: "+format, append([]interface{}{ErrForbidden}, args...)...)
+}
+
+// PermissionService 权限服务
+type PermissionService struct{}
+
+// CheckRole 检查全局角色
+func (s *PermissionService) CheckRole(actor Actor, required string) error {
+	if !RoleAtLeast(actor.Role, required) {
+		return forbidden("需要%s角色", required)
+	}
+	return nil
+}
+
+// PipelineRole 获取用户在流水线上的角色
+func (s *PermissionService) PipelineRole(actor Actor, pipelineID uint) (string, error) {
+	if actor.IsAdmin() {
+		return RoleAdmin, nil
+	}
+
+	var pipeline model.Pipeline
+	if err := global.DB.Select("id", "creator_id").First(&pipeline, pipelineID).Error; err != nil {
+		return "", err
+	}
+	if pipeline.CreatorID == actor.ID {
+		return RoleMaintainer, nil
+	}
+
+	var member model.PipelineMember
+	err := global.DB.Where("pipeline_id = ? AND user_id = ?", pipelineID, actor.ID).First(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return RoleViewer, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return s.capRole(actor, member.Role), nil
+}
+
+// capRole 成员角色不超过全局角色，全局viewer即使被加入成员也只能查看
+func (s *PermissionService) capRole(actor Actor, role string) string {
+	if actor.Role == RoleViewer {
+		return RoleViewer
+	}
+	return role
+}
+
+// CheckPipeline 检查用户在流水线上的角色
+func (s *PermissionService) CheckPipeline(actor Actor, pipelineID uint, required string) error {
+	role, err := s.PipelineRole(actor, pipelineID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("流水线不存在")
+		}
+		return err
+	}
+	if !RoleAtLeast(role, required) {
+		return forbidden("需要流水线的%s角色", required)
+	}
+	return nil
+}
+
+// environmentAccess 用户在环境上的角色和能否部署生产环境
+func (s *PermissionService) environmentAccess(actor Actor, environment *model.Environment) (string, bool, error) {
+	if actor.IsAdmin() {
+		return RoleAdmin, true, nil
+	}
+	if environment.CreatedBy == actor.ID {
+		return RoleMaintainer, true, nil
+	}
+
+	var member model.EnvironmentMember
+	err := global.DB.Where("environment_id = ? AND user_id = ?", environment.ID, actor.ID).First(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return RoleViewer, false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	role := s.capRole(actor, member.Role)
+	return role, member.CanDeployProduction && RoleAtLeast(role, RoleDeveloper), nil
+}
+
+// CheckEnvironment 检查用户在环境上的角色
+func (s *PermissionService) CheckEnvironment(actor Actor, environmentID uint, required string) error {
+	var environment model.Environment
+	if err := global.DB.First(&environment, environmentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("环境不存在")
+		}
+		return err
+	}
+	role, _, err := s.environmentAccess(actor, &environment)
+	if err != nil {
+		return err
+	}
+	if !RoleAtLeast(role, required) {
+		return forbidden("需要环境的%s角色", required)
+	}
+	return nil
+}
+
+// CanDeployProduction 用户能否部署到指定环境的生产环境，用于授权其他成员
+func (s *PermissionService) CanDeployProduction(actor Actor, environmentID uint) (bool, error) {
+	var environment model.Environment
+	if err := global.DB.First(&environment, environmentID).Error; err != nil {
+		return false, err
+	}
+	_, canDeploy, err := s.environmentAccess(actor, &environment)
+	return canDeploy, err
+}
+
+// ResolveDeployEnvironment 确定发布的目标环境
+// 指定environmentID时使用该环境，其类型必须与发布的环境类型一致；未指定时按类型匹配，
+// 同一类型登记了多个环境时必须指定；没有登记该类型的环境时返回nil
+func (s *PermissionService) ResolveDeployEnvironment(environmentID uint, environmentType string) (*model.Environment, error) {
+	if environmentID != 0 {
+		var environment model.Environment
+		err := global.DB.First(&environment, environmentID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("目标环境 %d 不存在", environmentID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if environment.Type != environmentType {
+			return nil, fmt.Errorf("环境%s的类型为%s，与发布的环境类型%s不一致", environment.Name, environment.Type, environmentType)
+		}
+		return &environment, nil
+	}
+
+	var environments []model.Environment
+	if err := global.DB.Where("type = ?", environmentType).Order("id").Limit(2).Find(&environments).Error; err != nil {
+		return nil, err
+	}
+	switch len(environments) {
+	case 0:
+		return nil, nil
+	case 1:
+		return &environments[0], nil
+	}
+	return nil, fmt.Errorf("存在多个类型为%s的环境，请通过environment_id指定目标环境", environmentType)
+}
+
+// CheckDeploy 检查用户能否将流水线的制品部署到目标环境
+// 需要流水线的developer角色和目标环境的developer角色，生产环境还需要单独的部署授权；
+// environment由ResolveDeployEnvironment确定，为nil表示没有登记该类型的环境
+func (s *PermissionService) CheckDeploy(actor Actor, pipelineID uint, environment *model.Environment, environmentType string) error {
+	if pipelineID == 0 {
+		// 未关联流水线的制品只检查全局角色
+		if err := s.CheckRole(actor, RoleDeveloper); err != nil {
+			return err
+		}
+	} else if err := s.CheckPipeline(actor, pipelineID, RoleDeveloper); err != nil {
+		return err
+	}
+	if actor.IsAdmin() {
+		return nil
+	}
+
+	if environment == nil {
+		// 没有登记的生产环境只有管理员可以部署
+		if environmentType == ProductionEnvironment {
+			return forbidden("没有部署到生产环境的权限")
+		}
+		return nil
+	}
+
+	role, canDeployProduction, err := s.environmentAccess(actor, environment)
+	if err != nil {
+		return err
+	}
+	if !RoleAtLeast(role, RoleDeveloper) {
+		return forbidden("需要环境%s的%s角色", environment.Name, RoleDeveloper)
+	}
+	if environment.Type == ProductionEnvironment && !canDeployProduction {
+		return forbidden("没有部署到生产环境的权限")
+	}
+	return nil
+}
+
+// CheckOwned 检查共享资源的权限，创建者或全局角色不低于要求的用户可以操作
+func (s *PermissionService) CheckOwned(actor Actor, creatorID uint, required string) error {
+	if creatorID != 0 && creatorID == actor.ID {
+		return nil
+	}
+	if !RoleAtLeast(actor.Role, required) {
+		return forbidden("只有创建者或%s可以操作", required)
+	}
+	return nil
+}
+
+// CheckResource 按资源类型检查路径参数指定的资源
+// 属于流水线的资源检查流水线角色，环境检查环境角色，共享资源检查创建者或全局角色
+func (s *PermissionService) CheckResource(actor Actor, resourceType string, id uint, required string) error {
+	switch resourceType {
+	case "pipeline":
+		return s.CheckPipeline(actor, id, required)
+	case "environment":
+		return s.CheckEnvironment(actor, id, required)
+	case "dag":
+		pipelineID, err := s.pluckUint(resourceType, id, "pipeline_id")
+		if err != nil {
+			return err
+		}
+		return s.CheckPipeline(actor, pipelineID, required)
+	case "artifact":
+		// 未关联流水线的制品按创建者检查
+		var artifact model.Artifact
+		if err := global.DB.Select("id", "pipeline_id", "created_by").First(&artifact, id).Error; err != nil {
+			return s.notFound(err)
+		}
+		if artifact.PipelineID == 0 {
+			return s.CheckOwned(actor, artifact.CreatedBy, required)
+		}
+		return s.CheckPipeline(actor, artifact.PipelineID, required)
+	case "release":
+		var release model.Release
+		if err := global.DB.Preload("Artifact").First(&release, id).Error; err != nil {
+			return s.notFound(err)
+		}
+		if release.Artifact.PipelineID == 0 {
+			return s.CheckOwned(actor, release.DeployedBy, required)
+		}
+		return s.CheckPipeline(actor, release.Artifact.PipelineID, required)
+	case "notification_rule", "notification_delivery":
+		// 不限流水线的规则和投递只有管理员可以操作
+		pipelineID, err := s.pluckUint(resourceType, id, "pipeline_id")
+		if err != nil {
+			return err
+		}
+		if pipelineID == 0 {
+			return s.CheckRole(actor, RoleAdmin)
+		}
+		return s.CheckPipeline(actor, pipelineID, required)
+	case "build_template":
+		creatorID, err := s.pluckUint(resourceType, id, "created_by")
+		if err != nil {
+			return err
+		}
+		return s.CheckOwned(actor, creatorID, required)
+	case "template_category", "template", "template_version", "yaml_schema":
+		creatorID, err := s.pluckUint(resourceType, id, "creator_id")
+		if err != nil {
+			return err
+		}
+		return s.CheckOwned(actor, creatorID, required)
+	}
+	return fmt.Errorf("未知的资源类型: %s", resourceType)
+}
+
+// pluckUint 读取资源的一个字段
+func (s *PermissionService) pluckUint(resourceType string, id uint, column string) (uint, error) {
+	newModel, ok := resourceModels[resourceType]
+	if !ok {
+		return 0, fmt.Errorf("未知的资源类型: %s", resourceType)
+	}
+	var values []uint
+	if err := global.DB.Model(newModel()).Where("id = ?", id).Limit(1).Pluck(column, &values).Error; err != nil {
+		return 0, err
+	}
+	if len(values) == 0 {
+		return 0, s.notFound(gorm.ErrRecordNotFound)
+	}
+	return values[0], nil
+}
+
+// notFound 将记录不存在转换为可读的错误
+func (s *PermissionService) notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.New("资源不存在")
+	}
+	return err
+}
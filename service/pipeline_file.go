@@ -0,0 +1,272 @@
+package service
+
+// 流水线文件
+//
+// 仓库中的流水线文件（默认 .pipeline.yml）描述流水线的DAG，触发时在解析出的提交上读取，
+// 校验通过后作为本次运行的DAG快照；文件不存在时使用流水线的活动DAG。
+//
+//	version: 1              # 文件格式版本，目前只支持1
+//	name: build-and-deploy  # 可选，DAG名称
+//	nodes:
+//	  - id: build           # 必填，节点ID，在文件内唯一
+//	    name: 构建          # 可选，默认与id相同
+//	    type: docker        # 必填，任务类型: shell, docker, kubernetes 或插件提供的类型
+//	    runs_on: linux && docker  # 可选，执行器标签表达式
+//	    config:             # 可选，任务配置，支持 ${{ params.name }} 参数占位符
+//	      image: golang:1.22
+//	      command: go build ./...
+//	      timeout: 600
+//	  - id: deploy
+//	    type: shell
+//	    needs: [build]      # 可选，依赖的节点ID，也可写作 dependencies
+//	    config:
+//	      command: ./deploy.sh ${{ params.env }}
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"gin_pipeline/global"
+	"gin_pipeline/model"
+	"gopkg.in/yaml.v3"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// 运行使用的DAG来源
+const (
+	DAGSourcePipelineFile = "pipeline_file"
+	DAGSourceDAG          = "dag"
+	DAGSourceStages       = "stages"
+)
+
+// ErrPipelineFileNotFound 仓库中没有流水线文件
+var ErrPipelineFileNotFound = errors.New("流水线文件不存在")
+
+var (
+	// gitCommitPattern 提交只能是十六进制的对象ID
+	gitCommitPattern = regexp.MustCompile(`^[0-9a-fA-F]{7,64}$`)
+	// scpRepoPattern scp风格的仓库地址，如 git@github.com:org/repo.git
+	scpRepoPattern = regexp.MustCompile(`^([A-Za-z0-9._-]+@)?[A-Za-z0-9][A-Za-z0-9.-]*:[^:]`)
+)
+
+// gitProtocolArgs 只允许通过网络协议拉取，禁止 file:// 和本地路径，
+// 子模块或重定向等非用户直接指定的地址也不能使用其他协议
+var gitProtocolArgs = []string{
+	"-c", "protocol.allow=user",
+	"-c", "protocol.file.allow=never",
+	"-c", "protocol.ext.allow=never",
+}
+
+// PipelineFile 流水线文件结构
+type PipelineFile struct {
+	Version int                `yaml:"version"`
+	Name    string             `yaml:"name"`
+	Nodes   []PipelineFileNode `yaml:"nodes"`
+}
+
+// PipelineFileNode 流水线文件中的节点
+type PipelineFileNode struct {
+	ID           string                 `yaml:"id"`
+	Name         string                 `yaml:"name"`
+	Type         string                 `yaml:"type"`
+	RunsOn       string                 `yaml:"runs_on"`
+	Needs        []string               `yaml:"needs"`
+	Dependencies []string               `yaml:"dependencies"`
+	Config       map[string]interface{} `yaml:"config"`
+}
+
+// ParsePipelineFile 解析流水线文件并转换为DAG节点
+func ParsePipelineFile(content []byte) (*PipelineFile, []model.DAGNode, error) {
+	var file PipelineFile
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return nil, nil, fmt.Errorf("解析流水线文件失败: %v", err)
+	}
+
+	if file.Version != 1 {
+		return nil, nil, fmt.Errorf("不支持的流水线文件版本: %d", file.Version)
+	}
+
+	seen := make(map[string]bool, len(file.Nodes))
+	nodes := make([]model.DAGNode, 0, len(file.Nodes))
+	for i, item := range file.Nodes {
+		if item.ID == "" {
+			return nil, nil, fmt.Errorf("第%d个节点缺少id", i+1)
+		}
+		if item.Type == "" {
+			return nil, nil, fmt.Errorf("节点 %s 缺少type", item.ID)
+		}
+		if seen[item.ID] {
+			return nil, nil, fmt.Errorf("节点ID重复: %s", item.ID)
+		}
+		seen[item.ID] = true
+
+		name := item.Name
+		if name == "" {
+			name = item.ID
+		}
+		dependencies := append(append([]string{}, item.Needs...), item.Dependencies...)
+
+		nodes = append(nodes, model.DAGNode{
+			ID:           item.ID,
+			Name:         name,
+			Type:         item.Type,
+			Config:       model.JSONMap(item.Config),
+			Dependencies: dependencies,
+			RunsOn:       item.RunsOn,
+		})
+	}
+	return &file, nodes, nil
+}
+
+// LoadPipelineFileDAG 从仓库读取流水线文件并校验，返回DAG快照和解析出的提交
+// 文件不存在时返回 ErrPipelineFileNotFound
+func LoadPipelineFileDAG(pipeline *model.Pipeline, ref, commit string) (*model.DAG, string, error) {
+	cfg := global.Config.PipelineFile
+	filePath := cfg.Path
+	if filePath == "" {
+		filePath = ".pipeline.yml"
+	}
+	timeout := time.Duration(cfg.FetchTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = 60 * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	content, resolved, err := fetchRepoFile(ctx, pipeline.GitRepo, ref, commit, filePath)
+	if err != nil {
+		return nil, "", err
+	}
+
+	file, nodes, err := ParsePipelineFile(content)
+	if err != nil {
+		return nil, resolved, fmt.Errorf("%s: %v", filePath, err)
+	}
+	if err := new(DAGService).ValidateDAG(nodes); err != nil {
+		return nil, resolved, fmt.Errorf("%s: %v", filePath, err)
+	}
+
+	name := file.Name
+	if name == "" {
+		name = pipeline.Name
+	}
+	return &model.DAG{
+		Name:       name,
+		PipelineID: pipeline.ID,
+		NodesData:  nodes,
+	}, resolved, nil
+}
+
+// ValidateGitSource 校验仓库地址、引用和提交，它们都会作为git命令的参数，
+// 不能以 - 开头被解析为选项，仓库只能是 http(s)、ssh、git 协议或scp风格的地址
+func ValidateGitSource(repo, ref, commit string) error {
+	if strings.HasPrefix(repo, "-") || !validGitRepo(repo) {
+		return fmt.Errorf("不支持的仓库地址: %s", repo)
+	}
+	if ref != "" && (strings.HasPrefix(ref, "-") || strings.ContainsAny(ref, " ~^:?*[\\") || strings.Contains(ref, "..") || hasControlChar(ref)) {
+		return fmt.Errorf("无效的Git引用: %s", ref)
+	}
+	if commit != "" && !gitCommitPattern.MatchString(commit) {
+		return fmt.Errorf("无效的Git提交: %s", commit)
+	}
+	return nil
+}
+
+// validGitRepo 判断仓库地址是否为允许的远程地址
+func validGitRepo(repo string) bool {
+	if hasControlChar(repo) || strings.ContainsAny(repo, " ") {
+		return false
+	}
+	if scheme, _, ok := strings.Cut(repo, "://"); ok {
+		switch strings.ToLower(scheme) {
+		case "http", "https", "ssh", "git":
+			return true
+		}
+		return false
+	}
+	// 不含 :// 时只接受scp风格的地址，本地路径不能使用
+	return !strings.HasPrefix(repo, "/") && !strings.HasPrefix(repo, ".") && scpRepoPattern.MatchString(repo)
+}
+
+// hasControlChar 判断字符串是否包含控制字符
+func hasControlChar(value string) bool {
+	for _, r := range value {
+		if r < 0x20 || r == 0x7f {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchRepoFile 浅拉取仓库的指定提交或引用，读取其中的文件
+func fetchRepoFile(ctx context.Context, repo, ref, commit, filePath string) ([]byte, string, error) {
+	if err := ValidateGitSource(repo, ref, commit); err != nil {
+		return nil, "", err
+	}
+
+	dir, err := os.MkdirTemp("", "pipeline-file-")
+	if err != nil {
+		return nil, "", err
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := runGit(ctx, dir, "init", "-q"); err != nil {
+		return nil, "", err
+	}
+
+	// 优先按提交拉取，平台不支持按提交拉取时退回到引用
+	var fetchErr error
+	for _, target := range []string{commit, ref} {
+		if target == "" {
+			continue
+		}
+		if _, fetchErr = runGit(ctx, dir, "fetch", "-q", "--depth", "1", "--no-tags", "--end-of-options", repo, target); fetchErr == nil {
+			break
+		}
+	}
+	if fetchErr != nil {
+		return nil, "", fmt.Errorf("拉取仓库失败: %v", fetchErr)
+	}
+
+	resolved, err := runGit(ctx, dir, "rev-parse", "FETCH_HEAD")
+	if err != nil {
+		return nil, "", err
+	}
+	resolved = strings.TrimSpace(resolved)
+
+	content, err := runGit(ctx, dir, "show", "FETCH_HEAD:"+strings.TrimPrefix(filePath, "/"))
+	if err != nil {
+		if strings.Contains(err.Error(), "does not exist") || strings.Contains(err.Error(), "exists on disk, but not in") {
+			return nil, resolved, ErrPipelineFileNotFound
+		}
+		return nil, resolved, err
+	}
+	return []byte(content), resolved, nil
+}
+
+// runGit 执行git命令，禁止交互式输入凭据和不安全的协议
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append(append([]string{}, gitProtocolArgs...), args...)...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+		return "", fmt.Errorf("git %s: %s", args[0], message)
+	}
+	return stdout.String(), nil
+}
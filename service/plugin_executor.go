@@ -0,0 +1,499 @@
+package service
+
+// 执行器插件协议
+//
+// 插件是插件目录下的可执行文件，服务端通过标准输入/输出与插件进程进行
+// JSON-RPC 2.0 通信，每条消息占一行。插件的标准错误输出会作为任务日志保存。
+//
+// 1. describe：服务启动时调用，插件返回自身描述和支持的任务类型
+//
+//	-> {"jsonrpc":"2.0","id":1,"method":"describe"}
+//	<- {"jsonrpc":"2.0","id":1,"result":{"name":"helm","version":"1.0.0","task_types":[
+//	     {"type":"helm","description":"Helm部署","config_schema":{"type":"object","required":["chart"],
+//	      "properties":{"chart":{"type":"string"}}}}]}}
+//
+// 2. execute：每个任务启动一个插件进程并调用一次
+//
+//	-> {"jsonrpc":"2.0","id":1,"method":"execute","params":{"run_id":1,"task":{"id":"deploy","name":"部署","type":"helm","config":{...},"env":{"PARAM_ENV":"prod"}}}}
+//	<- {"jsonrpc":"2.0","method":"log","params":{"line":"Release \"web\" has been upgraded"}}
+//	<- {"jsonrpc":"2.0","id":1,"result":{"status":"success","outputs":{"revision":3}}}
+//
+// 任务执行期间插件可以发送任意多条 log 通知；status 为 failed 或返回 error 时任务失败。
+// 任务被取消或超时时，服务端发送 {"jsonrpc":"2.0","method":"cancel"} 通知，
+// 插件未在宽限期内退出则被强制结束。
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gin_pipeline/global"
+	"go.uber.org/zap"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	pluginProtocolVersion = "2.0"
+	pluginCancelGrace     = 5 * time.Second
+	pluginStderrWait      = 2 * time.Second // 结束进程后等待标准错误输出读取完毕的最长时间
+)
+
+// PluginTaskType 插件声明的任务类型
+type PluginTaskType struct {
+	Type         string        `json:"type"`
+	Description  string        `json:"description"`
+	ConfigSchema *ConfigSchema `json:"config_schema,omitempty"`
+}
+
+// PluginDescriptor 插件描述
+type PluginDescriptor struct {
+	Name      string           `json:"name"`
+	Version   string           `json:"version"`
+	TaskTypes []PluginTaskType `json:"task_types"`
+}
+
+// Plugin 已加载的插件
+type Plugin struct {
+	Path       string           `json:"path"`
+	Descriptor PluginDescriptor `json:"descriptor"`
+}
+
+// pluginMessage JSON-RPC消息
+type pluginMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int            `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *pluginRPCError `json:"error,omitempty"`
+}
+
+// pluginRPCError JSON-RPC错误
+type pluginRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// pluginExecuteParams execute方法参数
+type pluginExecuteParams struct {
+	RunID uint             `json:"run_id"`
+	Task  pluginTaskObject `json:"task"`
+}
+
+// pluginTaskObject 传递给插件的任务
+type pluginTaskObject struct {
+	ID     string                 `json:"id"`
+	Name   string                 `json:"name"`
+	Type   string                 `json:"type"`
+	Config map[string]interface{} `json:"config"`
+	Env    map[string]string      `json:"env,omitempty"`
+}
+
+// pluginExecuteResult execute方法返回值
+type pluginExecuteResult struct {
+	Status  string                 `json:"status"`
+	Message string                 `json:"message"`
+	Outputs map[string]interface{} `json:"outputs"`
+}
+
+// pluginLogParams log通知参数
+type pluginLogParams struct {
+	Line string `json:"line"`
+}
+
+// PluginManager 插件管理器
+type PluginManager struct {
+	plugins   []*Plugin
+	taskTypes map[string]*Plugin
+	mutex     sync.RWMutex
+}
+
+var pluginManager = &PluginManager{taskTypes: make(map[string]*Plugin)}
+
+// GetPluginManager 获取插件管理器
+func GetPluginManager() *PluginManager {
+	return pluginManager
+}
+
+// LoadDir 加载目录下的所有插件
+func (m *PluginManager) LoadDir(dir string, timeout time.Duration) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		info, err := entry.Info()
+		if err != nil || info.Mode()&0111 == 0 {
+			// 跳过不可执行的文件
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		descriptor, err := describePlugin(ctx, path)
+		cancel()
+		if err != nil {
+			global.Log.Error("加载插件失败", zap.String("path", path), zap.Error(err))
+			continue
+		}
+
+		if err := m.Register(&Plugin{Path: path, Descriptor: *descriptor}); err != nil {
+			global.Log.Error("注册插件失败", zap.String("path", path), zap.Error(err))
+			continue
+		}
+
+		global.Log.Info("加载插件成功",
+			zap.String("name", descriptor.Name),
+			zap.String("version", descriptor.Version),
+			zap.Int("taskTypes", len(descriptor.TaskTypes)))
+	}
+
+	return nil
+}
+
+// Register 注册插件
+func (m *PluginManager) Register(plugin *Plugin) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if len(plugin.Descriptor.TaskTypes) == 0 {
+		return errors.New("插件未声明任务类型")
+	}
+
+	for _, taskType := range plugin.Descriptor.TaskTypes {
+		if taskType.Type == "" {
+			return errors.New("插件声明的任务类型为空")
+		}
+		if isBuiltinTaskType(taskType.Type) {
+			return errors.New("插件不能覆盖内置任务类型: " + taskType.Type)
+		}
+		if existing, ok := m.taskTypes[taskType.Type]; ok {
+			return fmt.Errorf("任务类型 %s 已由插件 %s 注册", taskType.Type, existing.Descriptor.Name)
+		}
+	}
+
+	for _, taskType := range plugin.Descriptor.TaskTypes {
+		m.taskTypes[taskType.Type] = plugin
+	}
+	m.plugins = append(m.plugins, plugin)
+	return nil
+}
+
+// Plugins 获取已加载的插件
+func (m *PluginManager) Plugins() []*Plugin {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	plugins := make([]*Plugin, len(m.plugins))
+	copy(plugins, m.plugins)
+	return plugins
+}
+
+// Lookup 根据任务类型查找插件
+func (m *PluginManager) Lookup(taskType string) (*Plugin, *PluginTaskType, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	plugin, ok := m.taskTypes[taskType]
+	if !ok {
+		return nil, nil, false
+	}
+	for i := range plugin.Descriptor.TaskTypes {
+		if plugin.Descriptor.TaskTypes[i].Type == taskType {
+			return plugin, &plugin.Descriptor.TaskTypes[i], true
+		}
+	}
+	return nil, nil, false
+}
+
+// TaskTypes 获取插件提供的所有任务类型
+func (m *PluginManager) TaskTypes() []string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	types := make([]string, 0, len(m.taskTypes))
+	for taskType := range m.taskTypes {
+		types = append(types, taskType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// RegisterExecutors 将插件提供的任务类型注册到工作流引擎
+func (m *PluginManager) RegisterExecutors(engine *WorkflowEngine) {
+	for _, taskType := range m.TaskTypes() {
+		plugin, _, ok := m.Lookup(taskType)
+		if !ok {
+			continue
+		}
+		engine.RegisterExecutor(taskType, &PluginTaskExecutor{plugin: plugin})
+	}
+}
+
+// isBuiltinTaskType 是否为内置任务类型
+func isBuiltinTaskType(taskType string) bool {
+	switch taskType {
+	case "shell", "docker", "kubernetes":
+		return true
+	}
+	return false
+}
+
+// PluginTaskExecutor 插件任务执行器
+type PluginTaskExecutor struct {
+	plugin *Plugin
+}
+
+// Execute 启动插件进程执行任务
+func (e *PluginTaskExecutor) Execute(ctx context.Context, task *WorkflowTask) error {
+	global.Log.Info("执行插件任务",
+		zap.String("taskID", task.ID),
+		zap.String("name", task.Name),
+		zap.String("plugin", e.plugin.Descriptor.Name))
+
+	session, err := startPluginSession(e.plugin.Path)
+	if err != nil {
+		return err
+	}
+
+	var logs strings.Builder
+	var logMutex sync.Mutex
+	appendLog := func(line string) {
+		logMutex.Lock()
+		defer logMutex.Unlock()
+		logs.WriteString(line)
+		logs.WriteString("\n")
+	}
+	go session.drainStderr(appendLog)
+
+	params := pluginExecuteParams{
+		RunID: task.RunID,
+		Task: pluginTaskObject{
+			ID:     task.ID,
+			Name:   task.Name,
+			Type:   task.Type,
+			Config: task.Config,
+			Env:    task.Env,
+		},
+	}
+
+	var result pluginExecuteResult
+	err = session.call(ctx, "execute", params, &result, func(msg *pluginMessage) {
+		if msg.Method != "log" {
+			return
+		}
+		var logParams pluginLogParams
+		if err := json.Unmarshal(msg.Params, &logParams); err == nil {
+			appendLog(logParams.Line)
+		}
+	})
+	session.close()
+
+	logMutex.Lock()
+	task.Logs = logs.String()
+	logMutex.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	task.Outputs = result.Outputs
+	if result.Status != "" && result.Status != "success" {
+		if result.Message != "" {
+			return errors.New(result.Message)
+		}
+		return errors.New("插件任务执行失败: " + result.Status)
+	}
+	return nil
+}
+
+// describePlugin 获取插件描述
+func describePlugin(ctx context.Context, path string) (*PluginDescriptor, error) {
+	session, err := startPluginSession(path)
+	if err != nil {
+		return nil, err
+	}
+	defer session.close()
+	go session.drainStderr(func(string) {})
+
+	var descriptor PluginDescriptor
+	if err := session.call(ctx, "describe", nil, &descriptor, nil); err != nil {
+		return nil, err
+	}
+	if descriptor.Name == "" {
+		descriptor.Name = filepath.Base(path)
+	}
+	return &descriptor, nil
+}
+
+// pluginSession 插件进程会话，一个会话对应一个插件进程
+type pluginSession struct {
+	cmd        *exec.Cmd
+	stdin      io.WriteCloser
+	messages   chan pluginReadResult
+	stderr     io.ReadCloser
+	stderrDone chan struct{}
+	done       chan struct{}
+	nextID     int
+}
+
+// pluginReadResult 从插件读取到的消息
+type pluginReadResult struct {
+	msg *pluginMessage
+	err error
+}
+
+// startPluginSession 启动插件进程
+func startPluginSession(path string) (*pluginSession, error) {
+	cmd := exec.Command(path)
+	setPluginProcessGroup(cmd)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("启动插件失败: %w", err)
+	}
+
+	s := &pluginSession{
+		cmd:        cmd,
+		stdin:      stdin,
+		messages:   make(chan pluginReadResult, 64),
+		stderr:     stderr,
+		stderrDone: make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	go s.readStdout(stdout)
+	return s, nil
+}
+
+// readStdout 持续读取插件的标准输出
+func (s *pluginSession) readStdout(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var msg pluginMessage
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			// 忽略非协议输出
+			continue
+		}
+		select {
+		case s.messages <- pluginReadResult{msg: &msg}:
+		case <-s.done:
+			return
+		}
+	}
+	err := scanner.Err()
+	if err == nil {
+		err = io.EOF
+	}
+	select {
+	case s.messages <- pluginReadResult{err: err}:
+	case <-s.done:
+	}
+}
+
+// call 调用插件方法，等待响应期间收到的通知交给onNotify处理
+func (s *pluginSession) call(ctx context.Context, method string, params interface{}, result interface{}, onNotify func(*pluginMessage)) error {
+	s.nextID++
+	id := s.nextID
+
+	request := pluginMessage{JSONRPC: pluginProtocolVersion, ID: &id, Method: method}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		request.Params = data
+	}
+	if err := s.send(&request); err != nil {
+		return fmt.Errorf("发送插件请求失败: %w", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			// 通知插件取消，进程由close在宽限期后强制结束
+			_ = s.send(&pluginMessage{JSONRPC: pluginProtocolVersion, Method: "cancel"})
+			return ctx.Err()
+		case r := <-s.messages:
+			if r.err != nil {
+				return fmt.Errorf("插件进程意外退出: %w", r.err)
+			}
+			msg := r.msg
+			if msg.ID == nil {
+				if onNotify != nil {
+					onNotify(msg)
+				}
+				continue
+			}
+			if *msg.ID != id {
+				continue
+			}
+			if msg.Error != nil {
+				return errors.New(msg.Error.Message)
+			}
+			if result != nil && len(msg.Result) > 0 {
+				if err := json.Unmarshal(msg.Result, result); err != nil {
+					return fmt.Errorf("解析插件响应失败: %w", err)
+				}
+			}
+			return nil
+		}
+	}
+}
+
+// send 发送一条消息
+func (s *pluginSession) send(msg *pluginMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = s.stdin.Write(append(data, '\n'))
+	return err
+}
+
+// drainStderr 读取插件的标准错误输出
+func (s *pluginSession) drainStderr(appendLog func(string)) {
+	defer close(s.stderrDone)
+	scanner := bufio.NewScanner(s.stderr)
+	for scanner.Scan() {
+		appendLog(scanner.Text())
+	}
+}
+
+// close 关闭会话，插件未在宽限期内退出则强制结束其进程组；
+// 插件派生的子进程可能继承标准错误输出，结束后最多再等待pluginStderrWait
+func (s *pluginSession) close() {
+	_ = s.stdin.Close()
+	select {
+	case <-s.stderrDone:
+	case <-time.After(pluginCancelGrace):
+	}
+	// 插件已退出时进程组中可能还有遗留的子进程，同样结束
+	killPluginProcess(s.cmd)
+	select {
+	case <-s.stderrDone:
+	case <-time.After(pluginStderrWait):
+		_ = s.stderr.Close()
+	}
+	close(s.done)
+	_ = s.cmd.Wait()
+}
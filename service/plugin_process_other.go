@@ -0,0 +1,15 @@
+//go:build !unix
+
+package service
+
+import "os/exec"
+
+// setPluginProcessGroup 非Unix平台不支持进程组
+func setPluginProcessGroup(cmd *exec.Cmd) {}
+
+// killPluginProcess 结束插件进程
+func killPluginProcess(cmd *exec.Cmd) {
+	if cmd.Process != nil {
+		_ = cmd.Process.Kill()
+	}
+}
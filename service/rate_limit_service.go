@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"gin_pipeline/global"
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+	"strings"
+	"sync"
+	"time"
+)
+
+// incrWindowScript 计数加一，窗口内第一次计数时设置过期时间，返回计数和剩余毫秒数
+var incrWindowScript = redis.NewScript(`
+local count = redis.call("INCR", KEYS[1])
+if count == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return {count, redis.call("PTTL", KEYS[1])}`)
+
+// localCounter 进程内计数器
+type localCounter struct {
+	count     int64
+	expiresAt time.Time
+}
+
+// localCounters 未配置Redis时使用的进程内计数器，仅在单实例部署下有效
+var localCounters = struct {
+	counters  map[string]*localCounter
+	cleanedAt time.Time
+	mutex     sync.Mutex
+}{counters: make(map[string]*localCounter)}
+
+// incrCounter 在固定窗口内计数，返回计数和窗口剩余时间
+func incrCounter(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error) {
+	if global.Redis == nil {
+		localCounters.mutex.Lock()
+		defer localCounters.mutex.Unlock()
+		now := time.Now()
+		// 定期清理过期的计数器
+		if now.Sub(localCounters.cleanedAt) > time.Minute {
+			for k, counter := range localCounters.counters {
+				if !now.Before(counter.expiresAt) {
+					delete(localCounters.counters, k)
+				}
+			}
+			localCounters.cleanedAt = now
+		}
+		counter, ok := localCounters.counters[key]
+		if !ok || !now.Before(counter.expiresAt) {
+			counter = &localCounter{expiresAt: now.Add(window)}
+			localCounters.counters[key] = counter
+		}
+		counter.count++
+		return counter.count, counter.expiresAt.Sub(now), nil
+	}
+
+	values, err := incrWindowScript.Run(ctx, global.Redis, []string{key}, window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return 0, 0, err
+	}
+	ttl := time.Duration(values[1]) * time.Millisecond
+	if ttl < 0 {
+		ttl = window
+	}
+	return values[0], ttl, nil
+}
+
+// counterTTL 计数器的剩余时间，不存在时返回0
+func counterTTL(ctx context.Context, key string) (time.Duration, error) {
+	if global.Redis == nil {
+		localCounters.mutex.Lock()
+		defer localCounters.mutex.Unlock()
+		counter, ok := localCounters.counters[key]
+		if !ok {
+			return 0, nil
+		}
+		if ttl := time.Until(counter.expiresAt); ttl > 0 {
+			return ttl, nil
+		}
+		return 0, nil
+	}
+
+	ttl, err := global.Redis.PTTL(ctx, key).Result()
+	if err != nil || ttl < 0 {
+		return 0, err
+	}
+	return ttl, nil
+}
+
+// deleteCounters 删除计数器
+func deleteCounters(ctx context.Context, keys ...string) error {
+	if global.Redis == nil {
+		localCounters.mutex.Lock()
+		defer localCounters.mutex.Unlock()
+		for _, key := range keys {
+			delete(localCounters.counters, key)
+		}
+		return nil
+	}
+	return global.Redis.Del(ctx, keys...).Err()
+}
+
+// RateLimitResult 一次限流检查的结果
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	Reset     time.Duration // 距离窗口重置的时间
+}
+
+// RateLimitService 接口限流服务，按固定窗口计数
+type RateLimitService struct{}
+
+// Allow 检查并计入一次请求，计数失败时放行，避免Redis故障导致所有接口不可用
+func (s *RateLimitService) Allow(ctx context.Context, key string, limit, windowSeconds int) RateLimitResult {
+	if limit <= 0 || windowSeconds <= 0 {
+		return RateLimitResult{Allowed: true}
+	}
+	window := time.Duration(windowSeconds) * time.Second
+
+	count, reset, err := incrCounter(ctx, "ratelimit:"+key, window)
+	if err != nil {
+		global.Log.Warn("限流计数失败", zap.String("key", key), zap.Error(err))
+		return RateLimitResult{Allowed: true}
+	}
+	remaining := limit - int(count)
+	if remaining < 0 {
+		remaining = 0
+	}
+	return RateLimitResult{
+		Allowed:   count <= int64(limit),
+		Limit:     limit,
+		Remaining: remaining,
+		Reset:     reset,
+	}
+}
+
+// loginLockoutMemory 锁定次数的保留时间，超过后锁定时间重新从首次锁定开始计算
+const loginLockoutMemory = 24 * time.Hour
+
+// LoginLockoutService 登录失败锁定服务
+// 按用户名统计失败次数，连续失败达到上限后锁定，每次锁定的时间翻倍，登录成功后清零；
+// 不存在的用户名同样计数，避免通过锁定行为判断用户是否存在
+type LoginLockoutService struct{}
+
+// lockoutKeys 失败次数、锁定和锁定次数的键
+func (s *LoginLockoutService) lockoutKeys(username string) (string, string, string) {
+	name := strings.ToLower(strings.TrimSpace(username))
+	return "login:failures:" + name, "login:lockout:" + name, "login:lockouts:" + name
+}
+
+// Locked 返回账号剩余的锁定时间，未锁定时返回0
+func (s *LoginLockoutService) Locked(ctx context.Context, username string) time.Duration {
+	if global.Config.RateLimit.LoginLockout.MaxFailures <= 0 {
+		return 0
+	}
+	_, lockoutKey, _ := s.lockoutKeys(username)
+	ttl, err := counterTTL(ctx, lockoutKey)
+	if err != nil {
+		global.Log.Warn("查询登录锁定失败", zap.String("username", username), zap.Error(err))
+		return 0
+	}
+	return ttl
+}
+
+// RecordFailure 记录一次登录失败，达到上限时锁定账号并返回锁定时间
+func (s *LoginLockoutService) RecordFailure(ctx context.Context, username string) time.Duration {
+	config := global.Config.RateLimit.LoginLockout
+	if config.MaxFailures <= 0 {
+		return 0
+	}
+	failureWindow := time.Duration(config.FailureWindow) * time.Second
+	if failureWindow <= 0 {
+		failureWindow = 15 * time.Minute
+	}
+	failuresKey, lockoutKey, lockoutsKey := s.lockoutKeys(username)
+
+	failures, _, err := incrCounter(ctx, failuresKey, failureWindow)
+	if err != nil {
+		global.Log.Warn("记录登录失败次数失败", zap.String("username", username), zap.Error(err))
+		return 0
+	}
+	if failures < int64(config.MaxFailures) {
+		return 0
+	}
+
+	// 锁定时间按锁定次数翻倍
+	lockouts, _, err := incrCounter(ctx, lockoutsKey, loginLockoutMemory)
+	if err != nil {
+		global.Log.Warn("记录登录锁定次数失败", zap.String("username", username), zap.Error(err))
+		return 0
+	}
+	cooldown := time.Duration(config.Cooldown) * time.Second
+	if cooldown <= 0 {
+		cooldown = time.Minute
+	}
+	maxCooldown := time.Duration(config.MaxCooldown) * time.Second
+	for i := int64(1); i < lockouts && (maxCooldown <= 0 || cooldown < maxCooldown); i++ {
+		cooldown *= 2
+	}
+	if maxCooldown > 0 && cooldown > maxCooldown {
+		cooldown = maxCooldown
+	}
+
+	// 重新计时，锁定期间的并发失败不会沿用较短的剩余时间
+	_ = deleteCounters(ctx, lockoutKey)
+	if _, _, err := incrCounter(ctx, lockoutKey, cooldown); err != nil {
+		global.Log.Warn("锁定账号失败", zap.String("username", username), zap.Error(err))
+		return 0
+	}
+	_ = deleteCounters(ctx, failuresKey)
+	global.Log.Warn("登录失败次数过多，锁定账号", zap.String("username", username), zap.Duration("cooldown", cooldown))
+	return cooldown
+}
+
+// Reset 登录成功后清除失败记录
+func (s *LoginLockoutService) Reset(ctx context.Context, username string) {
+	if global.Config.RateLimit.LoginLockout.MaxFailures <= 0 {
+		return
+	}
+	failuresKey, lockoutKey, lockoutsKey := s.lockoutKeys(username)
+	if err := deleteCounters(ctx, failuresKey, lockoutKey, lockoutsKey); err != nil {
+		global.Log.Warn("清除登录失败记录失败", zap.String("username", username), zap.Error(err))
+	}
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"gin_pipeline/config"
+	"gin_pipeline/global"
+	"testing"
+	"time"
+)
+
+// forEachCounterBackend 分别使用进程内计数器和Redis运行测试
+func forEachCounterBackend(t *testing.T, run func(t *testing.T)) {
+	t.Run("local", func(t *testing.T) {
+		previous := global.Redis
+		global.Redis = nil
+		t.Cleanup(func() { global.Redis = previous })
+		localCounters.mutex.Lock()
+		localCounters.counters = make(map[string]*localCounter)
+		localCounters.mutex.Unlock()
+		run(t)
+	})
+	t.Run("redis", func(t *testing.T) {
+		useRedis(t)
+		run(t)
+	})
+}
+
+func TestRateLimitAllow(t *testing.T) {
+	forEachCounterBackend(t, func(t *testing.T) {
+		ctx := context.Background()
+		s := &RateLimitService{}
+
+		tests := []struct {
+			allowed   bool
+			remaining int
+		}{{true, 2}, {true, 1}, {true, 0}, {false, 0}, {false, 0}}
+		for i, tt := range tests {
+			result := s.Allow(ctx, "ip:10.0.0.1", 3, 60)
+			if result.Allowed != tt.allowed || result.Remaining != tt.remaining || result.Limit != 3 {
+				t.Fatalf("request %d: Allow() = %+v, want allowed %v remaining %d", i+1, result, tt.allowed, tt.remaining)
+			}
+			if result.Reset <= 0 || result.Reset > time.Minute {
+				t.Fatalf("request %d: Reset = %v, want within the window", i+1, result.Reset)
+			}
+		}
+
+		// 不同的键分别计数
+		if result := s.Allow(ctx, "ip:10.0.0.2", 3, 60); !result.Allowed || result.Remaining != 2 {
+			t.Fatalf("Allow(other key) = %+v, want a fresh window", result)
+		}
+		for _, limit := range []int{0, -1} {
+			if result := s.Allow(ctx, "ip:10.0.0.1", limit, 60); !result.Allowed {
+				t.Fatalf("Allow(limit %d) = %+v, want always allowed", limit, result)
+			}
+		}
+	})
+}
+
+func TestLoginLockout(t *testing.T) {
+	previous := global.Config.RateLimit.LoginLockout
+	t.Cleanup(func() { global.Config.RateLimit.LoginLockout = previous })
+	global.Config.RateLimit.LoginLockout = config.LoginLockout{MaxFailures: 3, FailureWindow: 60, Cooldown: 60, MaxCooldown: 240}
+
+	forEachCounterBackend(t, func(t *testing.T) {
+		ctx := context.Background()
+		s := &LoginLockoutService{}
+
+		// 每轮连续失败3次锁定一次，锁定时间翻倍直到上限
+		for round, want := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 4 * time.Minute} {
+			for i := 1; i < 3; i++ {
+				if cooldown := s.RecordFailure(ctx, "alice"); cooldown != 0 {
+					t.Fatalf("round %d failure %d: RecordFailure() = %v, want no lockout", round+1, i, cooldown)
+				}
+			}
+			// 用户名忽略大小写和首尾空格
+			if cooldown := s.RecordFailure(ctx, " Alice "); cooldown != want {
+				t.Fatalf("round %d: RecordFailure() = %v, want %v", round+1, cooldown, want)
+			}
+			if locked := s.Locked(ctx, "alice"); locked <= 0 || locked > want {
+				t.Fatalf("round %d: Locked() = %v, want within %v", round+1, locked, want)
+			}
+		}
+
+		if locked := s.Locked(ctx, "bob"); locked != 0 {
+			t.Fatalf("Locked(bob) = %v, want 0", locked)
+		}
+		s.Reset(ctx, "ALICE")
+		if locked := s.Locked(ctx, "alice"); locked != 0 {
+			t.Fatalf("Locked() after Reset = %v, want 0", locked)
+		}
+		// 清零后重新从首次锁定时间开始
+		for i := 1; i < 3; i++ {
+			s.RecordFailure(ctx, "alice")
+		}
+		if cooldown := s.RecordFailure(ctx, "alice"); cooldown != time.Minute {
+			t.Fatalf("RecordFailure() after Reset = %v, want %v", cooldown, time.Minute)
+		}
+	})
+}
+
+func TestLoginLockoutDisabled(t *testing.T) {
+	previous := global.Config.RateLimit.LoginLockout
+	t.Cleanup(func() { global.Config.RateLimit.LoginLockout = previous })
+	global.Config.RateLimit.LoginLockout = config.LoginLockout{}
+
+	forEachCounterBackend(t, func(t *testing.T) {
+		ctx := context.Background()
+		s := &LoginLockoutService{}
+		for i := 0; i < 10; i++ {
+			if cooldown := s.RecordFailure(ctx, "alice"); cooldown != 0 {
+				t.Fatalf("RecordFailure() = %v, want 0 when disabled", cooldown)
+			}
+		}
+		if locked := s.Locked(ctx, "alice"); locked != 0 {
+			t.Fatalf("Locked() = %v, want 0 when disabled", locked)
+		}
+	})
+}
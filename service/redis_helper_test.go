@@ -0,0 +1,24 @@
+package service
+
+import (
+	"gin_pipeline/global"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// useRedis 启动内存Redis并设置为global.Redis，测试结束后恢复
+// miniredis 会执行服务中的Lua脚本，保证测试覆盖脚本本身的逻辑
+func useRedis(t *testing.T) *miniredis.Miniredis {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	previous := global.Redis
+	global.Redis = client
+	t.Cleanup(func() {
+		global.Redis = previous
+		_ = client.Close()
+	})
+	return server
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"gin_pipeline/global"
+	"gin_pipeline/model"
+	"gin_pipeline/utils"
+	"go.uber.org/zap"
+	"os"
+	"time"
+)
+
+// 调度队列只保存在实例内存中，实例停止后其排队和运行中的运行需要由其他实例（或重启后的自身）接管：
+// 每个运行记录调度它的实例，实例通过Redis心跳表明存活；心跳过期的实例遗留的排队中运行重新入队，
+// 运行中的运行无法恢复执行进度，标记为失败。未启用Redis时按单实例处理，重启前的运行都视为遗留
+
+// schedulerHeartbeatTTL 实例心跳的过期时间，超过后其他实例接管它的运行
+const schedulerHeartbeatTTL = 30 * time.Second
+
+// schedulerInstance 当前实例的标识，每次启动不同
+var schedulerInstance = newSchedulerInstance()
+
+func newSchedulerInstance() string {
+	hostname, _ := os.Hostname()
+	random, err := utils.RandomToken(4)
+	if err != nil {
+		random = fmt.Sprint(time.Now().UnixNano())
+	}
+	return hostname + "-" + random
+}
+
+// SchedulerInstance 当前实例的标识
+func SchedulerInstance() string {
+	return schedulerInstance
+}
+
+func schedulerHeartbeatKey(instance string) string {
+	return "scheduler:instance:" + instance
+}
+
+// heartbeat 刷新当前实例的心跳
+func heartbeat() {
+	if global.Redis == nil {
+		return
+	}
+	if err := global.Redis.Set(context.Background(), schedulerHeartbeatKey(schedulerInstance), 1, schedulerHeartbeatTTL).Err(); err != nil {
+		global.Log.Warn("刷新调度实例心跳失败", zap.Error(err))
+	}
+}
+
+// instanceAlive 实例是否存活，查询失败时视为存活，避免误接管
+func instanceAlive(instance string) bool {
+	if instance == schedulerInstance {
+		return true
+	}
+	if global.Redis == nil || instance == "" {
+		return false
+	}
+	count, err := global.Redis.Exists(context.Background(), schedulerHeartbeatKey(instance)).Result()
+	if err != nil {
+		global.Log.Warn("查询调度实例心跳失败", zap.String("instance", instance), zap.Error(err))
+		return true
+	}
+	return count > 0
+}
+
+// recoverRuns 接管已停止实例遗留的排队中和运行中的运行
+func (s *RunScheduler) recoverRuns() {
+	if global.DB == nil {
+		return
+	}
+
+	var runs []model.PipelineRun
+	if err := global.DB.Where("status IN ? AND instance <> ?", []string{"pending", "running"}, schedulerInstance).
+		Order("id ASC").Limit(100).Find(&runs).Error; err != nil {
+		global.Log.Error("查询遗留的运行失败", zap.Error(err))
+		return
+	}
+
+	alive := make(map[string]bool)
+	for i := range runs {
+		run := &runs[i]
+		if _, ok := alive[run.Instance]; !ok {
+			alive[run.Instance] = instanceAlive(run.Instance)
+		}
+		if alive[run.Instance] {
+			continue
+		}
+
+		// 多个实例同时接管时只有一个能更新成功
+		result := global.DB.Model(&model.PipelineRun{}).
+			Where("id = ? AND instance = ? AND status = ?", run.ID, run.Instance, run.Status).
+			Update("instance", schedulerInstance)
+		if result.Error != nil {
+			global.Log.Error("接管运行失败", zap.Uint("runID", run.ID), zap.Error(result.Error))
+			continue
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		previous := run.Instance
+		run.Instance = schedulerInstance
+		if run.Status == "running" {
+			s.failInterruptedRun(run, previous)
+		} else {
+			s.requeueRun(run)
+		}
+	}
+}
+
+// failInterruptedRun 实例停止时正在执行的运行无法恢复，标记为失败
+func (s *RunScheduler) failInterruptedRun(run *model.PipelineRun, instance string) {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":   "failed",
+		"end_time": now,
+		"logs":     fmt.Sprintf("调度实例 %s 已停止，运行中断", instance),
+	}
+	duration := time.Duration(0)
+	if run.StartTime != nil {
+		duration = now.Sub(*run.StartTime)
+		updates["duration"] = int(duration.Seconds())
+	}
+	result := global.DB.Model(run).Where("status = ?", "running").Updates(updates)
+	if result.Error != nil {
+		global.Log.Error("更新中断的运行失败", zap.Uint("runID", run.ID), zap.Error(result.Error))
+		return
+	}
+	if result.RowsAffected == 0 {
+		return
+	}
+	global.Log.Warn("运行所在的实例已停止，标记为失败", zap.Uint("runID", run.ID), zap.String("instance", instance))
+
+	run.Status = "failed"
+	run.EndTime = &now
+	PublishEvent(newPipelineRunFinished(run, "failed", duration))
+	if err := global.DB.Model(&model.Pipeline{}).Where("id = ?", run.PipelineID).Update("status", "failed").Error; err != nil {
+		global.Log.Error("更新流水线状态失败", zap.Error(err))
+	}
+}
+
+// requeueRun 将遗留的排队中运行重新加入本实例的队列，尚未加载流水线文件的运行重新加载
+func (s *RunScheduler) requeueRun(run *model.PipelineRun) {
+	global.Log.Info("接管遗留的排队运行", zap.Uint("runID", run.ID))
+	runCtx, runSpan := startRunSpan(context.Background(), run.PipelineID, run.TriggerType)
+	runSpan.SetAttributes(runSpanAttributes(run)...)
+	workflowService := NewWorkflowService()
+
+	if len(run.DAGNodes) == 0 && run.DAGSource == DAGSourcePipelineFile {
+		var pipeline model.Pipeline
+		if err := global.DB.First(&pipeline, run.PipelineID).Error; err != nil {
+			workflowService.failPendingRun(run, fmt.Errorf("获取流水线失败: %w", err))
+			endRunSpan(runSpan, "failed", err)
+			return
+		}
+		ref := run.GitRef
+		if ref == "" {
+			ref = run.GitBranch
+		}
+		go workflowService.loadPipelineFile(runCtx, &pipeline, run, ref)
+		return
+	}
+
+	dag := &model.DAG{ID: run.DAGID, PipelineID: run.PipelineID, NodesData: run.DAGNodes}
+	s.Enqueue(runCtx, workflowService, dag, run)
+}
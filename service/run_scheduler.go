@@ -0,0 +1,469 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"gin_pipeline/global"
+	"gin_pipeline/model"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 运行优先级的范围
+const (
+	MinRunPriority = -100
+	MaxRunPriority = 100
+)
+
+// RunScheduler 流水线运行调度器
+// 超出并发上限的运行进入队列，按公平份额和优先级依次出队：
+// 先保证每个流水线（或用户）都能分到一份工作池，再按优先级、已占用数量和入队顺序排序；
+// 同一并发组内的运行依次执行。队列和并发上限都是每个实例独立的，多实例部署时各实例只调度自己触发的运行
+type RunScheduler struct {
+	queue   []*queuedRun
+	running map[uint]*activeRun
+	seq     uint64
+	mutex   sync.Mutex
+	once    sync.Once
+}
+
+// queuedRun 排队中的运行
+type queuedRun struct {
+	run     *model.PipelineRun
+	dag     *model.DAG
+	service *WorkflowService
+	key     string
+	group   string
+	seq     uint64
+	ctx     context.Context // 携带运行的根span
+	wait    trace.Span      // 排队等待的span，出队时结束
+}
+
+// activeRun 运行中的运行
+type activeRun struct {
+	runID      uint
+	pipelineID uint
+	key        string
+	group      string
+	startedAt  time.Time
+	cancel     context.CancelFunc
+}
+
+// RunQueueInfo 排队中运行的位置和预计开始时间
+type RunQueueInfo struct {
+	RunID          uint      `json:"run_id"`
+	PipelineID     uint      `json:"pipeline_id"`
+	TriggerBy      uint      `json:"trigger_by"`
+	Priority       int       `json:"priority"`
+	Position       int       `json:"position"` // 从1开始的出队顺序
+	QueuedAt       time.Time `json:"queued_at"`
+	EstimatedStart time.Time `json:"estimated_start"`
+}
+
+var runScheduler = &RunScheduler{
+	running: make(map[uint]*activeRun),
+}
+
+// GetRunScheduler 获取全局调度器
+func GetRunScheduler() *RunScheduler {
+	return runScheduler
+}
+
+// maxConcurrentRuns 同时运行的流水线数量上限
+func maxConcurrentRuns() int {
+	if n := global.Config.Scheduler.MaxConcurrentRuns; n > 0 {
+		return n
+	}
+	return 10
+}
+
+// fairShareKey 公平调度的分组键
+func fairShareKey(run *model.PipelineRun) string {
+	if global.Config.Scheduler.FairShareBy == "user" {
+		return fmt.Sprintf("user:%d", run.TriggerBy)
+	}
+	return fmt.Sprintf("pipeline:%d", run.PipelineID)
+}
+
+// Start 启动调度循环，并接管已停止实例遗留的运行
+func (s *RunScheduler) Start() {
+	s.once.Do(func() {
+		go s.loop()
+	})
+}
+
+// Enqueue 将运行加入队列，有空闲名额时立即开始
+// ctx 携带运行的根span，运行结束或在队列中被取消时关闭
+func (s *RunScheduler) Enqueue(ctx context.Context, service *WorkflowService, dag *model.DAG, run *model.PipelineRun) {
+	s.Start()
+
+	_, wait := tracer.Start(ctx, "pipeline.queue")
+
+	s.mutex.Lock()
+	s.seq++
+	s.queue = append(s.queue, &queuedRun{
+		run:     run,
+		dag:     dag,
+		service: service,
+		key:     fairShareKey(run),
+		group:   run.ConcurrencyGroup,
+		seq:     s.seq,
+		ctx:     ctx,
+		wait:    wait,
+	})
+	s.mutex.Unlock()
+
+	s.dispatch()
+}
+
+// loop 定期同步其他实例取消的运行，重新调度被并发组阻塞的运行，并接管已停止实例的运行
+func (s *RunScheduler) loop() {
+	heartbeat()
+	s.recoverRuns()
+
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for tick := 1; ; tick++ {
+		<-ticker.C
+		heartbeat()
+		s.syncCanceled()
+		s.dispatch()
+		if tick%6 == 0 {
+			s.recoverRuns()
+		}
+	}
+}
+
+// syncCanceled 取消在数据库中已被标记为取消的运行
+func (s *RunScheduler) syncCanceled() {
+	s.mutex.Lock()
+	ids := make([]uint, 0, len(s.queue)+len(s.running))
+	for _, item := range s.queue {
+		ids = append(ids, item.run.ID)
+	}
+	for id := range s.running {
+		ids = append(ids, id)
+	}
+	s.mutex.Unlock()
+
+	if len(ids) == 0 {
+		return
+	}
+
+	var canceled []uint
+	if err := global.DB.Model(&model.PipelineRun{}).
+		Where("id IN ? AND status = ?", ids, "canceled").
+		Pluck("id", &canceled).Error; err != nil {
+		global.Log.Error("同步取消的运行失败", zap.Error(err))
+		return
+	}
+	for _, id := range canceled {
+		s.Cancel(id)
+	}
+}
+
+// Cancel 取消运行：排队中的直接出队，运行中的取消其上下文
+// 返回运行是否由调度器管理
+func (s *RunScheduler) Cancel(runID uint) bool {
+	s.mutex.Lock()
+	var canceled *queuedRun
+	for i, item := range s.queue {
+		if item.run.ID == runID {
+			s.queue = append(s.queue[:i], s.queue[i+1:]...)
+			canceled = item
+			break
+		}
+	}
+	active, running := s.running[runID]
+	s.mutex.Unlock()
+
+	// 事件和span在锁外处理，订阅者阻塞时不影响调度
+	if canceled != nil {
+		PublishEvent(newPipelineRunFinished(canceled.run, "canceled", 0))
+		canceled.wait.End()
+		endRunSpan(trace.SpanFromContext(canceled.ctx), "canceled", nil)
+		return true
+	}
+	if running {
+		active.cancel()
+		return true
+	}
+	return false
+}
+
+// Stats 返回本实例排队中和运行中的运行数
+func (s *RunScheduler) Stats() (queued, running int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.queue), len(s.running)
+}
+
+// dispatch 在有空闲名额时启动排队的运行
+// 先在锁外查询其他实例上运行中的并发组，再持锁出队，避免数据库查询阻塞调度器
+func (s *RunScheduler) dispatch() {
+	s.mutex.Lock()
+	var groups []string
+	if len(s.running) < maxConcurrentRuns() {
+		for _, item := range s.queue {
+			if item.group != "" {
+				groups = append(groups, item.group)
+			}
+		}
+	}
+	s.mutex.Unlock()
+
+	remote := remoteBusyGroups(groups)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.dispatchLocked(remote)
+}
+
+// dispatchLocked 按其他实例上的并发组状态出队，调用方需持有锁
+// remote 中没有的并发组是查询后才入队的，本轮不调度
+func (s *RunScheduler) dispatchLocked(remote map[string]bool) {
+	limit := maxConcurrentRuns()
+	if len(s.running) >= limit || len(s.queue) == 0 {
+		return
+	}
+
+	busyGroups := make(map[string]bool)
+	for _, active := range s.running {
+		if active.group != "" {
+			busyGroups[active.group] = true
+		}
+	}
+	for len(s.running) < limit {
+		// 同一并发组中已有运行在执行或更早排队的运行不能出队
+		var candidates []*queuedRun
+		queued := make(map[string]bool)
+		for _, item := range s.queue {
+			if item.group != "" {
+				busy, checked := remote[item.group]
+				if !checked || busy || busyGroups[item.group] || queued[item.group] {
+					queued[item.group] = true
+					continue
+				}
+			}
+			candidates = append(candidates, item)
+			if item.group != "" {
+				queued[item.group] = true
+			}
+		}
+		if len(candidates) == 0 {
+			return
+		}
+
+		item := candidates[pickNextRun(candidates, s.runningByKey(), limit)]
+		for i := range s.queue {
+			if s.queue[i] == item {
+				s.queue = append(s.queue[:i], s.queue[i+1:]...)
+				break
+			}
+		}
+		if item.group != "" {
+			busyGroups[item.group] = true
+		}
+
+		item.wait.End()
+		ctx, cancel := context.WithCancel(item.ctx)
+		s.running[item.run.ID] = &activeRun{
+			runID:      item.run.ID,
+			pipelineID: item.run.PipelineID,
+			key:        item.key,
+			group:      item.group,
+			startedAt:  time.Now(),
+			cancel:     cancel,
+		}
+
+		go func(item *queuedRun) {
+			defer s.finish(item.run.ID)
+			item.service.executeWorkflow(ctx, item.dag, item.run)
+		}(item)
+	}
+}
+
+// finish 运行结束后释放名额并调度下一个
+func (s *RunScheduler) finish(runID uint) {
+	s.mutex.Lock()
+	if active, ok := s.running[runID]; ok {
+		active.cancel()
+		delete(s.running, runID)
+	}
+	s.mutex.Unlock()
+
+	s.dispatch()
+}
+
+// remoteBusyGroups 查询并发组是否有运行在执行，包括其他实例上的运行
+// 返回的map包含所有查询的并发组，查询失败时保守处理，本轮不调度这些并发组
+func remoteBusyGroups(groups []string) map[string]bool {
+	busy := make(map[string]bool, len(groups))
+	if len(groups) == 0 {
+		return busy
+	}
+
+	var remote []string
+	err := global.DB.Model(&model.PipelineRun{}).
+		Where("concurrency_group IN ? AND status = ?", groups, "running").
+		Distinct().
+		Pluck("concurrency_group", &remote).Error
+	for _, group := range groups {
+		busy[group] = err != nil
+	}
+	if err != nil {
+		global.Log.Error("查询并发组运行状态失败", zap.Error(err))
+		return busy
+	}
+	for _, group := range remote {
+		busy[group] = true
+	}
+	return busy
+}
+
+// runningByKey 统计各分组正在运行的数量，调用方需持有锁
+func (s *RunScheduler) runningByKey() map[string]int {
+	counts := make(map[string]int)
+	for _, active := range s.running {
+		counts[active.key]++
+	}
+	return counts
+}
+
+// pickNextRun 选出下一个出队的运行
+func pickNextRun(queue []*queuedRun, running map[string]int, limit int) int {
+	// 有运行或排队的分组平分工作池
+	groups := make(map[string]bool)
+	for key := range running {
+		groups[key] = true
+	}
+	for _, item := range queue {
+		groups[item.key] = true
+	}
+	share := (limit + len(groups) - 1) / len(groups)
+	if share < 1 {
+		share = 1
+	}
+
+	best := 0
+	for i := 1; i < len(queue); i++ {
+		if runBefore(queue[i], queue[best], running, share) {
+			best = i
+		}
+	}
+	return best
+}
+
+// runBefore 判断a是否应先于b出队
+func runBefore(a, b *queuedRun, running map[string]int, share int) bool {
+	// 未用完份额的分组优先
+	aUnder, bUnder := running[a.key] < share, running[b.key] < share
+	if aUnder != bUnder {
+		return aUnder
+	}
+	if a.run.Priority != b.run.Priority {
+		return a.run.Priority > b.run.Priority
+	}
+	if running[a.key] != running[b.key] {
+		return running[a.key] < running[b.key]
+	}
+	return a.seq < b.seq
+}
+
+// Queue 按出队顺序返回本实例排队中的运行及预计开始时间，不包括其他实例的队列
+func (s *RunScheduler) Queue() []RunQueueInfo {
+	durations := averageRunDurations()
+	defaultDuration := time.Duration(global.Config.Scheduler.DefaultRunDuration) * time.Second
+	if defaultDuration <= 0 {
+		defaultDuration = 5 * time.Minute
+	}
+	estimate := func(pipelineID uint) time.Duration {
+		if d, ok := durations[pipelineID]; ok {
+			return d
+		}
+		return defaultDuration
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	limit := maxConcurrentRuns()
+	now := time.Now()
+
+	// 每个名额的预计空闲时间
+	slots := make([]time.Time, 0, limit)
+	for _, active := range s.running {
+		end := active.startedAt.Add(estimate(active.pipelineID))
+		if end.Before(now) {
+			end = now
+		}
+		slots = append(slots, end)
+	}
+	for len(slots) < limit {
+		slots = append(slots, now)
+	}
+
+	// 模拟出队过程
+	queue := make([]*queuedRun, len(s.queue))
+	copy(queue, s.queue)
+	running := s.runningByKey()
+	result := make([]RunQueueInfo, 0, len(queue))
+
+	for len(queue) > 0 {
+		index := pickNextRun(queue, running, limit)
+		item := queue[index]
+		queue = append(queue[:index], queue[index+1:]...)
+
+		sort.Slice(slots, func(i, j int) bool { return slots[i].Before(slots[j]) })
+		start := slots[0]
+		slots[0] = start.Add(estimate(item.run.PipelineID))
+		running[item.key]++
+
+		result = append(result, RunQueueInfo{
+			RunID:          item.run.ID,
+			PipelineID:     item.run.PipelineID,
+			TriggerBy:      item.run.TriggerBy,
+			Priority:       item.run.Priority,
+			Position:       len(result) + 1,
+			QueuedAt:       item.run.CreatedAt,
+			EstimatedStart: start,
+		})
+	}
+	return result
+}
+
+// QueueInfo 获取指定运行的排队信息，不在本实例的队列中时返回nil
+func (s *RunScheduler) QueueInfo(runID uint) *RunQueueInfo {
+	for _, info := range s.Queue() {
+		if info.RunID == runID {
+			return &info
+		}
+	}
+	return nil
+}
+
+// averageRunDurations 根据最近成功的运行估算各流水线的运行时长
+func averageRunDurations() map[uint]time.Duration {
+	var rows []struct {
+		PipelineID uint
+		Average    float64
+	}
+	if err := global.DB.Model(&model.PipelineRun{}).
+		Select("pipeline_id, AVG(duration) AS average").
+		Where("status = ? AND duration > 0", "success").
+		Group("pipeline_id").
+		Scan(&rows).Error; err != nil {
+		global.Log.Error("统计流水线运行时长失败", zap.Error(err))
+		return nil
+	}
+
+	durations := make(map[uint]time.Duration, len(rows))
+	for _, row := range rows {
+		durations[row.PipelineID] = time.Duration(row.Average * float64(time.Second))
+	}
+	return durations
+}
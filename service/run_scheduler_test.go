@@ -0,0 +1,109 @@
+package service
+
+import (
+	"gin_pipeline/global"
+	"gin_pipeline/model"
+	"testing"
+)
+
+func TestPickNextRun(t *testing.T) {
+	queued := func(key string, priority int, seq uint64) *queuedRun {
+		return &queuedRun{run: &model.PipelineRun{Priority: priority}, key: key, seq: seq}
+	}
+
+	tests := []struct {
+		name    string
+		queue   []*queuedRun
+		running map[string]int
+		limit   int
+		want    int
+	}{
+		{
+			name:  "single run",
+			queue: []*queuedRun{queued("pipeline:1", 0, 1)},
+			limit: 10,
+			want:  0,
+		},
+		{
+			name:  "same group in enqueue order",
+			queue: []*queuedRun{queued("pipeline:1", 0, 2), queued("pipeline:1", 0, 1)},
+			limit: 10,
+			want:  1,
+		},
+		{
+			name:  "higher priority first",
+			queue: []*queuedRun{queued("pipeline:1", 0, 1), queued("pipeline:2", 5, 2)},
+			limit: 10,
+			want:  1,
+		},
+		{
+			name:    "group over its share waits behind groups under their share",
+			queue:   []*queuedRun{queued("pipeline:1", 10, 1), queued("pipeline:2", 0, 2)},
+			running: map[string]int{"pipeline:1": 2},
+			limit:   4,
+			want:    1,
+		},
+		{
+			name:    "priority wins when every group is under its share",
+			queue:   []*queuedRun{queued("pipeline:1", 0, 1), queued("pipeline:2", 1, 2)},
+			running: map[string]int{"pipeline:1": 0, "pipeline:2": 1},
+			limit:   10,
+			want:    1,
+		},
+		{
+			name:    "fewer running runs first at equal priority",
+			queue:   []*queuedRun{queued("pipeline:1", 0, 1), queued("pipeline:2", 0, 2)},
+			running: map[string]int{"pipeline:1": 2, "pipeline:2": 1},
+			limit:   10,
+			want:    1,
+		},
+		{
+			name:    "groups without queued runs still take a share",
+			queue:   []*queuedRun{queued("pipeline:1", 0, 1), queued("pipeline:2", 0, 2)},
+			running: map[string]int{"pipeline:1": 1, "pipeline:3": 1},
+			limit:   3,
+			want:    1,
+		},
+		{
+			name:    "share is at least one when groups outnumber the limit",
+			queue:   []*queuedRun{queued("pipeline:1", 0, 1), queued("pipeline:2", 0, 2), queued("pipeline:3", 0, 3)},
+			running: map[string]int{"pipeline:1": 1},
+			limit:   1,
+			want:    1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			running := tt.running
+			if running == nil {
+				running = map[string]int{}
+			}
+			if got := pickNextRun(tt.queue, running, tt.limit); got != tt.want {
+				t.Fatalf("pickNextRun() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFairShareKey(t *testing.T) {
+	previous := global.Config.Scheduler
+	t.Cleanup(func() { global.Config.Scheduler = previous })
+
+	run := &model.PipelineRun{PipelineID: 3, TriggerBy: 7}
+	tests := []struct {
+		by   string
+		want string
+	}{
+		{by: "", want: "pipeline:3"},
+		{by: "pipeline", want: "pipeline:3"},
+		{by: "user", want: "user:7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.by, func(t *testing.T) {
+			global.Config.Scheduler.FairShareBy = tt.by
+			if got := fairShareKey(run); got != tt.want {
+				t.Fatalf("fairShareKey() with %q = %q, want %q", tt.by, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,673 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gin_pipeline/global"
+	"gin_pipeline/model"
+	"gin_pipeline/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 远程任务状态
+const (
+	RunnerTaskPending  = "pending"
+	RunnerTaskAssigned = "assigned"
+	RunnerTaskRunning  = "running"
+	RunnerTaskSuccess  = "success"
+	RunnerTaskFailed   = "failed"
+	RunnerTaskCanceled = "canceled"
+)
+
+// placeholderRegistrationToken 示例配置中的注册令牌，未修改时不允许注册
+const placeholderRegistrationToken = "your-runner-registration-token"
+
+// RegistrationTokenConfigured 是否配置了可用的注册令牌，为空或仍为示例值时拒绝注册
+func RegistrationTokenConfigured() bool {
+	token := global.Config.Runner.RegistrationToken
+	return token != "" && token != placeholderRegistrationToken
+}
+
+// RunnerService 远程执行器服务
+type RunnerService struct{}
+
+// taskSignal 任务入队通知，用于唤醒本实例上正在长轮询的执行器
+type taskSignal struct {
+	ch    chan struct{}
+	mutex sync.Mutex
+}
+
+var runnerTaskSignal = &taskSignal{ch: make(chan struct{})}
+
+// wait 获取下一次通知的通道
+func (s *taskSignal) wait() <-chan struct{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.ch
+}
+
+// notify 唤醒所有等待者
+func (s *taskSignal) notify() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	close(s.ch)
+	s.ch = make(chan struct{})
+}
+
+// isTerminalTaskStatus 是否为终止状态
+func isTerminalTaskStatus(status string) bool {
+	switch status {
+	case RunnerTaskSuccess, RunnerTaskFailed, RunnerTaskCanceled:
+		return true
+	}
+	return false
+}
+
+// Register 注册执行器，返回执行器及其访问令牌（令牌只返回这一次）
+func (s *RunnerService) Register(runner *model.Runner) (string, error) {
+	token, err := utils.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	runner.TokenHash = utils.HashToken(token)
+	runner.Status = "online"
+	runner.LastHeartbeatAt = &now
+	if runner.Capacity <= 0 {
+		runner.Capacity = 1
+	}
+
+	if err := global.DB.Create(runner).Error; err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Authenticate 根据访问令牌获取执行器
+func (s *RunnerService) Authenticate(token string) (*model.Runner, error) {
+	if token == "" {
+		return nil, errors.New("缺少执行器令牌")
+	}
+	var runner model.Runner
+	if err := global.DB.Where("token_hash = ?", utils.HashToken(token)).First(&runner).Error; err != nil {
+		return nil, errors.New("执行器令牌无效")
+	}
+	return &runner, nil
+}
+
+// Heartbeat 处理执行器心跳，返回执行器正在运行但已被取消或重新分配的任务
+func (s *RunnerService) Heartbeat(runner *model.Runner, updates map[string]interface{}, runningTaskIDs []uint) ([]uint, error) {
+	now := time.Now()
+	updates["status"] = "online"
+	updates["last_heartbeat_at"] = now
+	if err := global.DB.Model(runner).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+
+	if len(runningTaskIDs) == 0 {
+		return []uint{}, nil
+	}
+
+	// 查询仍然属于该执行器且未终止的任务
+	var active []uint
+	if err := global.DB.Model(&model.RunnerTask{}).
+		Where("id IN ? AND runner_id = ? AND status IN ?", runningTaskIDs, runner.ID,
+			[]string{RunnerTaskAssigned, RunnerTaskRunning}).
+		Pluck("id", &active).Error; err != nil {
+		return nil, err
+	}
+
+	activeSet := make(map[uint]bool, len(active))
+	for _, id := range active {
+		activeSet[id] = true
+	}
+	stopped := []uint{}
+	for _, id := range runningTaskIDs {
+		if !activeSet[id] {
+			stopped = append(stopped, id)
+		}
+	}
+	return stopped, nil
+}
+
+// PollTask 长轮询获取分配给执行器的任务，超时返回nil
+func (s *RunnerService) PollTask(ctx context.Context, runner *model.Runner, wait time.Duration) (*model.RunnerTask, error) {
+	deadline := time.NewTimer(wait)
+	defer deadline.Stop()
+
+	for {
+		signal := runnerTaskSignal.wait()
+
+		task, err := s.claimTask(runner)
+		if err != nil || task != nil {
+			return task, err
+		}
+
+		// 其他实例创建的任务无法通过本地通知唤醒，因此定期重试
+		select {
+		case <-ctx.Done():
+			return nil, nil
+		case <-deadline.C:
+			return nil, nil
+		case <-signal:
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+// claimTask 尝试为执行器认领一个待执行的任务
+func (s *RunnerService) claimTask(runner *model.Runner) (*model.RunnerTask, error) {
+	var candidates []model.RunnerTask
+	if err := global.DB.Where("status = ?", RunnerTaskPending).
+		Order("id ASC").
+		Limit(100).
+		Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	labels := ParseRunnerLabels(runner.Labels)
+	var matched []*model.RunnerTask
+	for i := range candidates {
+		// 只认领标签表达式匹配的任务
+		selector, err := ParseLabelSelector(candidates[i].RunsOn)
+		if err != nil || !selector.Matches(labels) {
+			continue
+		}
+		matched = append(matched, &candidates[i])
+	}
+	if len(matched) == 0 {
+		return nil, nil
+	}
+
+	var claimed *model.RunnerTask
+	err := global.DB.Transaction(func(tx *gorm.DB) error {
+		// 锁定执行器记录，同一执行器的并发轮询(多个连接或多个实例)串行检查容量，避免超额认领
+		var locked model.Runner
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, runner.ID).Error; err != nil {
+			return err
+		}
+		var inFlight int64
+		if err := tx.Model(&model.RunnerTask{}).
+			Where("runner_id = ? AND status IN ?", runner.ID, []string{RunnerTaskAssigned, RunnerTaskRunning}).
+			Count(&inFlight).Error; err != nil {
+			return err
+		}
+		if int(inFlight) >= locked.Capacity {
+			return nil
+		}
+
+		for _, candidate := range matched {
+			task, err := claimCandidate(tx, runner, candidate)
+			if err != nil {
+				return err
+			}
+			if task != nil {
+				claimed = task
+				return nil
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claimed, nil
+}
+
+// claimCandidate 认领单个任务，任务已被其他执行器认领时返回nil
+func claimCandidate(tx *gorm.DB, runner *model.Runner, candidate *model.RunnerTask) (*model.RunnerTask, error) {
+	now := time.Now()
+	// 通过条件更新实现乐观锁，多个执行器同时认领时只有一个成功
+	result := tx.Model(&model.RunnerTask{}).
+		Where("id = ? AND status = ?", candidate.ID, RunnerTaskPending).
+		Updates(map[string]interface{}{
+			"status":             RunnerTaskAssigned,
+			"runner_id":          runner.ID,
+			"assigned_at":        now,
+			"attempts":           gorm.Expr("attempts + 1"),
+			"unscheduled_reason": "",
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected != 1 {
+		return nil, nil
+	}
+	candidate.Status = RunnerTaskAssigned
+	candidate.RunnerID = runner.ID
+	candidate.AssignedAt = &now
+	candidate.Attempts++
+	candidate.UnscheduledReason = ""
+	return candidate, nil
+}
+
+// AppendLogs 追加任务日志，返回任务是否已被取消
+func (s *RunnerService) AppendLogs(runner *model.Runner, taskID uint, logs string) (bool, error) {
+	var task model.RunnerTask
+	if err := global.DB.Where("id = ? AND runner_id = ?", taskID, runner.ID).First(&task).Error; err != nil {
+		return false, errors.New("任务不存在或未分配给该执行器")
+	}
+	if isTerminalTaskStatus(task.Status) || task.Status == RunnerTaskPending {
+		return true, nil
+	}
+
+	updates := map[string]interface{}{}
+	if task.Status == RunnerTaskAssigned {
+		updates["status"] = RunnerTaskRunning
+		updates["started_at"] = time.Now()
+	}
+	if logs != "" {
+		updates["logs"] = gorm.Expr("CONCAT(COALESCE(logs, ''), ?)", logs)
+	}
+	if len(updates) == 0 {
+		return false, nil
+	}
+
+	if err := global.DB.Model(&model.RunnerTask{}).
+		Where("id = ? AND runner_id = ? AND status IN ?", taskID, runner.ID,
+			[]string{RunnerTaskAssigned, RunnerTaskRunning}).
+		Updates(updates).Error; err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+// CompleteTask 上报任务结果
+func (s *RunnerService) CompleteTask(runner *model.Runner, taskID uint, status string, outputs model.JSONMap, errMsg string) error {
+	if status != RunnerTaskSuccess && status != RunnerTaskFailed {
+		return errors.New("无效的任务状态: " + status)
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":      status,
+		"error":       errMsg,
+		"finished_at": now,
+	}
+	if outputs != nil {
+		updates["outputs"] = outputs
+	}
+
+	result := global.DB.Model(&model.RunnerTask{}).
+		Where("id = ? AND runner_id = ? AND status IN ?", taskID, runner.ID,
+			[]string{RunnerTaskAssigned, RunnerTaskRunning}).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("任务不存在、未分配给该执行器或已结束")
+	}
+	return nil
+}
+
+// RequeueStaleRunners 将心跳超时的执行器标记为离线，并重新排队其上的任务
+func (s *RunnerService) RequeueStaleRunners(timeout time.Duration, maxAttempts int) error {
+	threshold := time.Now().Add(-timeout)
+
+	var stale []model.Runner
+	if err := global.DB.Where("status = ? AND last_heartbeat_at < ?", "online", threshold).
+		Find(&stale).Error; err != nil {
+		return err
+	}
+
+	for _, runner := range stale {
+		if err := global.DB.Model(&model.Runner{}).
+			Where("id = ? AND last_heartbeat_at < ?", runner.ID, threshold).
+			Update("status", "offline").Error; err != nil {
+			return err
+		}
+
+		active := []string{RunnerTaskAssigned, RunnerTaskRunning}
+		note := fmt.Sprintf("\n[执行器 %s 心跳超时，任务重新排队]\n", runner.Name)
+
+		// 超过最大分配次数的任务直接失败
+		if maxAttempts > 0 {
+			if err := global.DB.Model(&model.RunnerTask{}).
+				Where("runner_id = ? AND status IN ? AND attempts >= ?", runner.ID, active, maxAttempts).
+				Updates(map[string]interface{}{
+					"status":      RunnerTaskFailed,
+					"error":       "执行器失联且已达到最大重试次数",
+					"finished_at": time.Now(),
+				}).Error; err != nil {
+				return err
+			}
+		}
+
+		result := global.DB.Model(&model.RunnerTask{}).
+			Where("runner_id = ? AND status IN ?", runner.ID, active).
+			Updates(map[string]interface{}{
+				"status":      RunnerTaskPending,
+				"runner_id":   0,
+				"assigned_at": nil,
+				"started_at":  nil,
+				"logs":        gorm.Expr("CONCAT(COALESCE(logs, ''), ?)", note),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		global.Log.Warn("执行器心跳超时",
+			zap.Uint("runnerID", runner.ID),
+			zap.String("name", runner.Name),
+			zap.Int64("requeued", result.RowsAffected))
+		if result.RowsAffected > 0 {
+			runnerTaskSignal.notify()
+		}
+	}
+
+	return nil
+}
+
+// GetRunners 获取执行器列表
+func (s *RunnerService) GetRunners() ([]model.Runner, error) {
+	var runners []model.Runner
+	if err := global.DB.Order("id ASC").Find(&runners).Error; err != nil {
+		return nil, err
+	}
+	return runners, nil
+}
+
+// DeleteRunner 删除执行器，其上未完成的任务重新排队
+func (s *RunnerService) DeleteRunner(id uint) error {
+	var requeued int64
+	err := global.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.RunnerTask{}).
+			Where("runner_id = ? AND status IN ?", id, []string{RunnerTaskAssigned, RunnerTaskRunning}).
+			Updates(map[string]interface{}{
+				"status":      RunnerTaskPending,
+				"runner_id":   0,
+				"assigned_at": nil,
+				"started_at":  nil,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		requeued = result.RowsAffected
+		return tx.Delete(&model.Runner{}, id).Error
+	})
+	if err != nil {
+		return err
+	}
+	// 唤醒正在长轮询的执行器认领重新排队的任务
+	if requeued > 0 {
+		runnerTaskSignal.notify()
+	}
+	return nil
+}
+
+// remoteTaskTypes 分发给远程执行器的任务类型
+func remoteTaskTypes() []string {
+	if len(global.Config.Runner.TaskTypes) > 0 {
+		return global.Config.Runner.TaskTypes
+	}
+	return []string{"shell", "docker"}
+}
+
+// RemoteTaskExecutor 将任务分发给远程执行器执行
+type RemoteTaskExecutor struct{}
+
+// Execute 创建远程任务并等待执行器上报结果
+func (e *RemoteTaskExecutor) Execute(ctx context.Context, task *WorkflowTask) error {
+	remoteTask := model.RunnerTask{
+		RunID:  task.RunID,
+		NodeID: task.ID,
+		Name:   task.Name,
+		Type:   task.Type,
+		Config: task.Config,
+		RunsOn: task.RunsOn,
+		Status: RunnerTaskPending,
+	}
+	if len(task.Env) > 0 {
+		remoteTask.Env = model.JSONMap{}
+		for key, value := range task.Env {
+			remoteTask.Env[key] = value
+		}
+	}
+
+	// 入队前先判断是否有可调度的执行器，便于排查任务长时间排队的原因
+	if capacities, err := loadRunnerCapacities(); err == nil {
+		remoteTask.UnscheduledReason = unscheduledReason(remoteTask.RunsOn, capacities)
+	}
+
+	if err := global.DB.Create(&remoteTask).Error; err != nil {
+		return fmt.Errorf("创建远程任务失败: %w", err)
+	}
+	runnerTaskSignal.notify()
+
+	global.Log.Info("任务已分发给远程执行器",
+		zap.String("taskID", task.ID),
+		zap.Uint("remoteTaskID", remoteTask.ID))
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// 取消远程任务，执行器在下一次心跳或上报日志时得知
+			global.DB.Model(&model.RunnerTask{}).
+				Where("id = ? AND status IN ?", remoteTask.ID,
+					[]string{RunnerTaskPending, RunnerTaskAssigned, RunnerTaskRunning}).
+				Updates(map[string]interface{}{
+					"status":      RunnerTaskCanceled,
+					"finished_at": time.Now(),
+				})
+			return ctx.Err()
+		case <-ticker.C:
+			var current model.RunnerTask
+			if err := global.DB.First(&current, remoteTask.ID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return errors.New("远程任务已被删除")
+				}
+				continue
+			}
+			if !isTerminalTaskStatus(current.Status) {
+				continue
+			}
+
+			task.Logs = current.Logs
+			task.Outputs = current.Outputs
+			switch current.Status {
+			case RunnerTaskSuccess:
+				return nil
+			case RunnerTaskCanceled:
+				return errors.New("远程任务已取消")
+			default:
+				if current.Error != "" {
+					return errors.New(current.Error)
+				}
+				return errors.New("远程任务执行失败")
+			}
+		}
+	}
+}
+
+// runnerCapacity 在线执行器的标签与空闲容量
+type runnerCapacity struct {
+	Runner model.Runner
+	Labels map[string]bool
+	Free   int
+}
+
+// loadRunnerCapacities 获取所有在线执行器及其空闲容量
+func loadRunnerCapacities() ([]runnerCapacity, error) {
+	var runners []model.Runner
+	if err := global.DB.Where("status = ?", "online").Find(&runners).Error; err != nil {
+		return nil, err
+	}
+
+	var counts []struct {
+		RunnerID uint
+		Total    int
+	}
+	if err := global.DB.Model(&model.RunnerTask{}).
+		Select("runner_id, COUNT(*) AS total").
+		Where("status IN ?", []string{RunnerTaskAssigned, RunnerTaskRunning}).
+		Group("runner_id").
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	inFlight := make(map[uint]int, len(counts))
+	for _, count := range counts {
+		inFlight[count.RunnerID] = count.Total
+	}
+
+	capacities := make([]runnerCapacity, 0, len(runners))
+	for _, runner := range runners {
+		free := runner.Capacity - inFlight[runner.ID]
+		if free < 0 {
+			free = 0
+		}
+		capacities = append(capacities, runnerCapacity{
+			Runner: runner,
+			Labels: ParseRunnerLabels(runner.Labels),
+			Free:   free,
+		})
+	}
+	return capacities, nil
+}
+
+// unscheduledReason 计算任务无法被调度的原因，可以调度时返回空字符串
+func unscheduledReason(runsOn string, capacities []runnerCapacity) string {
+	selector, err := ParseLabelSelector(runsOn)
+	if err != nil {
+		return "标签表达式无效: " + err.Error()
+	}
+	if len(capacities) == 0 {
+		return "没有在线的执行器"
+	}
+
+	matched := 0
+	for _, capacity := range capacities {
+		if !selector.Matches(capacity.Labels) {
+			continue
+		}
+		matched++
+		if capacity.Free > 0 {
+			return ""
+		}
+	}
+	if matched == 0 {
+		if selector.String() == "" {
+			return "没有在线的执行器"
+		}
+		return fmt.Sprintf("没有在线的执行器匹配标签 %s", selector.String())
+	}
+	return fmt.Sprintf("匹配的%d个执行器均已满载", matched)
+}
+
+// RefreshUnscheduledReasons 重新计算所有排队任务未被调度的原因
+func (s *RunnerService) RefreshUnscheduledReasons() error {
+	capacities, err := loadRunnerCapacities()
+	if err != nil {
+		return err
+	}
+
+	var pending []model.RunnerTask
+	if err := global.DB.Select("id", "runs_on", "unscheduled_reason").
+		Where("status = ?", RunnerTaskPending).
+		Find(&pending).Error; err != nil {
+		return err
+	}
+
+	for _, task := range pending {
+		reason := unscheduledReason(task.RunsOn, capacities)
+		if reason == task.UnscheduledReason {
+			continue
+		}
+		if err := global.DB.Model(&model.RunnerTask{}).
+			Where("id = ? AND status = ?", task.ID, RunnerTaskPending).
+			Update("unscheduled_reason", reason).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetPendingTasks 获取排队中的远程任务及其未被调度的原因
+func (s *RunnerService) GetPendingTasks() ([]model.RunnerTask, error) {
+	var tasks []model.RunnerTask
+	if err := global.DB.Omit("logs").
+		Where("status = ?", RunnerTaskPending).
+		Order("id ASC").
+		Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// RunnerQueueDepth 某个标签的排队情况
+type RunnerQueueDepth struct {
+	Label         string `json:"label"`          // 标签，未指定runs_on的任务归入 "*"
+	Pending       int    `json:"pending"`        // 需要该标签的排队任务数
+	Unscheduled   int    `json:"unscheduled"`    // 其中当前无法调度的任务数
+	OnlineRunners int    `json:"online_runners"` // 拥有该标签的在线执行器数
+	FreeCapacity  int    `json:"free_capacity"`  // 拥有该标签的在线执行器空闲容量
+}
+
+// GetQueueDepth 按标签统计排队任务数
+func (s *RunnerService) GetQueueDepth() ([]RunnerQueueDepth, error) {
+	capacities, err := loadRunnerCapacities()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []model.RunnerTask
+	if err := global.DB.Select("id", "runs_on", "unscheduled_reason").
+		Where("status = ?", RunnerTaskPending).
+		Find(&pending).Error; err != nil {
+		return nil, err
+	}
+
+	depths := make(map[string]*RunnerQueueDepth)
+	depthOf := func(label string) *RunnerQueueDepth {
+		if depth, ok := depths[label]; ok {
+			return depth
+		}
+		depth := &RunnerQueueDepth{Label: label}
+		for _, capacity := range capacities {
+			if label == "*" || capacity.Labels[label] {
+				depth.OnlineRunners++
+				depth.FreeCapacity += capacity.Free
+			}
+		}
+		depths[label] = depth
+		return depth
+	}
+
+	for _, task := range pending {
+		labels := []string{"*"}
+		if selector, err := ParseLabelSelector(task.RunsOn); err == nil {
+			if required := selector.RequiredLabels(); len(required) > 0 {
+				labels = required
+			}
+		}
+		for _, label := range labels {
+			depth := depthOf(label)
+			depth.Pending++
+			if task.UnscheduledReason != "" {
+				depth.Unscheduled++
+			}
+		}
+	}
+
+	result := make([]RunnerQueueDepth, 0, len(depths))
+	for _, depth := range depths {
+		result = append(result, *depth)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Pending != result[j].Pending {
+			return result[i].Pending > result[j].Pending
+		}
+		return result[i].Label < result[j].Label
+	})
+	return result, nil
+}
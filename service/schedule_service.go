@@ -0,0 +1,265 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"gin_pipeline/global"
+	"gin_pipeline/model"
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+	"time"
+)
+
+// cronParser 标准5段cron表达式解析器，支持 @daily、@every 1h 等描述符
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// ScheduleService 定时触发服务
+type ScheduleService struct{}
+
+// ScheduleDetail 定时任务及其前后触发时间
+type ScheduleDetail struct {
+	model.PipelineSchedule
+	NextFireTimes     []time.Time `json:"next_fire_times"`     // 接下来的触发时间
+	PreviousFireTimes []time.Time `json:"previous_fire_times"` // 最近的触发时间
+}
+
+// ParseCronSchedule 解析cron表达式和时区
+func ParseCronSchedule(expr, timezone string) (cron.Schedule, *time.Location, error) {
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, nil, fmt.Errorf("无效的时区: %s", timezone)
+	}
+	schedule, err := cronParser.Parse(expr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("无效的cron表达式: %v", err)
+	}
+	return schedule, location, nil
+}
+
+// nextFireTime 计算指定时间之后的下次触发时间
+func nextFireTime(schedule *model.PipelineSchedule, after time.Time) (*time.Time, error) {
+	parsed, location, err := ParseCronSchedule(schedule.Cron, schedule.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	next := parsed.Next(after.In(location))
+	if next.IsZero() {
+		return nil, nil
+	}
+	return &next, nil
+}
+
+// CreateSchedule 创建定时任务
+func (s *ScheduleService) CreateSchedule(schedule *model.PipelineSchedule) error {
+	var pipeline model.Pipeline
+	if err := global.DB.First(&pipeline, schedule.PipelineID).Error; err != nil {
+		return err
+	}
+	if _, err := ResolveTriggerParameters(pipeline.Parameters, schedule.Parameters); err != nil {
+		return err
+	}
+
+	next, err := nextFireTime(schedule, time.Now())
+	if err != nil {
+		return err
+	}
+	if schedule.Enabled {
+		schedule.NextFireAt = next
+	}
+	return global.DB.Create(schedule).Error
+}
+
+// GetSchedules 获取流水线的定时任务
+func (s *ScheduleService) GetSchedules(pipelineID uint) ([]ScheduleDetail, error) {
+	var schedules []model.PipelineSchedule
+	if err := global.DB.Where("pipeline_id = ?", pipelineID).
+		Order("id ASC").
+		Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+
+	details := make([]ScheduleDetail, 0, len(schedules))
+	for _, schedule := range schedules {
+		details = append(details, s.detail(schedule, 1))
+	}
+	return details, nil
+}
+
+// GetSchedule 获取定时任务详情
+func (s *ScheduleService) GetSchedule(pipelineID, id uint) (*ScheduleDetail, error) {
+	var schedule model.PipelineSchedule
+	if err := global.DB.Where("pipeline_id = ? AND id = ?", pipelineID, id).
+		First(&schedule).Error; err != nil {
+		return nil, err
+	}
+	detail := s.detail(schedule, 5)
+	return &detail, nil
+}
+
+// detail 计算接下来和最近的触发时间
+func (s *ScheduleService) detail(schedule model.PipelineSchedule, count int) ScheduleDetail {
+	detail := ScheduleDetail{
+		PipelineSchedule:  schedule,
+		NextFireTimes:     []time.Time{},
+		PreviousFireTimes: []time.Time{},
+	}
+
+	if parsed, location, err := ParseCronSchedule(schedule.Cron, schedule.Timezone); err == nil && schedule.Enabled {
+		next := time.Now().In(location)
+		for i := 0; i < count; i++ {
+			next = parsed.Next(next)
+			if next.IsZero() {
+				break
+			}
+			detail.NextFireTimes = append(detail.NextFireTimes, next)
+		}
+	}
+
+	var runs []model.PipelineRun
+	if err := global.DB.Select("created_at").
+		Where("schedule_id = ?", schedule.ID).
+		Order("id DESC").
+		Limit(count).
+		Find(&runs).Error; err == nil {
+		for _, run := range runs {
+			detail.PreviousFireTimes = append(detail.PreviousFireTimes, run.CreatedAt)
+		}
+	}
+	return detail
+}
+
+// UpdateSchedule 更新定时任务
+func (s *ScheduleService) UpdateSchedule(pipelineID, id uint, updates map[string]interface{}) (*model.PipelineSchedule, error) {
+	var schedule model.PipelineSchedule
+	if err := global.DB.Where("pipeline_id = ? AND id = ?", pipelineID, id).
+		First(&schedule).Error; err != nil {
+		return nil, err
+	}
+
+	// 更新前校验新的表达式和时区
+	expr, timezone := schedule.Cron, schedule.Timezone
+	if value, ok := updates["cron"].(string); ok {
+		expr = value
+	}
+	if value, ok := updates["timezone"].(string); ok {
+		timezone = value
+	}
+	if _, _, err := ParseCronSchedule(expr, timezone); err != nil {
+		return nil, err
+	}
+	if parameters, ok := updates["parameters"].(model.JSONMap); ok {
+		var pipeline model.Pipeline
+		if err := global.DB.First(&pipeline, pipelineID).Error; err != nil {
+			return nil, err
+		}
+		if _, err := ResolveTriggerParameters(pipeline.Parameters, parameters); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := global.DB.Model(&schedule).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	if err := global.DB.First(&schedule, schedule.ID).Error; err != nil {
+		return nil, err
+	}
+
+	// 表达式、时区或启用状态变化后重新计算下次触发时间
+	var next *time.Time
+	if schedule.Enabled {
+		var err error
+		if next, err = nextFireTime(&schedule, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	if err := global.DB.Model(&schedule).Update("next_fire_at", next).Error; err != nil {
+		return nil, err
+	}
+	schedule.NextFireAt = next
+	return &schedule, nil
+}
+
+// DeleteSchedule 删除定时任务
+func (s *ScheduleService) DeleteSchedule(pipelineID, id uint) error {
+	result := global.DB.Where("pipeline_id = ? AND id = ?", pipelineID, id).
+		Delete(&model.PipelineSchedule{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("定时任务不存在")
+	}
+	return nil
+}
+
+// FireDueSchedules 触发所有到期的定时任务，错过的多次触发只补触发一次
+func (s *ScheduleService) FireDueSchedules() error {
+	now := time.Now()
+
+	var due []model.PipelineSchedule
+	if err := global.DB.Where("enabled = ? AND next_fire_at IS NOT NULL AND next_fire_at <= ?", true, now).
+		Find(&due).Error; err != nil {
+		return err
+	}
+
+	for i := range due {
+		schedule := &due[i]
+		next, err := nextFireTime(schedule, now)
+		if err != nil {
+			global.Log.Error("计算下次触发时间失败", zap.Uint("scheduleID", schedule.ID), zap.Error(err))
+			continue
+		}
+
+		// 条件更新保证同一次触发只会执行一次
+		fireAt := *schedule.NextFireAt
+		result := global.DB.Model(&model.PipelineSchedule{}).
+			Where("id = ? AND next_fire_at = ?", schedule.ID, fireAt).
+			Updates(map[string]interface{}{
+				"last_fire_at": fireAt,
+				"next_fire_at": next,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		s.fire(schedule)
+	}
+	return nil
+}
+
+// fire 以定时任务创建者的身份触发流水线
+func (s *ScheduleService) fire(schedule *model.PipelineSchedule) {
+	workflowService := NewWorkflowService()
+	run, err := workflowService.TriggerWorkflow(schedule.PipelineID, schedule.CreatorID, TriggerOptions{
+		GitBranch:   schedule.GitBranch,
+		Parameters:  schedule.Parameters,
+		ScheduleID:  schedule.ID,
+		TriggerType: "schedule",
+	})
+
+	updates := map[string]interface{}{"last_error": ""}
+	if err != nil {
+		global.Log.Error("定时触发流水线失败",
+			zap.Uint("scheduleID", schedule.ID),
+			zap.Uint("pipelineID", schedule.PipelineID),
+			zap.Error(err))
+		updates["last_error"] = err.Error()
+	} else {
+		global.Log.Info("定时触发流水线",
+			zap.Uint("scheduleID", schedule.ID),
+			zap.Uint("runID", run.ID))
+		updates["last_run_id"] = run.ID
+	}
+
+	if err := global.DB.Model(&model.PipelineSchedule{}).
+		Where("id = ?", schedule.ID).
+		Updates(updates).Error; err != nil {
+		global.Log.Error("更新定时任务状态失败", zap.Error(err))
+	}
+}
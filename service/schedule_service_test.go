@@ -0,0 +1,65 @@
+package service
+
+import (
+	"gin_pipeline/model"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestParseCronSchedule(t *testing.T) {
+	tests := []struct {
+		expr     string
+		timezone string
+		wantErr  bool
+	}{
+		{expr: "0 2 * * *"},
+		{expr: "*/15 9-18 * * 1-5", timezone: "Asia/Shanghai"},
+		{expr: "@daily"},
+		{expr: "@every 1h30m"},
+		{expr: "0 0 1 JAN *", timezone: "America/New_York"},
+		{expr: "", wantErr: true},
+		{expr: "* * * *", wantErr: true},
+		// 不支持秒字段
+		{expr: "0 0 2 * * *", wantErr: true},
+		{expr: "61 * * * *", wantErr: true},
+		{expr: "@fortnightly", wantErr: true},
+		{expr: "0 2 * * *", timezone: "Mars/Olympus", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr+"|"+tt.timezone, func(t *testing.T) {
+			_, _, err := ParseCronSchedule(tt.expr, tt.timezone)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCronSchedule(%q, %q) error = %v, wantErr %v", tt.expr, tt.timezone, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNextFireTime(t *testing.T) {
+	after := time.Date(2024, 3, 9, 23, 30, 0, 0, time.UTC)
+	tests := []struct {
+		cron     string
+		timezone string
+		want     time.Time
+	}{
+		{cron: "0 2 * * *", want: time.Date(2024, 3, 10, 2, 0, 0, 0, time.UTC)},
+		// 按时区计算：上海时间已是3月10日07:30，下一次为3月11日02:00(UTC 3月10日18:00)
+		{cron: "0 2 * * *", timezone: "Asia/Shanghai", want: time.Date(2024, 3, 10, 18, 0, 0, 0, time.UTC)},
+		{cron: "@hourly", want: time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)},
+		{cron: "0 9 * * 1", want: time.Date(2024, 3, 11, 9, 0, 0, 0, time.UTC)},
+		// 纽约3月10日切换为夏令时，之后按UTC-4计算
+		{cron: "0 12 11 3 *", timezone: "America/New_York", want: time.Date(2024, 3, 11, 16, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cron+"|"+tt.timezone, func(t *testing.T) {
+			got, err := nextFireTime(&model.PipelineSchedule{Cron: tt.cron, Timezone: tt.timezone}, after)
+			if err != nil {
+				t.Fatalf("nextFireTime() error = %v", err)
+			}
+			if got == nil || !got.Equal(tt.want) {
+				t.Fatalf("nextFireTime(%q, %q) = %v, want %v", tt.cron, tt.timezone, got, tt.want.UTC())
+			}
+		})
+	}
+}
@@ -0,0 +1,298 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"gin_pipeline/global"
+	"gin_pipeline/model"
+	"gin_pipeline/utils"
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// RefreshTokenPrefix 刷新令牌的前缀
+const RefreshTokenPrefix = "rt_"
+
+// 会话相关的错误
+var (
+	ErrSessionUnavailable  = errors.New("未启用Redis，会话管理不可用")
+	ErrSessionRevoked      = errors.New("会话已失效，请重新登录")
+	ErrRefreshTokenReused  = errors.New("刷新令牌已被使用，会话已注销，请重新登录")
+	ErrRefreshTokenInvalid = errors.New("刷新令牌无效或已过期")
+)
+
+// takeKeyScript 取出并删除键的值，保证刷新令牌、登录state等一次性凭据只能使用一次
+var takeKeyScript = redis.NewScript(`
+local value = redis.call("GET", KEYS[1])
+if value then
+	redis.call("DEL", KEYS[1])
+end
+return value`)
+
+// Redis键
+func sessionKey(sessionID string) string { return "session:" + sessionID }
+func refreshKey(hash string) string      { return "session:refresh:" + hash }
+func rotatedKey(hash string) string      { return "session:rotated:" + hash }
+func userSessionsKey(userID uint) string {
+	return "session:user:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+// sessionRecord Redis中保存的会话，包含当前刷新令牌的摘要
+type sessionRecord struct {
+	model.Session
+	RefreshHash string `json:"refresh_hash"`
+}
+
+// SessionTokens 登录或刷新后返回的令牌
+type SessionTokens struct {
+	Token            string     `json:"token"`                        // 访问令牌
+	ExpiresAt        time.Time  `json:"expires_at"`                   // 访问令牌过期时间
+	RefreshToken     string     `json:"refresh_token,omitempty"`      // 刷新令牌，每次刷新后更换
+	RefreshExpiresAt *time.Time `json:"refresh_expires_at,omitempty"` // 刷新令牌过期时间
+	SessionID        string     `json:"session_id,omitempty"`
+}
+
+// SessionService 登录会话服务
+// 访问令牌是短期JWT，携带会话ID；刷新令牌保存在Redis中，每次刷新轮换，
+// 已轮换的旧令牌再次使用时视为泄露，注销整个会话
+type SessionService struct{}
+
+// refreshTTL 刷新令牌的有效期
+func (s *SessionService) refreshTTL() time.Duration {
+	if global.Config.System.RefreshExpire > 0 {
+		return time.Duration(global.Config.System.RefreshExpire) * time.Second
+	}
+	return 7 * 24 * time.Hour
+}
+
+// CreateSession 登录后创建会话，未启用Redis时只签发访问令牌
+// 开启多点登录拦截时，新登录会注销该用户的其他会话
+func (s *SessionService) CreateSession(ctx context.Context, user *model.User, device, ip string) (*SessionTokens, error) {
+	if global.Redis == nil {
+		token, err := utils.NewJWT().GenerateToken(user.ID, user.Username, user.Role)
+		if err != nil {
+			return nil, err
+		}
+		return &SessionTokens{Token: token, ExpiresAt: time.Now().Add(utils.TokenExpire(""))}, nil
+	}
+
+	if global.Config.System.UseMultipoint {
+		if _, err := s.RevokeAll(ctx, user.ID, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	sessionID, err := utils.RandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	if len(device) > 255 {
+		device = device[:255]
+	}
+	now := time.Now()
+	record := &sessionRecord{Session: model.Session{
+		ID:            sessionID,
+		UserID:        user.ID,
+		Device:        device,
+		IP:            ip,
+		CreatedAt:     now,
+		LastRefreshAt: now,
+	}}
+	return s.issue(ctx, record, user, "")
+}
+
+// Refresh 使用刷新令牌换取新的访问令牌和刷新令牌，角色等用户信息从数据库重新读取
+func (s *SessionService) Refresh(ctx context.Context, refreshToken, ip string) (*SessionTokens, error) {
+	if global.Redis == nil {
+		return nil, ErrSessionUnavailable
+	}
+	hash := utils.HashToken(refreshToken)
+
+	sessionID, err := takeKeyScript.Run(ctx, global.Redis, []string{refreshKey(hash)}).Text()
+	if errors.Is(err, redis.Nil) {
+		// 已轮换的令牌被再次使用，说明令牌可能已泄露，注销整个会话
+		reusedSessionID, err := global.Redis.Get(ctx, rotatedKey(hash)).Result()
+		if err == nil {
+			if record, err := s.load(ctx, reusedSessionID); err == nil {
+				global.Log.Warn("刷新令牌被重复使用，注销会话",
+					zap.Uint("user_id", record.UserID), zap.String("session_id", reusedSessionID), zap.String("ip", ip))
+				_ = s.remove(ctx, record.UserID, reusedSessionID)
+			}
+			return nil, ErrRefreshTokenReused
+		}
+		return nil, ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := s.load(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	var user model.User
+	if err := global.DB.First(&user, record.UserID).Error; err != nil {
+		_ = s.remove(ctx, record.UserID, sessionID)
+		return nil, errors.New("用户不存在")
+	}
+
+	record.IP = ip
+	record.LastRefreshAt = time.Now()
+	return s.issue(ctx, record, &user, hash)
+}
+
+// issue 为会话签发新的访问令牌和刷新令牌，previousHash 为被轮换的刷新令牌
+func (s *SessionService) issue(ctx context.Context, record *sessionRecord, user *model.User, previousHash string) (*SessionTokens, error) {
+	random, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken := RefreshTokenPrefix + random
+	ttl := s.refreshTTL()
+
+	record.RefreshHash = utils.HashToken(refreshToken)
+	record.ExpiresAt = time.Now().Add(ttl)
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = global.Redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, sessionKey(record.ID), data, ttl)
+		pipe.Set(ctx, refreshKey(record.RefreshHash), record.ID, ttl)
+		pipe.SAdd(ctx, userSessionsKey(record.UserID), record.ID)
+		if previousHash != "" {
+			pipe.Set(ctx, rotatedKey(previousHash), record.ID, ttl)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := utils.NewJWT().GenerateSessionToken(user.ID, user.Username, user.Role, record.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &SessionTokens{
+		Token:            token,
+		ExpiresAt:        time.Now().Add(utils.TokenExpire(record.ID)),
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: &record.ExpiresAt,
+		SessionID:        record.ID,
+	}, nil
+}
+
+// load 读取会话
+func (s *SessionService) load(ctx context.Context, sessionID string) (*sessionRecord, error) {
+	data, err := global.Redis.Get(ctx, sessionKey(sessionID)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrSessionRevoked
+	}
+	if err != nil {
+		return nil, err
+	}
+	var record sessionRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// remove 删除会话和它当前的刷新令牌
+func (s *SessionService) remove(ctx context.Context, userID uint, sessionID string) error {
+	keys := []string{sessionKey(sessionID)}
+	if record, err := s.load(ctx, sessionID); err == nil {
+		keys = append(keys, refreshKey(record.RefreshHash))
+	}
+	_, err := global.Redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, keys...)
+		pipe.SRem(ctx, userSessionsKey(userID), sessionID)
+		return nil
+	})
+	return err
+}
+
+// Check 检查访问令牌所属的会话是否仍然有效
+// 未启用Redis时无法校验，视为有效
+func (s *SessionService) Check(ctx context.Context, sessionID string) error {
+	if global.Redis == nil {
+		return nil
+	}
+	count, err := global.Redis.Exists(ctx, sessionKey(sessionID)).Result()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrSessionRevoked
+	}
+	return nil
+}
+
+// GetSessions 获取用户的有效会话，按最近刷新时间倒序
+func (s *SessionService) GetSessions(ctx context.Context, userID uint, currentSessionID string) ([]model.Session, error) {
+	if global.Redis == nil {
+		return nil, ErrSessionUnavailable
+	}
+	sessionIDs, err := global.Redis.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]model.Session, 0, len(sessionIDs))
+	for _, sessionID := range sessionIDs {
+		record, err := s.load(ctx, sessionID)
+		if errors.Is(err, ErrSessionRevoked) {
+			// 会话已过期，顺便清理索引
+			global.Redis.SRem(ctx, userSessionsKey(userID), sessionID)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		record.Current = record.ID == currentSessionID
+		sessions = append(sessions, record.Session)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastRefreshAt.After(sessions[j].LastRefreshAt)
+	})
+	return sessions, nil
+}
+
+// Revoke 注销用户的一个会话
+func (s *SessionService) Revoke(ctx context.Context, userID uint, sessionID string) error {
+	if global.Redis == nil {
+		return ErrSessionUnavailable
+	}
+	record, err := s.load(ctx, sessionID)
+	if err != nil || record.UserID != userID {
+		return errors.New("会话不存在")
+	}
+	return s.remove(ctx, userID, sessionID)
+}
+
+// RevokeAll 注销用户的所有会话，exceptSessionID 不为空时保留该会话，返回注销的会话数
+func (s *SessionService) RevokeAll(ctx context.Context, userID uint, exceptSessionID string) (int, error) {
+	if global.Redis == nil {
+		return 0, ErrSessionUnavailable
+	}
+	sessionIDs, err := global.Redis.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return 0, err
+	}
+	revoked := 0
+	for _, sessionID := range sessionIDs {
+		if sessionID == exceptSessionID {
+			continue
+		}
+		if err := s.remove(ctx, userID, sessionID); err != nil {
+			return revoked, err
+		}
+		revoked++
+	}
+	return revoked, nil
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"gin_pipeline/global"
+	"gin_pipeline/model"
+	"gin_pipeline/utils"
+	"testing"
+	"time"
+)
+
+func useSessionConfig(t *testing.T) {
+	t.Helper()
+	previous := global.Config.System
+	t.Cleanup(func() { global.Config.System = previous })
+	global.Config.System.JwtSecret = "test-secret"
+	global.Config.System.JwtExpire = 86400
+	global.Config.System.AccessExpire = 900
+	global.Config.System.UseMultipoint = false
+}
+
+// rotate 模拟一次成功的刷新：取出旧刷新令牌并签发新令牌
+// Refresh 需要从数据库读取用户，这里直接调用 issue
+func rotate(t *testing.T, s *SessionService, tokens *SessionTokens, user *model.User) *SessionTokens {
+	t.Helper()
+	ctx := context.Background()
+	hash := utils.HashToken(tokens.RefreshToken)
+	if err := global.Redis.Del(ctx, refreshKey(hash)).Err(); err != nil {
+		t.Fatalf("del refresh key: %v", err)
+	}
+	record, err := s.load(ctx, tokens.SessionID)
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	rotated, err := s.issue(ctx, record, user, hash)
+	if err != nil {
+		t.Fatalf("issue() error = %v", err)
+	}
+	return rotated
+}
+
+func TestSessionCreateAndCheck(t *testing.T) {
+	useSessionConfig(t)
+	useRedis(t)
+	ctx := context.Background()
+	s := &SessionService{}
+	user := &model.User{ID: 7, Username: "alice", Role: "admin"}
+
+	tokens, err := s.CreateSession(ctx, user, "curl", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+	if tokens.SessionID == "" || tokens.RefreshToken == "" || tokens.RefreshExpiresAt == nil {
+		t.Fatalf("CreateSession() = %+v, want a session with a refresh token", tokens)
+	}
+	claims, err := utils.NewJWT().ParseToken(tokens.Token)
+	if err != nil {
+		t.Fatalf("ParseToken() error = %v", err)
+	}
+	if claims.SessionID != tokens.SessionID || claims.ID != user.ID {
+		t.Fatalf("claims = %+v, want session %s of user %d", claims, tokens.SessionID, user.ID)
+	}
+	// 可刷新的会话使用较短的access_expire
+	assertExpiresIn(t, claims.ExpiresAt, 900*time.Second)
+	if err := s.Check(ctx, tokens.SessionID); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if err := s.Check(ctx, "missing"); !errors.Is(err, ErrSessionRevoked) {
+		t.Fatalf("Check(missing) error = %v, want ErrSessionRevoked", err)
+	}
+
+	sessions, err := s.GetSessions(ctx, user.ID, tokens.SessionID)
+	if err != nil {
+		t.Fatalf("GetSessions() error = %v", err)
+	}
+	if len(sessions) != 1 || !sessions[0].Current {
+		t.Fatalf("GetSessions() = %+v, want the current session", sessions)
+	}
+
+	if err := s.Revoke(ctx, user.ID+1, tokens.SessionID); err == nil {
+		t.Fatal("Revoke() by another user succeeded")
+	}
+	if err := s.Revoke(ctx, user.ID, tokens.SessionID); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if err := s.Check(ctx, tokens.SessionID); !errors.Is(err, ErrSessionRevoked) {
+		t.Fatalf("Check() after revoke error = %v, want ErrSessionRevoked", err)
+	}
+	if _, err := s.Refresh(ctx, tokens.RefreshToken, "127.0.0.1"); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Fatalf("Refresh() after revoke error = %v, want ErrRefreshTokenInvalid", err)
+	}
+}
+
+// assertExpiresIn 校验令牌的剩余有效期
+func assertExpiresIn(t *testing.T, expiresAt int64, want time.Duration) {
+	t.Helper()
+	if got := time.Until(time.Unix(expiresAt, 0)); got > want || got < want-time.Minute {
+		t.Fatalf("token expires in %v, want %v", got, want)
+	}
+}
+
+func TestSessionRefreshTokenReuse(t *testing.T) {
+	useSessionConfig(t)
+	useRedis(t)
+	ctx := context.Background()
+	s := &SessionService{}
+	user := &model.User{ID: 7, Username: "alice", Role: "user"}
+
+	first, err := s.CreateSession(ctx, user, "", "")
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+	second := rotate(t, s, first, user)
+	if second.SessionID != first.SessionID || second.RefreshToken == first.RefreshToken {
+		t.Fatalf("rotation = %+v, want a new refresh token for the same session", second)
+	}
+
+	if _, err := s.Refresh(ctx, "rt_unknown", ""); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Fatalf("Refresh(unknown) error = %v, want ErrRefreshTokenInvalid", err)
+	}
+	// 旧令牌被重复使用时注销整个会话，新令牌也随之失效
+	if _, err := s.Refresh(ctx, first.RefreshToken, ""); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("Refresh(rotated) error = %v, want ErrRefreshTokenReused", err)
+	}
+	if err := s.Check(ctx, first.SessionID); !errors.Is(err, ErrSessionRevoked) {
+		t.Fatalf("Check() after reuse error = %v, want ErrSessionRevoked", err)
+	}
+	if _, err := s.Refresh(ctx, second.RefreshToken, ""); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Fatalf("Refresh(current) after reuse error = %v, want ErrRefreshTokenInvalid", err)
+	}
+}
+
+func TestSessionRevokeAll(t *testing.T) {
+	useSessionConfig(t)
+	useRedis(t)
+	ctx := context.Background()
+	s := &SessionService{}
+	user := &model.User{ID: 7, Username: "alice"}
+
+	var sessionIDs []string
+	for i := 0; i < 3; i++ {
+		tokens, err := s.CreateSession(ctx, user, "", "")
+		if err != nil {
+			t.Fatalf("CreateSession() error = %v", err)
+		}
+		sessionIDs = append(sessionIDs, tokens.SessionID)
+	}
+
+	revoked, err := s.RevokeAll(ctx, user.ID, sessionIDs[0])
+	if err != nil || revoked != 2 {
+		t.Fatalf("RevokeAll() = %d, %v, want 2", revoked, err)
+	}
+	for i, sessionID := range sessionIDs {
+		err := s.Check(ctx, sessionID)
+		if wantValid := i == 0; (err == nil) != wantValid {
+			t.Errorf("Check(%s) error = %v, want valid %v", sessionID, err, wantValid)
+		}
+	}
+
+	// 开启多点登录拦截时，新登录注销其他会话
+	global.Config.System.UseMultipoint = true
+	latest, err := s.CreateSession(ctx, user, "", "")
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+	if err := s.Check(ctx, sessionIDs[0]); !errors.Is(err, ErrSessionRevoked) {
+		t.Fatalf("Check(previous) error = %v, want ErrSessionRevoked", err)
+	}
+	if err := s.Check(ctx, latest.SessionID); err != nil {
+		t.Fatalf("Check(latest) error = %v", err)
+	}
+}
+
+func TestSessionWithoutRedis(t *testing.T) {
+	useSessionConfig(t)
+	previous := global.Redis
+	global.Redis = nil
+	t.Cleanup(func() { global.Redis = previous })
+	ctx := context.Background()
+	s := &SessionService{}
+	user := &model.User{ID: 7, Username: "alice"}
+
+	tokens, err := s.CreateSession(ctx, user, "", "")
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+	if tokens.RefreshToken != "" || tokens.SessionID != "" {
+		t.Fatalf("CreateSession() = %+v, want only an access token", tokens)
+	}
+	claims, err := utils.NewJWT().ParseToken(tokens.Token)
+	if err != nil || claims.SessionID != "" {
+		t.Fatalf("ParseToken() = %+v, %v, want a token without session", claims, err)
+	}
+	// 无法刷新的令牌使用jwt_expire
+	assertExpiresIn(t, claims.ExpiresAt, 86400*time.Second)
+	if err := s.Check(ctx, "anything"); err != nil {
+		t.Fatalf("Check() error = %v, want nil", err)
+	}
+	if _, err := s.Refresh(ctx, "rt_x", ""); !errors.Is(err, ErrSessionUnavailable) {
+		t.Fatalf("Refresh() error = %v, want ErrSessionUnavailable", err)
+	}
+	if _, err := s.GetSessions(ctx, user.ID, ""); !errors.Is(err, ErrSessionUnavailable) {
+		t.Fatalf("GetSessions() error = %v, want ErrSessionUnavailable", err)
+	}
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"gin_pipeline/global"
+	"gin_pipeline/model"
+	"gorm.io/gorm"
+	"sort"
+)
+
+// CompileStages 将流水线的阶段/作业模型编译为DAG节点
+// 同一阶段内的作业并行执行，阶段之间按顺序执行：
+// 每个作业依赖上一阶段的全部作业
+func CompileStages(stages []model.Stage) ([]model.DAGNode, error) {
+	if len(stages) == 0 {
+		return nil, errors.New("流水线没有定义阶段")
+	}
+
+	// 按阶段顺序排序，顺序相同时按ID排序
+	sorted := make([]model.Stage, len(stages))
+	copy(sorted, stages)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Order == sorted[j].Order {
+			return sorted[i].ID < sorted[j].ID
+		}
+		return sorted[i].Order < sorted[j].Order
+	})
+
+	var nodes []model.DAGNode
+	var previous []string
+	for stageIndex, stage := range sorted {
+		if len(stage.Jobs) == 0 {
+			// 空阶段不产生节点，也不阻断前后阶段的依赖
+			continue
+		}
+
+		var current []string
+		for jobIndex, job := range stage.Jobs {
+			nodeID := fmt.Sprintf("job-%d", job.ID)
+
+			// 指定了镜像的作业在容器中执行
+			taskType := "shell"
+			if job.Image != "" {
+				taskType = "docker"
+			}
+
+			config := model.JSONMap{
+				"command": job.Command,
+				"stage":   stage.Name,
+			}
+			if job.Image != "" {
+				config["image"] = job.Image
+			}
+			if job.Timeout > 0 {
+				config["timeout"] = job.Timeout
+			}
+
+			dependencies := make([]string, len(previous))
+			copy(dependencies, previous)
+
+			nodes = append(nodes, model.DAGNode{
+				ID:           nodeID,
+				Name:         stage.Name + "/" + job.Name,
+				Type:         taskType,
+				Config:       config,
+				Dependencies: dependencies,
+				RunsOn:       job.RunsOn,
+				Position: model.JSONMap{
+					"x": stageIndex * 240,
+					"y": jobIndex * 120,
+				},
+			})
+			current = append(current, nodeID)
+		}
+		previous = current
+	}
+
+	if len(nodes) == 0 {
+		return nil, errors.New("流水线的阶段中没有作业")
+	}
+
+	return nodes, nil
+}
+
+// loadPipelineStages 加载流水线的阶段及作业
+func loadPipelineStages(pipelineID uint) ([]model.Stage, error) {
+	var stages []model.Stage
+	if err := global.DB.Where("pipeline_id = ?", pipelineID).
+		Preload("Jobs", func(db *gorm.DB) *gorm.DB {
+			return db.Order("id ASC")
+		}).
+		Order("`order` ASC, id ASC").
+		Find(&stages).Error; err != nil {
+		return nil, err
+	}
+	return stages, nil
+}
+
+// CompilePipelineStages 将指定流水线的阶段编译为临时DAG（不保存）
+func CompilePipelineStages(pipelineID uint) (*model.DAG, error) {
+	stages, err := loadPipelineStages(pipelineID)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := CompileStages(stages)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.DAG{
+		Name:       "stages",
+		PipelineID: pipelineID,
+		NodesData:  nodes,
+	}, nil
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"gin_pipeline/model"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer 流水线运行的链路追踪，未启用时为空实现
+var tracer = otel.Tracer("gin_pipeline/service")
+
+// 链路追踪属性
+const (
+	attrPipelineID  = attribute.Key("pipeline.id")
+	attrRunID       = attribute.Key("pipeline.run.id")
+	attrTriggerType = attribute.Key("pipeline.trigger_type")
+	attrRunStatus   = attribute.Key("pipeline.run.status")
+	attrDAGSource   = attribute.Key("pipeline.dag_source")
+	attrNodeID      = attribute.Key("pipeline.node.id")
+	attrNodeName    = attribute.Key("pipeline.node.name")
+	attrNodeType    = attribute.Key("pipeline.node.type")
+	attrTaskStatus  = attribute.Key("pipeline.task.status")
+)
+
+// startRunSpan 为一次运行创建新链路的根span，并链接到触发它的请求
+// 运行在触发请求返回后才异步执行，因此不作为请求链路的子span
+func startRunSpan(parent context.Context, pipelineID uint, triggerType string) (context.Context, trace.Span) {
+	if parent == nil {
+		parent = context.Background()
+	}
+	return tracer.Start(context.Background(), "pipeline.run",
+		trace.WithNewRoot(),
+		trace.WithLinks(trace.LinkFromContext(parent)),
+		trace.WithAttributes(attrPipelineID.Int64(int64(pipelineID)), attrTriggerType.String(triggerType)))
+}
+
+// runSpanAttributes 运行记录的链路属性
+func runSpanAttributes(run *model.PipelineRun) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attrPipelineID.Int64(int64(run.PipelineID)),
+		attrRunID.Int64(int64(run.ID)),
+		attrTriggerType.String(run.TriggerType),
+		attrDAGSource.String(run.DAGSource),
+	}
+}
+
+// traceIDFromContext 返回上下文中的链路ID，未采样或未启用时为空
+func traceIDFromContext(ctx context.Context) string {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() || !spanContext.IsSampled() {
+		return ""
+	}
+	return spanContext.TraceID().String()
+}
+
+// endSpan 结束span，出错时记录错误
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// endRunSpan 以运行的最终状态结束运行的根span
+func endRunSpan(span trace.Span, status string, err error) {
+	span.SetAttributes(attrRunStatus.String(status))
+	if err == nil && status == "failed" {
+		span.SetStatus(codes.Error, "运行失败")
+	}
+	endSpan(span, err)
+}
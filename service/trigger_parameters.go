@@ -0,0 +1,240 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"gin_pipeline/model"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 参数类型
+const (
+	ParamString  = "string"
+	ParamNumber  = "number"
+	ParamBoolean = "boolean"
+	ParamChoice  = "choice"
+)
+
+// paramNamePattern 参数名格式，同时需要作为环境变量名的一部分
+var paramNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// paramPlaceholder 节点配置中的参数占位符，如 ${{ params.env }}
+var paramPlaceholder = regexp.MustCompile(`\$\{\{\s*params\.([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// ValidatePipelineParameters 校验流水线的参数声明
+func ValidatePipelineParameters(params []model.PipelineParameter) error {
+	seen := make(map[string]string, len(params))
+	for _, param := range params {
+		if !paramNamePattern.MatchString(param.Name) {
+			return fmt.Errorf("参数名 %q 只能包含字母、数字和下划线，且不能以数字开头", param.Name)
+		}
+		// 参数转换为环境变量时名称转为大写，只有大小写不同的参数会互相覆盖
+		key := strings.ToUpper(param.Name)
+		if previous, exists := seen[key]; exists {
+			if previous == param.Name {
+				return fmt.Errorf("参数 %s 重复声明", param.Name)
+			}
+			return fmt.Errorf("参数 %s 与 %s 只有大小写不同，对应相同的环境变量 PARAM_%s", param.Name, previous, key)
+		}
+		seen[key] = param.Name
+
+		switch param.Type {
+		case ParamString, ParamNumber, ParamBoolean:
+		case ParamChoice:
+			if len(param.Options) == 0 {
+				return fmt.Errorf("choice参数 %s 必须声明可选值", param.Name)
+			}
+		default:
+			return fmt.Errorf("参数 %s 的类型 %q 无效，可选 string、number、boolean、choice", param.Name, param.Type)
+		}
+
+		if param.Default != nil {
+			if _, err := coerceParameter(param, param.Default); err != nil {
+				return fmt.Errorf("参数 %s 的默认值无效: %v", param.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ResolveTriggerParameters 根据参数声明校验触发时提供的参数值，并补全默认值
+func ResolveTriggerParameters(params []model.PipelineParameter, supplied map[string]interface{}) (model.JSONMap, error) {
+	declared := make(map[string]bool, len(params))
+	for _, param := range params {
+		declared[param.Name] = true
+	}
+
+	var unknown []string
+	for name := range supplied {
+		if !declared[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("未声明的参数: %s", strings.Join(unknown, ", "))
+	}
+
+	resolved := model.JSONMap{}
+	for _, param := range params {
+		value, ok := supplied[param.Name]
+		if !ok || value == nil {
+			value = param.Default
+		}
+		if value == nil {
+			if param.Required {
+				return nil, fmt.Errorf("缺少必填参数: %s", param.Name)
+			}
+			continue
+		}
+
+		coerced, err := coerceParameter(param, value)
+		if err != nil {
+			return nil, fmt.Errorf("参数 %s 无效: %v", param.Name, err)
+		}
+		resolved[param.Name] = coerced
+	}
+	return resolved, nil
+}
+
+// coerceParameter 将参数值转换为声明的类型，字符串形式的数字和布尔值也被接受
+func coerceParameter(param model.PipelineParameter, value interface{}) (interface{}, error) {
+	switch param.Type {
+	case ParamNumber:
+		if s, ok := value.(string); ok {
+			n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+			if err != nil {
+				return nil, fmt.Errorf("%q 不是数字", s)
+			}
+			return n, nil
+		}
+		if n, ok := value.(json.Number); ok {
+			return n.Float64()
+		}
+		if n, ok := toFloat(value); ok {
+			return n, nil
+		}
+		return nil, fmt.Errorf("期望数字")
+	case ParamBoolean:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			b, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("%q 不是布尔值", v)
+			}
+			return b, nil
+		}
+		return nil, fmt.Errorf("期望布尔值")
+	case ParamChoice:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("期望字符串")
+		}
+		for _, option := range param.Options {
+			if option == s {
+				return s, nil
+			}
+		}
+		return nil, fmt.Errorf("%q 不在可选值 %v 中", s, param.Options)
+	default:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("期望字符串")
+		}
+		return s, nil
+	}
+}
+
+// formatParameter 将参数值格式化为字符串
+func formatParameter(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return fmt.Sprint(value)
+}
+
+// ParameterEnv 将参数转换为任务环境变量，如 env -> PARAM_ENV
+// 声明时已拒绝只有大小写不同的参数，历史数据中仍存在时按名称排序后者覆盖前者，保证结果稳定
+func ParameterEnv(values map[string]interface{}) map[string]string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	env := make(map[string]string, len(values))
+	for _, name := range names {
+		env["PARAM_"+strings.ToUpper(name)] = formatParameter(values[name])
+	}
+	return env
+}
+
+// InterpolateParameters 替换节点配置中的参数占位符
+// 整个字符串只是一个占位符时保留参数的原始类型，否则按字符串拼接
+func InterpolateParameters(config map[string]interface{}, values map[string]interface{}) map[string]interface{} {
+	if len(values) == 0 || config == nil {
+		return config
+	}
+	result, _ := interpolateValue(config, paramPlaceholder, values).(map[string]interface{})
+	return result
+}
+
+// hasPlaceholder 文本中是否包含参数或片段输入占位符
+func hasPlaceholder(text string) bool {
+	return paramPlaceholder.MatchString(text) || inputPlaceholder.MatchString(text)
+}
+
+// isWholePlaceholder 文本是否整体为一个占位符，替换时保留参数值的类型
+func isWholePlaceholder(text string) bool {
+	for _, placeholder := range []*regexp.Regexp{paramPlaceholder, inputPlaceholder} {
+		if match := placeholder.FindString(text); match != "" && match == text {
+			return true
+		}
+	}
+	return false
+}
+
+// interpolateValue 递归替换占位符，placeholder的第一个分组为名称
+func interpolateValue(value interface{}, placeholder *regexp.Regexp, values map[string]interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if match := placeholder.FindStringSubmatch(v); match != nil && match[0] == v {
+			if param, ok := values[match[1]]; ok {
+				return param
+			}
+		}
+		return placeholder.ReplaceAllStringFunc(v, func(text string) string {
+			name := placeholder.FindStringSubmatch(text)[1]
+			if param, ok := values[name]; ok {
+				return formatParameter(param)
+			}
+			// 未提供的参数替换为空字符串
+			return ""
+		})
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[key] = interpolateValue(item, placeholder, values)
+		}
+		return result
+	case model.JSONMap:
+		return interpolateValue(map[string]interface{}(v), placeholder, values)
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = interpolateValue(item, placeholder, values)
+		}
+		return result
+	}
+	return value
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"gin_pipeline/model"
+	"reflect"
+	"testing"
+)
+
+func TestValidatePipelineParameters(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  []model.PipelineParameter
+		wantErr bool
+	}{
+		{name: "empty"},
+		{
+			name: "valid",
+			params: []model.PipelineParameter{
+				{Name: "env", Type: ParamChoice, Options: []string{"dev", "prod"}, Default: "dev"},
+				{Name: "replicas", Type: ParamNumber, Default: "3"},
+				{Name: "dry_run", Type: ParamBoolean},
+				{Name: "Tag", Type: ParamString},
+			},
+		},
+		{name: "invalid name", params: []model.PipelineParameter{{Name: "1st", Type: ParamString}}, wantErr: true},
+		{name: "name with dash", params: []model.PipelineParameter{{Name: "image-tag", Type: ParamString}}, wantErr: true},
+		{
+			name:    "duplicate",
+			params:  []model.PipelineParameter{{Name: "env", Type: ParamString}, {Name: "env", Type: ParamString}},
+			wantErr: true,
+		},
+		{
+			// 两者都对应 PARAM_ENV
+			name:    "differ only in case",
+			params:  []model.PipelineParameter{{Name: "env", Type: ParamString}, {Name: "Env", Type: ParamString}},
+			wantErr: true,
+		},
+		{name: "unknown type", params: []model.PipelineParameter{{Name: "env", Type: "date"}}, wantErr: true},
+		{name: "choice without options", params: []model.PipelineParameter{{Name: "env", Type: ParamChoice}}, wantErr: true},
+		{
+			name:    "default outside options",
+			params:  []model.PipelineParameter{{Name: "env", Type: ParamChoice, Options: []string{"dev"}, Default: "prod"}},
+			wantErr: true,
+		},
+		{name: "bad number default", params: []model.PipelineParameter{{Name: "n", Type: ParamNumber, Default: "many"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidatePipelineParameters(tt.params); (err != nil) != tt.wantErr {
+				t.Fatalf("ValidatePipelineParameters() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResolveTriggerParameters(t *testing.T) {
+	params := []model.PipelineParameter{
+		{Name: "env", Type: ParamChoice, Options: []string{"dev", "prod"}, Default: "dev"},
+		{Name: "replicas", Type: ParamNumber},
+		{Name: "dry_run", Type: ParamBoolean, Default: false},
+		{Name: "version", Type: ParamString, Required: true},
+	}
+	tests := []struct {
+		name     string
+		supplied map[string]interface{}
+		want     model.JSONMap
+		wantErr  bool
+	}{
+		{
+			name:     "defaults filled and strings coerced",
+			supplied: map[string]interface{}{"version": "1.2.0", "replicas": "3", "dry_run": "true"},
+			want:     model.JSONMap{"env": "dev", "replicas": float64(3), "dry_run": true, "version": "1.2.0"},
+		},
+		{name: "missing required", supplied: map[string]interface{}{"env": "prod"}, wantErr: true},
+		{name: "undeclared", supplied: map[string]interface{}{"version": "1", "ENV": "prod"}, wantErr: true},
+		{name: "choice outside options", supplied: map[string]interface{}{"version": "1", "env": "staging"}, wantErr: true},
+		{name: "not a number", supplied: map[string]interface{}{"version": "1", "replicas": "three"}, wantErr: true},
+		{name: "string expected", supplied: map[string]interface{}{"version": 1.0}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveTriggerParameters(params, tt.supplied)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveTriggerParameters() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ResolveTriggerParameters() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInterpolateParameters(t *testing.T) {
+	values := map[string]interface{}{"env": "prod", "replicas": float64(3), "debug": true}
+	config := map[string]interface{}{
+		"command":  "./deploy.sh ${{ params.env }} --replicas=${{params.replicas}}",
+		"replicas": "${{ params.replicas }}",
+		"debug":    "${{ params.debug }}",
+		"missing":  "x${{ params.none }}y",
+		"args":     []interface{}{"${{ params.env }}", 1.0},
+		"nested":   map[string]interface{}{"target": "${{ params.env }}"},
+	}
+	want := map[string]interface{}{
+		"command":  "./deploy.sh prod --replicas=3",
+		"replicas": float64(3),
+		"debug":    true,
+		"missing":  "xy",
+		"args":     []interface{}{"prod", 1.0},
+		"nested":   map[string]interface{}{"target": "prod"},
+	}
+	if got := InterpolateParameters(config, values); !reflect.DeepEqual(got, want) {
+		t.Fatalf("InterpolateParameters() = %v, want %v", got, want)
+	}
+}
+
+func TestParameterEnv(t *testing.T) {
+	got := ParameterEnv(map[string]interface{}{"env": "prod", "replicas": float64(3), "dry_run": false})
+	want := map[string]string{"PARAM_ENV": "prod", "PARAM_REPLICAS": "3", "PARAM_DRY_RUN": "false"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ParameterEnv() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,388 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gin_pipeline/global"
+	"gin_pipeline/model"
+	"gin_pipeline/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm/clause"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Webhook投递状态
+const (
+	WebhookTriggered = "triggered"
+	WebhookIgnored   = "ignored"
+	WebhookFailed    = "failed"
+)
+
+// ErrWebhookSignature Webhook签名校验失败
+var ErrWebhookSignature = errors.New("Webhook签名校验失败")
+
+// ErrWebhookDuplicate 重复投递
+var ErrWebhookDuplicate = errors.New("重复的Webhook投递")
+
+// WebhookService Git Webhook触发服务
+type WebhookService struct{}
+
+// WebhookEvent 从不同平台的推送事件中提取的公共字段
+type WebhookEvent struct {
+	Provider   string   // github, gitlab, gitea
+	Event      string   // 平台的事件名
+	DeliveryID string   // 投递ID
+	Ref        string   // 完整的Git引用
+	Commit     string   // 推送后的提交
+	Pusher     string   // 推送者
+	RepoURLs   []string // 仓库的各种地址
+	Deleted    bool     // 是否为删除分支或标签
+}
+
+// IsTag 是否为标签推送
+func (e *WebhookEvent) IsTag() bool {
+	return strings.HasPrefix(e.Ref, "refs/tags/")
+}
+
+// ShortRef 去掉前缀的分支名或标签名
+func (e *WebhookEvent) ShortRef() string {
+	return strings.TrimPrefix(strings.TrimPrefix(e.Ref, "refs/heads/"), "refs/tags/")
+}
+
+// webhookPayload 三个平台推送事件的并集
+type webhookPayload struct {
+	Ref         string `json:"ref"`
+	After       string `json:"after"`
+	CheckoutSHA string `json:"checkout_sha"`
+	Deleted     bool   `json:"deleted"`
+	Pusher      struct {
+		Name     string `json:"name"`
+		Login    string `json:"login"`
+		Username string `json:"username"`
+	} `json:"pusher"`
+	UserUsername string `json:"user_username"`
+	UserName     string `json:"user_name"`
+	Repository   struct {
+		CloneURL string `json:"clone_url"`
+		HTMLURL  string `json:"html_url"`
+		SSHURL   string `json:"ssh_url"`
+		URL      string `json:"url"`
+		Homepage string `json:"homepage"`
+	} `json:"repository"`
+	Project struct {
+		GitHTTPURL string `json:"git_http_url"`
+		GitSSHURL  string `json:"git_ssh_url"`
+		WebURL     string `json:"web_url"`
+	} `json:"project"`
+}
+
+// GenerateWebhookSecret 生成新的Webhook密钥
+func (s *WebhookService) GenerateWebhookSecret(pipelineID uint) (string, error) {
+	secret, err := utils.RandomToken(20)
+	if err != nil {
+		return "", err
+	}
+	if err := global.DB.Model(&model.Pipeline{}).
+		Where("id = ?", pipelineID).
+		Update("webhook_secret", secret).Error; err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// ParseWebhook 识别平台、校验签名并解析推送事件
+func (s *WebhookService) ParseWebhook(header http.Header, body []byte, secret string) (*WebhookEvent, error) {
+	event := &WebhookEvent{}
+
+	// Gitea同时会发送GitHub和Gogs的兼容头，因此需要先判断
+	switch {
+	case header.Get("X-Gitea-Event") != "":
+		event.Provider = "gitea"
+		event.Event = header.Get("X-Gitea-Event")
+		event.DeliveryID = header.Get("X-Gitea-Delivery")
+		if !verifyHMAC(secret, body, header.Get("X-Gitea-Signature")) {
+			return nil, ErrWebhookSignature
+		}
+	case header.Get("X-Gitlab-Event") != "":
+		event.Provider = "gitlab"
+		event.Event = header.Get("X-Gitlab-Event")
+		event.DeliveryID = header.Get("X-Gitlab-Event-UUID")
+		if event.DeliveryID == "" {
+			event.DeliveryID = header.Get("Idempotency-Key")
+		}
+		// GitLab不签名，只在请求头中携带配置的令牌
+		token := header.Get("X-Gitlab-Token")
+		if secret == "" || subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+			return nil, ErrWebhookSignature
+		}
+	case header.Get("X-GitHub-Event") != "":
+		event.Provider = "github"
+		event.Event = header.Get("X-GitHub-Event")
+		event.DeliveryID = header.Get("X-GitHub-Delivery")
+		signature := strings.TrimPrefix(header.Get("X-Hub-Signature-256"), "sha256=")
+		if !verifyHMAC(secret, body, signature) {
+			return nil, ErrWebhookSignature
+		}
+	default:
+		return nil, errors.New("无法识别的Webhook来源")
+	}
+
+	// 没有投递ID时使用请求体摘要去重
+	if event.DeliveryID == "" {
+		sum := sha256.Sum256(body)
+		event.DeliveryID = hex.EncodeToString(sum[:])
+	}
+
+	var payload webhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("解析Webhook请求体失败: %w", err)
+	}
+
+	event.Ref = payload.Ref
+	event.Commit = payload.After
+	if payload.CheckoutSHA != "" {
+		event.Commit = payload.CheckoutSHA
+	}
+	event.Deleted = payload.Deleted || strings.Trim(payload.After, "0") == "" && payload.After != ""
+
+	for _, name := range []string{payload.Pusher.Login, payload.Pusher.Username, payload.Pusher.Name, payload.UserUsername, payload.UserName} {
+		if name != "" {
+			event.Pusher = name
+			break
+		}
+	}
+
+	for _, url := range []string{
+		payload.Repository.CloneURL, payload.Repository.HTMLURL, payload.Repository.SSHURL,
+		payload.Repository.URL, payload.Repository.Homepage,
+		payload.Project.GitHTTPURL, payload.Project.GitSSHURL, payload.Project.WebURL,
+	} {
+		if url != "" {
+			event.RepoURLs = append(event.RepoURLs, url)
+		}
+	}
+	return event, nil
+}
+
+// verifyHMAC 校验HMAC-SHA256签名（十六进制）
+func verifyHMAC(secret string, body []byte, signature string) bool {
+	if secret == "" || signature == "" {
+		return false
+	}
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// isPushEvent 是否为推送或标签推送事件
+func isPushEvent(event *WebhookEvent) bool {
+	switch event.Provider {
+	case "github", "gitea":
+		return event.Event == "push"
+	case "gitlab":
+		return event.Event == "Push Hook" || event.Event == "Tag Push Hook"
+	}
+	return false
+}
+
+// normalizeRepoURL 将各种格式的仓库地址统一为 host/owner/repo
+func normalizeRepoURL(url string) string {
+	url = strings.ToLower(strings.TrimSpace(url))
+	if i := strings.Index(url, "://"); i >= 0 {
+		url = url[i+3:]
+	} else if i := strings.Index(url, ":"); i >= 0 && !strings.Contains(url[:i], "/") {
+		// scp格式: git@host:owner/repo.git
+		url = url[:i] + "/" + url[i+1:]
+	}
+	if i := strings.Index(url, "@"); i >= 0 && i < strings.Index(url+"/", "/") {
+		url = url[i+1:]
+	}
+	url = strings.TrimSuffix(strings.TrimSuffix(url, "/"), ".git")
+	return url
+}
+
+// matchRefFilter 判断名称是否匹配逗号分隔的通配符列表
+func matchRefFilter(filter, name string) bool {
+	for _, pattern := range strings.Split(filter, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if matched, err := path.Match(pattern, name); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPipeline 判断事件是否应触发流水线，不触发时返回原因
+func matchPipeline(pipeline *model.Pipeline, event *WebhookEvent) string {
+	if !isPushEvent(event) {
+		return "不支持的事件: " + event.Event
+	}
+	if event.Deleted {
+		return "删除分支或标签不触发"
+	}
+
+	repo := normalizeRepoURL(pipeline.GitRepo)
+	matched := false
+	for _, url := range event.RepoURLs {
+		if normalizeRepoURL(url) == repo {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return "仓库与流水线不匹配"
+	}
+
+	name := event.ShortRef()
+	if event.IsTag() {
+		if !matchRefFilter(pipeline.WebhookTagFilter, name) {
+			return "标签不匹配: " + name
+		}
+		return ""
+	}
+
+	filter := pipeline.WebhookBranchFilter
+	if filter == "" {
+		filter = pipeline.GitBranch
+	}
+	if !matchRefFilter(filter, name) {
+		return "分支不匹配: " + name
+	}
+	return ""
+}
+
+// HandleWebhook 处理流水线的Webhook投递，返回投递记录
+// parameters 来自查询参数，只对以令牌认证的GitLab生效：GitHub和Gitea的签名只覆盖请求体，
+// 截获的投递可以改写查询参数后重放
+func (s *WebhookService) HandleWebhook(ctx context.Context, pipelineID uint, header http.Header, body []byte, parameters map[string]interface{}) (*model.WebhookDelivery, error) {
+	var pipeline model.Pipeline
+	if err := global.DB.First(&pipeline, pipelineID).Error; err != nil {
+		return nil, err
+	}
+
+	event, err := s.ParseWebhook(header, body, pipeline.WebhookSecret)
+	if err != nil {
+		return nil, err
+	}
+	if event.Provider != "gitlab" {
+		parameters = nil
+	}
+
+	delivery := model.WebhookDelivery{
+		PipelineID: pipeline.ID,
+		Provider:   event.Provider,
+		DeliveryID: event.DeliveryID,
+		Event:      event.Event,
+		Ref:        event.Ref,
+		Commit:     event.Commit,
+	}
+
+	claimed, err := s.claimDelivery(&delivery)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, ErrWebhookDuplicate
+	}
+
+	updates := map[string]interface{}{}
+	if reason := matchPipeline(&pipeline, event); reason != "" {
+		delivery.Status = WebhookIgnored
+		delivery.Message = reason
+	} else {
+		workflowService := NewWorkflowService()
+		run, err := workflowService.TriggerWorkflow(pipeline.ID, pipeline.CreatorID, TriggerOptions{
+			GitBranch:   event.ShortRef(),
+			GitCommit:   event.Commit,
+			GitRef:      event.Ref,
+			Pusher:      event.Pusher,
+			Parameters:  parameters,
+			TriggerType: "webhook",
+			Context:     ctx,
+		})
+		if err != nil {
+			delivery.Status = WebhookFailed
+			delivery.Message = err.Error()
+		} else {
+			delivery.Status = WebhookTriggered
+			delivery.RunID = run.ID
+			updates["run_id"] = run.ID
+		}
+	}
+
+	updates["status"] = delivery.Status
+	updates["message"] = delivery.Message
+	if err := global.DB.Model(&delivery).Updates(updates).Error; err != nil {
+		global.Log.Error("更新Webhook投递记录失败", zap.Error(err))
+	}
+
+	global.Log.Info("处理Webhook投递",
+		zap.Uint("pipelineID", pipeline.ID),
+		zap.String("provider", event.Provider),
+		zap.String("ref", event.Ref),
+		zap.String("status", delivery.Status))
+	return &delivery, nil
+}
+
+// claimDelivery 插入投递记录，返回false表示重复投递
+// 通过唯一索引去重；之前处理失败的投递(如触发时数据库出错)允许平台重新投递时再次处理
+func (s *WebhookService) claimDelivery(delivery *model.WebhookDelivery) (bool, error) {
+	result := global.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(delivery)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return true, nil
+	}
+
+	// 条件更新失败的记录，并发的重新投递只有一个能更新成功
+	result = global.DB.Model(&model.WebhookDelivery{}).
+		Where("pipeline_id = ? AND provider = ? AND delivery_id = ? AND status = ?",
+			delivery.PipelineID, delivery.Provider, delivery.DeliveryID, WebhookFailed).
+		Updates(map[string]interface{}{
+			"event":   delivery.Event,
+			"ref":     delivery.Ref,
+			"commit":  delivery.Commit,
+			"status":  "",
+			"message": "",
+			"run_id":  0,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	if err := global.DB.Where("pipeline_id = ? AND provider = ? AND delivery_id = ?",
+		delivery.PipelineID, delivery.Provider, delivery.DeliveryID).First(delivery).Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetDeliveries 获取流水线最近的Webhook投递记录
+func (s *WebhookService) GetDeliveries(pipelineID uint, limit int) ([]model.WebhookDelivery, error) {
+	var deliveries []model.WebhookDelivery
+	if err := global.DB.Where("pipeline_id = ?", pipelineID).
+		Order("id DESC").
+		Limit(limit).
+		Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
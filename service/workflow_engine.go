@@ -2,9 +2,15 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"gin_pipeline/global"
+	"gin_pipeline/model"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"sync"
 	"time"
 )
@@ -26,6 +32,10 @@ type WorkflowTask struct {
 	EndTime      *time.Time
 	Logs         string
 	Error        string
+	RunID        uint                   // 所属的流水线运行ID
+	Outputs      map[string]interface{} // 任务输出
+	RunsOn       string                 // 执行器标签表达式
+	Env          map[string]string      // 任务环境变量，包括 PARAM_ 开头的触发参数
 }
 
 // WorkflowEngine 工作流引擎
@@ -60,12 +70,16 @@ func (e *WorkflowEngine) GetExecutor(taskType string) (TaskExecutor, error) {
 }
 
 // ExecuteWorkflow 执行工作流
-func (e *WorkflowEngine) ExecuteWorkflow(ctx context.Context, tasks []*WorkflowTask, runID uint) error {
+func (e *WorkflowEngine) ExecuteWorkflow(ctx context.Context, tasks []*WorkflowTask, runID uint) (err error) {
+	ctx, span := tracer.Start(ctx, "workflow.execute", trace.WithAttributes(attrRunID.Int64(int64(runID))))
+	defer func() { endSpan(span, err) }()
+
 	// 构建任务依赖图
 	taskMap := make(map[string]*WorkflowTask)
 	for _, task := range tasks {
 		taskMap[task.ID] = task
 		task.Status = "pending"
+		task.RunID = runID
 	}
 
 	// 验证依赖关系
@@ -85,60 +99,52 @@ func (e *WorkflowEngine) ExecuteWorkflow(ctx context.Context, tasks []*WorkflowT
 	var wg sync.WaitGroup
 	// 创建错误通道
 	errChan := make(chan error, len(tasks))
-	// 创建任务完成通道
-	doneChan := make(chan string, len(tasks))
-
-	// 跟踪已完成的任务
+	// 跟踪已完成和已启动的任务，多个依赖同时完成时都会尝试启动汇合任务，
+	// 先在锁内标记已启动，保证每个任务只启动一次
 	completedTasks := make(map[string]bool)
-	var completedMutex sync.Mutex
+	scheduledTasks := make(map[string]bool)
+	var scheduleMutex sync.Mutex
 
-	// 启动任务监控协程
-	go func() {
-		for {
-			select {
-			case <-cancelCtx.Done():
-				return
-			case taskID := <-doneChan:
-				completedMutex.Lock()
-				completedTasks[taskID] = true
-				completedMutex.Unlock()
-
-				// 检查是否有新任务可以启动
-				for _, task := range tasks {
-					if task.Status != "pending" {
-						continue
-					}
-
-					// 检查依赖是否都已完成
-					allDepsCompleted := true
-					for _, depID := range task.Dependencies {
-						completedMutex.Lock()
-						completed := completedTasks[depID]
-						completedMutex.Unlock()
-
-						if !completed {
-							allDepsCompleted = false
-							break
-						}
-					}
-
-					if allDepsCompleted {
-						// 启动任务
-						wg.Add(1)
-						go e.executeTask(cancelCtx, task, &wg, errChan, doneChan, runID)
-					}
+	// scheduleReady 启动依赖都已完成且尚未启动的任务，调用方需持有scheduleMutex
+	var onDone func(taskID string)
+	scheduleReady := func() {
+		if cancelCtx.Err() != nil {
+			return
+		}
+		for _, task := range tasks {
+			if scheduledTasks[task.ID] {
+				continue
+			}
+
+			// 检查依赖是否都已完成
+			allDepsCompleted := true
+			for _, depID := range task.Dependencies {
+				if !completedTasks[depID] {
+					allDepsCompleted = false
+					break
 				}
 			}
-		}
-	}()
 
-	// 启动没有依赖的任务
-	for _, task := range tasks {
-		if len(task.Dependencies) == 0 {
-			wg.Add(1)
-			go e.executeTask(cancelCtx, task, &wg, errChan, doneChan, runID)
+			if allDepsCompleted {
+				// 启动任务
+				scheduledTasks[task.ID] = true
+				wg.Add(1)
+				go e.executeTask(cancelCtx, task, &wg, errChan, onDone, runID)
+			}
 		}
 	}
+	// 任务结束时在自身协程内同步启动后续任务，保证等待组计数在后续任务启动前不会归零
+	onDone = func(taskID string) {
+		scheduleMutex.Lock()
+		defer scheduleMutex.Unlock()
+		completedTasks[taskID] = true
+		scheduleReady()
+	}
+
+	// 启动没有依赖的任务
+	scheduleMutex.Lock()
+	scheduleReady()
+	scheduleMutex.Unlock()
 
 	// 等待所有任务完成或出错
 	go func() {
@@ -163,50 +169,132 @@ func (e *WorkflowEngine) ExecuteWorkflow(ctx context.Context, tasks []*WorkflowT
 }
 
 // executeTask 执行单个任务
-func (e *WorkflowEngine) executeTask(ctx context.Context, task *WorkflowTask, wg *sync.WaitGroup, errChan chan<- error, doneChan chan<- string, runID uint) {
+func (e *WorkflowEngine) executeTask(ctx context.Context, task *WorkflowTask, wg *sync.WaitGroup, errChan chan<- error, onDone func(taskID string), runID uint) {
 	defer wg.Done()
 
+	ctx, span := tracer.Start(ctx, "task "+task.ID, trace.WithAttributes(
+		attrRunID.Int64(int64(runID)),
+		attrNodeID.String(task.ID),
+		attrNodeName.String(task.Name),
+		attrNodeType.String(task.Type),
+	))
+	var taskErr error
+	defer func() {
+		span.SetAttributes(attrTaskStatus.String(task.Status))
+		endSpan(span, taskErr)
+	}()
+
 	// 更新任务状态为运行中
 	task.Status = "running"
 	now := time.Now()
 	task.StartTime = &now
 
 	// 更新数据库中的任务状态
-	e.updateTaskStatus(runID, task.ID, "running", "", "")
+	e.updateTaskStatus(ctx, runID, task.ID, "running", "", "")
+	PublishEvent(TaskStarted{RunID: runID, TaskID: task.ID, TaskName: task.Name, TaskType: task.Type, Time: now})
+	tasksRunning.WithLabelValues(task.Type).Inc()
+	defer tasksRunning.WithLabelValues(task.Type).Dec()
 
 	// 获取任务执行器
 	executor, err := e.GetExecutor(task.Type)
 	if err != nil {
 		task.Status = "failed"
 		task.Error = err.Error()
+		taskErr = err
+		executorErrorsTotal.WithLabelValues(task.Type).Inc()
 		errChan <- err
-		e.updateTaskStatus(runID, task.ID, "failed", "", err.Error())
+		e.updateTaskStatus(ctx, runID, task.ID, "failed", "", err.Error())
+		e.publishTaskFinished(task, runID, now)
 		return
 	}
 
+	// 如果任务配置了超时时间，则在超时后取消任务
+	taskCtx := ctx
+	if timeout := configInt(task.Config, "timeout", 0); timeout > 0 {
+		var cancel context.CancelFunc
+		taskCtx, cancel = context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+		defer cancel()
+	}
+
 	// 执行任务
-	err = executor.Execute(ctx, task)
+	err = executor.Execute(taskCtx, task)
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		err = fmt.Errorf("任务执行超时(%d秒)", configInt(task.Config, "timeout", 0))
+	}
 	endTime := time.Now()
 	task.EndTime = &endTime
+	if err != nil && ctx.Err() == nil {
+		executorErrorsTotal.WithLabelValues(task.Type).Inc()
+	}
 
 	if err != nil {
 		task.Status = "failed"
 		task.Error = err.Error()
+		taskErr = err
 		errChan <- err
-		e.updateTaskStatus(runID, task.ID, "failed", task.Logs, err.Error())
+		e.updateTaskStatus(ctx, runID, task.ID, "failed", task.Logs, err.Error())
 	} else {
 		task.Status = "success"
-		e.updateTaskStatus(runID, task.ID, "success", task.Logs, "")
+		e.updateTaskStatus(ctx, runID, task.ID, "success", task.Logs, "")
 	}
+	taskDurationSeconds.WithLabelValues(task.Type, task.Status).Observe(endTime.Sub(now).Seconds())
+	e.publishTaskFinished(task, runID, now)
 
-	// 通知任务完成
-	doneChan <- task.ID
+	// 任务成功后启动后续任务，失败的任务会取消整个工作流
+	if task.Status == "success" {
+		onDone(task.ID)
+	}
+}
+
+// publishTaskFinished 发布任务结束事件
+func (e *WorkflowEngine) publishTaskFinished(task *WorkflowTask, runID uint, startTime time.Time) {
+	endTime := time.Now()
+	if task.EndTime != nil {
+		endTime = *task.EndTime
+	}
+	PublishEvent(TaskFinished{
+		RunID:    runID,
+		TaskID:   task.ID,
+		TaskName: task.Name,
+		TaskType: task.Type,
+		Status:   task.Status,
+		Error:    task.Error,
+		Duration: endTime.Sub(startTime).Seconds(),
+		Time:     endTime,
+	})
 }
 
 // updateTaskStatus 更新任务状态
-func (e *WorkflowEngine) updateTaskStatus(runID uint, taskID string, status string, logs string, errMsg string) {
-	// 在实际项目中，这里应该更新数据库中的任务状态
-	// 例如，可以将任务状态和日志保存到pipeline_run_tasks表中
+func (e *WorkflowEngine) updateTaskStatus(ctx context.Context, runID uint, taskID string, status string, logs string, errMsg string) {
+	// 任务被取消后仍需保存状态
+	db := global.DB.WithContext(context.WithoutCancel(ctx))
+
+	// 记录到运行的任务状态中，并发任务各自更新自己的键
+	if err := db.Model(&model.PipelineRun{}).Where("id = ?", runID).
+		Update("task_statuses", gorm.Expr("JSON_SET(COALESCE(task_statuses, JSON_OBJECT()), CONCAT('$.', JSON_QUOTE(?)), ?)", taskID, status)).Error; err != nil {
+		global.Log.Error("保存任务状态失败", zap.Uint("runID", runID), zap.String("taskID", taskID), zap.Error(err))
+	}
+
+	// 记录任务的执行时间，用于估算任务耗时
+	now := time.Now()
+	var err error
+	if status == "running" {
+		err = db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "run_id"}, {Name: "node_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"status", "started_at", "finished_at", "duration", "error", "updated_at"}),
+		}).Create(&model.PipelineRunTask{RunID: runID, NodeID: taskID, Status: status, StartedAt: &now}).Error
+	} else {
+		err = db.Model(&model.PipelineRunTask{}).Where("run_id = ? AND node_id = ?", runID, taskID).Updates(map[string]interface{}{
+			"status":      status,
+			"finished_at": now,
+			"duration":    gorm.Expr("TIMESTAMPDIFF(MICROSECOND, started_at, ?) / 1000000", now),
+			"error":       errMsg,
+		}).Error
+	}
+	if err != nil {
+		global.Log.Error("保存任务执行记录失败", zap.Uint("runID", runID), zap.String("taskID", taskID), zap.Error(err))
+	}
+
 	global.Log.Info("更新任务状态",
 		zap.Uint("runID", runID),
 		zap.String("taskID", taskID),
@@ -221,3 +309,28 @@ func (e *WorkflowEngine) CancelWorkflow(runID uint) error {
 	global.Log.Info("取消工作流", zap.Uint("runID", runID))
 	return nil
 }
+
+// configString 从任务配置中读取字符串
+func configString(config map[string]interface{}, key string, defaultValue string) string {
+	if value, ok := config[key].(string); ok && value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// configInt 从任务配置中读取整数，兼容JSON解析得到的float64
+func configInt(config map[string]interface{}, key string, defaultValue int) int {
+	switch value := config[key].(type) {
+	case int:
+		return value
+	case int64:
+		return int(value)
+	case float64:
+		return int(value)
+	case json.Number:
+		if n, err := value.Int64(); err == nil {
+			return int(n)
+		}
+	}
+	return defaultValue
+}
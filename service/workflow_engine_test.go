@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// countingExecutor 记录每个任务的执行次数
+type countingExecutor struct {
+	mutex sync.Mutex
+	calls map[string]int
+}
+
+func (e *countingExecutor) Execute(ctx context.Context, task *WorkflowTask) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.calls[task.ID]++
+	return nil
+}
+
+func TestExecuteWorkflowStartsFanInTasksOnce(t *testing.T) {
+	// 阶段之间全连接依赖：每个作业依赖上一阶段的全部作业
+	stages := [][]string{{"build-a", "build-b", "build-c"}, {"test-a", "test-b", "test-c"}, {"deploy"}}
+	var tasks []*WorkflowTask
+	var previous []string
+	for _, stage := range stages {
+		for _, id := range stage {
+			tasks = append(tasks, &WorkflowTask{ID: id, Name: id, Type: "count", Dependencies: previous})
+		}
+		previous = stage
+	}
+
+	mock := useMockDB(t)
+	mock.MatchExpectationsInOrder(false)
+	// 每个任务开始和结束时各更新一次运行状态和执行记录
+	for range tasks {
+		for i := 0; i < 3; i++ {
+			mock.ExpectExec("^UPDATE").WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+		mock.ExpectExec("^INSERT INTO `pipeline_run_tasks`").WillReturnResult(sqlmock.NewResult(1, 1))
+	}
+
+	executor := &countingExecutor{calls: make(map[string]int)}
+	engine := NewWorkflowEngine()
+	engine.RegisterExecutor("count", executor)
+	if err := engine.ExecuteWorkflow(context.Background(), tasks, 1); err != nil {
+		t.Fatalf("ExecuteWorkflow() error = %v", err)
+	}
+
+	// ExecuteWorkflow返回时所有任务都应恰好执行一次
+	executor.mutex.Lock()
+	defer executor.mutex.Unlock()
+	for _, task := range tasks {
+		if got := executor.calls[task.ID]; got != 1 {
+			t.Errorf("task %s executed %d times, want 1", task.ID, got)
+		}
+		if task.Status != "success" {
+			t.Errorf("task %s status = %q, want success", task.ID, task.Status)
+		}
+	}
+}
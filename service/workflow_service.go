@@ -3,9 +3,14 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"gin_pipeline/global"
 	"gin_pipeline/model"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 	"time"
 )
 
@@ -23,45 +28,193 @@ func NewWorkflowService() *WorkflowService {
 	engine.RegisterExecutor("docker", &DockerTaskExecutor{})
 	engine.RegisterExecutor("kubernetes", &KubernetesTaskExecutor{})
 
+	// 注册插件提供的任务执行器
+	GetPluginManager().RegisterExecutors(engine)
+
+	// 启用远程执行器时，指定类型的任务分发给远程执行器
+	if global.Config.Runner.Enabled {
+		for _, taskType := range remoteTaskTypes() {
+			engine.RegisterExecutor(taskType, &RemoteTaskExecutor{})
+		}
+	}
+
 	return &WorkflowService{
 		engine: engine,
 	}
 }
 
+// TriggerOptions 触发工作流的参数
+type TriggerOptions struct {
+	GitBranch   string                 // 为空时使用流水线的默认分支
+	GitCommit   string                 // 触发的提交
+	GitRef      string                 // 完整的Git引用
+	Pusher      string                 // Webhook推送者
+	Priority    *int                   // 为空时使用流水线的默认优先级
+	Parameters  map[string]interface{} // 触发参数，未提供的参数使用默认值
+	ScheduleID  uint                   // 触发该运行的定时任务
+	TriggerType string                 // manual, schedule, webhook，为空时为manual
+	Context     context.Context        // 触发方的上下文，运行的链路会链接到其中的span
+}
+
 // TriggerWorkflow 触发工作流
-func (s *WorkflowService) TriggerWorkflow(pipelineID uint, userID uint, gitBranch string) (*model.PipelineRun, error) {
+func (s *WorkflowService) TriggerWorkflow(pipelineID uint, userID uint, opts TriggerOptions) (*model.PipelineRun, error) {
+	// 每次运行是一条独立的链路，覆盖触发、排队和执行
+	triggerType := opts.TriggerType
+	if triggerType == "" {
+		triggerType = "manual"
+	}
+	runCtx, runSpan := startRunSpan(opts.Context, pipelineID, triggerType)
+
+	pipelineRun, queued, err := s.triggerWorkflow(runCtx, pipelineID, userID, opts)
+	if err != nil {
+		endRunSpan(runSpan, "failed", err)
+		return nil, err
+	}
+
+	runSpan.SetAttributes(runSpanAttributes(pipelineRun)...)
+	if !queued {
+		// 被跳过的运行不会执行，链路到此结束
+		endRunSpan(runSpan, pipelineRun.Status, nil)
+	}
+
+	// 在触发方的链路中记录运行及其链路ID，便于从请求找到运行
+	if opts.Context != nil {
+		trace.SpanFromContext(opts.Context).AddEvent("pipeline.run.triggered", trace.WithAttributes(
+			attrRunID.Int64(int64(pipelineRun.ID)),
+			attribute.String("pipeline.run.trace_id", pipelineRun.TraceID),
+		))
+	}
+	return pipelineRun, nil
+}
+
+// triggerWorkflow 创建运行记录并加入调度队列，第二个返回值表示运行是否已入队
+// runCtx 携带运行的根span，入队后交给调度器在执行结束时关闭
+func (s *WorkflowService) triggerWorkflow(runCtx context.Context, pipelineID uint, userID uint, opts TriggerOptions) (*model.PipelineRun, bool, error) {
+	ctx, span := tracer.Start(runCtx, "pipeline.trigger")
+	defer span.End()
+	db := global.DB.WithContext(ctx)
+
 	// 获取流水线
 	var pipeline model.Pipeline
-	if err := global.DB.First(&pipeline, pipelineID).Error; err != nil {
+	if err := db.First(&pipeline, pipelineID).Error; err != nil {
 		global.Log.Error("获取流水线失败", zap.Error(err))
-		return nil, err
+		return nil, false, err
 	}
 
-	// 获取活动DAG
-	dagService := new(DAGService)
-	dag, err := dagService.GetActiveDAGByPipelineID(pipelineID)
+	gitBranch := opts.GitBranch
+	if gitBranch == "" {
+		gitBranch = pipeline.GitBranch
+	}
+
+	// 校验触发参数并补全默认值
+	parameters, err := ResolveTriggerParameters(pipeline.Parameters, opts.Parameters)
 	if err != nil {
-		global.Log.Error("获取活动DAG失败", zap.Error(err))
-		return nil, err
+		return nil, false, err
+	}
+
+	// 优先使用仓库中的流水线文件，其次是活动DAG，都没有时使用流水线的阶段/作业
+	// 拉取仓库较慢，流水线文件在创建运行记录后异步加载，这里只校验仓库地址和引用
+	var dag *model.DAG
+	dagSource := DAGSourcePipelineFile
+	gitCommit := opts.GitCommit
+	ref := opts.GitRef
+	if ref == "" {
+		ref = gitBranch
+	}
+	if global.Config.PipelineFile.Enabled && pipeline.GitRepo != "" {
+		if err := ValidateGitSource(pipeline.GitRepo, ref, gitCommit); err != nil {
+			return nil, false, err
+		}
+	} else {
+		dag, dagSource, err = resolveStoredDAG(pipelineID)
+		if err != nil {
+			return nil, false, err
+		}
+		if dag, err = expandRunDAG(dag); err != nil {
+			return nil, false, err
+		}
 	}
 
 	// 创建流水线运行记录
 	now := time.Now()
 	pipelineRun := model.PipelineRun{
-		PipelineID: pipelineID,
-		Status:     "pending",
-		StartTime:  &now,
-		GitBranch:  gitBranch,
-		TriggerBy:  userID,
+		PipelineID:       pipelineID,
+		Status:           "pending",
+		Priority:         pipeline.Priority,
+		StartTime:        &now,
+		GitBranch:        gitBranch,
+		TriggerBy:        userID,
+		ScheduleID:       opts.ScheduleID,
+		TriggerType:      opts.TriggerType,
+		GitCommit:        gitCommit,
+		GitRef:           opts.GitRef,
+		Pusher:           opts.Pusher,
+		Parameters:       parameters,
+		ConcurrencyGroup: resolveConcurrencyGroup(&pipeline, gitBranch),
+		DAGSource:        dagSource,
+		TraceID:          traceIDFromContext(ctx),
+		Instance:         SchedulerInstance(),
+	}
+	if dag != nil {
+		pipelineRun.DAGID = dag.ID
+		pipelineRun.DAGNodes = dag.NodesData
+	}
+
+	if opts.Priority != nil {
+		pipelineRun.Priority = *opts.Priority
+	}
+	if pipelineRun.TriggerType == "" {
+		pipelineRun.TriggerType = "manual"
 	}
 
-	if err := global.DB.Create(&pipelineRun).Error; err != nil {
+	// 同一并发组的触发通过分布式锁串行化，保证多实例下策略判断一致
+	if pipelineRun.ConcurrencyGroup != "" {
+		ctx := context.Background()
+		lock, err := AcquireLock(ctx, "concurrency:"+pipelineRun.ConcurrencyGroup, 30*time.Second, 10*time.Second)
+		if err != nil {
+			global.Log.Error("获取并发组锁失败", zap.Error(err))
+			return nil, false, fmt.Errorf("获取并发组锁失败: %w", err)
+		}
+		defer lock.Release(ctx)
+
+		if pipeline.ConcurrencyPolicy == ConcurrencySkip {
+			active, err := activeGroupRuns(pipelineRun.ConcurrencyGroup, 0)
+			if err != nil {
+				return nil, false, err
+			}
+			if active = liveGroupRuns(active); len(active) > 0 {
+				if err := skipSupersededRun(&pipelineRun, &active[0]); err != nil {
+					global.Log.Error("创建流水线运行记录失败", zap.Error(err))
+					return nil, false, err
+				}
+				return &pipelineRun, false, nil
+			}
+		}
+	}
+
+	if err := db.Create(&pipelineRun).Error; err != nil {
 		global.Log.Error("创建流水线运行记录失败", zap.Error(err))
-		return nil, err
+		return nil, false, err
+	}
+	PublishEvent(PipelineRunCreated{
+		RunID:       pipelineRun.ID,
+		PipelineID:  pipelineRun.PipelineID,
+		TriggerType: pipelineRun.TriggerType,
+		TriggerBy:   pipelineRun.TriggerBy,
+		GitBranch:   pipelineRun.GitBranch,
+		GitCommit:   pipelineRun.GitCommit,
+		Time:        pipelineRun.CreatedAt,
+	})
+
+	if pipelineRun.ConcurrencyGroup != "" && pipeline.ConcurrencyPolicy == ConcurrencyCancel {
+		if err := s.cancelSupersededRuns(&pipelineRun); err != nil {
+			global.Log.Error("取消被取代的运行失败", zap.Error(err))
+			// 不影响新的运行，继续执行
+		}
 	}
 
 	// 更新流水线状态
-	if err := global.DB.Model(&pipeline).Updates(map[string]interface{}{
+	if err := db.Model(&pipeline).Updates(map[string]interface{}{
 		"status":      "running",
 		"last_run_at": now,
 	}).Error; err != nil {
@@ -69,19 +222,154 @@ func (s *WorkflowService) TriggerWorkflow(pipelineID uint, userID uint, gitBranc
 		// 不影响结果，继续执行
 	}
 
-	// 异步执行工作流
-	go s.executeWorkflow(dag, &pipelineRun)
+	if dag == nil {
+		// 加载流水线文件后再入队，使用副本避免与返回给调用方的记录并发读写
+		run := pipelineRun
+		go s.loadPipelineFile(runCtx, &pipeline, &run, ref)
+		return &pipelineRun, true, nil
+	}
+
+	// 加入调度队列，有空闲名额时异步执行，运行的链路随队列传递给执行
+	GetRunScheduler().Enqueue(runCtx, s, dag, &pipelineRun)
 
-	return &pipelineRun, nil
+	return &pipelineRun, true, nil
 }
 
-// executeWorkflow 执行工作流
-func (s *WorkflowService) executeWorkflow(dag *model.DAG, pipelineRun *model.PipelineRun) {
-	// 更新运行状态为运行中
-	if err := global.DB.Model(pipelineRun).Update("status", "running").Error; err != nil {
+// resolveStoredDAG 获取流水线的活动DAG，没有时编译流水线的阶段/作业
+func resolveStoredDAG(pipelineID uint) (*model.DAG, string, error) {
+	dag, err := new(DAGService).GetActiveDAGByPipelineID(pipelineID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		dag, err = CompilePipelineStages(pipelineID)
+		if err != nil {
+			global.Log.Error("编译流水线阶段失败", zap.Error(err))
+			return nil, "", err
+		}
+		return dag, DAGSourceStages, nil
+	}
+	if err != nil {
+		global.Log.Error("获取活动DAG失败", zap.Error(err))
+		return nil, "", err
+	}
+	return dag, DAGSourceDAG, nil
+}
+
+// expandRunDAG 展开片段节点，跟随最新版本的片段在每次触发时重新解析
+func expandRunDAG(dag *model.DAG) (*model.DAG, error) {
+	if !HasFragmentNodes(dag.NodesData) {
+		return dag, nil
+	}
+	expanded, err := ExpandDAGFragments(dag.NodesData)
+	if err != nil {
+		global.Log.Error("展开DAG片段失败", zap.Error(err))
+		return nil, fmt.Errorf("展开DAG片段失败: %w", err)
+	}
+	expandedDAG := *dag
+	expandedDAG.NodesData = expanded
+	return &expandedDAG, nil
+}
+
+// loadPipelineFile 从仓库加载流水线文件作为运行的DAG快照后加入调度队列
+// 文件不存在时使用活动DAG；加载失败时运行直接失败，期间被取消的运行不再入队
+func (s *WorkflowService) loadPipelineFile(runCtx context.Context, pipeline *model.Pipeline, run *model.PipelineRun, ref string) {
+	runSpan := trace.SpanFromContext(runCtx)
+
+	_, fileSpan := tracer.Start(runCtx, "pipeline_file.load", trace.WithAttributes(attribute.String("git.ref", ref)))
+	dag, resolved, err := LoadPipelineFileDAG(pipeline, ref, run.GitCommit)
+	dagSource := DAGSourcePipelineFile
+	if errors.Is(err, ErrPipelineFileNotFound) {
+		endSpan(fileSpan, nil)
+		dag, dagSource, err = resolveStoredDAG(run.PipelineID)
+	} else {
+		endSpan(fileSpan, err)
+		if err != nil {
+			global.Log.Error("加载流水线文件失败", zap.Uint("runID", run.ID), zap.Error(err))
+			err = fmt.Errorf("加载流水线文件失败: %w", err)
+		}
+	}
+	if err == nil {
+		dag, err = expandRunDAG(dag)
+	}
+	if err != nil {
+		s.failPendingRun(run, err)
+		endRunSpan(runSpan, "failed", err)
+		return
+	}
+
+	if run.GitCommit == "" {
+		run.GitCommit = resolved
+	}
+	result := global.DB.Model(run).Where("status = ?", "pending").Updates(map[string]interface{}{
+		"dag_id":     dag.ID,
+		"dag_source": dagSource,
+		"dag_nodes":  model.DAGNodeList(dag.NodesData),
+		"git_commit": run.GitCommit,
+	})
+	if result.Error != nil {
+		global.Log.Error("保存运行DAG失败", zap.Uint("runID", run.ID), zap.Error(result.Error))
+		s.failPendingRun(run, result.Error)
+		endRunSpan(runSpan, "failed", result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		// 加载期间运行已被取消
+		endRunSpan(runSpan, "canceled", nil)
+		return
+	}
+	run.DAGID = dag.ID
+	run.DAGSource = dagSource
+	run.DAGNodes = dag.NodesData
+
+	GetRunScheduler().Enqueue(runCtx, s, dag, run)
+}
+
+// failPendingRun 将尚未入队的运行标记为失败，错误信息记录在运行日志中
+func (s *WorkflowService) failPendingRun(run *model.PipelineRun, cause error) {
+	now := time.Now()
+	result := global.DB.Model(run).Where("status = ?", "pending").Updates(map[string]interface{}{
+		"status":   "failed",
+		"end_time": now,
+		"logs":     cause.Error(),
+	})
+	if result.Error != nil {
+		global.Log.Error("更新流水线运行状态失败", zap.Uint("runID", run.ID), zap.Error(result.Error))
+		return
+	}
+	if result.RowsAffected == 0 {
+		return
+	}
+	run.Status = "failed"
+	run.EndTime = &now
+	PublishEvent(newPipelineRunFinished(run, "failed", 0))
+
+	if err := global.DB.Model(&model.Pipeline{}).Where("id = ?", run.PipelineID).Update("status", "failed").Error; err != nil {
+		global.Log.Error("更新流水线状态失败", zap.Error(err))
+	}
+}
+
+// executeWorkflow 执行工作流，ctx中携带运行的根span，执行结束时关闭
+func (s *WorkflowService) executeWorkflow(ctx context.Context, dag *model.DAG, pipelineRun *model.PipelineRun) {
+	runSpan := trace.SpanFromContext(ctx)
+	// 取消运行后仍需保存结果
+	db := global.DB.WithContext(context.WithoutCancel(ctx))
+
+	// 更新运行状态为运行中，开始时间为实际出队的时间
+	startTime := time.Now()
+	pipelineRun.StartTime = &startTime
+	if err := db.Model(pipelineRun).Updates(map[string]interface{}{
+		"status":     "running",
+		"start_time": startTime,
+	}).Error; err != nil {
 		global.Log.Error("更新流水线运行状态失败", zap.Error(err))
+		endRunSpan(runSpan, "failed", err)
 		return
 	}
+	PublishEvent(PipelineRunStarted{
+		RunID:       pipelineRun.ID,
+		PipelineID:  pipelineRun.PipelineID,
+		TriggerType: pipelineRun.TriggerType,
+		GitBranch:   pipelineRun.GitBranch,
+		Time:        startTime,
+	})
 
 	// 将DAG节点转换为工作流任务
 	var tasks []*WorkflowTask
@@ -95,29 +383,34 @@ func (s *WorkflowService) executeWorkflow(dag *model.DAG, pipelineRun *model.Pip
 			ID:           node.ID,
 			Name:         node.Name,
 			Type:         node.Type,
-			Config:       config,
+			Config:       InterpolateParameters(config, pipelineRun.Parameters),
+			Env:          ParameterEnv(pipelineRun.Parameters),
 			Dependencies: node.Dependencies,
+			RunsOn:       node.RunsOn,
 			Status:       "pending",
 		}
 		tasks = append(tasks, task)
 	}
 
 	// 执行工作流
-	ctx := context.Background()
 	err := s.engine.ExecuteWorkflow(ctx, tasks, pipelineRun.ID)
 
 	// 更新运行结果
 	now := time.Now()
 	duration := int(now.Sub(*pipelineRun.StartTime).Seconds())
 	status := "success"
-	if err != nil {
+	if ctx.Err() != nil {
+		status = "canceled"
+	} else if err != nil {
 		status = "failed"
 		global.Log.Error("工作流执行失败", zap.Error(err))
 	}
 
-	// 收集任务日志
+	// 收集任务日志和状态
 	taskLogs := make(map[string]map[string]string)
+	taskStatuses := make(model.JSONMap, len(tasks))
 	for _, task := range tasks {
+		taskStatuses[task.ID] = task.Status
 		if taskLogs[task.Type] == nil {
 			taskLogs[task.Type] = make(map[string]string)
 		}
@@ -127,19 +420,37 @@ func (s *WorkflowService) executeWorkflow(dag *model.DAG, pipelineRun *model.Pip
 	logsJSON, _ := json.Marshal(taskLogs)
 
 	updates := map[string]interface{}{
-		"status":   status,
-		"end_time": now,
-		"duration": duration,
-		"logs":     string(logsJSON),
+		"status":        status,
+		"end_time":      now,
+		"duration":      duration,
+		"logs":          string(logsJSON),
+		"task_statuses": taskStatuses,
 	}
 
-	if err := global.DB.Model(pipelineRun).Updates(updates).Error; err != nil {
-		global.Log.Error("更新流水线运行结果失败", zap.Error(err))
+	// 运行可能已在其他实例上被取消，取消状态不能被覆盖
+	result := db.Model(pipelineRun).Where("status <> ?", "canceled").Updates(updates)
+	updateErr := result.Error
+	if updateErr == nil && result.RowsAffected == 0 {
+		status = "canceled"
+		updateErr = db.Model(pipelineRun).Updates(map[string]interface{}{
+			"logs":          string(logsJSON),
+			"task_statuses": taskStatuses,
+		}).Error
+	}
+	defer endRunSpan(runSpan, status, nil)
+
+	pipelineRun.Status = status
+	pipelineRun.EndTime = &now
+	pipelineRun.Duration = duration
+	pipelineRun.TaskStatuses = taskStatuses
+	PublishEvent(newPipelineRunFinished(pipelineRun, status, now.Sub(*pipelineRun.StartTime)))
+	if updateErr != nil {
+		global.Log.Error("更新流水线运行结果失败", zap.Error(updateErr))
 		return
 	}
 
 	// 更新流水线状态
-	if err := global.DB.Model(&model.Pipeline{}).Where("id = ?", pipelineRun.PipelineID).Update("status", status).Error; err != nil {
+	if err := db.Model(&model.Pipeline{}).Where("id = ?", pipelineRun.PipelineID).Update("status", status).Error; err != nil {
 		global.Log.Error("更新流水线状态失败", zap.Error(err))
 		return
 	}
@@ -169,6 +480,9 @@ func (s *WorkflowService) CancelWorkflow(runID uint) error {
 		return err
 	}
 
+	// 排队中的运行出队，运行中的运行取消其上下文
+	GetRunScheduler().Cancel(runID)
+
 	// 更新状态
 	now := time.Now()
 	updates := map[string]interface{}{
@@ -205,7 +519,8 @@ func (e *ShellTaskExecutor) Execute(ctx context.Context, task *WorkflowTask) err
 		return ctx.Err()
 	case <-time.After(2 * time.Second):
 		// 模拟执行成功
-		task.Logs = "Shell任务执行成功\n$ echo 'Hello World'\nHello World"
+		command := configString(task.Config, "command", "echo 'Hello World'")
+		task.Logs = fmt.Sprintf("Shell任务执行成功\n$ %s", command)
 		return nil
 	}
 }
@@ -218,7 +533,8 @@ func (e *DockerTaskExecutor) Execute(ctx context.Context, task *WorkflowTask) er
 	// 模拟执行Docker命令
 	global.Log.Info("执行Docker任务",
 		zap.String("taskID", task.ID),
-		zap.String("name", task.Name))
+		zap.String("name", task.Name),
+		zap.String("image", configString(task.Config, "image", "")))
 
 	// 模拟执行时间
 	select {
@@ -226,7 +542,13 @@ func (e *DockerTaskExecutor) Execute(ctx context.Context, task *WorkflowTask) er
 		return ctx.Err()
 	case <-time.After(3 * time.Second):
 		// 模拟执行成功
-		task.Logs = "Docker任务执行成功\n$ docker run hello-world\nHello from Docker!"
+		image := configString(task.Config, "image", "hello-world")
+		command := configString(task.Config, "command", "")
+		if command == "" {
+			task.Logs = fmt.Sprintf("Docker任务执行成功\n$ docker run --rm %s", image)
+		} else {
+			task.Logs = fmt.Sprintf("Docker任务执行成功\n$ docker run --rm %s sh -c %q", image, command)
+		}
 		return nil
 	}
 }
@@ -20,9 +20,10 @@ var (
 
 // CustomClaims 自定义JWT载荷
 type CustomClaims struct {
-	ID       uint
-	Username string
-	Role     string
+	ID        uint
+	Username  string
+	Role      string
+	SessionID string `json:",omitempty"` // 会话ID，为空表示未启用会话管理
 	jwt.StandardClaims
 }
 
@@ -66,14 +67,28 @@ func (j *JWT) ParseToken(tokenString string) (*CustomClaims, error) {
 
 // GenerateToken 生成Token
 func (j *JWT) GenerateToken(userID uint, username, role string) (string, error) {
+	return j.GenerateSessionToken(userID, username, role, "")
+}
+
+// TokenExpire Token的有效期，属于会话的Token可以刷新，使用较短的access_expire
+func TokenExpire(sessionID string) time.Duration {
+	if sessionID != "" && global.Config.System.AccessExpire > 0 {
+		return time.Duration(global.Config.System.AccessExpire) * time.Second
+	}
+	return time.Duration(global.Config.System.JwtExpire) * time.Second
+}
+
+// GenerateSessionToken 生成属于指定会话的Token，会话注销后Token随之失效
+func (j *JWT) GenerateSessionToken(userID uint, username, role, sessionID string) (string, error) {
 	claims := CustomClaims{
-		ID:       userID,
-		Username: username,
-		Role:     role,
+		ID:        userID,
+		Username:  username,
+		Role:      role,
+		SessionID: sessionID,
 		StandardClaims: jwt.StandardClaims{
-			NotBefore: time.Now().Unix() - 1000,                                  // 签名生效时间
-			ExpiresAt: time.Now().Unix() + int64(global.Config.System.JwtExpire), // 过期时间
-			Issuer:    "gin-pipeline",                                            // 签名的发行者
+			NotBefore: time.Now().Unix() - 1000,                      // 签名生效时间
+			ExpiresAt: time.Now().Add(TokenExpire(sessionID)).Unix(), // 过期时间
+			Issuer:    "gin-pipeline",                                // 签名的发行者
 		},
 	}
 	return j.CreateToken(claims)